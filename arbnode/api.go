@@ -8,6 +8,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/offchainlabs/nitro/arbnode/dataposter"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/staker"
 	"github.com/offchainlabs/nitro/validator"
@@ -55,3 +56,144 @@ func (a *BlockValidatorDebugAPI) ValidateMessageNumber(
 	result.Valid = valid
 	return result, err
 }
+
+type BatchPosterAPI struct {
+	bp *BatchPoster
+}
+
+type BatchPosterPositionResult struct {
+	MessageCount        hexutil.Uint64 `json:"messageCount"`
+	DelayedMessageCount hexutil.Uint64 `json:"delayedMessageCount"`
+	NextSeqNum          hexutil.Uint64 `json:"nextSeqNum"`
+	NextNonce           hexutil.Uint64 `json:"nextNonce"`
+}
+
+func (a *BatchPosterAPI) Position(ctx context.Context) (*BatchPosterPositionResult, error) {
+	position, nonce, err := a.bp.Position(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchPosterPositionResult{
+		MessageCount:        hexutil.Uint64(position.MessageCount),
+		DelayedMessageCount: hexutil.Uint64(position.DelayedMessageCount),
+		NextSeqNum:          hexutil.Uint64(position.NextSeqNum),
+		NextNonce:           hexutil.Uint64(nonce),
+	}, nil
+}
+
+// BuildingBatch returns null if no batch is being built.
+func (a *BatchPosterAPI) BuildingBatch() *BuildingBatchStatus {
+	return a.bp.BuildingBatch()
+}
+
+type BatchPosterStatusResult struct {
+	Paused           bool                      `json:"paused"`
+	PostNowRequested bool                      `json:"postNowRequested"`
+	LastDestination  *BatchDestinationDecision `json:"lastDestination"`
+}
+
+func (a *BatchPosterAPI) Status() *BatchPosterStatusResult {
+	return &BatchPosterStatusResult{
+		Paused:           a.bp.Paused(),
+		PostNowRequested: a.bp.postNow.Load(),
+		LastDestination:  a.bp.LastDestination(),
+	}
+}
+
+type QueuedTransactionResult struct {
+	Nonce           hexutil.Uint64                 `json:"nonce"`
+	Hash            *common.Hash                   `json:"hash"`
+	GasFeeCap       *hexutil.Big                   `json:"gasFeeCap"`
+	GasTipCap       *hexutil.Big                   `json:"gasTipCap"`
+	BlobFeeCap      *hexutil.Big                   `json:"blobFeeCap,omitempty"`
+	Gas             hexutil.Uint64                 `json:"gas"`
+	Blobs           int                            `json:"blobs"`
+	Meta            hexutil.Bytes                  `json:"meta"`
+	Sent            bool                           `json:"sent"`
+	Created         time.Time                      `json:"created"`
+	NextReplacement time.Time                      `json:"nextReplacement"`
+	Replacements    []dataposter.ReplacementRecord `json:"replacements"`
+}
+
+type BatchPosterQueueResult struct {
+	Sender       common.Address            `json:"sender"`
+	Balance      *hexutil.Big              `json:"balance"`
+	Nonce        hexutil.Uint64            `json:"nonce"`
+	Transactions []QueuedTransactionResult `json:"transactions"`
+}
+
+// Queue returns up to maxResults (default 100) of the unconfirmed transactions of each batch poster account.
+func (a *BatchPosterAPI) Queue(ctx context.Context, maxResults *hexutil.Uint64) ([]*BatchPosterQueueResult, error) {
+	limit := uint64(100)
+	if maxResults != nil {
+		limit = uint64(*maxResults)
+	}
+	queues, err := a.bp.DataPoster().Queue(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*BatchPosterQueueResult, 0, len(queues))
+	for _, queue := range queues {
+		results = append(results, queueResult(queue))
+	}
+	return results, nil
+}
+
+func queueResult(queue *dataposter.QueueStatus) *BatchPosterQueueResult {
+	result := &BatchPosterQueueResult{
+		Sender:       queue.Sender,
+		Balance:      (*hexutil.Big)(queue.Balance),
+		Nonce:        hexutil.Uint64(queue.Nonce),
+		Transactions: make([]QueuedTransactionResult, 0, len(queue.Transactions)),
+	}
+	for _, tx := range queue.Transactions {
+		txResult := QueuedTransactionResult{
+			Nonce:           hexutil.Uint64(tx.Data.Nonce),
+			GasFeeCap:       (*hexutil.Big)(tx.Data.GasFeeCap),
+			GasTipCap:       (*hexutil.Big)(tx.Data.GasTipCap),
+			Gas:             hexutil.Uint64(tx.Data.Gas),
+			Meta:            tx.Meta,
+			Sent:            tx.Sent,
+			Created:         tx.Created,
+			NextReplacement: tx.NextReplacement,
+			Replacements:    queue.Replacements[tx.Data.Nonce],
+		}
+		if tx.FullTx != nil {
+			hash := tx.FullTx.Hash()
+			txResult.Hash = &hash
+			txResult.Blobs = len(tx.FullTx.BlobHashes())
+			if txResult.Blobs > 0 {
+				txResult.BlobFeeCap = (*hexutil.Big)(tx.FullTx.BlobGasFeeCap())
+			}
+		}
+		result.Transactions = append(result.Transactions, txResult)
+	}
+	return result
+}
+
+// PostNow posts the batch being built without waiting for it to fill up or for the max delay to pass.
+func (a *BatchPosterAPI) PostNow() {
+	a.bp.PostNow()
+}
+
+func (a *BatchPosterAPI) Pause() {
+	a.bp.Pause()
+}
+
+func (a *BatchPosterAPI) Resume() {
+	a.bp.Resume()
+}
+
+// BumpFee replaces the unconfirmed transaction with the given nonce, raising its fees, and returns the new hash.
+// The sender defaults to the batch poster's main account.
+func (a *BatchPosterAPI) BumpFee(ctx context.Context, nonce hexutil.Uint64, sender *common.Address) (common.Hash, error) {
+	from := a.bp.DataPoster().Sender()
+	if sender != nil {
+		from = *sender
+	}
+	tx, err := a.bp.DataPoster().BumpFee(ctx, from, uint64(nonce))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
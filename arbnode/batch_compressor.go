@@ -0,0 +1,124 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	batchPosterCompressionRatioGauge = metrics.NewRegisteredGaugeFloat64("arb/batchposter/compression/ratio", nil)
+	batchPosterCompressionTimer      = metrics.NewRegisteredTimer("arb/batchposter/compression/duration", nil)
+	batchPosterCloseTimer            = metrics.NewRegisteredTimer("arb/batchposter/compression/close/duration", nil)
+)
+
+// The most segments queued for the background compressor before adding segments blocks
+const backgroundCompressorQueueSize = 1024
+
+// backgroundCompressor compresses batch segments at the recompression level in its own thread,
+// as they're added to the batch. The result is identical to recompressing all segments when the batch
+// is closed, as the same segments are written to the brotli writer in the same order, but closing the
+// batch only has to wait for the most recent segments to be compressed.
+type backgroundCompressor struct {
+	ctx       context.Context
+	segments  chan []byte
+	done      chan struct{}
+	abandoned atomic.Bool
+
+	// These are only accessed by the compressing goroutine until done is closed
+	buffer           *bytes.Buffer
+	writer           *brotli.Writer
+	uncompressedSize int
+	compressTime     time.Duration
+	err              error
+}
+
+// newBackgroundCompressor launches a compressing thread on stopWaiter, which stops with it.
+func newBackgroundCompressor(stopWaiter *stopwaiter.StopWaiterSafe, level int, sizeHint int) (*backgroundCompressor, error) {
+	ctx, err := stopWaiter.GetContextSafe()
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, sizeHint))
+	c := &backgroundCompressor{
+		ctx:      ctx,
+		segments: make(chan []byte, backgroundCompressorQueueSize),
+		done:     make(chan struct{}),
+		buffer:   buffer,
+		writer:   brotli.NewWriterLevel(buffer, level),
+	}
+	if err := stopWaiter.LaunchThreadSafe(c.compress); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *backgroundCompressor) compress(ctx context.Context) {
+	defer close(c.done)
+	for {
+		var segment []byte
+		var ok bool
+		select {
+		case segment, ok = <-c.segments:
+		case <-ctx.Done():
+			c.err = ctx.Err()
+			return
+		}
+		if !ok {
+			return
+		}
+		if c.err != nil || c.abandoned.Load() {
+			continue
+		}
+		start := time.Now()
+		encoded, err := rlp.EncodeToBytes(segment)
+		if err != nil {
+			c.err = err
+			continue
+		}
+		lenWritten, err := c.writer.Write(encoded)
+		c.uncompressedSize += lenWritten
+		c.err = err
+		c.compressTime += time.Since(start)
+	}
+}
+
+// add queues a segment to be compressed. Segments must not be modified afterwards.
+// If the compressor has stopped, the segment is dropped and finish returns the error it stopped with.
+func (c *backgroundCompressor) add(segment []byte) {
+	select {
+	case c.segments <- segment:
+	case <-c.done:
+	}
+}
+
+// finish waits for all queued segments to be compressed and returns the buffer and writer they were
+// compressed into, along with the number of uncompressed bytes written. The writer isn't closed yet.
+func (c *backgroundCompressor) finish() (*bytes.Buffer, *brotli.Writer, int, error) {
+	close(c.segments)
+	select {
+	case <-c.done:
+	case <-c.ctx.Done():
+		// The thread may never have been launched if the batch poster was stopping
+		return nil, nil, 0, c.ctx.Err()
+	}
+	batchPosterCompressionTimer.Update(c.compressTime)
+	return c.buffer, c.writer, c.uncompressedSize, c.err
+}
+
+// abandon stops the compressing thread without waiting for it.
+func (c *backgroundCompressor) abandon() {
+	c.abandoned.Store(true)
+	close(c.segments)
+}
@@ -0,0 +1,156 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+// testStopWaiter returns a started StopWaiter to run background compressors on, stopped when the test ends.
+func testStopWaiter(t *testing.T) *stopwaiter.StopWaiterSafe {
+	t.Helper()
+	stopWaiter := &stopwaiter.StopWaiterSafe{}
+	Require(t, stopWaiter.Start(context.Background(), t))
+	t.Cleanup(func() {
+		Require(t, stopWaiter.StopAndWait())
+	})
+	return stopWaiter
+}
+
+func testBatchMessages(seed int64, count int) []*arbostypes.MessageWithMetadata {
+	rng := rand.New(rand.NewSource(seed))
+	var messages []*arbostypes.MessageWithMetadata
+	var delayedRead, blockNumber, timestamp uint64
+	for i := 0; i < count; i++ {
+		if rng.Intn(10) == 0 {
+			delayedRead++
+		}
+		if rng.Intn(3) == 0 {
+			blockNumber += uint64(rng.Intn(3))
+			timestamp += uint64(rng.Intn(20))
+		}
+		// Compressible but not trivially so
+		l2msg := make([]byte, 50+rng.Intn(500))
+		for j := range l2msg {
+			l2msg[j] = byte(rng.Intn(16))
+		}
+		messages = append(messages, &arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{
+					Kind:        arbostypes.L1MessageType_L2Message,
+					BlockNumber: blockNumber,
+					Timestamp:   timestamp,
+				},
+				L2msg: l2msg,
+			},
+			DelayedMessagesRead: delayedRead,
+		})
+	}
+	return messages
+}
+
+// buildTestBatch adds messages until the batch is full and returns the batch and how many messages it has.
+func buildTestBatch(t *testing.T, config *BatchPosterConfig, backlog uint64, messages []*arbostypes.MessageWithMetadata) ([]byte, int) {
+	t.Helper()
+	segments := newBatchSegments(0, config, backlog, BatchDestinationCalldata, testStopWaiter(t))
+	added := 0
+	for _, msg := range messages {
+		success, err := segments.AddMessage(msg)
+		Require(t, err)
+		if !success {
+			break
+		}
+		added++
+	}
+	batch, err := segments.CloseAndGetBytes()
+	Require(t, err)
+	return batch, added
+}
+
+func TestBackgroundCompressionMatchesRecompression(t *testing.T) {
+	messages := testBatchMessages(1, 600)
+	for _, level := range []int{2, 6, brotli.BestCompression} {
+		for _, backlog := range []uint64{0, 30, 50, 70} {
+			for _, maxSize := range []int{1000, 20000, 500000} {
+				config := TestBatchPosterConfig
+				config.CompressionLevel = level
+				config.MaxSize = maxSize
+
+				config.BackgroundCompression = false
+				expected, expectedCount := buildTestBatch(t, &config, backlog, messages)
+				config.BackgroundCompression = true
+				got, gotCount := buildTestBatch(t, &config, backlog, messages)
+
+				if gotCount != expectedCount {
+					Fail(t, "level", level, "backlog", backlog, "max size", maxSize, "background compression added", gotCount, "messages, expected", expectedCount)
+				}
+				if !bytes.Equal(got, expected) {
+					Fail(t, "level", level, "backlog", backlog, "max size", maxSize, "background compression output differs from recompression")
+				}
+				if len(expected) == 0 {
+					Fail(t, "empty batch")
+				}
+			}
+		}
+	}
+}
+
+func TestBackgroundCompressionTrailingHeaders(t *testing.T) {
+	config := TestBatchPosterConfig
+	config.MaxSize = 2000
+	for _, background := range []bool{false, true} {
+		config.BackgroundCompression = background
+		segments := newBatchSegments(0, &config, 0, BatchDestinationCalldata, testStopWaiter(t))
+		msg := testBatchMessages(2, 1)[0]
+		success, err := segments.AddMessage(msg)
+		Require(t, err)
+		if !success {
+			Fail(t, "failed to add first message")
+		}
+		segmentCount := len(segments.rawSegments)
+		// The timestamp header fits but the incompressible message after it doesn't, so the header is trimmed.
+		large := &arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{
+					Kind:        arbostypes.L1MessageType_L2Message,
+					BlockNumber: msg.Message.Header.BlockNumber,
+					Timestamp:   msg.Message.Header.Timestamp + 1,
+				},
+				L2msg: make([]byte, 4000),
+			},
+			DelayedMessagesRead: msg.DelayedMessagesRead,
+		}
+		rand.New(rand.NewSource(3)).Read(large.Message.L2msg)
+		success, err = segments.AddMessage(large)
+		Require(t, err)
+		if success {
+			Fail(t, "added message larger than the batch")
+		}
+		if len(segments.rawSegments) != segmentCount {
+			Fail(t, "background", background, "expected trailing header to be trimmed, have", len(segments.rawSegments), "segments instead of", segmentCount)
+		}
+		_, err = segments.CloseAndGetBytes()
+		Require(t, err)
+	}
+}
+
+func TestAbandonBackgroundCompression(t *testing.T) {
+	config := TestBatchPosterConfig
+	segments := newBatchSegments(0, &config, 0, BatchDestinationCalldata, testStopWaiter(t))
+	for _, msg := range testBatchMessages(4, 50) {
+		_, err := segments.AddMessage(msg)
+		Require(t, err)
+	}
+	background := segments.background
+	segments.abandon()
+	<-background.done
+}
@@ -0,0 +1,379 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/blobs"
+)
+
+// BatchDestination is where a batch's data is posted.
+type BatchDestination uint8
+
+const (
+	// BatchDestinationCalldata posts the batch data in the parent chain transaction's calldata.
+	BatchDestinationCalldata BatchDestination = iota
+	// BatchDestinationBlobs posts the batch data in EIP-4844 blobs.
+	BatchDestinationBlobs
+	// BatchDestinationDAS stores the batch data with the data availability service and posts its certificate.
+	BatchDestinationDAS
+)
+
+func (d BatchDestination) String() string {
+	switch d {
+	case BatchDestinationCalldata:
+		return "calldata"
+	case BatchDestinationBlobs:
+		return "blobs"
+	case BatchDestinationDAS:
+		return "das"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(d))
+	}
+}
+
+func (d BatchDestination) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func parseBatchDestination(s string) (BatchDestination, error) {
+	switch s {
+	case "calldata":
+		return BatchDestinationCalldata, nil
+	case "blobs":
+		return BatchDestinationBlobs, nil
+	case "das":
+		return BatchDestinationDAS, nil
+	default:
+		return 0, fmt.Errorf("unknown batch destination \"%v\" (expected \"calldata\", \"blobs\", or \"das\")", s)
+	}
+}
+
+// Reasons recorded for batch destination decisions, also used in metric names.
+const (
+	BatchDestinationReasonForcedWindow  = "forced-window"
+	BatchDestinationReasonForcedMode    = "forced-mode"
+	BatchDestinationReasonOnlyOption    = "only-option"
+	BatchDestinationReasonCheapest      = "cheapest"
+	BatchDestinationReasonDASPreferred  = "das-preferred"
+	BatchDestinationReasonL1Premium     = "l1-data-premium"
+	BatchDestinationReasonDASUnhealthy  = "das-unhealthy"
+	BatchDestinationReasonDASStoreFault = "das-store-failed"
+)
+
+// BatchDestinationInfo is what a BatchDestinationPolicy knows about the batch being built.
+type BatchDestinationInfo struct {
+	Now time.Time
+	// Which destinations the batch poster is able to post this batch to
+	CalldataAvailable bool
+	BlobsAvailable    bool
+	DASAvailable      bool
+	// Whether the data availability committee has been storing batches successfully
+	DASHealthy bool
+	// The size limit of calldata and DAS batches
+	MaxCalldataBatchSize int
+	// The latest parent chain base fee, and blob base fee if blobs are available
+	BaseFee     *big.Int
+	BlobBaseFee *big.Int
+}
+
+func (i *BatchDestinationInfo) available(destination BatchDestination) bool {
+	switch destination {
+	case BatchDestinationCalldata:
+		return i.CalldataAvailable
+	case BatchDestinationBlobs:
+		return i.BlobsAvailable
+	case BatchDestinationDAS:
+		return i.DASAvailable
+	default:
+		return false
+	}
+}
+
+// BatchDestinationDecision is a destination chosen for a batch and why.
+type BatchDestinationDecision struct {
+	Destination BatchDestination `json:"destination"`
+	Reason      string           `json:"reason"`
+	Time        time.Time        `json:"time"`
+}
+
+// BatchDestinationPolicy chooses where each batch is posted. It's consulted when the batch poster
+// starts building a batch, as the destination determines how much data the batch can hold.
+type BatchDestinationPolicy interface {
+	ChooseBatchDestination(ctx context.Context, info *BatchDestinationInfo) (BatchDestinationDecision, error)
+}
+
+type BatchDestinationPolicyConfig struct {
+	// "auto" to choose per batch, or a destination to always use
+	Mode string `koanf:"mode" reload:"hot"`
+	// Windows during which a destination is forced, taking precedence over the mode
+	ForcedWindows []string `koanf:"forced-windows" reload:"hot"`
+	// Whether to post to the parent chain instead of a healthy committee when it's within the premium
+	AllowL1OverDAS bool `koanf:"allow-l1-over-das" reload:"hot"`
+	// How much more, in percent, to pay to post data to the parent chain instead of trusting the committee
+	L1DataPremiumPercent uint64 `koanf:"l1-data-premium-percent" reload:"hot"`
+	// Consecutive DAS store failures after which the committee is considered unhealthy
+	DASUnhealthyAfterFailures uint64 `koanf:"das-unhealthy-after-failures" reload:"hot"`
+	// How long after the last DAS store failure to consider the committee again
+	DASRetryInterval time.Duration `koanf:"das-retry-interval" reload:"hot"`
+
+	mode          BatchDestination
+	autoMode      bool
+	forcedWindows []forcedDestinationWindow
+}
+
+func (c *BatchDestinationPolicyConfig) Validate() error {
+	if c.Mode == "" || c.Mode == "auto" {
+		c.autoMode = true
+	} else {
+		mode, err := parseBatchDestination(c.Mode)
+		if err != nil {
+			return err
+		}
+		c.autoMode = false
+		c.mode = mode
+	}
+	c.forcedWindows = nil
+	for _, s := range c.ForcedWindows {
+		window, err := parseForcedDestinationWindow(s)
+		if err != nil {
+			return err
+		}
+		c.forcedWindows = append(c.forcedWindows, window)
+	}
+	return nil
+}
+
+func BatchDestinationPolicyConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.String(prefix+".mode", DefaultBatchDestinationPolicyConfig.Mode, "where to post batches: \"auto\" to choose per batch by estimated cost, DAS health, and the L1 data premium, or always \"calldata\", \"blobs\", or \"das\"")
+	f.StringSlice(prefix+".forced-windows", DefaultBatchDestinationPolicyConfig.ForcedWindows, "destinations to force during time windows, taking precedence over the mode, as \"destination@HH:MM-HH:MM\" daily in UTC or \"destination@start/end\" with RFC 3339 times")
+	f.Bool(prefix+".allow-l1-over-das", DefaultBatchDestinationPolicyConfig.AllowL1OverDAS, "in auto mode, post batches of AnyTrust chains to the parent chain (e.g. as blobs) rather than to a healthy data availability committee when that costs at most the L1 data premium more")
+	f.Uint64(prefix+".l1-data-premium-percent", DefaultBatchDestinationPolicyConfig.L1DataPremiumPercent, "in auto mode with allow-l1-over-das, how much more in percent to pay to post batch data to the parent chain rather than trusting the data availability committee")
+	f.Uint64(prefix+".das-unhealthy-after-failures", DefaultBatchDestinationPolicyConfig.DASUnhealthyAfterFailures, "in auto mode, the number of consecutive DAS store failures after which batches are posted to the parent chain instead (0 to never consider DAS unhealthy)")
+	f.Duration(prefix+".das-retry-interval", DefaultBatchDestinationPolicyConfig.DASRetryInterval, "how long after the last DAS store failure to try DAS again once it was considered unhealthy")
+}
+
+var DefaultBatchDestinationPolicyConfig = BatchDestinationPolicyConfig{
+	Mode:                      "auto",
+	ForcedWindows:             []string{},
+	AllowL1OverDAS:            false,
+	L1DataPremiumPercent:      0,
+	DASUnhealthyAfterFailures: 3,
+	DASRetryInterval:          10 * time.Minute,
+}
+
+// A time window during which a destination is forced. Windows are either absolute,
+// or daily in UTC if start and end are both zero.
+type forcedDestinationWindow struct {
+	destination BatchDestination
+	start       time.Time
+	end         time.Time
+	// For daily windows, offsets from midnight UTC; end may be before start for windows spanning midnight
+	dailyStart time.Duration
+	dailyEnd   time.Duration
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time of day \"%v\" (expected HH:MM)", s)
+	}
+	h, err := strconv.ParseUint(hours, 10, 8)
+	if err != nil || h > 23 {
+		return 0, fmt.Errorf("invalid hour in time of day \"%v\"", s)
+	}
+	m, err := strconv.ParseUint(minutes, 10, 8)
+	if err != nil || m > 59 {
+		return 0, fmt.Errorf("invalid minute in time of day \"%v\"", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func parseForcedDestinationWindow(s string) (forcedDestinationWindow, error) {
+	destinationStr, windowStr, ok := strings.Cut(s, "@")
+	if !ok {
+		return forcedDestinationWindow{}, fmt.Errorf("invalid forced batch destination window \"%v\" (expected destination@window)", s)
+	}
+	destination, err := parseBatchDestination(destinationStr)
+	if err != nil {
+		return forcedDestinationWindow{}, err
+	}
+	window := forcedDestinationWindow{destination: destination}
+	if startStr, endStr, ok := strings.Cut(windowStr, "/"); ok {
+		window.start, err = time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return forcedDestinationWindow{}, fmt.Errorf("invalid start of forced batch destination window \"%v\": %w", s, err)
+		}
+		window.end, err = time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return forcedDestinationWindow{}, fmt.Errorf("invalid end of forced batch destination window \"%v\": %w", s, err)
+		}
+		if !window.end.After(window.start) {
+			return forcedDestinationWindow{}, fmt.Errorf("forced batch destination window \"%v\" ends before it starts", s)
+		}
+		return window, nil
+	}
+	startStr, endStr, ok := strings.Cut(windowStr, "-")
+	if !ok {
+		return forcedDestinationWindow{}, fmt.Errorf("invalid forced batch destination window \"%v\" (expected HH:MM-HH:MM or start/end)", s)
+	}
+	window.dailyStart, err = parseTimeOfDay(startStr)
+	if err != nil {
+		return forcedDestinationWindow{}, err
+	}
+	window.dailyEnd, err = parseTimeOfDay(endStr)
+	if err != nil {
+		return forcedDestinationWindow{}, err
+	}
+	if window.dailyStart == window.dailyEnd {
+		return forcedDestinationWindow{}, fmt.Errorf("forced batch destination window \"%v\" is empty", s)
+	}
+	return window, nil
+}
+
+func (w *forcedDestinationWindow) contains(now time.Time) bool {
+	if !w.start.IsZero() {
+		return !now.Before(w.start) && now.Before(w.end)
+	}
+	now = now.UTC()
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	if w.dailyStart < w.dailyEnd {
+		return sinceMidnight >= w.dailyStart && sinceMidnight < w.dailyEnd
+	}
+	return sinceMidnight >= w.dailyStart || sinceMidnight < w.dailyEnd
+}
+
+// The approximate size of a serialized DAS certificate, which is posted in calldata instead of the batch
+const dasCertificateSize = 1 + 32 + 32 + 8 + 8 + 96 + 1
+
+// Costs are compared per kilobyte of batch data, ignoring the fixed cost of the transaction itself.
+const costComparisonBytes = 1024
+
+// estimateBatchDataCosts returns the estimated wei per kilobyte of batch data for each available destination.
+func estimateBatchDataCosts(info *BatchDestinationInfo) map[BatchDestination]*big.Int {
+	costs := make(map[BatchDestination]*big.Int)
+	if info.BaseFee == nil {
+		return costs
+	}
+	calldataGasPerKb := arbmath.UintToBig(costComparisonBytes * params.TxDataNonZeroGasEIP2028)
+	if info.CalldataAvailable {
+		costs[BatchDestinationCalldata] = new(big.Int).Mul(calldataGasPerKb, info.BaseFee)
+	}
+	if info.BlobsAvailable && info.BlobBaseFee != nil {
+		blobGasPerKb := arbmath.UintToBig(costComparisonBytes * params.BlobTxBlobGasPerBlob)
+		cost := new(big.Int).Mul(blobGasPerKb, info.BlobBaseFee)
+		costs[BatchDestinationBlobs] = cost.Div(cost, big.NewInt(blobs.BlobEncodableData))
+	}
+	if info.DASAvailable && info.MaxCalldataBatchSize > 0 {
+		// The certificate's cost is spread over a full batch
+		cost := new(big.Int).Mul(calldataGasPerKb, info.BaseFee)
+		cost.Mul(cost, big.NewInt(dasCertificateSize))
+		costs[BatchDestinationDAS] = cost.Div(cost, big.NewInt(int64(info.MaxCalldataBatchSize)))
+	}
+	return costs
+}
+
+// CostBatchDestinationPolicy is the default BatchDestinationPolicy. Unless a destination is forced,
+// it chooses the cheapest destination, keeping to a healthy committee unless configured to pay a premium
+// to avoid trusting it, and avoiding the committee while it's unhealthy.
+type CostBatchDestinationPolicy struct {
+	config func() *BatchDestinationPolicyConfig
+}
+
+func NewCostBatchDestinationPolicy(config func() *BatchDestinationPolicyConfig) *CostBatchDestinationPolicy {
+	return &CostBatchDestinationPolicy{config: config}
+}
+
+func (p *CostBatchDestinationPolicy) ChooseBatchDestination(_ context.Context, info *BatchDestinationInfo) (BatchDestinationDecision, error) {
+	config := p.config()
+	decide := func(destination BatchDestination, reason string) (BatchDestinationDecision, error) {
+		return BatchDestinationDecision{Destination: destination, Reason: reason, Time: info.Now}, nil
+	}
+	for _, window := range config.forcedWindows {
+		if !window.contains(info.Now) {
+			continue
+		}
+		if info.available(window.destination) {
+			return decide(window.destination, BatchDestinationReasonForcedWindow)
+		}
+		log.Warn("batch destination forced by window is unavailable, choosing automatically", "destination", window.destination)
+	}
+	if !config.autoMode {
+		if info.available(config.mode) {
+			return decide(config.mode, BatchDestinationReasonForcedMode)
+		}
+		log.Warn("configured batch destination is unavailable, choosing automatically", "destination", config.mode)
+	}
+
+	var options []BatchDestination
+	for _, destination := range []BatchDestination{BatchDestinationCalldata, BatchDestinationBlobs, BatchDestinationDAS} {
+		if info.available(destination) {
+			options = append(options, destination)
+		}
+	}
+	if len(options) == 0 {
+		return BatchDestinationDecision{}, errors.New("no batch destination available")
+	}
+	if len(options) == 1 {
+		return decide(options[0], BatchDestinationReasonOnlyOption)
+	}
+
+	costs := estimateBatchDataCosts(info)
+	var cheapestL1 *BatchDestination
+	for i, destination := range options {
+		if destination == BatchDestinationDAS || costs[destination] == nil {
+			continue
+		}
+		if cheapestL1 == nil || costs[destination].Cmp(costs[*cheapestL1]) < 0 {
+			cheapestL1 = &options[i]
+		}
+	}
+	if !info.DASAvailable {
+		if cheapestL1 == nil {
+			return decide(options[0], BatchDestinationReasonOnlyOption)
+		}
+		return decide(*cheapestL1, BatchDestinationReasonCheapest)
+	}
+	if cheapestL1 == nil {
+		return decide(BatchDestinationDAS, BatchDestinationReasonCheapest)
+	}
+	if !info.DASHealthy {
+		return decide(*cheapestL1, BatchDestinationReasonDASUnhealthy)
+	}
+	if !config.AllowL1OverDAS {
+		return decide(BatchDestinationDAS, BatchDestinationReasonDASPreferred)
+	}
+	dasCost := costs[BatchDestinationDAS]
+	if dasCost == nil {
+		return decide(BatchDestinationDAS, BatchDestinationReasonCheapest)
+	}
+	// Post to the parent chain if it costs at most the premium more than DAS
+	l1Cost := new(big.Int).Mul(costs[*cheapestL1], big.NewInt(100))
+	acceptableCost := new(big.Int).Mul(dasCost, arbmath.UintToBig(100+config.L1DataPremiumPercent))
+	if l1Cost.Cmp(acceptableCost) <= 0 {
+		if costs[*cheapestL1].Cmp(dasCost) > 0 {
+			return decide(*cheapestL1, BatchDestinationReasonL1Premium)
+		}
+		return decide(*cheapestL1, BatchDestinationReasonCheapest)
+	}
+	return decide(BatchDestinationDAS, BatchDestinationReasonCheapest)
+}
+
+func recordBatchDestinationDecision(decision BatchDestinationDecision) {
+	metrics.GetOrRegisterCounter("arb/batchposter/destination/"+decision.Destination.String(), nil).Inc(1)
+	metrics.GetOrRegisterCounter("arb/batchposter/destination/reason/"+decision.Reason, nil).Inc(1)
+}
@@ -0,0 +1,108 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestForcedDestinationWindows(t *testing.T) {
+	daily, err := parseForcedDestinationWindow("calldata@22:30-02:00")
+	Require(t, err)
+	absolute, err := parseForcedDestinationWindow("das@2023-10-01T00:00:00Z/2023-10-02T00:00:00Z")
+	Require(t, err)
+	if daily.destination != BatchDestinationCalldata || absolute.destination != BatchDestinationDAS {
+		Fail(t, "unexpected forced destinations", daily.destination, absolute.destination)
+	}
+	for _, tc := range []struct {
+		window   forcedDestinationWindow
+		now      string
+		expected bool
+	}{
+		{daily, "2023-10-01T23:00:00Z", true},
+		{daily, "2023-10-01T01:59:00Z", true},
+		{daily, "2023-10-01T02:00:00Z", false},
+		{daily, "2023-10-01T12:00:00Z", false},
+		{absolute, "2023-10-01T12:00:00Z", true},
+		{absolute, "2023-10-02T00:00:00Z", false},
+	} {
+		now, err := time.Parse(time.RFC3339, tc.now)
+		Require(t, err)
+		if tc.window.contains(now) != tc.expected {
+			Fail(t, "window containing", tc.now, "expected", tc.expected)
+		}
+	}
+	for _, invalid := range []string{"calldata", "tape@00:00-01:00", "das@25:00-01:00", "das@01:00-01:00", "das@2023-10-02T00:00:00Z/2023-10-01T00:00:00Z"} {
+		if _, err := parseForcedDestinationWindow(invalid); err == nil {
+			Fail(t, "expected an error parsing forced window", invalid)
+		}
+	}
+}
+
+func TestCostBatchDestinationPolicy(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultBatchDestinationPolicyConfig
+	Require(t, config.Validate())
+	policy := NewCostBatchDestinationPolicy(func() *BatchDestinationPolicyConfig { return &config })
+	noon, err := time.Parse(time.RFC3339, "2023-10-01T12:00:00Z")
+	Require(t, err)
+	info := BatchDestinationInfo{
+		Now:                  noon,
+		CalldataAvailable:    true,
+		BlobsAvailable:       true,
+		DASAvailable:         true,
+		DASHealthy:           true,
+		MaxCalldataBatchSize: 100000,
+		BaseFee:              big.NewInt(params.GWei),
+		BlobBaseFee:          big.NewInt(1),
+	}
+	check := func(info BatchDestinationInfo, destination BatchDestination, reason string) {
+		t.Helper()
+		decision, err := policy.ChooseBatchDestination(ctx, &info)
+		Require(t, err)
+		if decision.Destination != destination || decision.Reason != reason {
+			Fail(t, "expected", destination, reason, "but got", decision.Destination, decision.Reason)
+		}
+	}
+
+	// A healthy committee is kept unless posting to the parent chain instead is allowed.
+	check(info, BatchDestinationDAS, BatchDestinationReasonDASPreferred)
+	config.AllowL1OverDAS = true
+	// Cheap blobs beat DAS.
+	check(info, BatchDestinationBlobs, BatchDestinationReasonCheapest)
+	// Expensive blobs don't, unless the L1 data premium covers the difference.
+	expensiveBlobs := info
+	expensiveBlobs.BlobBaseFee = big.NewInt(params.GWei)
+	check(expensiveBlobs, BatchDestinationDAS, BatchDestinationReasonCheapest)
+	config.L1DataPremiumPercent = 5000
+	check(expensiveBlobs, BatchDestinationBlobs, BatchDestinationReasonL1Premium)
+	config.L1DataPremiumPercent = 0
+	// An unhealthy committee is avoided.
+	expensiveBlobs.DASHealthy = false
+	check(expensiveBlobs, BatchDestinationBlobs, BatchDestinationReasonDASUnhealthy)
+	// Without blobs, calldata is more expensive than DAS.
+	noBlobs := info
+	noBlobs.BlobsAvailable = false
+	check(noBlobs, BatchDestinationDAS, BatchDestinationReasonCheapest)
+	onlyDAS := noBlobs
+	onlyDAS.CalldataAvailable = false
+	onlyDAS.DASHealthy = false
+	check(onlyDAS, BatchDestinationDAS, BatchDestinationReasonOnlyOption)
+
+	// The mode forces a destination, and windows take precedence over it.
+	config.Mode = "calldata"
+	config.ForcedWindows = []string{"das@11:00-13:00"}
+	Require(t, config.Validate())
+	check(info, BatchDestinationDAS, BatchDestinationReasonForcedWindow)
+	info.Now = noon.Add(2 * time.Hour)
+	check(info, BatchDestinationCalldata, BatchDestinationReasonForcedMode)
+	// Unavailable forced destinations fall back to choosing automatically.
+	onlyDAS.Now = info.Now
+	check(onlyDAS, BatchDestinationDAS, BatchDestinationReasonOnlyOption)
+}
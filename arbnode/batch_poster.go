@@ -6,6 +6,7 @@ package arbnode
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -22,7 +23,10 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -39,6 +43,8 @@ import (
 	"github.com/offchainlabs/nitro/das"
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/blobs"
+	"github.com/offchainlabs/nitro/util/containers"
 	"github.com/offchainlabs/nitro/util/headerreader"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
@@ -70,6 +76,7 @@ type BatchPoster struct {
 	bridgeAddr          common.Address
 	gasRefunderAddr     common.Address
 	building            *buildingBatch
+	speculative         *speculativeBatch // the next batch, built while the current one is being posted
 	daWriter            das.DataAvailabilityServiceWriter
 	dataPoster          *dataposter.DataPoster
 	redisLock           *redislock.Simple
@@ -79,6 +86,17 @@ type BatchPoster struct {
 	backlog         uint64
 	lastHitL1Bounds time.Time // The last time we wanted to post a message but hit the L1 bounds
 
+	destinationPolicy BatchDestinationPolicy
+	dasStoreFailures  uint64    // consecutive DAS store failures
+	lastDASFailure    time.Time // the last time storing a batch with DAS failed
+
+	// Operator controls and status, accessed by the admin API
+	paused          atomic.Bool
+	postNow         atomic.Bool
+	postNowChan     chan struct{}
+	buildingStatus  atomic.Pointer[BuildingBatchStatus]
+	lastDestination atomic.Pointer[BatchDestinationDecision]
+
 	batchReverted        atomic.Bool // indicates whether data poster batch was reverted
 	nextRevertCheckBlock int64       // the last parent block scanned for reverting batches
 
@@ -109,20 +127,29 @@ type BatchPosterConfig struct {
 	// Batch post polling interval.
 	PollInterval time.Duration `koanf:"poll-interval" reload:"hot"`
 	// Batch posting error delay.
-	ErrorDelay         time.Duration               `koanf:"error-delay" reload:"hot"`
-	CompressionLevel   int                         `koanf:"compression-level" reload:"hot"`
-	DASRetentionPeriod time.Duration               `koanf:"das-retention-period" reload:"hot"`
-	GasRefunderAddress string                      `koanf:"gas-refunder-address" reload:"hot"`
-	DataPoster         dataposter.DataPosterConfig `koanf:"data-poster" reload:"hot"`
-	RedisUrl           string                      `koanf:"redis-url"`
-	RedisLock          redislock.SimpleCfg         `koanf:"redis-lock" reload:"hot"`
-	ExtraBatchGas      uint64                      `koanf:"extra-batch-gas" reload:"hot"`
-	ParentChainWallet  genericconf.WalletConfig    `koanf:"parent-chain-wallet"`
-	L1BlockBound       string                      `koanf:"l1-block-bound" reload:"hot"`
-	L1BlockBoundBypass time.Duration               `koanf:"l1-block-bound-bypass" reload:"hot"`
-
-	gasRefunder  common.Address
-	l1BlockBound l1BlockBound
+	ErrorDelay         time.Duration                `koanf:"error-delay" reload:"hot"`
+	CompressionLevel   int                          `koanf:"compression-level" reload:"hot"`
+	DASRetentionPeriod time.Duration                `koanf:"das-retention-period" reload:"hot"`
+	GasRefunderAddress string                       `koanf:"gas-refunder-address" reload:"hot"`
+	DataPoster         dataposter.DataPosterConfig  `koanf:"data-poster" reload:"hot"`
+	RedisUrl           string                       `koanf:"redis-url"`
+	RedisLock          redislock.SimpleCfg          `koanf:"redis-lock" reload:"hot"`
+	ExtraBatchGas      uint64                       `koanf:"extra-batch-gas" reload:"hot"`
+	ParentChainWallet  genericconf.WalletConfig     `koanf:"parent-chain-wallet"`
+	L1BlockBound       string                       `koanf:"l1-block-bound" reload:"hot"`
+	L1BlockBoundBypass time.Duration                `koanf:"l1-block-bound-bypass" reload:"hot"`
+	Post4844Blobs      bool                         `koanf:"post-4844-blobs" reload:"hot"`
+	DestinationPolicy  BatchDestinationPolicyConfig `koanf:"destination-policy" reload:"hot"`
+	// Compress batches at the recompression level in the background while they're built.
+	BackgroundCompression bool `koanf:"background-compression" reload:"hot"`
+	// Start building the next batch while the current one is being posted.
+	SpeculativeBatchBuilding bool `koanf:"speculative-batch-building" reload:"hot"`
+	// Private keys of additional accounts to post batches from if the main account's transactions get stuck.
+	LanePrivateKeys []string `koanf:"lane-private-keys"`
+
+	gasRefunder     common.Address
+	l1BlockBound    l1BlockBound
+	lanePrivateKeys []*ecdsa.PrivateKey
 }
 
 func (c *BatchPosterConfig) Validate() error {
@@ -133,6 +160,22 @@ func (c *BatchPosterConfig) Validate() error {
 	if c.MaxSize <= 40 {
 		return errors.New("MaxBatchSize too small")
 	}
+	if c.Post4844Blobs {
+		// The arbitrator can't resolve blob preimages yet, and replay only reads blob batches from
+		// arbstate.BlobHashesMinArbOSVersion on, so blob batches couldn't be validated or proven.
+		return errors.New("posting batches as EIP-4844 blobs isn't supported yet")
+	}
+	c.lanePrivateKeys = nil
+	for i, key := range c.LanePrivateKeys {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid batch poster lane private key %v: %w", i, err)
+		}
+		c.lanePrivateKeys = append(c.lanePrivateKeys, privateKey)
+	}
+	if err := c.DestinationPolicy.Validate(); err != nil {
+		return err
+	}
 	if c.L1BlockBound == "" {
 		c.l1BlockBound = l1BlockBoundDefault
 	} else if c.L1BlockBound == "safe" {
@@ -166,8 +209,13 @@ func BatchPosterConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.String(prefix+".redis-url", DefaultBatchPosterConfig.RedisUrl, "if non-empty, the Redis URL to store queued transactions in")
 	f.String(prefix+".l1-block-bound", DefaultBatchPosterConfig.L1BlockBound, "only post messages to batches when they're within the max future block/timestamp as of this L1 block tag (\"safe\", \"finalized\", \"latest\", or \"ignore\" to ignore this check)")
 	f.Duration(prefix+".l1-block-bound-bypass", DefaultBatchPosterConfig.L1BlockBoundBypass, "post batches even if not within the layer 1 future bounds if we're within this margin of the max delay")
+	f.Bool(prefix+".post-4844-blobs", DefaultBatchPosterConfig.Post4844Blobs, "allow posting batches as EIP-4844 blobs when the parent chain supports them and the destination policy chooses blobs (max-size is ignored for such batches); not supported yet, as blob batches can't be validated")
 	redislock.AddConfigOptions(prefix+".redis-lock", f)
 	dataposter.DataPosterConfigAddOptions(prefix+".data-poster", f, dataposter.DefaultDataPosterConfig)
+	f.Bool(prefix+".background-compression", DefaultBatchPosterConfig.BackgroundCompression, "compress batches in a background thread while they're built, rather than recompressing them when they're posted")
+	f.Bool(prefix+".speculative-batch-building", DefaultBatchPosterConfig.SpeculativeBatchBuilding, "start building the next batch from already sequenced messages while the current batch is being posted")
+	f.StringSlice(prefix+".lane-private-keys", DefaultBatchPosterConfig.LanePrivateKeys, "private keys of additional accounts to post batches from when the main account's transactions are stuck (each must be allowed to post batches by the sequencer inbox, and requires database, SQL, or Redis data poster storage)")
+	BatchDestinationPolicyConfigAddOptions(prefix+".destination-policy", f)
 	genericconf.WalletConfigAddOptions(prefix+".parent-chain-wallet", f, DefaultBatchPosterConfig.ParentChainWallet.Pathname)
 }
 
@@ -175,20 +223,24 @@ var DefaultBatchPosterConfig = BatchPosterConfig{
 	Enable:                             false,
 	DisableDasFallbackStoreDataOnChain: false,
 	// This default is overridden for L3 chains in applyChainParameters in cmd/nitro/nitro.go
-	MaxSize:            100000,
-	PollInterval:       time.Second * 10,
-	ErrorDelay:         time.Second * 10,
-	MaxDelay:           time.Hour,
-	WaitForMaxDelay:    false,
-	CompressionLevel:   brotli.BestCompression,
-	DASRetentionPeriod: time.Hour * 24 * 15,
-	GasRefunderAddress: "",
-	ExtraBatchGas:      50_000,
-	DataPoster:         dataposter.DefaultDataPosterConfig,
-	ParentChainWallet:  DefaultBatchPosterL1WalletConfig,
-	L1BlockBound:       "",
-	L1BlockBoundBypass: time.Hour,
-	RedisLock:          redislock.DefaultCfg,
+	MaxSize:                  100000,
+	PollInterval:             time.Second * 10,
+	ErrorDelay:               time.Second * 10,
+	MaxDelay:                 time.Hour,
+	WaitForMaxDelay:          false,
+	CompressionLevel:         brotli.BestCompression,
+	DASRetentionPeriod:       time.Hour * 24 * 15,
+	GasRefunderAddress:       "",
+	ExtraBatchGas:            50_000,
+	DataPoster:               dataposter.DefaultDataPosterConfig,
+	ParentChainWallet:        DefaultBatchPosterL1WalletConfig,
+	L1BlockBound:             "",
+	L1BlockBoundBypass:       time.Hour,
+	RedisLock:                redislock.DefaultCfg,
+	Post4844Blobs:            false,
+	DestinationPolicy:        DefaultBatchDestinationPolicyConfig,
+	BackgroundCompression:    false,
+	SpeculativeBatchBuilding: false,
 }
 
 var DefaultBatchPosterL1WalletConfig = genericconf.WalletConfig{
@@ -200,20 +252,23 @@ var DefaultBatchPosterL1WalletConfig = genericconf.WalletConfig{
 }
 
 var TestBatchPosterConfig = BatchPosterConfig{
-	Enable:             true,
-	MaxSize:            100000,
-	PollInterval:       time.Millisecond * 10,
-	ErrorDelay:         time.Millisecond * 10,
-	MaxDelay:           0,
-	WaitForMaxDelay:    false,
-	CompressionLevel:   2,
-	DASRetentionPeriod: time.Hour * 24 * 15,
-	GasRefunderAddress: "",
-	ExtraBatchGas:      10_000,
-	DataPoster:         dataposter.TestDataPosterConfig,
-	ParentChainWallet:  DefaultBatchPosterL1WalletConfig,
-	L1BlockBound:       "",
-	L1BlockBoundBypass: time.Hour,
+	Enable:                   true,
+	MaxSize:                  100000,
+	PollInterval:             time.Millisecond * 10,
+	ErrorDelay:               time.Millisecond * 10,
+	MaxDelay:                 0,
+	WaitForMaxDelay:          false,
+	CompressionLevel:         2,
+	DASRetentionPeriod:       time.Hour * 24 * 15,
+	GasRefunderAddress:       "",
+	ExtraBatchGas:            10_000,
+	DataPoster:               dataposter.TestDataPosterConfig,
+	ParentChainWallet:        DefaultBatchPosterL1WalletConfig,
+	L1BlockBound:             "",
+	L1BlockBoundBypass:       time.Hour,
+	DestinationPolicy:        DefaultBatchDestinationPolicyConfig,
+	BackgroundCompression:    true,
+	SpeculativeBatchBuilding: true,
 }
 
 type BatchPosterOpts struct {
@@ -226,6 +281,11 @@ type BatchPosterOpts struct {
 	DeployInfo   *chaininfo.RollupAddresses
 	TransactOpts *bind.TransactOpts
 	DAWriter     das.DataAvailabilityServiceWriter
+	// DestinationPolicy chooses where batches are posted, or if nil, a CostBatchDestinationPolicy.
+	DestinationPolicy BatchDestinationPolicy
+	// DataPosterLaneDB returns the database to store the data poster queue of a lane account in.
+	// It's required if lane private keys are configured.
+	DataPosterLaneDB func(common.Address) ethdb.Database
 }
 
 func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, error) {
@@ -258,19 +318,21 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 		return nil, err
 	}
 	b := &BatchPoster{
-		l1Reader:        opts.L1Reader,
-		inbox:           opts.Inbox,
-		streamer:        opts.Streamer,
-		syncMonitor:     opts.SyncMonitor,
-		config:          opts.Config,
-		bridge:          bridge,
-		seqInbox:        seqInbox,
-		seqInboxABI:     seqInboxABI,
-		seqInboxAddr:    opts.DeployInfo.SequencerInbox,
-		gasRefunderAddr: opts.Config().gasRefunder,
-		bridgeAddr:      opts.DeployInfo.Bridge,
-		daWriter:        opts.DAWriter,
-		redisLock:       redisLock,
+		l1Reader:          opts.L1Reader,
+		inbox:             opts.Inbox,
+		streamer:          opts.Streamer,
+		syncMonitor:       opts.SyncMonitor,
+		config:            opts.Config,
+		bridge:            bridge,
+		seqInbox:          seqInbox,
+		seqInboxABI:       seqInboxABI,
+		seqInboxAddr:      opts.DeployInfo.SequencerInbox,
+		gasRefunderAddr:   opts.Config().gasRefunder,
+		bridgeAddr:        opts.DeployInfo.Bridge,
+		daWriter:          opts.DAWriter,
+		redisLock:         redisLock,
+		destinationPolicy: opts.DestinationPolicy,
+		postNowChan:       make(chan struct{}, 1),
 		accessList: func(SequencerInboxAccs, AfterDelayedMessagesRead int) types.AccessList {
 			return AccessList(&AccessListOpts{
 				SequencerInboxAddr:       opts.DeployInfo.SequencerInbox,
@@ -282,9 +344,16 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 			})
 		},
 	}
+	if b.destinationPolicy == nil {
+		b.destinationPolicy = NewCostBatchDestinationPolicy(func() *BatchDestinationPolicyConfig { return &opts.Config().DestinationPolicy })
+	}
 	dataPosterConfigFetcher := func() *dataposter.DataPosterConfig {
 		return &(opts.Config().DataPoster)
 	}
+	lanes, err := batchPosterLanes(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
 	b.dataPoster, err = dataposter.NewDataPoster(ctx,
 		&dataposter.DataPosterOpts{
 			Database:          opts.DataPosterDB,
@@ -295,6 +364,7 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 			Config:            dataPosterConfigFetcher,
 			MetadataRetriever: b.getBatchPosterPosition,
 			RedisKey:          "data-poster.queue",
+			Lanes:             lanes,
 		})
 	if err != nil {
 		return nil, err
@@ -302,6 +372,33 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 	return b, nil
 }
 
+func batchPosterLanes(ctx context.Context, opts *BatchPosterOpts) ([]dataposter.DataPosterLaneOpts, error) {
+	keys := opts.Config().lanePrivateKeys
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if opts.DataPosterLaneDB == nil {
+		return nil, errors.New("batch poster lanes configured without a database for them")
+	}
+	chainId, err := opts.L1Reader.Client().ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting parent chain id: %w", err)
+	}
+	var lanes []dataposter.DataPosterLaneOpts
+	for _, key := range keys {
+		auth, err := bind.NewKeyedTransactorWithChainID(key, chainId)
+		if err != nil {
+			return nil, err
+		}
+		lanes = append(lanes, dataposter.DataPosterLaneOpts{
+			Auth:     auth,
+			Database: opts.DataPosterLaneDB(auth.From),
+			RedisKey: "data-poster.queue." + auth.From.Hex(),
+		})
+	}
+	return lanes, nil
+}
+
 type AccessListOpts struct {
 	SequencerInboxAddr       common.Address
 	BridgeAddr               common.Address
@@ -397,7 +494,7 @@ func (b *BatchPoster) checkReverts(ctx context.Context, to int64) (bool, error)
 			if err != nil {
 				return false, fmt.Errorf("getting sender of transaction tx: %v, %w", tx.Hash(), err)
 			}
-			if from == b.dataPoster.Sender() {
+			if b.dataPoster.IsSender(from) {
 				r, err := b.l1Reader.Client().TransactionReceipt(ctx, tx.Hash())
 				if err != nil {
 					return false, fmt.Errorf("getting a receipt for transaction: %v, %w", tx.Hash(), err)
@@ -506,6 +603,10 @@ type batchSegments struct {
 	lastCompressedSize    int
 	trailingHeaders       int // how many trailing segments are headers
 	isDone                bool
+
+	// If set, compresses the segments which can no longer be trimmed at the recompression level
+	background        *backgroundCompressor
+	committedSegments int // how many segments were given to the background compressor
 }
 
 type buildingBatch struct {
@@ -513,13 +614,24 @@ type buildingBatch struct {
 	startMsgCount     arbutil.MessageIndex
 	msgCount          arbutil.MessageIndex
 	haveUsefulMessage bool
+	destination       BatchDestinationDecision
+	hitL1Bounds       time.Time // when building the batch speculatively last stopped at the L1 bounds
 }
 
-func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64) *batchSegments {
-	compressedBuffer := bytes.NewBuffer(make([]byte, 0, config.MaxSize*2))
+// The most batch data that fits in the blobs of a single transaction, leaving room for the RLP length prefix blobs.EncodeBlobs adds.
+const maxBlobBatchSize = blobs.MaxBlobsPerTransaction*blobs.BlobEncodableData - 16
+
+func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64, destination BatchDestination, stopWaiter *stopwaiter.StopWaiterSafe) *batchSegments {
 	if config.MaxSize <= 40 {
 		panic("MaxBatchSize too small")
 	}
+	// The 40 byte sequencer message header is added by the sequencer inbox contract
+	sizeLimit := config.MaxSize - 40 // TODO
+	if destination == BatchDestinationBlobs {
+		// Blob batches' header isn't part of the blobs
+		sizeLimit = maxBlobBatchSize
+	}
+	compressedBuffer := bytes.NewBuffer(make([]byte, 0, sizeLimit*2))
 	compressionLevel := config.CompressionLevel
 	recompressionLevel := config.CompressionLevel
 	if backlog > 20 {
@@ -540,14 +652,23 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog ui
 		)
 		recompressionLevel = compressionLevel
 	}
-	return &batchSegments{
+	segments := &batchSegments{
 		compressedBuffer:   compressedBuffer,
 		compressedWriter:   brotli.NewWriterLevel(compressedBuffer, compressionLevel),
-		sizeLimit:          config.MaxSize - 40, // TODO
+		sizeLimit:          sizeLimit,
 		recompressionLevel: recompressionLevel,
 		rawSegments:        make([][]byte, 0, 128),
 		delayedMsg:         firstDelayed,
 	}
+	if config.BackgroundCompression {
+		background, err := newBackgroundCompressor(stopWaiter, recompressionLevel, sizeLimit*2)
+		if err != nil {
+			log.Warn("not compressing batch in the background", "err", err)
+		} else {
+			segments.background = background
+		}
+	}
+	return segments
 }
 
 func (s *batchSegments) recompressAll() error {
@@ -561,6 +682,30 @@ func (s *batchSegments) recompressAll() error {
 			return err
 		}
 	}
+	return s.checkLimits()
+}
+
+// finishBackgroundCompression replaces the compressed data with that of the background compressor,
+// which is what recompressAll would produce.
+func (s *batchSegments) finishBackgroundCompression() error {
+	background := s.background
+	s.background = nil
+	if s.committedSegments != len(s.rawSegments) {
+		background.abandon()
+		return fmt.Errorf("background compressor has %v segments but the batch has %v", s.committedSegments, len(s.rawSegments))
+	}
+	buffer, writer, uncompressedSize, err := background.finish()
+	if err != nil {
+		return err
+	}
+	s.compressedBuffer = buffer
+	s.compressedWriter = writer
+	s.newUncompressedSize = 0
+	s.totalUncompressedSize = uncompressedSize
+	return s.checkLimits()
+}
+
+func (s *batchSegments) checkLimits() error {
 	if s.totalUncompressedSize > arbstate.MaxDecompressedLen {
 		return fmt.Errorf("batch size %v exceeds maximum decompressed length %v", s.totalUncompressedSize, arbstate.MaxDecompressedLen)
 	}
@@ -600,16 +745,31 @@ func (s *batchSegments) testForOverflow(isHeader bool) (bool, error) {
 }
 
 func (s *batchSegments) close() error {
+	start := time.Now()
 	s.rawSegments = s.rawSegments[:len(s.rawSegments)-s.trailingHeaders]
 	s.trailingHeaders = 0
-	err := s.recompressAll()
+	var err error
+	if s.background != nil {
+		err = s.finishBackgroundCompression()
+	} else {
+		err = s.recompressAll()
+	}
 	if err != nil {
 		return err
 	}
+	batchPosterCloseTimer.UpdateSince(start)
 	s.isDone = true
 	return nil
 }
 
+// abandon releases the resources of a batch which won't be closed.
+func (s *batchSegments) abandon() {
+	if s.background != nil {
+		s.background.abandon()
+		s.background = nil
+	}
+}
+
 func (s *batchSegments) addSegmentToCompressed(segment []byte) error {
 	encoded, err := rlp.EncodeToBytes(segment)
 	if err != nil {
@@ -643,6 +803,13 @@ func (s *batchSegments) addSegment(segment []byte, isHeader bool) (bool, error)
 		s.trailingHeaders++
 	} else {
 		s.trailingHeaders = 0
+		if s.background != nil {
+			// Trailing headers are trimmed when closing the batch, so only hand them over once followed by a message
+			for _, committed := range s.rawSegments[s.committedSegments:] {
+				s.background.add(committed)
+			}
+			s.committedSegments = len(s.rawSegments)
+		}
 	}
 	return true, nil
 }
@@ -730,25 +897,45 @@ func (s *batchSegments) CloseAndGetBytes() ([]byte, error) {
 		return nil, err
 	}
 	compressedBytes := s.compressedBuffer.Bytes()
+	if len(compressedBytes) > 0 {
+		batchPosterCompressionRatioGauge.Update(float64(s.totalUncompressedSize) / float64(len(compressedBytes)))
+	}
 	fullMsg := make([]byte, 1, len(compressedBytes)+1)
 	fullMsg[0] = arbstate.BrotliMessageHeaderByte
 	fullMsg = append(fullMsg, compressedBytes...)
 	return fullMsg, nil
 }
 
-func (b *BatchPoster) encodeAddBatch(seqNum *big.Int, prevMsgNum arbutil.MessageIndex, newMsgNum arbutil.MessageIndex, message []byte, delayedMsg uint64) ([]byte, error) {
-	method, ok := b.seqInboxABI.Methods["addSequencerL2BatchFromOrigin0"]
+func (b *BatchPoster) encodeAddBatch(seqNum *big.Int, prevMsgNum arbutil.MessageIndex, newMsgNum arbutil.MessageIndex, message []byte, delayedMsg uint64, use4844 bool) ([]byte, error) {
+	methodName := "addSequencerL2BatchFromOrigin0"
+	if use4844 {
+		// The batch data is in the transaction's blobs rather than its calldata
+		methodName = "addSequencerL2BatchFromBlobs"
+	}
+	method, ok := b.seqInboxABI.Methods[methodName]
 	if !ok {
-		return nil, errors.New("failed to find add batch method")
-	}
-	inputData, err := method.Inputs.Pack(
-		seqNum,
-		message,
-		new(big.Int).SetUint64(delayedMsg),
-		b.config().gasRefunder,
-		new(big.Int).SetUint64(uint64(prevMsgNum)),
-		new(big.Int).SetUint64(uint64(newMsgNum)),
-	)
+		return nil, fmt.Errorf("failed to find add batch method %v", methodName)
+	}
+	var inputData []byte
+	var err error
+	if use4844 {
+		inputData, err = method.Inputs.Pack(
+			seqNum,
+			new(big.Int).SetUint64(delayedMsg),
+			b.config().gasRefunder,
+			new(big.Int).SetUint64(uint64(prevMsgNum)),
+			new(big.Int).SetUint64(uint64(newMsgNum)),
+		)
+	} else {
+		inputData, err = method.Inputs.Pack(
+			seqNum,
+			message,
+			new(big.Int).SetUint64(delayedMsg),
+			b.config().gasRefunder,
+			new(big.Int).SetUint64(uint64(prevMsgNum)),
+			new(big.Int).SetUint64(uint64(newMsgNum)),
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -757,7 +944,7 @@ func (b *BatchPoster) encodeAddBatch(seqNum *big.Int, prevMsgNum arbutil.Message
 	return fullData, nil
 }
 
-func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte, delayedMessages uint64) (uint64, error) {
+func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte, delayedMessages uint64, blobHashes []common.Hash) (uint64, error) {
 	config := b.config()
 	callOpts := &bind.CallOpts{
 		Context: ctx,
@@ -787,14 +974,15 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 	// However, we set nextMsgNum to 1 because it is necessary for a correct estimation for the final to be non-zero.
 	// Because we're likely estimating against older state, this might not be the actual next message,
 	// but the gas used should be the same.
-	data, err := b.encodeAddBatch(abi.MaxUint256, 0, 1, sequencerMessage, delayedMessages)
+	data, err := b.encodeAddBatch(abi.MaxUint256, 0, 1, sequencerMessage, delayedMessages, len(blobHashes) > 0)
 	if err != nil {
 		return 0, err
 	}
 	gas, err := b.l1Reader.Client().EstimateGas(ctx, ethereum.CallMsg{
-		From: b.dataPoster.Sender(),
-		To:   &b.seqInboxAddr,
-		Data: data,
+		From:       b.dataPoster.Sender(),
+		To:         &b.seqInboxAddr,
+		Data:       data,
+		BlobHashes: blobHashes,
 	})
 	if err != nil {
 		sequencerMessageHeader := sequencerMessage
@@ -808,6 +996,7 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 			"safeDelayedMessages", safeDelayedMessages,
 			"sequencerMessageHeader", hex.EncodeToString(sequencerMessageHeader),
 			"sequencerMessageLen", len(sequencerMessage),
+			"blobs", len(blobHashes),
 		)
 		return 0, fmt.Errorf("error estimating gas for batch: %w", err)
 	}
@@ -816,55 +1005,65 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 
 const ethPosBlockTime = 12 * time.Second
 
-func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error) {
-	if b.batchReverted.Load() {
-		return false, fmt.Errorf("batch was reverted, not posting any more batches")
-	}
-	nonce, batchPositionBytes, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+// chooseDestination asks the destination policy where to post the next batch, given what's available:
+// blobs if enabled and supported by the parent chain, DAS if configured and not excluded, and the
+// parent chain at all unless DAS is configured without an on-chain fallback.
+func (b *BatchPoster) chooseDestination(ctx context.Context, excludeDAS bool) (BatchDestinationDecision, error) {
+	config := b.config()
+	latestHeader, err := b.l1Reader.LastHeader(ctx)
 	if err != nil {
-		return false, err
-	}
-	var batchPosition batchPosterPosition
-	if err := rlp.DecodeBytes(batchPositionBytes, &batchPosition); err != nil {
-		return false, fmt.Errorf("decoding batch position: %w", err)
-	}
-
-	dbBatchCount, err := b.inbox.GetBatchCount()
+		return BatchDestinationDecision{}, err
+	}
+	now := time.Now()
+	l1Available := b.daWriter == nil || !config.DisableDasFallbackStoreDataOnChain
+	info := &BatchDestinationInfo{
+		Now:                  now,
+		CalldataAvailable:    l1Available,
+		BlobsAvailable:       l1Available && config.Post4844Blobs && latestHeader.ExcessBlobGas != nil,
+		DASAvailable:         b.daWriter != nil && !excludeDAS,
+		DASHealthy:           b.dasHealthy(now),
+		MaxCalldataBatchSize: config.MaxSize,
+		BaseFee:              latestHeader.BaseFee,
+	}
+	if config.Post4844Blobs && latestHeader.ExcessBlobGas == nil {
+		log.Warn("posting 4844 blobs is enabled but the parent chain doesn't support them yet")
+	}
+	if info.BlobsAvailable {
+		info.BlobBaseFee = eip4844.CalcBlobFee(*latestHeader.ExcessBlobGas)
+	}
+	decision, err := b.destinationPolicy.ChooseBatchDestination(ctx, info)
 	if err != nil {
-		return false, err
+		return BatchDestinationDecision{}, err
 	}
-	if dbBatchCount > batchPosition.NextSeqNum {
-		return false, fmt.Errorf("attempting to post batch %v, but the local inbox tracker database already has %v batches", batchPosition.NextSeqNum, dbBatchCount)
+	if !info.available(decision.Destination) {
+		return BatchDestinationDecision{}, fmt.Errorf("batch destination policy chose unavailable destination %v", decision.Destination)
 	}
+	return decision, nil
+}
 
-	if b.building == nil || b.building.startMsgCount != batchPosition.MessageCount {
-		b.building = &buildingBatch{
-			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.backlog),
-			msgCount:      batchPosition.MessageCount,
-			startMsgCount: batchPosition.MessageCount,
-		}
-	}
-	msgCount, err := b.streamer.GetMessageCount()
-	if err != nil {
-		return false, err
+func (b *BatchPoster) dasHealthy(now time.Time) bool {
+	config := b.config().DestinationPolicy
+	if config.DASUnhealthyAfterFailures == 0 || b.dasStoreFailures < config.DASUnhealthyAfterFailures {
+		return true
 	}
-	if msgCount <= batchPosition.MessageCount {
-		// There's nothing after the newest batch, therefore batch posting was not required
-		return false, nil
-	}
-	firstMsg, err := b.streamer.GetMessage(batchPosition.MessageCount)
-	if err != nil {
-		return false, err
-	}
-	firstMsgTime := time.Unix(int64(firstMsg.Message.Header.Timestamp), 0)
+	return now.Sub(b.lastDASFailure) >= config.DASRetryInterval
+}
 
-	config := b.config()
-	forcePostBatch := time.Since(firstMsgTime) >= config.MaxDelay
+// l1Bounds limits the L1 block numbers and timestamps of the messages a batch can include,
+// so the sequencer inbox accepts the batch.
+type l1Bounds struct {
+	maxBlockNumber uint64
+	maxTimestamp   uint64
+	// Messages before these are close to the maximum delay and are posted regardless of the bounds
+	minBlockNumber uint64
+	minTimestamp   uint64
+}
 
-	var l1BoundMaxBlockNumber uint64 = math.MaxUint64
-	var l1BoundMaxTimestamp uint64 = math.MaxUint64
-	var l1BoundMinBlockNumber uint64
-	var l1BoundMinTimestamp uint64
+func (b *BatchPoster) getL1Bounds(ctx context.Context, config *BatchPosterConfig) (*l1Bounds, error) {
+	bounds := &l1Bounds{
+		maxBlockNumber: math.MaxUint64,
+		maxTimestamp:   math.MaxUint64,
+	}
 	hasL1Bound := config.l1BlockBound != l1BlockBoundIgnore
 	if hasL1Bound {
 		var l1Bound *types.Header
@@ -889,7 +1088,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			l1Bound, err = b.l1Reader.LatestFinalizedBlockHeader(ctx)
 		}
 		if err != nil {
-			return false, fmt.Errorf("error getting L1 bound block: %w", err)
+			return nil, fmt.Errorf("error getting L1 bound block: %w", err)
 		}
 
 		maxTimeVariation, err := b.seqInbox.MaxTimeVariation(&bind.CallOpts{
@@ -901,60 +1100,141 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			log.Warn("error getting max time variation on L1 bound block; falling back on latest block", "err", err)
 			maxTimeVariation, err = b.seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
 			if err != nil {
-				return false, fmt.Errorf("error getting max time variation: %w", err)
+				return nil, fmt.Errorf("error getting max time variation: %w", err)
 			}
 		}
 
 		l1BoundBlockNumber := arbutil.ParentHeaderToL1BlockNumber(l1Bound)
-		l1BoundMaxBlockNumber = arbmath.SaturatingUAdd(l1BoundBlockNumber, arbmath.BigToUintSaturating(maxTimeVariation.FutureBlocks))
-		l1BoundMaxTimestamp = arbmath.SaturatingUAdd(l1Bound.Time, arbmath.BigToUintSaturating(maxTimeVariation.FutureSeconds))
+		bounds.maxBlockNumber = arbmath.SaturatingUAdd(l1BoundBlockNumber, arbmath.BigToUintSaturating(maxTimeVariation.FutureBlocks))
+		bounds.maxTimestamp = arbmath.SaturatingUAdd(l1Bound.Time, arbmath.BigToUintSaturating(maxTimeVariation.FutureSeconds))
 
 		if config.L1BlockBoundBypass > 0 {
 			latestHeader, err := b.l1Reader.LastHeader(ctx)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
 			latestBlockNumber := arbutil.ParentHeaderToL1BlockNumber(latestHeader)
 			blockNumberWithPadding := arbmath.SaturatingUAdd(latestBlockNumber, uint64(config.L1BlockBoundBypass/ethPosBlockTime))
 			timestampWithPadding := arbmath.SaturatingUAdd(latestHeader.Time, uint64(config.L1BlockBoundBypass/time.Second))
 
-			l1BoundMinBlockNumber = arbmath.SaturatingUSub(blockNumberWithPadding, arbmath.BigToUintSaturating(maxTimeVariation.DelayBlocks))
-			l1BoundMinTimestamp = arbmath.SaturatingUSub(timestampWithPadding, arbmath.BigToUintSaturating(maxTimeVariation.DelaySeconds))
+			bounds.minBlockNumber = arbmath.SaturatingUSub(blockNumberWithPadding, arbmath.BigToUintSaturating(maxTimeVariation.DelayBlocks))
+			bounds.minTimestamp = arbmath.SaturatingUSub(timestampWithPadding, arbmath.BigToUintSaturating(maxTimeVariation.DelaySeconds))
 		}
 	}
+	return bounds, nil
+}
 
-	for b.building.msgCount < msgCount {
+// allows returns whether msg is within the bounds. Once a message is close to the maximum delay,
+// the bounds are disabled for the rest of the batch.
+func (l *l1Bounds) allows(msg *arbostypes.MessageWithMetadata) bool {
+	if msg.Message.Header.BlockNumber < l.minBlockNumber || msg.Message.Header.Timestamp < l.minTimestamp {
+		log.Error(
+			"disabling L1 bound as batch posting message is close to the maximum delay",
+			"blockNumber", msg.Message.Header.BlockNumber,
+			"l1BoundMinBlockNumber", l.minBlockNumber,
+			"timestamp", msg.Message.Header.Timestamp,
+			"l1BoundMinTimestamp", l.minTimestamp,
+		)
+		l.maxBlockNumber = math.MaxUint64
+		l.maxTimestamp = math.MaxUint64
+	}
+	if msg.Message.Header.BlockNumber > l.maxBlockNumber || msg.Message.Header.Timestamp > l.maxTimestamp {
+		log.Info(
+			"not posting more messages because block number or timestamp exceed L1 bounds",
+			"blockNumber", msg.Message.Header.BlockNumber,
+			"l1BoundMaxBlockNumber", l.maxBlockNumber,
+			"timestamp", msg.Message.Header.Timestamp,
+			"l1BoundMaxTimestamp", l.maxTimestamp,
+		)
+		return false
+	}
+	return true
+}
+
+func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error) {
+	if b.batchReverted.Load() {
+		return false, fmt.Errorf("batch was reverted, not posting any more batches")
+	}
+	nonce, batchPositionBytes, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+	if err != nil {
+		return false, err
+	}
+	var batchPosition batchPosterPosition
+	if err := rlp.DecodeBytes(batchPositionBytes, &batchPosition); err != nil {
+		return false, fmt.Errorf("decoding batch position: %w", err)
+	}
+
+	dbBatchCount, err := b.inbox.GetBatchCount()
+	if err != nil {
+		return false, err
+	}
+	if dbBatchCount > batchPosition.NextSeqNum {
+		return false, fmt.Errorf("attempting to post batch %v, but the local inbox tracker database already has %v batches", batchPosition.NextSeqNum, dbBatchCount)
+	}
+
+	if b.building != nil && b.building.startMsgCount != batchPosition.MessageCount {
+		b.resetBuilding()
+	}
+	if b.building == nil {
+		b.building = b.takeSpeculativeBatch(batchPosition)
+	}
+	if b.building == nil {
+		destination, err := b.chooseDestination(ctx, false)
+		if err != nil {
+			return false, fmt.Errorf("error choosing batch destination: %w", err)
+		}
+		log.Debug("BatchPoster: chose batch destination", "destination", destination.Destination, "reason", destination.Reason)
+		b.building = &buildingBatch{
+			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.backlog, destination.Destination, &b.StopWaiterSafe),
+			msgCount:      batchPosition.MessageCount,
+			startMsgCount: batchPosition.MessageCount,
+			destination:   destination,
+		}
+	}
+	msgCount, err := b.streamer.GetMessageCount()
+	if err != nil {
+		return false, err
+	}
+	if msgCount <= batchPosition.MessageCount {
+		// There's nothing after the newest batch, therefore batch posting was not required
+		return false, nil
+	}
+	firstMsg, err := b.streamer.GetMessage(batchPosition.MessageCount)
+	if err != nil {
+		return false, err
+	}
+	firstMsgTime := time.Unix(int64(firstMsg.Message.Header.Timestamp), 0)
+
+	config := b.config()
+	forcePostBatch := time.Since(firstMsgTime) >= config.MaxDelay
+	if b.postNow.Swap(false) {
+		log.Info("BatchPoster: posting batch now as requested")
+		forcePostBatch = true
+	}
+
+	bounds, err := b.getL1Bounds(ctx, config)
+	if err != nil {
+		return false, err
+	}
+
+	if b.building.segments.IsDone() && !config.WaitForMaxDelay {
+		// the batch filled up while it was being built speculatively
+		forcePostBatch = true
+	}
+	for !b.building.segments.IsDone() && b.building.msgCount < msgCount {
 		msg, err := b.streamer.GetMessage(b.building.msgCount)
 		if err != nil {
 			log.Error("error getting message from streamer", "error", err)
 			break
 		}
-		if msg.Message.Header.BlockNumber < l1BoundMinBlockNumber || msg.Message.Header.Timestamp < l1BoundMinTimestamp {
-			log.Error(
-				"disabling L1 bound as batch posting message is close to the maximum delay",
-				"blockNumber", msg.Message.Header.BlockNumber,
-				"l1BoundMinBlockNumber", l1BoundMinBlockNumber,
-				"timestamp", msg.Message.Header.Timestamp,
-				"l1BoundMinTimestamp", l1BoundMinTimestamp,
-			)
-			l1BoundMaxBlockNumber = math.MaxUint64
-			l1BoundMaxTimestamp = math.MaxUint64
-		}
-		if msg.Message.Header.BlockNumber > l1BoundMaxBlockNumber || msg.Message.Header.Timestamp > l1BoundMaxTimestamp {
+		if !bounds.allows(msg) {
 			b.lastHitL1Bounds = time.Now()
-			log.Info(
-				"not posting more messages because block number or timestamp exceed L1 bounds",
-				"blockNumber", msg.Message.Header.BlockNumber,
-				"l1BoundMaxBlockNumber", l1BoundMaxBlockNumber,
-				"timestamp", msg.Message.Header.Timestamp,
-				"l1BoundMaxTimestamp", l1BoundMaxTimestamp,
-			)
 			break
 		}
 		success, err := b.building.segments.AddMessage(msg)
 		if err != nil {
 			// Clear our cache
-			b.building = nil
+			b.resetBuilding()
 			return false, fmt.Errorf("error adding message to batch: %w", err)
 		}
 		if !success {
@@ -971,6 +1251,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		b.building.msgCount++
 	}
 
+	b.buildingStatus.Store(b.building.status())
 	if !forcePostBatch || !b.building.haveUsefulMessage {
 		// the batch isn't full yet and we've posted a batch recently
 		// don't post anything for now
@@ -982,29 +1263,55 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	}
 	if sequencerMsg == nil {
 		log.Debug("BatchPoster: batch nil", "sequence nr.", batchPosition.NextSeqNum, "from", batchPosition.MessageCount, "prev delayed", batchPosition.DelayedMessageCount)
-		b.building = nil // a closed batchSegments can't be reused
+		b.resetBuilding() // a closed batchSegments can't be reused
 		return false, nil
 	}
+	if config.SpeculativeBatchBuilding && b.building.msgCount < msgCount {
+		b.startSpeculativeBatch(ctx, b.building.msgCount, b.building.segments.delayedMsg, msgCount)
+	}
 
-	if b.daWriter != nil {
+	destination := b.building.destination
+	if destination.Destination == BatchDestinationDAS {
 		cert, err := b.daWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), []byte{}) // b.daWriter will append signature if enabled
-		if errors.Is(err, das.BatchToDasFailed) {
+		if errors.Is(err, das.BatchToDasFailed) && ctx.Err() == nil {
+			b.dasStoreFailures++
+			b.lastDASFailure = time.Now()
+			// The next batch's destination should take the failure into account
+			b.discardSpeculativeBatch()
 			if config.DisableDasFallbackStoreDataOnChain {
 				return false, errors.New("unable to batch to DAS and fallback storing data on chain is disabled")
 			}
 			log.Warn("Falling back to storing data on chain", "err", err)
+			destination, err = b.chooseDestination(ctx, true)
+			if err != nil {
+				return false, err
+			}
+			destination.Reason = BatchDestinationReasonDASStoreFault
 		} else if err != nil {
 			return false, err
 		} else {
+			b.dasStoreFailures = 0
 			sequencerMsg = das.Serialize(cert)
 		}
 	}
+	var kzgBlobs []kzg4844.Blob
+	var blobHashes []common.Hash
+	if destination.Destination == BatchDestinationBlobs {
+		kzgBlobs, err = blobs.EncodeBlobs(sequencerMsg)
+		if err != nil {
+			return false, fmt.Errorf("error encoding batch into blobs: %w", err)
+		}
+		_, blobHashes, err = blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+		if err != nil {
+			return false, fmt.Errorf("error computing blob commitments: %w", err)
+		}
+	}
 
-	gasLimit, err := b.estimateGas(ctx, sequencerMsg, b.building.segments.delayedMsg)
+	gasLimit, err := b.estimateGas(ctx, sequencerMsg, b.building.segments.delayedMsg, blobHashes)
 	if err != nil {
 		return false, err
 	}
-	data, err := b.encodeAddBatch(new(big.Int).SetUint64(batchPosition.NextSeqNum), batchPosition.MessageCount, b.building.msgCount, sequencerMsg, b.building.segments.delayedMsg)
+	data, err := b.encodeAddBatch(new(big.Int).SetUint64(batchPosition.NextSeqNum), batchPosition.MessageCount, b.building.msgCount, sequencerMsg, b.building.segments.delayedMsg, destination.Destination == BatchDestinationBlobs)
 	if err != nil {
 		return false, err
 	}
@@ -1024,6 +1331,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		data,
 		gasLimit,
 		new(big.Int),
+		kzgBlobs,
 		b.accessList(
 			int(batchPosition.NextSeqNum),
 			int(b.building.segments.delayedMsg)),
@@ -1039,7 +1347,12 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		"prev delayed", batchPosition.DelayedMessageCount,
 		"current delayed", b.building.segments.delayedMsg,
 		"total segments", len(b.building.segments.rawSegments),
+		"destination", destination.Destination,
+		"destinationReason", destination.Reason,
+		"blobs", len(kzgBlobs),
 	)
+	recordBatchDestinationDecision(destination)
+	b.lastDestination.Store(&destination)
 	recentlyHitL1Bounds := time.Since(b.lastHitL1Bounds) < config.PollInterval*3
 	postedMessages := b.building.msgCount - batchPosition.MessageCount
 	unpostedMessages := msgCount - b.building.msgCount
@@ -1066,7 +1379,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		// Setting the backlog to 0 here ensures that we don't lower compression as a result.
 		b.backlog = 0
 	}
-	b.building = nil
+	b.resetBuilding()
 
 	// If we aren't queueing up transactions, wait for the receipt before moving on to the next batch.
 	if config.DataPoster.UseNoOpStorage {
@@ -1080,12 +1393,125 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	return true, nil
 }
 
+// resetBuilding drops the batch being built.
+func (b *BatchPoster) resetBuilding() {
+	if b.building != nil {
+		b.building.segments.abandon()
+		b.building = nil
+	}
+	b.buildingStatus.Store(nil)
+}
+
+// speculativeBatch is the batch after the one being posted, built in another thread.
+type speculativeBatch struct {
+	batch        *buildingBatch
+	startDelayed uint64
+	promise      containers.PromiseInterface[*buildingBatch]
+}
+
+// startSpeculativeBatch starts building a batch from the messages after the batch being posted,
+// so the next batch is mostly built and compressed by the time the current one is posted.
+// The compression level is based on the backlog as of the previous batch.
+func (b *BatchPoster) startSpeculativeBatch(ctx context.Context, start arbutil.MessageIndex, delayed uint64, msgCount arbutil.MessageIndex) {
+	b.discardSpeculativeBatch()
+	destination, err := b.chooseDestination(ctx, false)
+	if err != nil {
+		log.Warn("BatchPoster: not building the next batch speculatively", "err", err)
+		return
+	}
+	batch := &buildingBatch{
+		segments:      newBatchSegments(delayed, b.config(), b.backlog, destination.Destination, &b.StopWaiterSafe),
+		msgCount:      start,
+		startMsgCount: start,
+		destination:   destination,
+	}
+	b.speculative = &speculativeBatch{
+		batch:        batch,
+		startDelayed: delayed,
+		promise: stopwaiter.LaunchPromiseThread[*buildingBatch](&b.StopWaiterSafe, func(ctx context.Context) (*buildingBatch, error) {
+			return b.buildSpeculativeBatch(ctx, batch, msgCount)
+		}),
+	}
+}
+
+// buildSpeculativeBatch adds messages to batch within the L1 bounds as of now, which it gets itself
+// as those the current batch was built with may have been disabled for messages close to the maximum delay.
+func (b *BatchPoster) buildSpeculativeBatch(ctx context.Context, batch *buildingBatch, msgCount arbutil.MessageIndex) (*buildingBatch, error) {
+	bounds, err := b.getL1Bounds(ctx, b.config())
+	if err != nil {
+		return nil, err
+	}
+	for batch.msgCount < msgCount {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msg, err := b.streamer.GetMessage(batch.msgCount)
+		if err != nil {
+			return nil, err
+		}
+		if !bounds.allows(msg) {
+			batch.hitL1Bounds = time.Now()
+			break
+		}
+		success, err := batch.segments.AddMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		if !success {
+			// this batch is full
+			batch.haveUsefulMessage = true
+			break
+		}
+		if msg.Message.Header.Kind != arbostypes.L1MessageType_BatchPostingReport {
+			batch.haveUsefulMessage = true
+		}
+		batch.msgCount++
+	}
+	return batch, nil
+}
+
+// takeSpeculativeBatch waits for the speculative batch to be built and returns it if it starts at the given position.
+func (b *BatchPoster) takeSpeculativeBatch(position batchPosterPosition) *buildingBatch {
+	spec := b.speculative
+	if spec == nil {
+		return nil
+	}
+	b.speculative = nil
+	<-spec.promise.ReadyChan()
+	batch, err := spec.promise.Current()
+	if err != nil {
+		log.Warn("BatchPoster: failed to build the next batch speculatively", "err", err)
+		spec.batch.segments.abandon()
+		return nil
+	}
+	if batch.hitL1Bounds.After(b.lastHitL1Bounds) {
+		b.lastHitL1Bounds = batch.hitL1Bounds
+	}
+	if batch.startMsgCount != position.MessageCount || spec.startDelayed != position.DelayedMessageCount {
+		log.Debug("BatchPoster: discarding speculative batch at a different position", "start", batch.startMsgCount, "position", position.MessageCount)
+		batch.segments.abandon()
+		return nil
+	}
+	return batch
+}
+
+func (b *BatchPoster) discardSpeculativeBatch() {
+	spec := b.speculative
+	if spec == nil {
+		return
+	}
+	b.speculative = nil
+	spec.promise.Cancel()
+	<-spec.promise.ReadyChan()
+	spec.batch.segments.abandon()
+}
+
 func (b *BatchPoster) Start(ctxIn context.Context) {
 	b.dataPoster.Start(ctxIn)
 	b.redisLock.Start(ctxIn)
 	b.StopWaiter.Start(ctxIn, b)
 	b.LaunchThread(b.pollForReverts)
-	b.CallIteratively(func(ctx context.Context) time.Duration {
+	err := stopwaiter.CallIterativelyWith[struct{}](&b.StopWaiterSafe, func(ctx context.Context, _ struct{}) time.Duration {
 		var err error
 		if common.HexToAddress(b.config().GasRefunderAddress) != (common.Address{}) {
 			gasRefunderBalance, err := b.l1Reader.Client().BalanceAt(ctx, common.HexToAddress(b.config().GasRefunderAddress), nil)
@@ -1104,7 +1530,10 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 			}
 		}
 		if !b.redisLock.AttemptLock(ctx) {
-			b.building = nil
+			b.resetBuilding()
+			return b.config().PollInterval
+		}
+		if b.paused.Load() {
 			return b.config().PollInterval
 		}
 		posted, err := b.maybePostSequencerBatch(ctx)
@@ -1112,7 +1541,7 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 			b.firstEphemeralError = time.Time{}
 		}
 		if err != nil {
-			b.building = nil
+			b.resetBuilding()
 			logLevel := log.Error
 			// Likely the inbox tracker just isn't caught up.
 			// Let's see if this error disappears naturally.
@@ -1131,7 +1560,98 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 		} else {
 			return b.config().PollInterval
 		}
-	})
+	}, b.postNowChan)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BuildingBatchStatus describes the batch the batch poster is currently building.
+type BuildingBatchStatus struct {
+	StartMessage      arbutil.MessageIndex `json:"startMessage"`
+	MessageCount      arbutil.MessageIndex `json:"messageCount"`
+	Segments          int                  `json:"segments"`
+	UncompressedSize  int                  `json:"uncompressedSize"`
+	CompressedSize    int                  `json:"compressedSize"`
+	CompressionRatio  float64              `json:"compressionRatio"`
+	SizeLimit         int                  `json:"sizeLimit"`
+	HaveUsefulMessage bool                 `json:"haveUsefulMessage"`
+	Destination       string               `json:"destination"`
+	DestinationReason string               `json:"destinationReason"`
+}
+
+func (b *buildingBatch) status() *BuildingBatchStatus {
+	s := b.segments
+	// The compressed size excludes data the brotli writer hasn't flushed yet
+	compressedSize := s.compressedBuffer.Len()
+	status := &BuildingBatchStatus{
+		StartMessage:      b.startMsgCount,
+		MessageCount:      b.msgCount,
+		Segments:          len(s.rawSegments),
+		UncompressedSize:  s.totalUncompressedSize,
+		CompressedSize:    compressedSize,
+		SizeLimit:         s.sizeLimit,
+		HaveUsefulMessage: b.haveUsefulMessage,
+		Destination:       b.destination.Destination.String(),
+		DestinationReason: b.destination.Reason,
+	}
+	if compressedSize > 0 {
+		status.CompressionRatio = float64(s.totalUncompressedSize) / float64(compressedSize)
+	}
+	return status
+}
+
+// Position returns the position the next batch will be posted at.
+func (b *BatchPoster) Position(ctx context.Context) (*batchPosterPosition, uint64, error) {
+	nonce, batchPositionBytes, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	var batchPosition batchPosterPosition
+	if err := rlp.DecodeBytes(batchPositionBytes, &batchPosition); err != nil {
+		return nil, 0, fmt.Errorf("decoding batch position: %w", err)
+	}
+	return &batchPosition, nonce, nil
+}
+
+// BuildingBatch returns the status of the batch being built, or nil if there isn't one.
+func (b *BatchPoster) BuildingBatch() *BuildingBatchStatus {
+	return b.buildingStatus.Load()
+}
+
+// LastDestination returns the destination of the last posted batch, or nil if none was posted since starting.
+func (b *BatchPoster) LastDestination() *BatchDestinationDecision {
+	return b.lastDestination.Load()
+}
+
+// PostNow posts the batch being built as soon as possible, without waiting for it to fill up or the max delay.
+func (b *BatchPoster) PostNow() {
+	b.postNow.Store(true)
+	select {
+	case b.postNowChan <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops posting batches until Resume is called. Queued transactions are still replaced by fee.
+func (b *BatchPoster) Pause() {
+	b.paused.Store(true)
+}
+
+func (b *BatchPoster) Resume() {
+	b.paused.Store(false)
+	select {
+	case b.postNowChan <- struct{}{}:
+	default:
+	}
+}
+
+func (b *BatchPoster) Paused() bool {
+	return b.paused.Load()
+}
+
+func (b *BatchPoster) DataPoster() *dataposter.DataPoster {
+	return b.dataPoster
 }
 
 func (b *BatchPoster) StopAndWait() {
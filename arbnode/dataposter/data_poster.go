@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"database/sql"
 	"errors"
 	"fmt"
 	"math/big"
@@ -20,19 +21,24 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/go-redis/redis/v8"
+	"github.com/holiman/uint256"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/dbstorage"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/noop"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/slice"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/sqlstorage"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/blobs"
 	"github.com/offchainlabs/nitro/util/headerreader"
 	"github.com/offchainlabs/nitro/util/signature"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
@@ -52,24 +58,24 @@ type DataPoster struct {
 	stopwaiter.StopWaiter
 	headerReader      *headerreader.HeaderReader
 	client            arbutil.L1Interface
-	sender            common.Address
-	signer            signerFn
 	redisLock         AttemptLocker
 	config            ConfigFetcher
 	replacementTimes  []time.Duration
 	metadataRetriever func(ctx context.Context, blockNum *big.Int) ([]byte, error)
+	// Set if the fee strategy was given in the options rather than selected by the config
+	feeStrategyOverride FeeStrategy
 
 	// These fields are protected by the mutex.
 	// TODO: factor out these fields into separate structure, since now one
 	// needs to make sure call sites of methods that change these values hold
 	// the lock (currently ensured by having comments like:
 	// "the mutex must be held by the caller" above the function).
-	mutex      sync.Mutex
-	lastBlock  *big.Int
-	balance    *big.Int
-	nonce      uint64
-	queue      QueueStorage
-	errorCount map[uint64]int // number of consecutive intermittent errors rbf-ing or sending, per nonce
+	mutex sync.Mutex
+	// The accounts transactions are posted from, each with its own nonce and queue.
+	// The first is the main account; see getNextLaneNonceAndMaybeMeta for how the others are used.
+	lanes []*nonceLane
+	// The lane GetNextNonceAndMeta chose by balance, which PostTransaction then posts from
+	chosenLane *nonceLane
 }
 
 // signerFn is a signer function callback when a contract requires a method to
@@ -111,6 +117,10 @@ type DataPosterOpts struct {
 	Config            ConfigFetcher
 	MetadataRetriever func(ctx context.Context, blockNum *big.Int) ([]byte, error)
 	RedisKey          string // Redis storage key
+	// If set, this recommends fees instead of the fee strategy selected by the config.
+	FeeStrategy FeeStrategy
+	// Additional accounts to post transactions from, only for transactions which may come from any of them.
+	Lanes []DataPosterLaneOpts
 }
 
 func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, error) {
@@ -123,55 +133,112 @@ func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, erro
 		cfg.UseNoOpStorage = true
 		log.Info("Disabling data poster storage, as parent chain appears to be an Arbitrum chain without a mempool")
 	}
+	if opts.FeeStrategy == nil {
+		if _, err := newFeeStrategy(opts.HeaderReader.Client(), cfg); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Lanes) > 0 && cfg.LegacyStorageEncoding {
+		return nil, errors.New("data poster lanes can't be used with the legacy storage encoding")
+	}
+	var sqlDB *sql.DB
+	if !cfg.UseNoOpStorage && opts.RedisClient == nil && cfg.SQLStorage.DSN != "" {
+		sqlDB, err = sqlstorage.Open(&cfg.SQLStorage)
+		if err != nil {
+			return nil, fmt.Errorf("opening data poster SQL database: %w", err)
+		}
+	}
+	queue, err := newQueueStorage(ctx, opts, cfg, sqlDB, opts.Database, opts.RedisKey, cfg.SQLStorage.Table)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Lanes) > 0 && !queue.IsPersistent() {
+		// Which lane has unconfirmed transactions must survive restarts
+		return nil, errors.New("data poster lanes require queued transactions to be stored in a database, SQL, or Redis")
+	}
+	sender := opts.Auth.From
+	signer := func(_ context.Context, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		return opts.Auth.Signer(addr, tx)
+	}
+	if cfg.ExternalSigner.URL != "" {
+		signer, sender, err = externalSigner(ctx, &cfg.ExternalSigner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dp := &DataPoster{
+		headerReader:        opts.HeaderReader,
+		client:              opts.HeaderReader.Client(),
+		config:              opts.Config,
+		replacementTimes:    replacementTimes,
+		metadataRetriever:   opts.MetadataRetriever,
+		redisLock:           opts.RedisLock,
+		feeStrategyOverride: opts.FeeStrategy,
+		lanes:               []*nonceLane{newNonceLane(0, sender, signer, queue)},
+	}
+	for _, laneOpts := range opts.Lanes {
+		auth := laneOpts.Auth
+		if dp.IsSender(auth.From) {
+			return nil, fmt.Errorf("data poster account %v used by more than one lane", auth.From)
+		}
+		sqlTable := cfg.SQLStorage.Table + "_" + strings.ToLower(strings.TrimPrefix(auth.From.Hex(), "0x"))
+		queue, err := newQueueStorage(ctx, opts, cfg, sqlDB, laneOpts.Database, laneOpts.RedisKey, sqlTable)
+		if err != nil {
+			return nil, err
+		}
+		signer := func(_ context.Context, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return auth.Signer(addr, tx)
+		}
+		dp.lanes = append(dp.lanes, newNonceLane(len(dp.lanes), auth.From, signer, queue))
+	}
+	if len(dp.lanes) > 1 {
+		for _, lane := range dp.lanes {
+			lane.registerMetrics()
+		}
+	}
+	return dp, nil
+}
+
+// newQueueStorage creates the storage of a queue, stored under redisKey if Redis is used,
+// in sqlTable if a SQL database is used, or in db if database storage is used.
+func newQueueStorage(ctx context.Context, opts *DataPosterOpts, cfg *DataPosterConfig, sqlDB *sql.DB, db ethdb.Database, redisKey string, sqlTable string) (QueueStorage, error) {
 	encF := func() storage.EncoderDecoderInterface {
 		if opts.Config().LegacyStorageEncoding {
 			return &storage.LegacyEncoderDecoder{}
 		}
 		return &storage.EncoderDecoder{}
 	}
-	var queue QueueStorage
 	switch {
 	case cfg.UseNoOpStorage:
-		queue = &noop.Storage{}
+		return &noop.Storage{}, nil
 	case opts.RedisClient != nil:
-		var err error
-		queue, err = redisstorage.NewStorage(opts.RedisClient, opts.RedisKey, &cfg.RedisSigner, encF)
+		return redisstorage.NewStorage(opts.RedisClient, redisKey, &cfg.RedisSigner, encF)
+	case sqlDB != nil:
+		dialect, err := sqlstorage.DialectOf(cfg.SQLStorage.Driver)
 		if err != nil {
 			return nil, err
 		}
+		storage, err := sqlstorage.NewStorage(ctx, sqlDB, dialect, sqlTable, encF)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Dangerous.ClearDBStorage {
+			if err := storage.PruneAll(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return storage, nil
 	case cfg.UseDBStorage:
-		storage := dbstorage.New(opts.Database, func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
+		storage := dbstorage.New(db, func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
 		if cfg.Dangerous.ClearDBStorage {
 			if err := storage.PruneAll(ctx); err != nil {
 				return nil, err
 			}
 		}
-		queue = storage
+		return storage, nil
 	default:
-		queue = slice.NewStorage(func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
-	}
-	dp := &DataPoster{
-		headerReader: opts.HeaderReader,
-		client:       opts.HeaderReader.Client(),
-		sender:       opts.Auth.From,
-		signer: func(_ context.Context, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
-			return opts.Auth.Signer(addr, tx)
-		},
-		config:            opts.Config,
-		replacementTimes:  replacementTimes,
-		metadataRetriever: opts.MetadataRetriever,
-		queue:             queue,
-		redisLock:         opts.RedisLock,
-		errorCount:        make(map[uint64]int),
+		return slice.NewStorage(func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} }), nil
 	}
-	if cfg.ExternalSigner.URL != "" {
-		signer, sender, err := externalSigner(ctx, &cfg.ExternalSigner)
-		if err != nil {
-			return nil, err
-		}
-		dp.signer, dp.sender = signer, sender
-	}
-	return dp, nil
 }
 
 func rpcClient(ctx context.Context, opts *ExternalSignerCfg) (*rpc.Client, error) {
@@ -248,28 +315,29 @@ func externalSigner(ctx context.Context, opts *ExternalSignerCfg) (signerFn, com
 	}, sender, nil
 }
 
+// Sender returns the main account transactions are posted from.
 func (p *DataPoster) Sender() common.Address {
-	return p.sender
+	return p.lanes[0].sender
 }
 
 // Does basic check whether posting transaction with specified nonce would
 // result in exceeding maximum queue length or maximum transactions in mempool.
-func (p *DataPoster) canPostWithNonce(ctx context.Context, nextNonce uint64) error {
+func (p *DataPoster) canPostWithNonce(ctx context.Context, lane *nonceLane, nextNonce uint64) error {
 	cfg := p.config()
 	// If the queue has reached configured max size, don't post a transaction.
 	if cfg.MaxQueuedTransactions > 0 {
-		queueLen, err := p.queue.Length(ctx)
+		queueLen, err := lane.queue.Length(ctx)
 		if err != nil {
 			return fmt.Errorf("getting queue length: %w", err)
 		}
 		if queueLen >= cfg.MaxQueuedTransactions {
-			return fmt.Errorf("posting a transaction with nonce: %d will exceed max allowed dataposter queued transactions: %d, current nonce: %d", nextNonce, cfg.MaxQueuedTransactions, p.nonce)
+			return fmt.Errorf("posting a transaction with nonce: %d will exceed max allowed dataposter queued transactions: %d, current nonce: %d", nextNonce, cfg.MaxQueuedTransactions, lane.nonce)
 		}
 	}
 	// Check that posting a new transaction won't exceed maximum pending
 	// transactions in mempool.
 	if cfg.MaxMempoolTransactions > 0 {
-		unconfirmedNonce, err := p.client.NonceAt(ctx, p.sender, nil)
+		unconfirmedNonce, err := p.client.NonceAt(ctx, lane.sender, nil)
 		if err != nil {
 			return fmt.Errorf("getting nonce of a dataposter sender: %w", err)
 		}
@@ -285,41 +353,51 @@ func (p *DataPoster) waitForL1Finality() bool {
 }
 
 // Requires the caller hold the mutex.
-// Returns the next nonce, its metadata if stored, a bool indicating if the metadata is present, and an error.
+// Returns the lane to post from, the next nonce, its metadata if stored, a bool indicating if the metadata is present, and an error.
 // Unlike GetNextNonceAndMeta, this does not call the metadataRetriever if the metadata is not stored in the queue.
-func (p *DataPoster) getNextNonceAndMaybeMeta(ctx context.Context) (uint64, []byte, bool, error) {
+// If reuseChosenLane is set, a lane previously chosen by balance is used again rather than choosing anew.
+func (p *DataPoster) getNextNonceAndMaybeMeta(ctx context.Context, reuseChosenLane bool) (*nonceLane, uint64, []byte, bool, error) {
+	if len(p.lanes) > 1 {
+		return p.getNextLaneNonceAndMaybeMeta(ctx, reuseChosenLane)
+	}
+	lane := p.lanes[0]
+	nonce, meta, hasMeta, err := p.getLaneNextNonceAndMaybeMeta(ctx, lane)
+	return lane, nonce, meta, hasMeta, err
+}
+
+func (p *DataPoster) getLaneNextNonceAndMaybeMeta(ctx context.Context, lane *nonceLane) (uint64, []byte, bool, error) {
 	// Ensure latest finalized block state is available.
 	blockNum, err := p.client.BlockNumber(ctx)
 	if err != nil {
 		return 0, nil, false, err
 	}
-	lastQueueItem, err := p.queue.FetchLast(ctx)
+	lastQueueItem, err := lane.queue.FetchLast(ctx)
 	if err != nil {
 		return 0, nil, false, fmt.Errorf("fetching last element from queue: %w", err)
 	}
 	if lastQueueItem != nil {
 		nextNonce := lastQueueItem.Data.Nonce + 1
-		if err := p.canPostWithNonce(ctx, nextNonce); err != nil {
+		if err := p.canPostWithNonce(ctx, lane, nextNonce); err != nil {
 			return 0, nil, false, err
 		}
 		return nextNonce, lastQueueItem.Meta, true, nil
 	}
 
-	if err := p.updateNonce(ctx); err != nil {
-		if !p.queue.IsPersistent() && p.waitForL1Finality() {
+	if err := p.updateNonce(ctx, lane); err != nil {
+		if !lane.queue.IsPersistent() && p.waitForL1Finality() {
 			return 0, nil, false, fmt.Errorf("error getting latest finalized nonce (and queue is not persistent): %w", err)
 		}
 		// Fall back to using a recent block to get the nonce. This is safe because there's nothing in the queue.
 		nonceQueryBlock := arbmath.UintToBig(arbmath.SaturatingUSub(blockNum, 1))
 		log.Warn("failed to update nonce with queue empty; falling back to using a recent block", "recentBlock", nonceQueryBlock, "err", err)
-		nonce, err := p.client.NonceAt(ctx, p.sender, nonceQueryBlock)
+		nonce, err := p.client.NonceAt(ctx, lane.sender, nonceQueryBlock)
 		if err != nil {
 			return 0, nil, false, fmt.Errorf("failed to get nonce at block %v: %w", nonceQueryBlock, err)
 		}
-		p.lastBlock = nonceQueryBlock
-		p.nonce = nonce
+		lane.lastBlock = nonceQueryBlock
+		lane.nonce = nonce
 	}
-	return p.nonce, nil, false, nil
+	return lane.nonce, nil, false, nil
 }
 
 // GetNextNonceAndMeta retrieves generates next nonce, validates that a
@@ -328,45 +406,65 @@ func (p *DataPoster) getNextNonceAndMaybeMeta(ctx context.Context) (uint64, []by
 func (p *DataPoster) GetNextNonceAndMeta(ctx context.Context) (uint64, []byte, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-	nonce, meta, hasMeta, err := p.getNextNonceAndMaybeMeta(ctx)
+	_, nonce, meta, hasMeta, err := p.getNextNonceAndMaybeMeta(ctx, false)
 	if err != nil {
 		return 0, nil, err
 	}
 	if !hasMeta {
-		meta, err = p.metadataRetriever(ctx, p.lastBlock)
+		meta, err = p.metadataRetriever(ctx, p.metadataBlock())
 	}
 	return nonce, meta, err
 }
 
 const minRbfIncrease = arbmath.OneInBips * 11 / 10
 
-func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit uint64, lastFeeCap *big.Int, lastTipCap *big.Int, dataCreatedAt time.Time, backlogOfBatches uint64) (*big.Int, *big.Int, error) {
+// The parent chain's mempool requires blob transaction replacements to double every fee cap.
+const minBlobTxRbfIncrease = arbmath.OneInBips * 2
+
+func (p *DataPoster) feeAndTipCaps(ctx context.Context, lane *nonceLane, nonce uint64, gasLimit uint64, numBlobs uint64, lastFeeCap *big.Int, lastTipCap *big.Int, lastBlobFeeCap *big.Int, dataCreatedAt time.Time, backlogOfBatches uint64) (*big.Int, *big.Int, *big.Int, error) {
 	config := p.config()
 	latestHeader, err := p.headerReader.LastHeader(ctx)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if latestHeader.BaseFee == nil {
-		return nil, nil, fmt.Errorf("latest parent chain block %v missing BaseFee (either the parent chain does not have EIP-1559 or the parent chain node is not synced)", latestHeader.Number)
+		return nil, nil, nil, fmt.Errorf("latest parent chain block %v missing BaseFee (either the parent chain does not have EIP-1559 or the parent chain node is not synced)", latestHeader.Number)
+	}
+	var newBlobFeeCap *big.Int
+	if numBlobs > 0 {
+		if latestHeader.ExcessBlobGas == nil {
+			return nil, nil, nil, fmt.Errorf("latest parent chain block %v missing ExcessBlobGas but blobs were specified in data poster transaction (either the parent chain does not have EIP-4844 or the parent chain node is not synced)", latestHeader.Number)
+		}
+		newBlobFeeCap = new(big.Int).Mul(eip4844.CalcBlobFee(*latestHeader.ExcessBlobGas), big.NewInt(2))
 	}
 	softConfBlock := arbmath.BigSubByUint(latestHeader.Number, config.NonceRbfSoftConfs)
-	softConfNonce, err := p.client.NonceAt(ctx, p.sender, softConfBlock)
+	softConfNonce, err := p.client.NonceAt(ctx, lane.sender, softConfBlock)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get latest nonce %v blocks ago (block %v): %w", config.NonceRbfSoftConfs, softConfBlock, err)
+		return nil, nil, nil, fmt.Errorf("failed to get latest nonce %v blocks ago (block %v): %w", config.NonceRbfSoftConfs, softConfBlock, err)
 	}
-	newFeeCap := new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
-	newFeeCap = arbmath.BigMax(newFeeCap, arbmath.FloatToBig(config.MinFeeCapGwei*params.GWei))
-
-	newTipCap, err := p.client.SuggestGasTipCap(ctx)
+	strategy, err := p.feeStrategy(config)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	feeRequest := &FeeRequest{
+		Header:           latestHeader,
+		Nonce:            nonce,
+		NumBlobs:         numBlobs,
+		DataCreatedAt:    dataCreatedAt,
+		BacklogOfBatches: backlogOfBatches,
+	}
+	newFeeCap, newTipCap, err := strategy.SuggestFees(ctx, feeRequest)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	newTipCap = arbmath.BigMax(newTipCap, arbmath.FloatToBig(config.MinTipCapGwei*params.GWei))
-	newTipCap = arbmath.BigMin(newTipCap, arbmath.FloatToBig(config.MaxTipCapGwei*params.GWei))
 
+	rbfIncrease := minRbfIncrease
+	if numBlobs > 0 {
+		rbfIncrease = minBlobTxRbfIncrease
+	}
 	hugeTipIncrease := false
 	if lastTipCap != nil {
-		newTipCap = arbmath.BigMax(newTipCap, arbmath.BigMulByBips(lastTipCap, minRbfIncrease))
+		newTipCap = arbmath.BigMax(newTipCap, arbmath.BigMulByBips(lastTipCap, rbfIncrease))
 		// hugeTipIncrease is true if the new tip cap is at least 10x the last tip cap
 		hugeTipIncrease = lastTipCap.Sign() == 0 || arbmath.BigDiv(newTipCap, lastTipCap).Cmp(big.NewInt(10)) >= 0
 	}
@@ -377,16 +475,19 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		// If we're trying to drastically increase the tip, make sure we increase the fee cap by minRbfIncrease.
 		newFeeCap = arbmath.BigMax(newFeeCap, arbmath.BigMulByBips(lastFeeCap, minRbfIncrease))
 	}
+	if numBlobs > 0 {
+		// Blob transactions can't be replaced without raising every fee cap.
+		if lastFeeCap != nil {
+			newFeeCap = arbmath.BigMax(newFeeCap, arbmath.BigMulByBips(lastFeeCap, rbfIncrease))
+		}
+		if lastBlobFeeCap != nil {
+			newBlobFeeCap = arbmath.BigMax(newBlobFeeCap, arbmath.BigMulByBips(lastBlobFeeCap, rbfIncrease))
+		}
+	}
 
 	elapsed := time.Since(dataCreatedAt)
-	// MaxFeeCap = (BacklogOfBatches^2 * UrgencyGWei^2 + TargetPriceGWei) * GWei
-	maxFeeCap :=
-		arbmath.FloatToBig(
-			(float64(arbmath.SquareUint(backlogOfBatches))*
-				arbmath.SquareFloat(config.UrgencyGwei) +
-				config.TargetPriceGwei) *
-				params.GWei)
-	if arbmath.BigGreaterThan(newFeeCap, maxFeeCap) {
+	maxFeeCap := strategy.MaxFeeCap(feeRequest)
+	if maxFeeCap != nil && arbmath.BigGreaterThan(newFeeCap, maxFeeCap) {
 		log.Warn(
 			"reducing proposed fee cap to current maximum",
 			"proposedFeeCap", newFeeCap,
@@ -396,7 +497,7 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		newFeeCap = maxFeeCap
 	}
 
-	latestBalance := p.balance
+	latestBalance := lane.balance
 	balanceForTx := new(big.Int).Set(latestBalance)
 	if config.AllocateMempoolBalance && !config.UseNoOpStorage {
 		// We reserve half the balance for the first transaction, and then split the remaining balance for all after that.
@@ -407,6 +508,26 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 			balanceForTx.Div(balanceForTx, arbmath.UintToBig(config.MaxMempoolTransactions-1))
 		}
 	}
+	if numBlobs > 0 {
+		// The blob gas is paid for out of the same balance, so set it aside before computing the fee cap.
+		blobGas := arbmath.UintToBig(numBlobs * params.BlobTxBlobGasPerBlob)
+		blobCost := new(big.Int).Mul(blobGas, newBlobFeeCap)
+		if arbmath.BigGreaterThan(blobCost, balanceForTx) {
+			balanceBlobFeeCap := new(big.Int).Div(balanceForTx, blobGas)
+			log.Error(
+				"lack of L1 balance prevents posting transaction with desired blob fee cap",
+				"balance", latestBalance,
+				"balanceForTransaction", balanceForTx,
+				"numBlobs", numBlobs,
+				"desiredBlobFeeCap", newBlobFeeCap,
+				"balanceBlobFeeCap", balanceBlobFeeCap,
+				"nonce", nonce,
+			)
+			newBlobFeeCap = balanceBlobFeeCap
+			blobCost = new(big.Int).Mul(blobGas, newBlobFeeCap)
+		}
+		balanceForTx.Sub(balanceForTx, blobCost)
+	}
 	balanceFeeCap := arbmath.BigDivByUint(balanceForTx, gasLimit)
 	if arbmath.BigGreaterThan(newFeeCap, balanceFeeCap) {
 		log.Error(
@@ -432,14 +553,63 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		newTipCap = new(big.Int).Set(newFeeCap)
 	}
 
-	return newFeeCap, newTipCap, nil
+	return newFeeCap, newTipCap, newBlobFeeCap, nil
 }
 
-func (p *DataPoster) PostTransaction(ctx context.Context, dataCreatedAt time.Time, nonce uint64, meta []byte, to common.Address, calldata []byte, gasLimit uint64, value *big.Int, accessList types.AccessList) (*types.Transaction, error) {
+// feeStrategy returns the strategy set in the options if any, or otherwise the one selected by the config.
+func (p *DataPoster) feeStrategy(config *DataPosterConfig) (FeeStrategy, error) {
+	if p.feeStrategyOverride != nil {
+		return p.feeStrategyOverride, nil
+	}
+	return newFeeStrategy(p.client, config)
+}
+
+// signTx builds the transaction for data, a blob transaction if sidecar is non-nil, and signs it.
+func (p *DataPoster) signTx(ctx context.Context, lane *nonceLane, data *types.DynamicFeeTx, blobFeeCap *big.Int, sidecar *types.BlobTxSidecar) (*types.Transaction, error) {
+	if sidecar == nil {
+		return lane.signer(ctx, lane.sender, types.NewTx(data))
+	}
+	if data.To == nil {
+		return nil, errors.New("blob transactions must have a recipient")
+	}
+	blobHashes := make([]common.Hash, 0, len(sidecar.Commitments))
+	for _, commitment := range sidecar.Commitments {
+		blobHashes = append(blobHashes, blobs.VersionedHash(commitment))
+	}
+	value := data.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fullTx, err := lane.signer(ctx, lane.sender, types.NewTx(&types.BlobTx{
+		Nonce:      data.Nonce,
+		GasTipCap:  uint256.MustFromBig(data.GasTipCap),
+		GasFeeCap:  uint256.MustFromBig(data.GasFeeCap),
+		Gas:        data.Gas,
+		To:         *data.To,
+		Value:      uint256.MustFromBig(value),
+		Data:       data.Data,
+		AccessList: data.AccessList,
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: blobHashes,
+		Sidecar:    sidecar,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	// External signers return the transaction without its sidecar.
+	if fullTx.BlobTxSidecar() == nil {
+		fullTx = fullTx.WithBlobTxSidecar(sidecar)
+	}
+	return fullTx, nil
+}
+
+// PostTransaction posts a transaction with the given calldata. If kzgBlobs is non-empty, it's posted as
+// an EIP-4844 blob transaction carrying them.
+func (p *DataPoster) PostTransaction(ctx context.Context, dataCreatedAt time.Time, nonce uint64, meta []byte, to common.Address, calldata []byte, gasLimit uint64, value *big.Int, kzgBlobs []kzg4844.Blob, accessList types.AccessList) (*types.Transaction, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	expectedNonce, _, _, err := p.getNextNonceAndMaybeMeta(ctx)
+	lane, expectedNonce, _, _, err := p.getNextNonceAndMaybeMeta(ctx, true)
 	if err != nil {
 		return nil, err
 	}
@@ -447,15 +617,31 @@ func (p *DataPoster) PostTransaction(ctx context.Context, dataCreatedAt time.Tim
 		return nil, fmt.Errorf("data poster expected next transaction to have nonce %v but was requested to post transaction with nonce %v", expectedNonce, nonce)
 	}
 
-	err = p.updateBalance(ctx)
+	err = p.updateBalance(ctx, lane)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update data poster balance: %w", err)
 	}
 
-	feeCap, tipCap, err := p.feeAndTipCaps(ctx, nonce, gasLimit, nil, nil, dataCreatedAt, 0)
+	feeCap, tipCap, blobFeeCap, err := p.feeAndTipCaps(ctx, lane, nonce, gasLimit, uint64(len(kzgBlobs)), nil, nil, nil, dataCreatedAt, 0)
 	if err != nil {
 		return nil, err
 	}
+	var sidecar *types.BlobTxSidecar
+	if len(kzgBlobs) > 0 {
+		commitments, _, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute KZG commitments: %w", err)
+		}
+		proofs, err := blobs.ComputeBlobProofs(kzgBlobs, commitments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute KZG proofs: %w", err)
+		}
+		sidecar = &types.BlobTxSidecar{
+			Blobs:       kzgBlobs,
+			Commitments: commitments,
+			Proofs:      proofs,
+		}
+	}
 	inner := types.DynamicFeeTx{
 		Nonce:      nonce,
 		GasTipCap:  tipCap,
@@ -466,7 +652,7 @@ func (p *DataPoster) PostTransaction(ctx context.Context, dataCreatedAt time.Tim
 		Data:       calldata,
 		AccessList: accessList,
 	}
-	fullTx, err := p.signer(ctx, p.sender, types.NewTx(&inner))
+	fullTx, err := p.signTx(ctx, lane, &inner, blobFeeCap, sidecar)
 	if err != nil {
 		return nil, fmt.Errorf("signing transaction: %w", err)
 	}
@@ -478,26 +664,31 @@ func (p *DataPoster) PostTransaction(ctx context.Context, dataCreatedAt time.Tim
 		Created:         dataCreatedAt,
 		NextReplacement: time.Now().Add(p.replacementTimes[0]),
 	}
-	return fullTx, p.sendTx(ctx, nil, &queuedTx)
+	if err := p.sendTx(ctx, lane, nil, &queuedTx); err != nil {
+		return nil, err
+	}
+	p.chosenLane = nil
+	return fullTx, nil
 }
 
 // the mutex must be held by the caller
-func (p *DataPoster) saveTx(ctx context.Context, prevTx, newTx *storage.QueuedTransaction) error {
+func (p *DataPoster) saveTx(ctx context.Context, lane *nonceLane, prevTx, newTx *storage.QueuedTransaction) error {
 	if prevTx != nil && prevTx.Data.Nonce != newTx.Data.Nonce {
 		return fmt.Errorf("prevTx nonce %v doesn't match newTx nonce %v", prevTx.Data.Nonce, newTx.Data.Nonce)
 	}
-	if err := p.queue.Put(ctx, newTx.Data.Nonce, prevTx, newTx); err != nil {
+	if err := lane.queue.Put(ctx, newTx.Data.Nonce, prevTx, newTx); err != nil {
 		return fmt.Errorf("putting new tx in the queue: %w", err)
 	}
 	return nil
 }
 
-func (p *DataPoster) sendTx(ctx context.Context, prevTx *storage.QueuedTransaction, newTx *storage.QueuedTransaction) error {
+func (p *DataPoster) sendTx(ctx context.Context, lane *nonceLane, prevTx *storage.QueuedTransaction, newTx *storage.QueuedTransaction) error {
 	if prevTx == nil || (newTx.FullTx.Hash() != prevTx.FullTx.Hash()) {
-		if err := p.saveTx(ctx, prevTx, newTx); err != nil {
+		if err := p.saveTx(ctx, lane, prevTx, newTx); err != nil {
 			return err
 		}
 	}
+	lane.recordReplacement(newTx.FullTx)
 	if err := p.client.SendTransaction(ctx, newTx.FullTx); err != nil {
 		if !strings.Contains(err.Error(), "already known") && !strings.Contains(err.Error(), "nonce too low") {
 			log.Warn("DataPoster failed to send transaction", "err", err, "nonce", newTx.FullTx.Nonce(), "feeCap", newTx.FullTx.GasFeeCap(), "tipCap", newTx.FullTx.GasTipCap())
@@ -509,19 +700,39 @@ func (p *DataPoster) sendTx(ctx context.Context, prevTx *storage.QueuedTransacti
 	}
 	newerTx := *newTx
 	newerTx.Sent = true
-	return p.saveTx(ctx, newTx, &newerTx)
+	return p.saveTx(ctx, lane, newTx, &newerTx)
 }
 
 // The mutex must be held by the caller.
-func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransaction, backlogOfBatches uint64) error {
-	newFeeCap, newTipCap, err := p.feeAndTipCaps(ctx, prevTx.Data.Nonce, prevTx.Data.Gas, prevTx.Data.GasFeeCap, prevTx.Data.GasTipCap, prevTx.Created, backlogOfBatches)
+func (p *DataPoster) replaceTx(ctx context.Context, lane *nonceLane, prevTx *storage.QueuedTransaction, backlogOfBatches uint64) error {
+	return p.replaceTxImpl(ctx, lane, prevTx, backlogOfBatches, false)
+}
+
+// replaceTxImpl replaces prevTx if the recommended fees have risen enough, or if force is set,
+// with at least the minimum increase the parent chain's mempool accepts.
+// The mutex must be held by the caller.
+func (p *DataPoster) replaceTxImpl(ctx context.Context, lane *nonceLane, prevTx *storage.QueuedTransaction, backlogOfBatches uint64, force bool) error {
+	// The blobs and their fee cap are only kept in the full transaction.
+	sidecar := prevTx.FullTx.BlobTxSidecar()
+	numBlobs := uint64(len(prevTx.FullTx.BlobHashes()))
+	if numBlobs > 0 && sidecar == nil {
+		return fmt.Errorf("queued blob transaction with nonce %v is missing its blobs", prevTx.Data.Nonce)
+	}
+	newFeeCap, newTipCap, newBlobFeeCap, err := p.feeAndTipCaps(ctx, lane, prevTx.Data.Nonce, prevTx.Data.Gas, numBlobs, prevTx.Data.GasFeeCap, prevTx.Data.GasTipCap, prevTx.FullTx.BlobGasFeeCap(), prevTx.Created, backlogOfBatches)
 	if err != nil {
 		return err
 	}
 
-	minNewFeeCap := arbmath.BigMulByBips(prevTx.Data.GasFeeCap, minRbfIncrease)
+	rbfIncrease := minRbfIncrease
+	if numBlobs > 0 {
+		rbfIncrease = minBlobTxRbfIncrease
+	}
+	minNewFeeCap := arbmath.BigMulByBips(prevTx.Data.GasFeeCap, rbfIncrease)
+	if force {
+		newFeeCap, newTipCap, newBlobFeeCap = minimumReplacementFees(prevTx, numBlobs > 0, newFeeCap, newTipCap, newBlobFeeCap)
+	}
 	newTx := *prevTx
-	if newFeeCap.Cmp(minNewFeeCap) < 0 {
+	if newFeeCap.Cmp(minNewFeeCap) < 0 || (numBlobs > 0 && newBlobFeeCap.Cmp(arbmath.BigMulByBips(prevTx.FullTx.BlobGasFeeCap(), rbfIncrease)) < 0) {
 		log.Debug(
 			"no need to replace by fee transaction",
 			"nonce", prevTx.Data.Nonce,
@@ -531,7 +742,7 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 			"recommendedTipCap", newTipCap,
 		)
 		newTx.NextReplacement = time.Now().Add(time.Minute)
-		return p.sendTx(ctx, prevTx, &newTx)
+		return p.sendTx(ctx, lane, prevTx, &newTx)
 	}
 
 	elapsed := time.Since(prevTx.Created)
@@ -545,18 +756,127 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 	newTx.Sent = false
 	newTx.Data.GasFeeCap = newFeeCap
 	newTx.Data.GasTipCap = newTipCap
-	newTx.FullTx, err = p.signer(ctx, p.sender, types.NewTx(&newTx.Data))
+	newTx.FullTx, err = p.signTx(ctx, lane, &newTx.Data, newBlobFeeCap, sidecar)
 	if err != nil {
 		return err
 	}
 
-	return p.sendTx(ctx, prevTx, &newTx)
+	return p.sendTx(ctx, lane, prevTx, &newTx)
+}
+
+// BumpFee replaces the pending transaction from sender with the given nonce, raising its fees by at least
+// the minimum replacement increase even if the current fee recommendations don't call for it.
+func (p *DataPoster) BumpFee(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	lane := p.laneOf(sender)
+	if lane == nil {
+		return nil, fmt.Errorf("data poster doesn't post transactions from %v", sender)
+	}
+	if nonce < lane.nonce {
+		return nil, fmt.Errorf("transaction with nonce %v is already confirmed", nonce)
+	}
+	queueContents, err := lane.queue.FetchContents(ctx, nonce, 1)
+	if err != nil {
+		return nil, fmt.Errorf("fetching queue contents: %w", err)
+	}
+	if len(queueContents) == 0 || queueContents[0].Data.Nonce != nonce {
+		return nil, fmt.Errorf("no queued transaction with nonce %v", nonce)
+	}
+	if err := p.updateBalance(ctx, lane); err != nil {
+		return nil, fmt.Errorf("failed to update data poster balance: %w", err)
+	}
+	tx := queueContents[0]
+	err = p.replaceTxImpl(ctx, lane, tx, 0, true)
+	p.maybeLogError(lane, err, tx, "failed to bump fee of transaction")
+	if err != nil {
+		return nil, err
+	}
+	queueContents, err = lane.queue.FetchContents(ctx, nonce, 1)
+	if err != nil {
+		return nil, fmt.Errorf("fetching queue contents: %w", err)
+	}
+	if len(queueContents) == 0 {
+		return nil, fmt.Errorf("transaction with nonce %v disappeared from the queue", nonce)
+	}
+	return queueContents[0].FullTx, nil
+}
+
+// ReplacementRecord describes a transaction sent for a nonce.
+type ReplacementRecord struct {
+	Hash       common.Hash `json:"hash"`
+	GasFeeCap  *big.Int    `json:"gasFeeCap"`
+	GasTipCap  *big.Int    `json:"gasTipCap"`
+	BlobFeeCap *big.Int    `json:"blobFeeCap,omitempty"`
+	Sent       time.Time   `json:"sent"`
+}
+
+// The most transactions remembered per nonce; older ones are forgotten first.
+const maxReplacementHistory = 32
+
+// The data poster's mutex must be held by the caller.
+func (l *nonceLane) recordReplacement(tx *types.Transaction) {
+	history := l.replacementHistory[tx.Nonce()]
+	if len(history) > 0 && history[len(history)-1].Hash == tx.Hash() {
+		return
+	}
+	history = append(history, ReplacementRecord{
+		Hash:       tx.Hash(),
+		GasFeeCap:  tx.GasFeeCap(),
+		GasTipCap:  tx.GasTipCap(),
+		BlobFeeCap: tx.BlobGasFeeCap(),
+		Sent:       time.Now(),
+	})
+	if len(history) > maxReplacementHistory {
+		history = history[len(history)-maxReplacementHistory:]
+	}
+	l.replacementHistory[tx.Nonce()] = history
+}
+
+// QueueStatus is a snapshot of the unconfirmed transactions of one of the data poster's accounts.
+type QueueStatus struct {
+	Sender  common.Address
+	Balance *big.Int
+	// The nonce of the first unconfirmed transaction
+	Nonce        uint64
+	Transactions []*storage.QueuedTransaction
+	// The transactions sent for each nonce since this node started
+	Replacements map[uint64][]ReplacementRecord
+}
+
+// Queue returns up to maxResults of the queued transactions which aren't yet confirmed for each account.
+func (p *DataPoster) Queue(ctx context.Context, maxResults uint64) ([]*QueueStatus, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var statuses []*QueueStatus
+	for _, lane := range p.lanes {
+		txs, err := lane.queue.FetchContents(ctx, lane.nonce, maxResults)
+		if err != nil {
+			return nil, fmt.Errorf("fetching queue contents of %v: %w", lane.sender, err)
+		}
+		status := &QueueStatus{
+			Sender:       lane.sender,
+			Nonce:        lane.nonce,
+			Transactions: txs,
+			Replacements: make(map[uint64][]ReplacementRecord),
+		}
+		if lane.balance != nil {
+			status.Balance = new(big.Int).Set(lane.balance)
+		}
+		for _, tx := range txs {
+			if history, ok := lane.replacementHistory[tx.Data.Nonce]; ok {
+				status.Replacements[tx.Data.Nonce] = append([]ReplacementRecord{}, history...)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
 }
 
 // Gets latest known or finalized block header (depending on config flag),
-// gets the nonce of the dataposter sender and stores it if it has increased.
+// gets the nonce of the lane's sender and stores it if it has increased.
 // The mutex must be held by the caller.
-func (p *DataPoster) updateNonce(ctx context.Context) error {
+func (p *DataPoster) updateNonce(ctx context.Context, lane *nonceLane) error {
 	var blockNumQuery *big.Int
 	if p.waitForL1Finality() {
 		blockNumQuery = big.NewInt(int64(rpc.FinalizedBlockNumber))
@@ -565,73 +885,81 @@ func (p *DataPoster) updateNonce(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get the latest or finalized L1 header: %w", err)
 	}
-	if p.lastBlock != nil && arbmath.BigEquals(p.lastBlock, header.Number) {
+	if lane.lastBlock != nil && arbmath.BigEquals(lane.lastBlock, header.Number) {
 		return nil
 	}
-	nonce, err := p.client.NonceAt(ctx, p.sender, header.Number)
+	nonce, err := p.client.NonceAt(ctx, lane.sender, header.Number)
 	if err != nil {
-		if p.lastBlock != nil {
-			log.Warn("Failed to get current nonce", "lastBlock", p.lastBlock, "newBlock", header.Number, "err", err)
+		if lane.lastBlock != nil {
+			log.Warn("Failed to get current nonce", "lastBlock", lane.lastBlock, "newBlock", header.Number, "err", err)
 			return nil
 		}
 		return err
 	}
 	// Ignore if nonce hasn't increased.
-	if nonce <= p.nonce {
+	if nonce <= lane.nonce {
 		// Still update last block number.
-		if nonce == p.nonce {
-			p.lastBlock = header.Number
+		if nonce == lane.nonce {
+			lane.lastBlock = header.Number
 		}
 		return nil
 	}
-	log.Info("Data poster transactions confirmed", "previousNonce", p.nonce, "newNonce", nonce, "previousL1Block", p.lastBlock, "newL1Block", header.Number)
-	if len(p.errorCount) > 0 {
-		for x := p.nonce; x < nonce; x++ {
-			delete(p.errorCount, x)
+	log.Info("Data poster transactions confirmed", "sender", lane.sender, "previousNonce", lane.nonce, "newNonce", nonce, "previousL1Block", lane.lastBlock, "newL1Block", header.Number)
+	if len(lane.errorCount) > 0 {
+		for x := lane.nonce; x < nonce; x++ {
+			delete(lane.errorCount, x)
+		}
+	}
+	for x := range lane.replacementHistory {
+		if x < nonce {
+			delete(lane.replacementHistory, x)
 		}
 	}
 	// We don't prune the most recent transaction in order to ensure that the data poster
 	// always has a reference point in its queue of the latest transaction nonce and metadata.
-	// nonce > 0 is implied by nonce > p.nonce, so this won't underflow.
-	if err := p.queue.Prune(ctx, nonce-1); err != nil {
+	// nonce > 0 is implied by nonce > lane.nonce, so this won't underflow.
+	if err := lane.queue.Prune(ctx, nonce-1); err != nil {
 		return err
 	}
 	// We update these two variables together because they should remain in sync even if there's an error.
-	p.lastBlock = header.Number
-	p.nonce = nonce
+	lane.lastBlock = header.Number
+	lane.nonce = nonce
 	return nil
 }
 
-// Updates dataposter balance to balance at pending block.
-func (p *DataPoster) updateBalance(ctx context.Context) error {
+// Updates the lane's balance to balance at pending block.
+func (p *DataPoster) updateBalance(ctx context.Context, lane *nonceLane) error {
 	// Use the pending (representated as -1) balance because we're looking at batches we'd post,
 	// so we want to see how much gas we could afford with our pending state.
-	balance, err := p.client.BalanceAt(ctx, p.sender, big.NewInt(-1))
+	balance, err := p.client.BalanceAt(ctx, lane.sender, big.NewInt(-1))
 	if err != nil {
 		return err
 	}
-	p.balance = balance
+	lane.balance = balance
+	if lane.balanceGauge != nil {
+		lane.balanceGauge.Update(arbmath.BalancePerEther(balance))
+	}
 	return nil
 }
 
 const maxConsecutiveIntermittentErrors = 10
 
-func (p *DataPoster) maybeLogError(err error, tx *storage.QueuedTransaction, msg string) {
+func (p *DataPoster) maybeLogError(lane *nonceLane, err error, tx *storage.QueuedTransaction, msg string) {
 	nonce := tx.Data.Nonce
 	if err == nil {
-		delete(p.errorCount, nonce)
+		delete(lane.errorCount, nonce)
 		return
 	}
 	logLevel := log.Error
 	if errors.Is(err, storage.ErrStorageRace) {
-		p.errorCount[nonce]++
-		if p.errorCount[nonce] <= maxConsecutiveIntermittentErrors {
+		lane.errorCount[nonce]++
+		if lane.errorCount[nonce] <= maxConsecutiveIntermittentErrors {
 			logLevel = log.Debug
 		}
 	} else {
-		delete(p.errorCount, nonce)
+		delete(lane.errorCount, nonce)
 	}
-	logLevel(msg, "err", err, "nonce", nonce, "feeCap", tx.Data.GasFeeCap, "tipCap", tx.Data.GasTipCap, "gas", tx.Data.Gas)
+	logLevel(msg, "err", err, "sender", lane.sender, "nonce", nonce, "feeCap", tx.Data.GasFeeCap, "tipCap", tx.Data.GasTipCap, "gas", tx.Data.Gas)
 }
 
 const minWait = time.Second * 10
@@ -645,55 +973,11 @@ func (p *DataPoster) Start(ctxIn context.Context) {
 		if !p.redisLock.AttemptLock(ctx) {
 			return minWait
 		}
-		err := p.updateBalance(ctx)
-		if err != nil {
-			log.Warn("failed to update tx poster balance", "err", err)
-			return minWait
-		}
-		err = p.updateNonce(ctx)
-		if err != nil {
-			// This is non-fatal because it's only needed for clearing out old queue items.
-			log.Warn("failed to update tx poster nonce", "err", err)
-		}
-		now := time.Now()
-		nextCheck := now.Add(p.replacementTimes[0])
-		maxTxsToRbf := p.config().MaxMempoolTransactions
-		if maxTxsToRbf == 0 {
-			maxTxsToRbf = 512
-		}
-		unconfirmedNonce, err := p.client.NonceAt(ctx, p.sender, nil)
-		if err != nil {
-			log.Warn("Failed to get latest nonce", "err", err)
-			return minWait
-		}
-		// We use unconfirmedNonce here to replace-by-fee transactions that aren't in a block,
-		// excluding those that are in an unconfirmed block. If a reorg occurs, we'll continue
-		// replacing them by fee.
-		queueContents, err := p.queue.FetchContents(ctx, unconfirmedNonce, maxTxsToRbf)
-		if err != nil {
-			log.Error("Failed to fetch tx queue contents", "err", err)
-			return minWait
-		}
-		for index, tx := range queueContents {
-			backlogOfBatches := len(queueContents) - index - 1
-			replacing := false
-			if now.After(tx.NextReplacement) {
-				replacing = true
-				err := p.replaceTx(ctx, tx, uint64(backlogOfBatches))
-				p.maybeLogError(err, tx, "failed to replace-by-fee transaction")
-			}
-			if nextCheck.After(tx.NextReplacement) {
-				nextCheck = tx.NextReplacement
-			}
-			if !replacing && !tx.Sent {
-				err := p.sendTx(ctx, tx, tx)
-				p.maybeLogError(err, tx, "failed to re-send transaction")
-				if err != nil {
-					nextSend := time.Now().Add(time.Minute)
-					if nextCheck.After(nextSend) {
-						nextCheck = nextSend
-					}
-				}
+		nextCheck := time.Now().Add(p.replacementTimes[0])
+		for _, lane := range p.lanes {
+			laneNextCheck := p.maintainLane(ctx, lane)
+			if nextCheck.After(laneNextCheck) {
+				nextCheck = laneNextCheck
 			}
 		}
 		wait := time.Until(nextCheck)
@@ -704,6 +988,68 @@ func (p *DataPoster) Start(ctxIn context.Context) {
 	})
 }
 
+// maintainLane updates the lane's balance and nonce and replaces or re-sends its transactions as needed.
+// Returns when the lane should next be checked.
+// The mutex must be held by the caller.
+func (p *DataPoster) maintainLane(ctx context.Context, lane *nonceLane) time.Time {
+	err := p.updateBalance(ctx, lane)
+	if err != nil {
+		log.Warn("failed to update tx poster balance", "sender", lane.sender, "err", err)
+		return time.Now()
+	}
+	err = p.updateNonce(ctx, lane)
+	if err != nil {
+		// This is non-fatal because it's only needed for clearing out old queue items.
+		log.Warn("failed to update tx poster nonce", "sender", lane.sender, "err", err)
+	}
+	now := time.Now()
+	nextCheck := now.Add(p.replacementTimes[0])
+	maxTxsToRbf := p.config().MaxMempoolTransactions
+	if maxTxsToRbf == 0 {
+		maxTxsToRbf = 512
+	}
+	unconfirmedNonce, err := p.client.NonceAt(ctx, lane.sender, nil)
+	if err != nil {
+		log.Warn("Failed to get latest nonce", "sender", lane.sender, "err", err)
+		return now
+	}
+	// We use unconfirmedNonce here to replace-by-fee transactions that aren't in a block,
+	// excluding those that are in an unconfirmed block. If a reorg occurs, we'll continue
+	// replacing them by fee.
+	queueContents, err := lane.queue.FetchContents(ctx, unconfirmedNonce, maxTxsToRbf)
+	if err != nil {
+		log.Error("Failed to fetch tx queue contents", "sender", lane.sender, "err", err)
+		return now
+	}
+	if lane.pendingGauge != nil {
+		lane.pendingGauge.Update(int64(len(queueContents)))
+	}
+	p.maybeFailOver(ctx, lane, queueContents)
+	for index, tx := range queueContents {
+		backlogOfBatches := len(queueContents) - index - 1
+		replacing := false
+		if now.After(tx.NextReplacement) {
+			replacing = true
+			err := p.replaceTx(ctx, lane, tx, uint64(backlogOfBatches))
+			p.maybeLogError(lane, err, tx, "failed to replace-by-fee transaction")
+		}
+		if nextCheck.After(tx.NextReplacement) {
+			nextCheck = tx.NextReplacement
+		}
+		if !replacing && !tx.Sent {
+			err := p.sendTx(ctx, lane, tx, tx)
+			p.maybeLogError(lane, err, tx, "failed to re-send transaction")
+			if err != nil {
+				nextSend := time.Now().Add(time.Minute)
+				if nextCheck.After(nextSend) {
+					nextCheck = nextSend
+				}
+			}
+		}
+	}
+	return nextCheck
+}
+
 // Implements queue-alike storage that can
 // - Insert item at specified index
 // - Update item with the condition that existing value equals assumed value
@@ -731,21 +1077,26 @@ type DataPosterConfig struct {
 	ReplacementTimes string                     `koanf:"replacement-times"`
 	// This is forcibly disabled if the parent chain is an Arbitrum chain,
 	// so you should probably use DataPoster's waitForL1Finality method instead of reading this field directly.
-	WaitForL1Finality      bool              `koanf:"wait-for-l1-finality" reload:"hot"`
-	MaxMempoolTransactions uint64            `koanf:"max-mempool-transactions" reload:"hot"`
-	MaxQueuedTransactions  int               `koanf:"max-queued-transactions" reload:"hot"`
-	TargetPriceGwei        float64           `koanf:"target-price-gwei" reload:"hot"`
-	UrgencyGwei            float64           `koanf:"urgency-gwei" reload:"hot"`
-	MinFeeCapGwei          float64           `koanf:"min-fee-cap-gwei" reload:"hot"`
-	MinTipCapGwei          float64           `koanf:"min-tip-cap-gwei" reload:"hot"`
-	MaxTipCapGwei          float64           `koanf:"max-tip-cap-gwei" reload:"hot"`
-	NonceRbfSoftConfs      uint64            `koanf:"nonce-rbf-soft-confs" reload:"hot"`
-	AllocateMempoolBalance bool              `koanf:"allocate-mempool-balance" reload:"hot"`
-	UseDBStorage           bool              `koanf:"use-db-storage"`
-	UseNoOpStorage         bool              `koanf:"use-noop-storage"`
-	LegacyStorageEncoding  bool              `koanf:"legacy-storage-encoding" reload:"hot"`
-	Dangerous              DangerousConfig   `koanf:"dangerous"`
-	ExternalSigner         ExternalSignerCfg `koanf:"external-signer"`
+	WaitForL1Finality      bool                `koanf:"wait-for-l1-finality" reload:"hot"`
+	MaxMempoolTransactions uint64              `koanf:"max-mempool-transactions" reload:"hot"`
+	MaxQueuedTransactions  int                 `koanf:"max-queued-transactions" reload:"hot"`
+	TargetPriceGwei        float64             `koanf:"target-price-gwei" reload:"hot"`
+	UrgencyGwei            float64             `koanf:"urgency-gwei" reload:"hot"`
+	MinFeeCapGwei          float64             `koanf:"min-fee-cap-gwei" reload:"hot"`
+	MinTipCapGwei          float64             `koanf:"min-tip-cap-gwei" reload:"hot"`
+	MaxTipCapGwei          float64             `koanf:"max-tip-cap-gwei" reload:"hot"`
+	NonceRbfSoftConfs      uint64              `koanf:"nonce-rbf-soft-confs" reload:"hot"`
+	AllocateMempoolBalance bool                `koanf:"allocate-mempool-balance" reload:"hot"`
+	LaneStuckTimeout       time.Duration       `koanf:"lane-stuck-timeout" reload:"hot"`
+	FeeStrategy            string              `koanf:"fee-strategy" reload:"hot"`
+	PercentileFee          PercentileFeeConfig `koanf:"percentile-fee" reload:"hot"`
+	FixedFee               FixedFeeConfig      `koanf:"fixed-fee" reload:"hot"`
+	UseDBStorage           bool                `koanf:"use-db-storage"`
+	UseNoOpStorage         bool                `koanf:"use-noop-storage"`
+	SQLStorage             sqlstorage.Config   `koanf:"sql-storage"`
+	LegacyStorageEncoding  bool                `koanf:"legacy-storage-encoding" reload:"hot"`
+	Dangerous              DangerousConfig     `koanf:"dangerous"`
+	ExternalSigner         ExternalSignerCfg   `koanf:"external-signer"`
 }
 
 type ExternalSignerCfg struct {
@@ -789,6 +1140,8 @@ func DataPosterConfigAddOptions(prefix string, f *pflag.FlagSet, defaultDataPost
 	f.Float64(prefix+".max-tip-cap-gwei", defaultDataPosterConfig.MaxTipCapGwei, "the maximum tip cap to post transactions at")
 	f.Uint64(prefix+".nonce-rbf-soft-confs", defaultDataPosterConfig.NonceRbfSoftConfs, "the maximum probable reorg depth, used to determine when a transaction will no longer likely need replaced-by-fee")
 	f.Bool(prefix+".allocate-mempool-balance", defaultDataPosterConfig.AllocateMempoolBalance, "if true, don't put transactions in the mempool that spend a total greater than the batch poster's balance")
+	f.String(prefix+".fee-strategy", defaultDataPosterConfig.FeeStrategy, "how to choose transaction fees (\"target-price\" to offer twice the base fee and the suggested tip up to the target price and urgency, \"percentile\" to offer a percentile of recent tips, or \"fixed\" to offer fixed fees)")
+	f.Duration(prefix+".lane-stuck-timeout", defaultDataPosterConfig.LaneStuckTimeout, "if posting from multiple accounts, how long a transaction can wait to be included before its account's transactions are cancelled to post from another account (0 = never)")
 	f.Bool(prefix+".use-db-storage", defaultDataPosterConfig.UseDBStorage, "uses database storage when enabled")
 	f.Bool(prefix+".use-noop-storage", defaultDataPosterConfig.UseNoOpStorage, "uses noop storage, it doesn't store anything")
 	f.Bool(prefix+".legacy-storage-encoding", defaultDataPosterConfig.LegacyStorageEncoding, "encodes items in a legacy way (as it was before dropping generics)")
@@ -796,6 +1149,9 @@ func DataPosterConfigAddOptions(prefix string, f *pflag.FlagSet, defaultDataPost
 	signature.SimpleHmacConfigAddOptions(prefix+".redis-signer", f)
 	addDangerousOptions(prefix+".dangerous", f)
 	addExternalSignerOptions(prefix+".external-signer", f)
+	sqlstorage.ConfigAddOptions(prefix+".sql-storage", f)
+	PercentileFeeConfigAddOptions(prefix+".percentile-fee", f)
+	FixedFeeConfigAddOptions(prefix+".fixed-fee", f)
 }
 
 func addDangerousOptions(prefix string, f *pflag.FlagSet) {
@@ -821,8 +1177,13 @@ var DefaultDataPosterConfig = DataPosterConfig{
 	MaxTipCapGwei:          5,
 	NonceRbfSoftConfs:      1,
 	AllocateMempoolBalance: true,
+	LaneStuckTimeout:       30 * time.Minute,
+	FeeStrategy:            TargetPriceFeeStrategy,
+	PercentileFee:          DefaultPercentileFeeConfig,
+	FixedFee:               DefaultFixedFeeConfig,
 	UseDBStorage:           true,
 	UseNoOpStorage:         false,
+	SQLStorage:             sqlstorage.DefaultConfig,
 	LegacyStorageEncoding:  false,
 	Dangerous:              DangerousConfig{ClearDBStorage: false},
 	ExternalSigner:         ExternalSignerCfg{Method: "eth_signTransaction"},
@@ -845,8 +1206,12 @@ var TestDataPosterConfig = DataPosterConfig{
 	MaxTipCapGwei:          5,
 	NonceRbfSoftConfs:      1,
 	AllocateMempoolBalance: true,
+	FeeStrategy:            TargetPriceFeeStrategy,
+	PercentileFee:          DefaultPercentileFeeConfig,
+	FixedFee:               DefaultFixedFeeConfig,
 	UseDBStorage:           false,
 	UseNoOpStorage:         false,
+	SQLStorage:             sqlstorage.DefaultConfig,
 	LegacyStorageEncoding:  false,
 	ExternalSigner:         ExternalSignerCfg{Method: "eth_signTransaction"},
 }
@@ -13,14 +13,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 )
 
 func TestParseReplacementTimes(t *testing.T) {
@@ -58,6 +62,188 @@ func TestParseReplacementTimes(t *testing.T) {
 	}
 }
 
+func TestRecordReplacement(t *testing.T) {
+	lane := newNonceLane(0, common.Address{}, nil, nil)
+	for i := 0; i < maxReplacementHistory+5; i++ {
+		tx := types.NewTx(&types.DynamicFeeTx{
+			Nonce:     7,
+			GasFeeCap: big.NewInt(int64(100 + i)),
+			GasTipCap: big.NewInt(1),
+		})
+		lane.recordReplacement(tx)
+		// Resending the same transaction isn't a replacement.
+		lane.recordReplacement(tx)
+	}
+	history := lane.replacementHistory[7]
+	if len(history) != maxReplacementHistory {
+		t.Fatalf("Got %d replacements, want: %d", len(history), maxReplacementHistory)
+	}
+	if got, want := history[0].GasFeeCap.Int64(), int64(105); got != want {
+		t.Errorf("Oldest remembered fee cap: %d, want: %d", got, want)
+	}
+	if got, want := history[len(history)-1].GasFeeCap.Int64(), int64(100+maxReplacementHistory+4); got != want {
+		t.Errorf("Newest remembered fee cap: %d, want: %d", got, want)
+	}
+}
+
+func TestLaneCancellation(t *testing.T) {
+	sender := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	lane := newNonceLane(1, sender, nil, nil)
+	for _, tc := range []struct {
+		desc string
+		tx   storage.QueuedTransaction
+		want bool
+	}{
+		{
+			desc: "empty transfer to sender",
+			tx:   storage.QueuedTransaction{Data: types.DynamicFeeTx{To: &sender}},
+			want: true,
+		},
+		{
+			desc: "transfer to another account",
+			tx:   storage.QueuedTransaction{Data: types.DynamicFeeTx{To: &other}},
+		},
+		{
+			desc: "transaction with calldata",
+			tx:   storage.QueuedTransaction{Data: types.DynamicFeeTx{To: &sender, Data: []byte{1}}},
+		},
+		{
+			desc: "transaction with metadata",
+			tx:   storage.QueuedTransaction{Data: types.DynamicFeeTx{To: &sender}, Meta: []byte{1}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := lane.isCancellation(&tc.tx); got != tc.want {
+				t.Errorf("isCancellation() = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMinimumReplacementFees(t *testing.T) {
+	data := types.DynamicFeeTx{
+		GasFeeCap: big.NewInt(1000),
+		GasTipCap: big.NewInt(100),
+	}
+	prevTx := &storage.QueuedTransaction{Data: data, FullTx: types.NewTx(&data)}
+	feeCap, tipCap, _ := minimumReplacementFees(prevTx, false, big.NewInt(500), big.NewInt(50), nil)
+	if feeCap.Int64() != 1100 || tipCap.Int64() != 110 {
+		t.Errorf("Got fee cap %v and tip cap %v, want: 1100 and 110", feeCap, tipCap)
+	}
+	feeCap, tipCap, _ = minimumReplacementFees(prevTx, false, big.NewInt(2000), big.NewInt(5000), nil)
+	if feeCap.Int64() != 2000 || tipCap.Int64() != 2000 {
+		t.Errorf("Got fee cap %v and tip cap %v, want: 2000 and 2000", feeCap, tipCap)
+	}
+}
+
+type stubFeeHistory struct {
+	history *ethereum.FeeHistory
+}
+
+func (s *stubFeeHistory) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(params.GWei), nil
+}
+
+func (s *stubFeeHistory) FeeHistory(context.Context, uint64, *big.Int, []float64) (*ethereum.FeeHistory, error) {
+	return s.history, nil
+}
+
+func TestFeeStrategies(t *testing.T) {
+	ctx := context.Background()
+	gwei := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.GWei)) }
+	client := &stubFeeHistory{history: &ethereum.FeeHistory{
+		Reward:  [][]*big.Int{{gwei(3)}, {gwei(1)}, {gwei(2)}, {gwei(10)}, {gwei(4)}},
+		BaseFee: []*big.Int{gwei(5), gwei(5), gwei(5), gwei(5), gwei(5), gwei(7)},
+	}}
+	req := &FeeRequest{Header: &types.Header{Number: big.NewInt(100), BaseFee: gwei(6)}, BacklogOfBatches: 2}
+	for _, tc := range []struct {
+		desc       string
+		configure  func(*DataPosterConfig)
+		baseFeeCap *big.Int
+		tipCap     *big.Int
+		maxFeeCap  *big.Int
+	}{
+		{
+			desc:       "target price",
+			configure:  func(*DataPosterConfig) {},
+			baseFeeCap: gwei(12),
+			tipCap:     gwei(1),
+			maxFeeCap:  gwei(60 + 4*4),
+		},
+		{
+			desc: "percentile",
+			configure: func(c *DataPosterConfig) {
+				c.FeeStrategy = PercentileFeeStrategy
+				c.PercentileFee.MaxFeeCapGwei = 100
+			},
+			baseFeeCap: gwei(14),
+			tipCap:     gwei(3),
+			maxFeeCap:  gwei(100),
+		},
+		{
+			desc: "percentile without max fee cap",
+			configure: func(c *DataPosterConfig) {
+				c.FeeStrategy = PercentileFeeStrategy
+				c.MaxTipCapGwei = 2
+			},
+			baseFeeCap: gwei(14),
+			tipCap:     gwei(2),
+		},
+		{
+			desc: "fixed",
+			configure: func(c *DataPosterConfig) {
+				c.FeeStrategy = FixedFeeStrategy
+				c.FixedFee = FixedFeeConfig{FeeCapGwei: 20, TipCapGwei: 1}
+			},
+			baseFeeCap: gwei(19),
+			tipCap:     gwei(1),
+			maxFeeCap:  gwei(20),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := TestDataPosterConfig
+			tc.configure(&config)
+			strategy, err := newFeeStrategy(client, &config)
+			if err != nil {
+				t.Fatalf("newFeeStrategy() unexpected error: %v", err)
+			}
+			baseFeeCap, tipCap, err := strategy.SuggestFees(ctx, req)
+			if err != nil {
+				t.Fatalf("SuggestFees() unexpected error: %v", err)
+			}
+			if baseFeeCap.Cmp(tc.baseFeeCap) != 0 || tipCap.Cmp(tc.tipCap) != 0 {
+				t.Errorf("SuggestFees() = %v, %v, want: %v, %v", baseFeeCap, tipCap, tc.baseFeeCap, tc.tipCap)
+			}
+			maxFeeCap := strategy.MaxFeeCap(req)
+			if (maxFeeCap == nil) != (tc.maxFeeCap == nil) || (maxFeeCap != nil && maxFeeCap.Cmp(tc.maxFeeCap) != 0) {
+				t.Errorf("MaxFeeCap() = %v, want: %v", maxFeeCap, tc.maxFeeCap)
+			}
+		})
+	}
+}
+
+func TestInvalidFeeStrategy(t *testing.T) {
+	for _, configure := range []func(*DataPosterConfig){
+		func(c *DataPosterConfig) { c.FeeStrategy = "cheapest" },
+		func(c *DataPosterConfig) { c.FeeStrategy = FixedFeeStrategy },
+		func(c *DataPosterConfig) {
+			c.FeeStrategy = FixedFeeStrategy
+			c.FixedFee = FixedFeeConfig{FeeCapGwei: 1, TipCapGwei: 2}
+		},
+		func(c *DataPosterConfig) {
+			c.FeeStrategy = PercentileFeeStrategy
+			c.PercentileFee.Percentile = 101
+		},
+	} {
+		config := TestDataPosterConfig
+		configure(&config)
+		if _, err := newFeeStrategy(&stubFeeHistory{}, &config); err == nil {
+			t.Errorf("newFeeStrategy() with fee strategy %q and config %+v didn't fail", config.FeeStrategy, config)
+		}
+	}
+}
+
 func TestExternalSigner(t *testing.T) {
 	ctx := context.Background()
 	httpSrv, srv := newServer(ctx, t)
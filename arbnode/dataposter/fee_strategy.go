@@ -0,0 +1,230 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// FeeRequest describes the transaction a FeeStrategy is recommending fees for.
+type FeeRequest struct {
+	// The latest parent chain header, which has a base fee
+	Header *types.Header
+	Nonce  uint64
+	// The number of blobs, if it's a blob transaction
+	NumBlobs      uint64
+	DataCreatedAt time.Time
+	// The number of transactions queued after this one
+	BacklogOfBatches uint64
+}
+
+// FeeStrategy recommends the fees to post data poster transactions with.
+// The data poster raises the recommended fees as needed to replace a previous transaction,
+// and lowers them to what the balance can afford.
+type FeeStrategy interface {
+	// SuggestFees returns the most to pay per gas for the base fee, and the tip cap.
+	// The transaction's fee cap is their sum, after the tip cap is raised to replace any previous transaction.
+	SuggestFees(ctx context.Context, req *FeeRequest) (baseFeeCap *big.Int, tipCap *big.Int, err error)
+	// MaxFeeCap returns the most the fee cap may be raised to, or nil if it's only limited by the balance.
+	MaxFeeCap(req *FeeRequest) *big.Int
+}
+
+const (
+	TargetPriceFeeStrategy = "target-price"
+	PercentileFeeStrategy  = "percentile"
+	FixedFeeStrategy       = "fixed"
+)
+
+type feeHistoryReader interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+type tipCapSuggester interface {
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+}
+
+// newFeeStrategy returns the fee strategy selected by the config.
+// It's created for every transaction, so changes to the config take effect immediately.
+func newFeeStrategy(client tipCapSuggester, config *DataPosterConfig) (FeeStrategy, error) {
+	switch config.FeeStrategy {
+	case "", TargetPriceFeeStrategy:
+		return &targetPriceStrategy{client: client, config: config}, nil
+	case PercentileFeeStrategy:
+		reader, ok := client.(feeHistoryReader)
+		if !ok {
+			return nil, errors.New("the parent chain client doesn't support fee history, which the percentile fee strategy requires")
+		}
+		if err := config.PercentileFee.Validate(); err != nil {
+			return nil, err
+		}
+		return &percentileStrategy{client: reader, config: config}, nil
+	case FixedFeeStrategy:
+		if err := config.FixedFee.Validate(); err != nil {
+			return nil, err
+		}
+		return &fixedStrategy{config: &config.FixedFee}, nil
+	default:
+		return nil, fmt.Errorf("unknown data poster fee strategy \"%v\" (must be %v, %v or %v)", config.FeeStrategy, TargetPriceFeeStrategy, PercentileFeeStrategy, FixedFeeStrategy)
+	}
+}
+
+// clampTipCap limits the tip cap to the configured minimum and maximum.
+func clampTipCap(tipCap *big.Int, config *DataPosterConfig) *big.Int {
+	tipCap = arbmath.BigMax(tipCap, arbmath.FloatToBig(config.MinTipCapGwei*params.GWei))
+	return arbmath.BigMin(tipCap, arbmath.FloatToBig(config.MaxTipCapGwei*params.GWei))
+}
+
+// targetPriceStrategy offers twice the latest base fee and the parent chain node's suggested tip,
+// up to a max fee cap which grows quadratically with the backlog of transactions.
+type targetPriceStrategy struct {
+	client tipCapSuggester
+	config *DataPosterConfig
+}
+
+func (s *targetPriceStrategy) SuggestFees(ctx context.Context, req *FeeRequest) (*big.Int, *big.Int, error) {
+	baseFeeCap := new(big.Int).Mul(req.Header.BaseFee, big.NewInt(2))
+	baseFeeCap = arbmath.BigMax(baseFeeCap, arbmath.FloatToBig(s.config.MinFeeCapGwei*params.GWei))
+	tipCap, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return baseFeeCap, clampTipCap(tipCap, s.config), nil
+}
+
+func (s *targetPriceStrategy) MaxFeeCap(req *FeeRequest) *big.Int {
+	// MaxFeeCap = (BacklogOfBatches^2 * UrgencyGWei^2 + TargetPriceGWei) * GWei
+	return arbmath.FloatToBig(
+		(float64(arbmath.SquareUint(req.BacklogOfBatches))*
+			arbmath.SquareFloat(s.config.UrgencyGwei) +
+			s.config.TargetPriceGwei) *
+			params.GWei)
+}
+
+type PercentileFeeConfig struct {
+	Blocks            uint64  `koanf:"blocks" reload:"hot"`
+	Percentile        float64 `koanf:"percentile" reload:"hot"`
+	BaseFeeMultiplier float64 `koanf:"base-fee-multiplier" reload:"hot"`
+	MaxFeeCapGwei     float64 `koanf:"max-fee-cap-gwei" reload:"hot"`
+}
+
+func (c *PercentileFeeConfig) Validate() error {
+	if c.Blocks == 0 {
+		return errors.New("percentile fee strategy must look at at least one block")
+	}
+	if c.Percentile < 0 || c.Percentile > 100 {
+		return fmt.Errorf("invalid percentile fee strategy percentile %v", c.Percentile)
+	}
+	if c.BaseFeeMultiplier < 1 {
+		return fmt.Errorf("percentile fee strategy base fee multiplier %v is less than 1", c.BaseFeeMultiplier)
+	}
+	return nil
+}
+
+var DefaultPercentileFeeConfig = PercentileFeeConfig{
+	Blocks:            20,
+	Percentile:        50,
+	BaseFeeMultiplier: 2,
+	MaxFeeCapGwei:     0,
+}
+
+func PercentileFeeConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Uint64(prefix+".blocks", DefaultPercentileFeeConfig.Blocks, "the number of recent parent chain blocks to take the tip percentile of")
+	f.Float64(prefix+".percentile", DefaultPercentileFeeConfig.Percentile, "the percentile of the tips paid in each recent block to offer, the median of which across blocks is used")
+	f.Float64(prefix+".base-fee-multiplier", DefaultPercentileFeeConfig.BaseFeeMultiplier, "what to multiply the next block's base fee by to get the most to pay for the base fee")
+	f.Float64(prefix+".max-fee-cap-gwei", DefaultPercentileFeeConfig.MaxFeeCapGwei, "the maximum fee cap to post transactions at (0 = only limited by the balance)")
+}
+
+// percentileStrategy offers the median across recent blocks of a percentile of the tips paid in each,
+// as reported by eth_feeHistory, along with a multiple of the next block's base fee.
+type percentileStrategy struct {
+	client feeHistoryReader
+	config *DataPosterConfig
+}
+
+func (s *percentileStrategy) SuggestFees(ctx context.Context, req *FeeRequest) (*big.Int, *big.Int, error) {
+	config := &s.config.PercentileFee
+	history, err := s.client.FeeHistory(ctx, config.Blocks, req.Header.Number, []float64{config.Percentile})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get parent chain fee history: %w", err)
+	}
+	var tips []*big.Int
+	for _, rewards := range history.Reward {
+		if len(rewards) > 0 && rewards[0] != nil {
+			tips = append(tips, rewards[0])
+		}
+	}
+	tipCap := new(big.Int)
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tipCap.Set(tips[len(tips)/2])
+	}
+	// The fee history includes the base fee of the block after the last one.
+	baseFee := req.Header.BaseFee
+	if len(history.BaseFee) > 0 && history.BaseFee[len(history.BaseFee)-1] != nil {
+		baseFee = history.BaseFee[len(history.BaseFee)-1]
+	}
+	baseFeeCap := arbmath.BigMulByFrac(baseFee, int64(config.BaseFeeMultiplier*1000), 1000)
+	baseFeeCap = arbmath.BigMax(baseFeeCap, arbmath.FloatToBig(s.config.MinFeeCapGwei*params.GWei))
+	return baseFeeCap, clampTipCap(tipCap, s.config), nil
+}
+
+func (s *percentileStrategy) MaxFeeCap(*FeeRequest) *big.Int {
+	if s.config.PercentileFee.MaxFeeCapGwei == 0 {
+		return nil
+	}
+	return arbmath.FloatToBig(s.config.PercentileFee.MaxFeeCapGwei * params.GWei)
+}
+
+type FixedFeeConfig struct {
+	FeeCapGwei float64 `koanf:"fee-cap-gwei" reload:"hot"`
+	TipCapGwei float64 `koanf:"tip-cap-gwei" reload:"hot"`
+}
+
+func (c *FixedFeeConfig) Validate() error {
+	if c.FeeCapGwei <= 0 {
+		return errors.New("fixed fee strategy requires a positive fee cap")
+	}
+	if c.TipCapGwei < 0 || c.TipCapGwei > c.FeeCapGwei {
+		return fmt.Errorf("fixed fee strategy tip cap %v must be between 0 and the fee cap %v", c.TipCapGwei, c.FeeCapGwei)
+	}
+	return nil
+}
+
+var DefaultFixedFeeConfig = FixedFeeConfig{
+	FeeCapGwei: 0,
+	TipCapGwei: 0,
+}
+
+func FixedFeeConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Float64(prefix+".fee-cap-gwei", DefaultFixedFeeConfig.FeeCapGwei, "the fee cap to post every transaction at")
+	f.Float64(prefix+".tip-cap-gwei", DefaultFixedFeeConfig.TipCapGwei, "the tip cap to post every transaction at")
+}
+
+// fixedStrategy always offers the same fees, which suits parent chains without a fee market for tips,
+// such as Arbitrum chains. Replacements can't raise the fee cap above the fixed one.
+type fixedStrategy struct {
+	config *FixedFeeConfig
+}
+
+func (s *fixedStrategy) SuggestFees(context.Context, *FeeRequest) (*big.Int, *big.Int, error) {
+	feeCap := arbmath.FloatToBig(s.config.FeeCapGwei * params.GWei)
+	tipCap := arbmath.FloatToBig(s.config.TipCapGwei * params.GWei)
+	return new(big.Int).Sub(feeCap, tipCap), tipCap, nil
+}
+
+func (s *fixedStrategy) MaxFeeCap(*FeeRequest) *big.Int {
+	return arbmath.FloatToBig(s.config.FeeCapGwei * params.GWei)
+}
@@ -0,0 +1,281 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/blobs"
+)
+
+// DataPosterLaneOpts configures an additional account for the data poster to post transactions from.
+type DataPosterLaneOpts struct {
+	Auth *bind.TransactOpts
+	// The database the lane's queue is stored in if database storage is used.
+	// It must not overlap with the database of any other lane.
+	Database ethdb.Database
+	// The Redis key the lane's queue is stored under if Redis storage is used.
+	RedisKey string
+}
+
+// nonceLane is an account the data poster posts transactions from, with its own nonce sequence and queue.
+// All fields besides sender and signer are protected by the data poster's mutex.
+type nonceLane struct {
+	index  int
+	sender common.Address
+	signer signerFn
+
+	lastBlock  *big.Int
+	balance    *big.Int
+	nonce      uint64
+	queue      QueueStorage
+	errorCount map[uint64]int // number of consecutive intermittent errors rbf-ing or sending, per nonce
+	// Transactions sent for each unconfirmed nonce, for introspection only, so it isn't persisted
+	replacementHistory map[uint64][]ReplacementRecord
+
+	// The oldest nonce which isn't in a block yet, and since when it's been that, to detect stuck transactions
+	headNonce uint64
+	headSince time.Time
+
+	// Only set if the data poster has more than one lane
+	balanceGauge  metrics.GaugeFloat64
+	pendingGauge  metrics.Gauge
+	failoverCount metrics.Counter
+}
+
+func newNonceLane(index int, sender common.Address, signer signerFn, queue QueueStorage) *nonceLane {
+	return &nonceLane{
+		index:              index,
+		sender:             sender,
+		signer:             signer,
+		queue:              queue,
+		errorCount:         make(map[uint64]int),
+		replacementHistory: make(map[uint64][]ReplacementRecord),
+	}
+}
+
+func (l *nonceLane) registerMetrics() {
+	l.balanceGauge = metrics.GetOrRegisterGaugeFloat64(fmt.Sprintf("arb/dataposter/lane/%d/balanceether", l.index), nil)
+	l.pendingGauge = metrics.GetOrRegisterGauge(fmt.Sprintf("arb/dataposter/lane/%d/pending", l.index), nil)
+	l.failoverCount = metrics.GetOrRegisterCounter(fmt.Sprintf("arb/dataposter/lane/%d/failover", l.index), nil)
+}
+
+// A cancellation replaces a transaction with an empty transfer to the sender, and has no metadata.
+func (l *nonceLane) isCancellation(tx *storage.QueuedTransaction) bool {
+	return tx.Meta == nil && len(tx.Data.Data) == 0 && tx.Data.To != nil && *tx.Data.To == l.sender
+}
+
+// Senders returns the accounts the data poster posts transactions from, starting with the main account.
+func (p *DataPoster) Senders() []common.Address {
+	senders := make([]common.Address, 0, len(p.lanes))
+	for _, lane := range p.lanes {
+		senders = append(senders, lane.sender)
+	}
+	return senders
+}
+
+// IsSender returns whether the data poster posts transactions from the given account.
+func (p *DataPoster) IsSender(addr common.Address) bool {
+	return p.laneOf(addr) != nil
+}
+
+func (p *DataPoster) laneOf(addr common.Address) *nonceLane {
+	for _, lane := range p.lanes {
+		if lane.sender == addr {
+			return lane
+		}
+	}
+	return nil
+}
+
+// getNextLaneNonceAndMaybeMeta picks the lane to post the next transaction from when there's more than one.
+// Transactions from different accounts can be included in any order, so to keep transactions in the order
+// they're posted in, only one lane at a time can have transactions which aren't confirmed yet:
+//   - If a lane has unconfirmed transactions, the next transaction is posted after them from the same lane.
+//   - If its unconfirmed transactions are cancellations, nothing is posted until they're confirmed,
+//     as until then it isn't known whether they or the transactions they replaced were included.
+//   - Otherwise, the lane with the highest balance is used. As every transaction is confirmed, the
+//     metadata isn't taken from the queue but retrieved from the chain, like with an empty queue.
+//     The lane is chosen when GetNextNonceAndMeta is called, and PostTransaction reuses that choice
+//     so the transaction is posted with the nonce it was given.
+//
+// The mutex must be held by the caller.
+func (p *DataPoster) getNextLaneNonceAndMaybeMeta(ctx context.Context, reuseChosenLane bool) (*nonceLane, uint64, []byte, bool, error) {
+	var active *nonceLane
+	var activeLast *storage.QueuedTransaction
+	for _, lane := range p.lanes {
+		if err := p.updateNonce(ctx, lane); err != nil {
+			return nil, 0, nil, false, fmt.Errorf("failed to update nonce of %v: %w", lane.sender, err)
+		}
+		last, err := lane.queue.FetchLast(ctx)
+		if err != nil {
+			return nil, 0, nil, false, fmt.Errorf("fetching last element from queue of %v: %w", lane.sender, err)
+		}
+		if last == nil || last.Data.Nonce < lane.nonce {
+			continue
+		}
+		if lane.isCancellation(last) {
+			return nil, 0, nil, false, fmt.Errorf("waiting for cancelled transactions from %v to be confirmed", lane.sender)
+		}
+		if active != nil {
+			return nil, 0, nil, false, fmt.Errorf("both %v and %v have unconfirmed transactions", active.sender, lane.sender)
+		}
+		active, activeLast = lane, last
+	}
+	if active != nil {
+		nextNonce := activeLast.Data.Nonce + 1
+		if err := p.canPostWithNonce(ctx, active, nextNonce); err != nil {
+			return nil, 0, nil, false, err
+		}
+		return active, nextNonce, activeLast.Meta, true, nil
+	}
+	best := p.chosenLane
+	if best == nil || !reuseChosenLane {
+		best = nil
+		for _, lane := range p.lanes {
+			if err := p.updateBalance(ctx, lane); err != nil {
+				log.Warn("failed to update data poster balance", "sender", lane.sender, "err", err)
+				continue
+			}
+			if best == nil || lane.balance.Cmp(best.balance) > 0 {
+				best = lane
+			}
+		}
+		if best == nil {
+			return nil, 0, nil, false, errors.New("failed to get the balance of any data poster account")
+		}
+		p.chosenLane = best
+	}
+	if err := p.canPostWithNonce(ctx, best, best.nonce); err != nil {
+		return nil, 0, nil, false, err
+	}
+	return best, best.nonce, nil, false, nil
+}
+
+// metadataBlock is the block to retrieve metadata at when the queue doesn't have it.
+// The mutex must be held by the caller.
+func (p *DataPoster) metadataBlock() *big.Int {
+	var block *big.Int
+	for _, lane := range p.lanes {
+		if lane.lastBlock != nil && (block == nil || lane.lastBlock.Cmp(block) > 0) {
+			block = lane.lastBlock
+		}
+	}
+	return block
+}
+
+// maybeFailOver cancels the queued transactions of a lane whose oldest transaction that isn't in a block yet
+// has been waiting for longer than the lane stuck timeout. Once the cancellations are confirmed, transactions
+// are posted from another lane, rather than waiting for the stuck transaction to be replaced by fee.
+// Cancellations only use the minimum gas, so replacing them by fee is much cheaper than replacing the originals.
+// queueContents must start at the lane's oldest nonce that isn't in a block yet.
+// The mutex must be held by the caller.
+func (p *DataPoster) maybeFailOver(ctx context.Context, lane *nonceLane, queueContents []*storage.QueuedTransaction) {
+	if len(queueContents) == 0 {
+		return
+	}
+	head := queueContents[0]
+	if lane.headSince.IsZero() || head.Data.Nonce != lane.headNonce {
+		lane.headNonce = head.Data.Nonce
+		lane.headSince = time.Now()
+		return
+	}
+	timeout := p.config().LaneStuckTimeout
+	if len(p.lanes) < 2 || timeout == 0 || time.Since(lane.headSince) < timeout || lane.isCancellation(head) {
+		return
+	}
+	log.Warn("data poster transaction stuck, cancelling its lane's transactions to post from another lane", "sender", lane.sender, "nonce", head.Data.Nonce, "stuckFor", time.Since(lane.headSince), "transactions", len(queueContents))
+	if lane.failoverCount != nil {
+		lane.failoverCount.Inc(1)
+	}
+	for _, tx := range queueContents {
+		if lane.isCancellation(tx) {
+			continue
+		}
+		err := p.cancelTx(ctx, lane, tx)
+		p.maybeLogError(lane, err, tx, "failed to cancel transaction")
+	}
+	lane.headSince = time.Now()
+}
+
+// cancelTx replaces prevTx with an empty transfer to the sender. As the parent chain's mempool doesn't let
+// blob transactions be replaced by other kinds of transactions, blob transactions are cancelled by a blob
+// transaction with a single empty blob.
+// The mutex must be held by the caller.
+func (p *DataPoster) cancelTx(ctx context.Context, lane *nonceLane, prevTx *storage.QueuedTransaction) error {
+	var sidecar *types.BlobTxSidecar
+	numBlobs := uint64(0)
+	if len(prevTx.FullTx.BlobHashes()) > 0 {
+		kzgBlobs := make([]kzg4844.Blob, 1)
+		commitments, _, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+		if err != nil {
+			return fmt.Errorf("failed to compute KZG commitments: %w", err)
+		}
+		proofs, err := blobs.ComputeBlobProofs(kzgBlobs, commitments)
+		if err != nil {
+			return fmt.Errorf("failed to compute KZG proofs: %w", err)
+		}
+		sidecar = &types.BlobTxSidecar{
+			Blobs:       kzgBlobs,
+			Commitments: commitments,
+			Proofs:      proofs,
+		}
+		numBlobs = 1
+	}
+	feeCap, tipCap, blobFeeCap, err := p.feeAndTipCaps(ctx, lane, prevTx.Data.Nonce, params.TxGas, numBlobs, prevTx.Data.GasFeeCap, prevTx.Data.GasTipCap, prevTx.FullTx.BlobGasFeeCap(), prevTx.Created, 0)
+	if err != nil {
+		return err
+	}
+	feeCap, tipCap, blobFeeCap = minimumReplacementFees(prevTx, numBlobs > 0, feeCap, tipCap, blobFeeCap)
+	newTx := storage.QueuedTransaction{
+		Data: types.DynamicFeeTx{
+			Nonce:     prevTx.Data.Nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       params.TxGas,
+			To:        &lane.sender,
+			Value:     new(big.Int),
+		},
+		Created:         prevTx.Created,
+		NextReplacement: time.Now().Add(p.replacementTimes[0]),
+	}
+	newTx.FullTx, err = p.signTx(ctx, lane, &newTx.Data, blobFeeCap, sidecar)
+	if err != nil {
+		return err
+	}
+	return p.sendTx(ctx, lane, prevTx, &newTx)
+}
+
+// minimumReplacementFees raises the fee caps to at least the minimum increase over prevTx's that
+// the parent chain's mempool accepts for a replacement.
+func minimumReplacementFees(prevTx *storage.QueuedTransaction, hasBlobs bool, feeCap, tipCap, blobFeeCap *big.Int) (*big.Int, *big.Int, *big.Int) {
+	rbfIncrease := minRbfIncrease
+	if hasBlobs || len(prevTx.FullTx.BlobHashes()) > 0 {
+		rbfIncrease = minBlobTxRbfIncrease
+	}
+	feeCap = arbmath.BigMax(feeCap, arbmath.BigMulByBips(prevTx.Data.GasFeeCap, rbfIncrease))
+	tipCap = arbmath.BigMax(tipCap, arbmath.BigMulByBips(prevTx.Data.GasTipCap, rbfIncrease))
+	if hasBlobs && prevTx.FullTx.BlobGasFeeCap() != nil {
+		blobFeeCap = arbmath.BigMax(blobFeeCap, arbmath.BigMulByBips(prevTx.FullTx.BlobGasFeeCap(), rbfIncrease))
+	}
+	if arbmath.BigGreaterThan(tipCap, feeCap) {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+	return feeCap, tipCap, blobFeeCap
+}
@@ -0,0 +1,251 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package sqlstorage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
+	flag "github.com/spf13/pflag"
+
+	// Registers the "sqlite3" driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Dialect holds what differs between the SQL databases queues can be stored in.
+type Dialect struct {
+	Name     string
+	BlobType string
+	// Placeholder returns the placeholder of the n-th (starting at 1) query parameter.
+	Placeholder func(n int) string
+	// The suffix of a select statement which locks the selected rows until the transaction ends, if supported.
+	LockRows string
+}
+
+var (
+	SQLiteDialect = Dialect{
+		Name:        "sqlite",
+		BlobType:    "BLOB",
+		Placeholder: func(int) string { return "?" },
+	}
+	PostgresDialect = Dialect{
+		Name:        "postgres",
+		BlobType:    "BYTEA",
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		LockRows:    " FOR UPDATE",
+	}
+)
+
+// DialectOf returns the dialect of the database/sql driver with the given name.
+func DialectOf(driver string) (Dialect, error) {
+	switch driver {
+	case "sqlite3", "sqlite":
+		return SQLiteDialect, nil
+	case "postgres", "pgx":
+		return PostgresDialect, nil
+	default:
+		return Dialect{}, fmt.Errorf("unsupported SQL driver \"%v\"", driver)
+	}
+}
+
+type Config struct {
+	Driver string `koanf:"driver"`
+	DSN    string `koanf:"dsn"`
+	Table  string `koanf:"table"`
+}
+
+var DefaultConfig = Config{
+	Driver: "sqlite3",
+	DSN:    "",
+	Table:  "data_poster_queue",
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".driver", DefaultConfig.Driver, "the database/sql driver of the SQL database to store queued transactions in (\"sqlite3\" is built in, \"postgres\" requires a driver to be registered)")
+	f.String(prefix+".dsn", DefaultConfig.DSN, "if non-empty, the data source name of a SQL database to store queued transactions in, which may be shared by several nodes")
+	f.String(prefix+".table", DefaultConfig.Table, "the table to store queued transactions in, which is created if it doesn't exist")
+}
+
+var tableNameRegexp = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+func (c *Config) Validate() error {
+	if _, err := DialectOf(c.Driver); err != nil {
+		return err
+	}
+	if !tableNameRegexp.MatchString(c.Table) {
+		return fmt.Errorf("invalid SQL table name \"%v\"", c.Table)
+	}
+	return nil
+}
+
+// Open opens the database configured, which can be shared by the storages of several queues.
+func Open(config *Config) (*sql.DB, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return sql.Open(config.Driver, config.DSN)
+}
+
+// Storage implements a queue stored in a table of a SQL database, with a row per index.
+// Changes are made in database transactions, so several nodes can share the table.
+type Storage struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	encDec  storage.EncoderDecoderF
+}
+
+// NewStorage creates the table if it doesn't exist yet.
+func NewStorage(ctx context.Context, db *sql.DB, dialect Dialect, table string, enc storage.EncoderDecoderF) (*Storage, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return nil, fmt.Errorf("invalid SQL table name \"%v\"", table)
+	}
+	s := &Storage{db: db, dialect: dialect, table: table, encDec: enc}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (idx BIGINT PRIMARY KEY, item %s NOT NULL)", table, dialect.BlobType)); err != nil {
+		return nil, fmt.Errorf("creating table %v: %w", table, err)
+	}
+	return s, nil
+}
+
+// The index is stored as a signed integer, as that's all some databases support.
+func toSQLIndex(index uint64) int64 {
+	if index > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(index)
+}
+
+func (s *Storage) p(n int) string {
+	return s.dialect.Placeholder(n)
+}
+
+func (s *Storage) FetchContents(ctx context.Context, startingIndex uint64, maxResults uint64) ([]*storage.QueuedTransaction, error) {
+	if maxResults == 0 {
+		return nil, nil
+	}
+	query := fmt.Sprintf("SELECT item FROM %s WHERE idx >= %s ORDER BY idx LIMIT %s", s.table, s.p(1), s.p(2))
+	rows, err := s.db.QueryContext(ctx, query, toSQLIndex(startingIndex), toSQLIndex(maxResults))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*storage.QueuedTransaction
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		item, err := s.encDec().Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+	return res, rows.Err()
+}
+
+func (s *Storage) FetchLast(ctx context.Context) (*storage.QueuedTransaction, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT item FROM %s ORDER BY idx DESC LIMIT 1", s.table)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.encDec().Decode(data)
+}
+
+func (s *Storage) Prune(ctx context.Context, until uint64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE idx < %s", s.table, s.p(1)), toSQLIndex(until))
+	return err
+}
+
+// PruneAll deletes every item in the queue.
+func (s *Storage) PruneAll(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", s.table))
+	return err
+}
+
+func (s *Storage) Put(ctx context.Context, index uint64, prev, new *storage.QueuedTransaction) error {
+	if new == nil {
+		return fmt.Errorf("tried to insert nil item at index %v", index)
+	}
+	newEnc, err := s.encDec().Encode(new)
+	if err != nil {
+		return fmt.Errorf("encoding new item: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// This is a no-op if the transaction has been committed.
+	defer func() { _ = tx.Rollback() }()
+
+	var stored []byte
+	query := fmt.Sprintf("SELECT item FROM %s WHERE idx = %s%s", s.table, s.p(1), s.dialect.LockRows)
+	err = tx.QueryRowContext(ctx, query, toSQLIndex(index)).Scan(&stored)
+	exists := true
+	if errors.Is(err, sql.ErrNoRows) {
+		exists = false
+	} else if err != nil {
+		return err
+	}
+	if !exists {
+		if prev != nil {
+			return fmt.Errorf("%w: tried to replace item at index %v but no item exists there", storage.ErrStorageRace, index)
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (idx, item) VALUES (%s, %s)", s.table, s.p(1), s.p(2))
+		if _, err := tx.ExecContext(ctx, insert, toSQLIndex(index), newEnc); err != nil {
+			// Most likely another node inserted an item at the same index first.
+			return fmt.Errorf("%w: inserting item at index %v: %v", storage.ErrStorageRace, index, err.Error())
+		}
+	} else {
+		if prev == nil {
+			return fmt.Errorf("%w: tried to insert new item at index %v but an item exists there", storage.ErrStorageRace, index)
+		}
+		// Re-encode what's stored, in case it was stored with another encoding.
+		storedItem, err := s.encDec().Decode(stored)
+		if err != nil {
+			return fmt.Errorf("decoding item at index %v: %w", index, err)
+		}
+		storedEnc, err := s.encDec().Encode(storedItem)
+		if err != nil {
+			return err
+		}
+		prevEnc, err := s.encDec().Encode(prev)
+		if err != nil {
+			return fmt.Errorf("encoding previous item: %w", err)
+		}
+		if !bytes.Equal(storedEnc, prevEnc) {
+			return fmt.Errorf("%w: replacing different item than expected at index %v", storage.ErrStorageRace, index)
+		}
+		update := fmt.Sprintf("UPDATE %s SET item = %s WHERE idx = %s", s.table, s.p(1), s.p(2))
+		if _, err := tx.ExecContext(ctx, update, newEnc, toSQLIndex(index)); err != nil {
+			return fmt.Errorf("updating item at index %v: %w", index, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		// Unfortunately, we can't wrap two errors.
+		//nolint:errorlint
+		return fmt.Errorf("%w: %v", storage.ErrStorageRace, err.Error())
+	}
+	return nil
+}
+
+func (s *Storage) Length(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", s.table)).Scan(&count)
+	return count, err
+}
+
+func (s *Storage) IsPersistent() bool {
+	return true
+}
@@ -14,9 +14,11 @@ import (
 var (
 	ErrStorageRace = errors.New("storage race error")
 
-	BlockValidatorPrefix string = "v" // the prefix for all block validator keys
-	StakerPrefix         string = "S" // the prefix for all staker keys
-	BatchPosterPrefix    string = "b" // the prefix for all batch poster keys
+	BlockValidatorPrefix  string = "v" // the prefix for all block validator keys
+	StakerPrefix          string = "S" // the prefix for all staker keys
+	BatchPosterPrefix     string = "b" // the prefix for all batch poster keys
+	BatchPosterLanePrefix string = "L" // the prefix for the keys of a batch poster lane, followed by its address
+	SeqCoordinatorPrefix  string = "r" // the prefix for the sequencer coordinator's raft log
 	// TODO(anodar): move everything else from schema.go file to here once
 	// execution split is complete.
 )
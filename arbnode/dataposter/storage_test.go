@@ -2,6 +2,7 @@ package dataposter
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"path"
 	"testing"
@@ -15,6 +16,7 @@ import (
 	"github.com/offchainlabs/nitro/arbnode/dataposter/dbstorage"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/redis"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/slice"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/sqlstorage"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/redisutil"
@@ -48,6 +50,22 @@ func newPebbleDBStorage(t *testing.T, encF storage.EncoderDecoderF) *dbstorage.S
 	return dbstorage.New(db, encF)
 }
 
+func newSQLiteStorage(t *testing.T, encF storage.EncoderDecoderF) *sqlstorage.Storage {
+	t.Helper()
+	config := sqlstorage.DefaultConfig
+	config.DSN = path.Join(t.TempDir(), "queue.sqlite")
+	db, err := sqlstorage.Open(&config)
+	if err != nil {
+		t.Fatalf("sqlstorage.Open() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := sqlstorage.NewStorage(context.Background(), db, sqlstorage.SQLiteDialect, config.Table, encF)
+	if err != nil {
+		t.Fatalf("sqlstorage.NewStorage() unexpected error: %v", err)
+	}
+	return s
+}
+
 func newSliceStorage(encF storage.EncoderDecoderF) *slice.Storage {
 	return slice.NewStorage(encF)
 }
@@ -128,10 +146,12 @@ func storages(t *testing.T) map[string]QueueStorage {
 		"levelDBLegacy": newLevelDBStorage(t, f(&storage.LegacyEncoderDecoder{})),
 		"sliceLegacy":   newSliceStorage(f(&storage.LegacyEncoderDecoder{})),
 		"redisLegacy":   newRedisStorage(context.Background(), t, f(&storage.LegacyEncoderDecoder{})),
+		"sqliteLegacy":  newSQLiteStorage(t, f(&storage.LegacyEncoderDecoder{})),
 		"levelDB":       newLevelDBStorage(t, f(&storage.EncoderDecoder{})),
 		"pebbleDB":      newPebbleDBStorage(t, f(&storage.EncoderDecoder{})),
 		"slice":         newSliceStorage(f(&storage.EncoderDecoder{})),
 		"redis":         newRedisStorage(context.Background(), t, f(&storage.EncoderDecoder{})),
+		"sqlite":        newSQLiteStorage(t, f(&storage.EncoderDecoder{})),
 	}
 }
 
@@ -172,6 +192,26 @@ func TestPruneAll(t *testing.T) {
 	}
 }
 
+func TestSQLStoragePutRace(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStorage(t, func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
+	if err := s.Put(ctx, 0, nil, valueOf(t, 0)); err != nil {
+		t.Fatalf("Error putting a key/value: %v", err)
+	}
+	if err := s.Put(ctx, 0, nil, valueOf(t, 1)); !errors.Is(err, storage.ErrStorageRace) {
+		t.Errorf("Put() over an existing item returned %v, want a storage race error", err)
+	}
+	if err := s.Put(ctx, 0, valueOf(t, 2), valueOf(t, 1)); !errors.Is(err, storage.ErrStorageRace) {
+		t.Errorf("Put() replacing a different item returned %v, want a storage race error", err)
+	}
+	if err := s.Put(ctx, 1, valueOf(t, 0), valueOf(t, 1)); !errors.Is(err, storage.ErrStorageRace) {
+		t.Errorf("Put() replacing a missing item returned %v, want a storage race error", err)
+	}
+	if err := s.Put(ctx, 0, valueOf(t, 0), valueOf(t, 1)); err != nil {
+		t.Fatalf("Put() replacing the stored item unexpected error: %v", err)
+	}
+}
+
 func TestFetchContents(t *testing.T) {
 	ctx := context.Background()
 	for name, s := range initStorages(ctx, t) {
@@ -14,6 +14,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
 	flag "github.com/spf13/pflag"
 
@@ -570,7 +572,7 @@ func (r *InboxReader) getNextBlockToRead() (*big.Int, error) {
 	return msgBlock, nil
 }
 
-func (r *InboxReader) GetSequencerMessageBytes(ctx context.Context, seqNum uint64) ([]byte, error) {
+func (r *InboxReader) getSequencerBatch(ctx context.Context, seqNum uint64) (*SequencerInboxBatch, error) {
 	metadata, err := r.tracker.GetBatchMetadata(seqNum)
 	if err != nil {
 		return nil, err
@@ -583,13 +585,51 @@ func (r *InboxReader) GetSequencerMessageBytes(ctx context.Context, seqNum uint6
 	var seenBatches []uint64
 	for _, batch := range seqBatches {
 		if batch.SequenceNumber == seqNum {
-			return batch.Serialize(ctx, r.client)
+			return batch, nil
 		}
 		seenBatches = append(seenBatches, batch.SequenceNumber)
 	}
 	return nil, fmt.Errorf("sequencer batch %v not found in L1 block %v (found batches %v)", seqNum, metadata.ParentChainBlock, seenBatches)
 }
 
+func (r *InboxReader) GetSequencerMessageBytes(ctx context.Context, seqNum uint64) ([]byte, error) {
+	batch, err := r.getSequencerBatch(ctx, seqNum)
+	if err != nil {
+		return nil, err
+	}
+	return batch.Serialize(ctx, r.client)
+}
+
+// GetSequencerMessageBlobs returns the blobs sequencer batch seqNum was posted in and their
+// versioned hashes, or nothing if it wasn't posted in blobs.
+func (r *InboxReader) GetSequencerMessageBlobs(ctx context.Context, seqNum uint64) ([]common.Hash, []kzg4844.Blob, error) {
+	batch, err := r.getSequencerBatch(ctx, seqNum)
+	if err != nil {
+		return nil, nil, err
+	}
+	if batch.dataLocation != batchDataBlobHashes {
+		return nil, nil, nil
+	}
+	data, err := batch.Serialize(ctx, r.client)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The serialized batch is the header, the blob hashes header byte, and the versioned hashes
+	hashesData := data[41:]
+	versionedHashes := make([]common.Hash, len(hashesData)/len(common.Hash{}))
+	for i := range versionedHashes {
+		copy(versionedHashes[i][:], hashesData[i*len(common.Hash{}):])
+	}
+	if r.tracker.blobReader == nil {
+		return nil, nil, fmt.Errorf("sequencer batch %v was posted in blobs but no blob reader is configured", seqNum)
+	}
+	kzgBlobs, err := r.tracker.blobReader.GetBlobs(ctx, batch.BlockHash, versionedHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versionedHashes, kzgBlobs, nil
+}
+
 func (r *InboxReader) GetLastReadBlockAndBatchCount() (uint64, uint64) {
 	r.lastReadMutex.RLock()
 	defer r.lastReadMutex.RUnlock()
@@ -30,7 +30,7 @@ type MaintenanceRunner struct {
 
 	// lock is used to ensures that at any given time, only single node is on
 	// maintenance mode.
-	lock *redislock.Simple
+	lock lock
 }
 
 type MaintenanceConfig struct {
@@ -101,9 +101,9 @@ func NewMaintenanceRunner(config MaintenanceConfigFetcher, seqCoordinator *SeqCo
 	if seqCoordinator != nil {
 		c := func() *redislock.SimpleCfg { return &cfg.Lock }
 		r := func() bool { return true } // always ready to lock
-		rl, err := redislock.NewSimple(seqCoordinator.Client, c, r)
+		rl, err := seqCoordinator.backend.newLock(c, r)
 		if err != nil {
-			return nil, fmt.Errorf("creating new simple redis lock: %w", err)
+			return nil, fmt.Errorf("creating new maintenance lock: %w", err)
 		}
 		res.lock = rl
 	}
@@ -28,6 +28,7 @@ import (
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 	"github.com/offchainlabs/nitro/arbnode/redislock"
 	"github.com/offchainlabs/nitro/arbnode/resourcemanager"
+	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/broadcastclient"
 	"github.com/offchainlabs/nitro/broadcastclients"
@@ -35,6 +36,7 @@ import (
 	"github.com/offchainlabs/nitro/cmd/chaininfo"
 	"github.com/offchainlabs/nitro/das"
 	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/execution/execrpc"
 	"github.com/offchainlabs/nitro/execution/gethexec"
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
@@ -78,22 +80,24 @@ func GenerateRollupConfig(prod bool, wasmModuleRoot common.Hash, rollupOwner com
 }
 
 type Config struct {
-	Sequencer           bool                        `koanf:"sequencer"`
-	ParentChainReader   headerreader.Config         `koanf:"parent-chain-reader" reload:"hot"`
-	InboxReader         InboxReaderConfig           `koanf:"inbox-reader" reload:"hot"`
-	DelayedSequencer    DelayedSequencerConfig      `koanf:"delayed-sequencer" reload:"hot"`
-	BatchPoster         BatchPosterConfig           `koanf:"batch-poster" reload:"hot"`
-	MessagePruner       MessagePrunerConfig         `koanf:"message-pruner" reload:"hot"`
-	BlockValidator      staker.BlockValidatorConfig `koanf:"block-validator" reload:"hot"`
-	Feed                broadcastclient.FeedConfig  `koanf:"feed" reload:"hot"`
-	Staker              staker.L1ValidatorConfig    `koanf:"staker" reload:"hot"`
-	SeqCoordinator      SeqCoordinatorConfig        `koanf:"seq-coordinator"`
-	DataAvailability    das.DataAvailabilityConfig  `koanf:"data-availability"`
-	SyncMonitor         SyncMonitorConfig           `koanf:"sync-monitor"`
-	Dangerous           DangerousConfig             `koanf:"dangerous"`
-	TransactionStreamer TransactionStreamerConfig   `koanf:"transaction-streamer" reload:"hot"`
-	Maintenance         MaintenanceConfig           `koanf:"maintenance" reload:"hot"`
-	ResourceMgmt        resourcemanager.Config      `koanf:"resource-mgmt" reload:"hot"`
+	Sequencer           bool                          `koanf:"sequencer"`
+	ParentChainReader   headerreader.Config           `koanf:"parent-chain-reader" reload:"hot"`
+	InboxReader         InboxReaderConfig             `koanf:"inbox-reader" reload:"hot"`
+	DelayedSequencer    DelayedSequencerConfig        `koanf:"delayed-sequencer" reload:"hot"`
+	BatchPoster         BatchPosterConfig             `koanf:"batch-poster" reload:"hot"`
+	MessagePruner       MessagePrunerConfig           `koanf:"message-pruner" reload:"hot"`
+	BlockValidator      staker.BlockValidatorConfig   `koanf:"block-validator" reload:"hot"`
+	Feed                broadcastclient.FeedConfig    `koanf:"feed" reload:"hot"`
+	Staker              staker.L1ValidatorConfig      `koanf:"staker" reload:"hot"`
+	SeqCoordinator      SeqCoordinatorConfig          `koanf:"seq-coordinator"`
+	DataAvailability    das.DataAvailabilityConfig    `koanf:"data-availability"`
+	SyncMonitor         SyncMonitorConfig             `koanf:"sync-monitor"`
+	Dangerous           DangerousConfig               `koanf:"dangerous"`
+	TransactionStreamer TransactionStreamerConfig     `koanf:"transaction-streamer" reload:"hot"`
+	Maintenance         MaintenanceConfig             `koanf:"maintenance" reload:"hot"`
+	ResourceMgmt        resourcemanager.Config        `koanf:"resource-mgmt" reload:"hot"`
+	BlobClient          headerreader.BlobClientConfig `koanf:"blob-client"`
+	ExecutionClient     execrpc.ClientConfig          `koanf:"execution-client" reload:"hot"`
 }
 
 func (c *Config) Validate() error {
@@ -121,9 +125,19 @@ func (c *Config) Validate() error {
 	if err := c.Staker.Validate(); err != nil {
 		return err
 	}
+	if c.RemoteExecution() {
+		if err := c.ExecutionClient.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// RemoteExecution is whether the execution is in another process, reached at the execution client URL.
+func (c *Config) RemoteExecution() bool {
+	return c.ExecutionClient.RPC.URL != ""
+}
+
 func (c *Config) ValidatorRequired() bool {
 	if c.BlockValidator.Enable {
 		return true
@@ -150,6 +164,8 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet, feedInputEnable bool, feed
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 	TransactionStreamerConfigAddOptions(prefix+".transaction-streamer", f)
 	MaintenanceConfigAddOptions(prefix+".maintenance", f)
+	headerreader.BlobClientAddOptions(prefix+".blob-client", f)
+	execrpc.ClientConfigAddOptions(prefix+".execution-client", f)
 }
 
 var ConfigDefault = Config{
@@ -169,6 +185,8 @@ var ConfigDefault = Config{
 	TransactionStreamer: DefaultTransactionStreamerConfig,
 	ResourceMgmt:        resourcemanager.DefaultConfig,
 	Maintenance:         DefaultMaintenanceConfig,
+	BlobClient:          headerreader.DefaultBlobClientConfig,
+	ExecutionClient:     execrpc.DefaultClientConfig,
 }
 
 func ConfigDefaultL1Test() *Config {
@@ -392,6 +410,10 @@ func createNodeImpl(
 				return nil, errors.New("cannot sign outgoing feed")
 			}
 			maybeDataSigner = dataSigner
+			if execNode, ok := exec.(*gethexec.ExecutionNode); ok && execNode.Sequencer != nil {
+				// the sequencer's soft confirmations commit to blocks with the same key as its feed
+				execNode.Sequencer.SetCommitmentSigner(dataSigner)
+			}
 		}
 		broadcastServer = broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &configFetcher.Get().Feed.Output }, l2ChainId, fatalErrChan, maybeDataSigner)
 	}
@@ -414,7 +436,12 @@ func createNodeImpl(
 	}
 
 	if config.SeqCoordinator.Enable {
-		coordinator, err = NewSeqCoordinator(dataSigner, bpVerifier, txStreamer, exec, syncMonitor, config.SeqCoordinator)
+		coordinatorConfig := config.SeqCoordinator
+		if coordinatorConfig.Raft.DataDir == "" {
+			coordinatorConfig.Raft.DataDir = stack.ResolvePath("seq-coordinator-raft")
+		}
+		coordinatorDb := rawdb.NewTable(arbDb, storage.SeqCoordinatorPrefix)
+		coordinator, err = NewSeqCoordinator(dataSigner, bpVerifier, txStreamer, exec, syncMonitor, coordinatorDb, coordinatorConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -516,7 +543,19 @@ func createNodeImpl(
 		return nil, errors.New("a data availability service is required for this chain, but it was not configured")
 	}
 
-	inboxTracker, err := NewInboxTracker(arbDb, txStreamer, daReader)
+	var blobReader arbstate.BlobReader
+	if config.BlobClient.BeaconUrl != "" {
+		blobClient, err := headerreader.NewBlobClient(config.BlobClient, l1client)
+		if err != nil {
+			return nil, err
+		}
+		if err := blobClient.Initialize(ctx); err != nil {
+			return nil, fmt.Errorf("error initializing blob client: %w", err)
+		}
+		blobReader = blobClient
+	}
+
+	inboxTracker, err := NewInboxTracker(arbDb, txStreamer, daReader, blobReader)
 	if err != nil {
 		return nil, err
 	}
@@ -650,6 +689,9 @@ func createNodeImpl(
 			DeployInfo:   deployInfo,
 			TransactOpts: txOptsBatchPoster,
 			DAWriter:     daWriter,
+			DataPosterLaneDB: func(sender common.Address) ethdb.Database {
+				return rawdb.NewTable(arbDb, storage.BatchPosterLanePrefix+sender.Hex())
+			},
 		})
 		if err != nil {
 			return nil, err
@@ -731,6 +773,25 @@ func CreateNode(
 			Public: false,
 		})
 	}
+	if currentNode.BatchPoster != nil {
+		apis = append(apis, rpc.API{
+			Namespace:     "batchposter",
+			Version:       "1.0",
+			Service:       &BatchPosterAPI{bp: currentNode.BatchPoster},
+			Public:        false,
+			Authenticated: true,
+		})
+	}
+	if _, ok := exec.(*execrpc.ExecutionClient); ok {
+		// the execution is in another process, and its sequencer writes messages back through this
+		apis = append(apis, rpc.API{
+			Namespace:     execrpc.ConsensusNamespace,
+			Version:       "1.0",
+			Service:       execrpc.NewConsensusServerAPI(currentNode.TxStreamer),
+			Public:        false,
+			Authenticated: true,
+		})
+	}
 
 	stack.RegisterAPIs(apis)
 
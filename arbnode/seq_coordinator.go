@@ -17,14 +17,17 @@ import (
 	"github.com/go-redis/redis/v8"
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 
+	"github.com/offchainlabs/nitro/arbnode/redislock"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/contracts"
+	"github.com/offchainlabs/nitro/util/raftutil"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/signature"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
@@ -37,7 +40,7 @@ var (
 type SeqCoordinator struct {
 	stopwaiter.StopWaiter
 
-	redisutil.RedisCoordinator
+	backend seqCoordinatorBackend
 
 	sync             *SyncMonitor
 	streamer         *TransactionStreamer
@@ -53,28 +56,100 @@ type SeqCoordinator struct {
 
 	wantsLockoutMutex sync.Mutex // manages access to acquireLockoutAndWriteMessage and generally the wants lockout key
 	avoidLockout      int        // If > 0, prevents acquiring the lockout but not extending the lockout if no alternative sequencer wants the lockout. Protected by chosenUpdateMutex.
+	draining          bool       // If set, the sequencer is paused and no more messages are written, for a planned failover. Protected by wantsLockoutMutex.
 
 	redisErrors int // error counter, from workthread
 }
 
+// seqCoordinatorBackend holds the state the sequencers coordinate through: which of them is chosen,
+// the message count and messages written by the chosen one, and which of them want the lockout.
+type seqCoordinatorBackend interface {
+	// acquireLockout acquires or refreshes the lockout and writes the message count, and the message if any,
+	// atomically. It returns an error wrapping execution.ErrRetrySequencer if another sequencer holds the lockout
+	// or the message count is ahead of the expected one.
+	acquireLockout(ctx context.Context, update *lockoutUpdate) error
+	setWantsLockout(ctx context.Context, url string, until time.Time) error
+	releaseWantsLockout(ctx context.Context, url string) error
+	// releaseChosen releases the lockout if url holds it.
+	releaseChosen(ctx context.Context, url string) error
+	recommendSequencerWantingLockout(ctx context.Context) (string, error)
+	currentChosenSequencer(ctx context.Context) (string, error)
+	// signedMsgCount returns nil if no message count is set.
+	signedMsgCount(ctx context.Context) ([]byte, error)
+	// message returns the message at pos and its signature, which is nil if it's at the start of the message.
+	message(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error)
+	// failoverRequests returns what a planned failover asks of url: the sequencer to drain for, if it's the chosen one,
+	// and whether to report its progress catching up, if it's the target.
+	failoverRequests(ctx context.Context, url string) (string, bool, error)
+	// reportDrained publishes the final message count url wrote before draining.
+	reportDrained(ctx context.Context, url string, msgCount arbutil.MessageIndex) error
+	// reportProgress publishes the message count url has processed, while catching up to a draining sequencer.
+	reportProgress(ctx context.Context, url string, msgCount arbutil.MessageIndex) error
+	newLock(config redislock.SimpleCfgFetcher, readyToLock func() bool) (lock, error)
+	start(ctx context.Context)
+	close()
+}
+
+type lockoutUpdate struct {
+	url              string
+	msgCountExpected arbutil.MessageIndex
+	msgCount         arbutil.MessageIndex
+	signedMsgCount   []byte
+	// The message at msgCount-1 and its signature, which are nil if there's no message to write
+	message      []byte
+	messageSig   []byte
+	lockoutUntil time.Time
+	// Set if the sequencer shouldn't avoid the lockout
+	setWantsLockout bool
+	// If the message count is ahead, succeed without changing anything rather than failing.
+	// This is set when the chosen sequencer refreshes its lockout, as then the count was written by itself.
+	ignoreIfAhead bool
+}
+
+// lock ensures only one sequencer does something at a time.
+type lock interface {
+	AttemptLock(ctx context.Context) bool
+	Release(ctx context.Context)
+}
+
 type SeqCoordinatorConfig struct {
-	Enable                bool          `koanf:"enable"`
-	ChosenHealthcheckAddr string        `koanf:"chosen-healthcheck-addr"`
-	RedisUrl              string        `koanf:"redis-url"`
-	LockoutDuration       time.Duration `koanf:"lockout-duration"`
-	LockoutSpare          time.Duration `koanf:"lockout-spare"`
-	SeqNumDuration        time.Duration `koanf:"seq-num-duration"`
-	UpdateInterval        time.Duration `koanf:"update-interval"`
-	RetryInterval         time.Duration `koanf:"retry-interval"`
-	HandoffTimeout        time.Duration `koanf:"handoff-timeout"`
-	SafeShutdownDelay     time.Duration `koanf:"safe-shutdown-delay"`
-	ReleaseRetries        int           `koanf:"release-retries"`
+	Enable                bool            `koanf:"enable"`
+	ChosenHealthcheckAddr string          `koanf:"chosen-healthcheck-addr"`
+	Backend               string          `koanf:"backend"`
+	RedisUrl              string          `koanf:"redis-url"`
+	Raft                  raftutil.Config `koanf:"raft"`
+	LockoutDuration       time.Duration   `koanf:"lockout-duration"`
+	LockoutSpare          time.Duration   `koanf:"lockout-spare"`
+	SeqNumDuration        time.Duration   `koanf:"seq-num-duration"`
+	UpdateInterval        time.Duration   `koanf:"update-interval"`
+	RetryInterval         time.Duration   `koanf:"retry-interval"`
+	HandoffTimeout        time.Duration   `koanf:"handoff-timeout"`
+	SafeShutdownDelay     time.Duration   `koanf:"safe-shutdown-delay"`
+	ReleaseRetries        int             `koanf:"release-retries"`
 	// Max message per poll.
 	MsgPerPoll arbutil.MessageIndex       `koanf:"msg-per-poll"`
 	MyUrl      string                     `koanf:"my-url"`
 	Signer     signature.SignVerifyConfig `koanf:"signer"`
 }
 
+const (
+	RedisSeqCoordinatorBackend = "redis"
+	RaftSeqCoordinatorBackend  = "raft"
+)
+
+func (c *SeqCoordinatorConfig) Validate() error {
+	switch c.Backend {
+	case RedisSeqCoordinatorBackend, "":
+	case RaftSeqCoordinatorBackend:
+		if err := c.Raft.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown sequencer coordinator backend \"%v\" (must be %v or %v)", c.Backend, RedisSeqCoordinatorBackend, RaftSeqCoordinatorBackend)
+	}
+	return nil
+}
+
 func (c *SeqCoordinatorConfig) Url() string {
 	if c.MyUrl == "" {
 		return redisutil.INVALID_URL
@@ -84,7 +159,9 @@ func (c *SeqCoordinatorConfig) Url() string {
 
 func SeqCoordinatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultSeqCoordinatorConfig.Enable, "enable sequence coordinator")
+	f.String(prefix+".backend", DefaultSeqCoordinatorConfig.Backend, "what to coordinate via: \"redis\", or \"raft\" to replicate the coordination state among the sequencers themselves, in which case nodes which aren't sequencers should forward to a sequencer rather than use redis to find the chosen one")
 	f.String(prefix+".redis-url", DefaultSeqCoordinatorConfig.RedisUrl, "the Redis URL to coordinate via")
+	raftutil.ConfigAddOptions(prefix+".raft", f)
 	f.String(prefix+".chosen-healthcheck-addr", DefaultSeqCoordinatorConfig.ChosenHealthcheckAddr, "if non-empty, launch an HTTP service binding to this address that returns status code 200 when chosen and 503 otherwise")
 	f.Duration(prefix+".lockout-duration", DefaultSeqCoordinatorConfig.LockoutDuration, "")
 	f.Duration(prefix+".lockout-spare", DefaultSeqCoordinatorConfig.LockoutSpare, "")
@@ -102,7 +179,9 @@ func SeqCoordinatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 var DefaultSeqCoordinatorConfig = SeqCoordinatorConfig{
 	Enable:                false,
 	ChosenHealthcheckAddr: "",
+	Backend:               RedisSeqCoordinatorBackend,
 	RedisUrl:              "",
+	Raft:                  raftutil.DefaultConfig,
 	LockoutDuration:       time.Minute,
 	LockoutSpare:          30 * time.Second,
 	SeqNumDuration:        24 * time.Hour,
@@ -118,7 +197,9 @@ var DefaultSeqCoordinatorConfig = SeqCoordinatorConfig{
 
 var TestSeqCoordinatorConfig = SeqCoordinatorConfig{
 	Enable:            false,
+	Backend:           RedisSeqCoordinatorBackend,
 	RedisUrl:          "",
+	Raft:              raftutil.DefaultConfig,
 	LockoutDuration:   time.Second * 2,
 	LockoutSpare:      time.Millisecond * 10,
 	SeqNumDuration:    time.Minute * 10,
@@ -138,23 +219,32 @@ func NewSeqCoordinator(
 	streamer *TransactionStreamer,
 	sequencer execution.ExecutionSequencer,
 	sync *SyncMonitor,
+	raftDb ethdb.KeyValueStore,
 	config SeqCoordinatorConfig,
 ) (*SeqCoordinator, error) {
-	redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 	signer, err := signature.NewSignVerify(&config.Signer, dataSigner, bpvalidator)
 	if err != nil {
 		return nil, err
 	}
+	var backend seqCoordinatorBackend
+	if config.Backend == RaftSeqCoordinatorBackend {
+		backend, err = newRaftSeqCoordinatorBackend(&config, raftDb, signer)
+	} else {
+		backend, err = newRedisSeqCoordinatorBackend(&config, signer)
+	}
+	if err != nil {
+		return nil, err
+	}
 	coordinator := &SeqCoordinator{
-		RedisCoordinator: *redisCoordinator,
-		sync:             sync,
-		streamer:         streamer,
-		sequencer:        sequencer,
-		config:           config,
-		signer:           signer,
+		backend:   backend,
+		sync:      sync,
+		streamer:  streamer,
+		sequencer: sequencer,
+		config:    config,
+		signer:    signer,
 	}
 	streamer.SetSeqCoordinator(coordinator)
 	return coordinator, nil
@@ -204,19 +294,6 @@ func atomicTimeRead(addr *int64) time.Time {
 	return time.UnixMilli(asint64)
 }
 
-func execTestPipe(pipe redis.Pipeliner, ctx context.Context) error {
-	cmders, err := pipe.Exec(ctx)
-	if err != nil {
-		return err
-	}
-	for _, cmder := range cmders {
-		if err := cmder.Err(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (c *SeqCoordinator) msgCountToSignedBytes(msgCount arbutil.MessageIndex) ([]byte, error) {
 	var msgCountBytes [8]byte
 	binary.BigEndian.PutUint64(msgCountBytes[:], uint64(msgCount))
@@ -227,24 +304,24 @@ func (c *SeqCoordinator) msgCountToSignedBytes(msgCount arbutil.MessageIndex) ([
 	return append(sig, msgCountBytes[:]...), nil
 }
 
-func (c *SeqCoordinator) signedBytesToMsgCount(ctx context.Context, data []byte) (arbutil.MessageIndex, error) {
+func signedBytesToMsgCount(ctx context.Context, signer *signature.SignVerify, data []byte) (arbutil.MessageIndex, error) {
 	datalen := len(data)
 	if datalen < 8 {
 		return 0, errors.New("msgcount value too short")
 	}
 	msgCountBytes := data[datalen-8:]
 	sig := data[:datalen-8]
-	err := c.signer.VerifySignature(ctx, sig, msgCountBytes)
+	err := signer.VerifySignature(ctx, sig, msgCountBytes)
 	if err != nil {
 		return 0, err
 	}
 	return arbutil.MessageIndex(binary.BigEndian.Uint64(msgCountBytes)), nil
 }
 
-// Acquires or refreshes the chosen one lockout and optionally writes a message into redis atomically.
+// Acquires or refreshes the chosen one lockout and optionally writes a message atomically.
 func (c *SeqCoordinator) acquireLockoutAndWriteMessage(ctx context.Context, msgCountExpected, msgCountToWrite arbutil.MessageIndex, lastmsg *arbostypes.MessageWithMetadata) error {
-	var messageData *string
-	var messageSigData *string
+	var messageData []byte
+	var messageSigData []byte
 	if lastmsg != nil {
 		msgBytes, err := json.Marshal(lastmsg)
 		if err != nil {
@@ -255,13 +332,10 @@ func (c *SeqCoordinator) acquireLockoutAndWriteMessage(ctx context.Context, msgC
 			return err
 		}
 		if c.config.Signer.SymmetricSign {
-			messageString := string(append(msgSig, msgBytes...))
-			messageData = &messageString
+			messageData = append(msgSig, msgBytes...)
 		} else {
-			messageString := string(msgBytes)
-			sigString := string(msgSig)
-			messageData = &messageString
-			messageSigData = &sigString
+			messageData = msgBytes
+			messageSigData = msgSig
 		}
 	}
 	msgCountMsg, err := c.msgCountToSignedBytes(msgCountToWrite)
@@ -270,65 +344,23 @@ func (c *SeqCoordinator) acquireLockoutAndWriteMessage(ctx context.Context, msgC
 	}
 	c.wantsLockoutMutex.Lock()
 	defer c.wantsLockoutMutex.Unlock()
+	if lastmsg != nil && c.draining {
+		return fmt.Errorf("%w: draining for a planned failover", execution.ErrRetrySequencer)
+	}
 	setWantsLockout := c.avoidLockout <= 0
 	lockoutUntil := time.Now().Add(c.config.LockoutDuration)
-	err = c.Client.Watch(ctx, func(tx *redis.Tx) error {
-		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-		var wasEmpty bool
-		if errors.Is(err, redis.Nil) {
-			wasEmpty = true
-			err = nil
-		}
-		if err != nil {
-			return err
-		}
-		if !wasEmpty && (current != c.config.Url()) {
-			return fmt.Errorf("%w: failed to catch lock. redis shows chosen: %s", execution.ErrRetrySequencer, current)
-		}
-		remoteMsgCount, err := c.getRemoteMsgCountImpl(ctx, tx)
-		if err != nil {
-			return err
-		}
-		if remoteMsgCount > msgCountExpected {
-			if messageData == nil && c.CurrentlyChosen() {
-				// this was called from update(), while msgCount was changed by a call from SequencingMessage
-				// no need to do anything
-				return nil
-			}
-			log.Info("coordinator failed to become main", "expected", msgCountExpected, "found", remoteMsgCount, "message is nil?", messageData == nil)
-			return fmt.Errorf("%w: failed to catch lock. expected msg %d found %d", execution.ErrRetrySequencer, msgCountExpected, remoteMsgCount)
-		}
-		pipe := tx.TxPipeline()
-		initialDuration := c.config.LockoutDuration
-		if initialDuration < 2*time.Second {
-			initialDuration = 2 * time.Second
-		}
-		if wasEmpty {
-			pipe.Set(ctx, redisutil.CHOSENSEQ_KEY, c.config.Url(), initialDuration)
-		}
-		pipe.Set(ctx, redisutil.MSG_COUNT_KEY, msgCountMsg, c.config.SeqNumDuration)
-		if messageData != nil {
-			pipe.Set(ctx, redisutil.MessageKeyFor(msgCountToWrite-1), *messageData, c.config.SeqNumDuration)
-			if messageSigData != nil {
-				pipe.Set(ctx, redisutil.MessageSigKeyFor(msgCountToWrite-1), *messageSigData, c.config.SeqNumDuration)
-			}
-		}
-		pipe.PExpireAt(ctx, redisutil.CHOSENSEQ_KEY, lockoutUntil)
-		if setWantsLockout {
-			myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
-			pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
-			pipe.PExpireAt(ctx, myWantsLockoutKey, lockoutUntil)
-		}
-		err = execTestPipe(pipe, ctx)
-		if errors.Is(err, redis.TxFailedErr) {
-			return fmt.Errorf("%w: failed to catch sequencer lock", execution.ErrRetrySequencer)
-		}
-		if err != nil {
-			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
-		}
-		return nil
-	}, redisutil.CHOSENSEQ_KEY, redisutil.MSG_COUNT_KEY)
-
+	err = c.backend.acquireLockout(ctx, &lockoutUpdate{
+		url:              c.config.Url(),
+		msgCountExpected: msgCountExpected,
+		msgCount:         msgCountToWrite,
+		signedMsgCount:   msgCountMsg,
+		message:          messageData,
+		messageSig:       messageSigData,
+		lockoutUntil:     lockoutUntil,
+		setWantsLockout:  setWantsLockout,
+		// this was called from update(), while msgCount was changed by a call from SequencingMessage
+		ignoreIfAhead: messageData == nil && c.CurrentlyChosen(),
+	})
 	if err != nil {
 		return err
 	}
@@ -340,19 +372,19 @@ func (c *SeqCoordinator) acquireLockoutAndWriteMessage(ctx context.Context, msgC
 	return nil
 }
 
-func (c *SeqCoordinator) getRemoteMsgCountImpl(ctx context.Context, r redis.Cmdable) (arbutil.MessageIndex, error) {
-	resStr, err := r.Get(ctx, redisutil.MSG_COUNT_KEY).Result()
-	if errors.Is(err, redis.Nil) {
-		return 0, nil
-	}
+func (c *SeqCoordinator) getRemoteMsgCount(ctx context.Context) (arbutil.MessageIndex, error) {
+	data, err := c.backend.signedMsgCount(ctx)
 	if err != nil {
 		return 0, err
 	}
-	return c.signedBytesToMsgCount(ctx, []byte(resStr))
+	if data == nil {
+		return 0, nil
+	}
+	return signedBytesToMsgCount(ctx, c.signer, data)
 }
 
 func (c *SeqCoordinator) GetRemoteMsgCount() (arbutil.MessageIndex, error) {
-	return c.getRemoteMsgCountImpl(c.GetContext(), c.Client)
+	return c.getRemoteMsgCount(c.GetContext())
 }
 
 func (c *SeqCoordinator) wantsLockoutUpdate(ctx context.Context) error {
@@ -366,18 +398,9 @@ func (c *SeqCoordinator) wantsLockoutUpdateWithMutex(ctx context.Context) error
 	if c.avoidLockout > 0 {
 		return nil
 	}
-	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
 	wantsLockoutUntil := time.Now().Add(c.config.LockoutDuration)
-	pipe := c.Client.TxPipeline()
-	initialDuration := c.config.LockoutDuration
-	if initialDuration < 2*time.Second {
-		initialDuration = 2 * time.Second
-	}
-	pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
-	pipe.PExpireAt(ctx, myWantsLockoutKey, wantsLockoutUntil)
-	err := execTestPipe(pipe, ctx)
-	if err != nil {
-		return fmt.Errorf("failed to update wants lockout key in redis: %w", err)
+	if err := c.backend.setWantsLockout(ctx, c.config.Url(), wantsLockoutUntil); err != nil {
+		return fmt.Errorf("failed to update wants lockout: %w", err)
 	}
 	c.reportedWantsLockout = true
 	return nil
@@ -386,37 +409,7 @@ func (c *SeqCoordinator) wantsLockoutUpdateWithMutex(ctx context.Context) error
 func (c *SeqCoordinator) chosenOneRelease(ctx context.Context) error {
 	atomicTimeWrite(&c.lockoutUntil, time.Time{})
 	isActiveSequencer.Update(0)
-	releaseErr := c.Client.Watch(ctx, func(tx *redis.Tx) error {
-		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-		if errors.Is(err, redis.Nil) {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		if current != c.config.Url() {
-			return nil
-		}
-		pipe := tx.TxPipeline()
-		pipe.Del(ctx, redisutil.CHOSENSEQ_KEY)
-		err = execTestPipe(pipe, ctx)
-		if err != nil {
-			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
-		}
-		return nil
-	}, redisutil.CHOSENSEQ_KEY)
-	if releaseErr == nil {
-		return nil
-	}
-	// got error - was it still released?
-	current, readErr := c.Client.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-	if errors.Is(readErr, redis.Nil) {
-		return nil
-	}
-	if current != c.config.Url() {
-		return nil
-	}
-	return releaseErr
+	return c.backend.releaseChosen(ctx, c.config.Url())
 }
 
 func (c *SeqCoordinator) wantsLockoutRelease(ctx context.Context) error {
@@ -425,14 +418,8 @@ func (c *SeqCoordinator) wantsLockoutRelease(ctx context.Context) error {
 	if !c.reportedWantsLockout {
 		return nil
 	}
-	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
-	releaseErr := c.Client.Del(ctx, myWantsLockoutKey).Err()
-	if releaseErr != nil {
-		// got error - was it still deleted?
-		readErr := c.Client.Get(ctx, myWantsLockoutKey).Err()
-		if !errors.Is(readErr, redis.Nil) {
-			return releaseErr
-		}
+	if err := c.backend.releaseWantsLockout(ctx, c.config.Url()); err != nil {
+		return err
 	}
 	c.reportedWantsLockout = false
 	return nil
@@ -452,8 +439,52 @@ func (c *SeqCoordinator) noRedisError() time.Duration {
 	return c.config.UpdateInterval
 }
 
+// setDraining returns whether the sequencer was draining before.
+func (c *SeqCoordinator) setDraining(draining bool) bool {
+	c.wantsLockoutMutex.Lock()
+	defer c.wantsLockoutMutex.Unlock()
+	wasDraining := c.draining
+	c.draining = draining
+	return wasDraining
+}
+
+// drain pauses the sequencer and stops writing messages, while keeping the lockout, so that target can catch up to
+// the final message count before taking over. It's called on every update until the planned failover is done.
+func (c *SeqCoordinator) drain(ctx context.Context, target string) time.Duration {
+	if c.sequencer == nil {
+		log.Error("asked to drain for a planned failover, but no sequencer exists")
+		return c.noRedisError()
+	}
+	if !c.setDraining(true) {
+		log.Info("draining for planned failover", "myUrl", c.config.Url(), "target", target)
+	}
+	// pausing again is harmless, and covers the sequencer having been activated since
+	c.sequencer.Pause()
+	localMsgCount, err := c.streamer.GetMessageCount()
+	if err != nil {
+		log.Error("coordinator cannot read message count", "err", err)
+		return c.config.UpdateInterval
+	}
+	err = c.acquireLockoutAndWriteMessage(ctx, localMsgCount, localMsgCount, nil)
+	if err != nil {
+		log.Warn("coordinator failed chosen-one keepalive while draining", "err", err)
+		return c.retryAfterRedisError()
+	}
+	// No more messages are written while draining, so this is final.
+	finalMsgCount, err := c.getRemoteMsgCount(ctx)
+	if err != nil {
+		log.Warn("cannot get remote message count", "err", err)
+		return c.retryAfterRedisError()
+	}
+	if err := c.backend.reportDrained(ctx, c.config.Url(), finalMsgCount); err != nil {
+		log.Warn("failed to report drained message count", "err", err)
+		return c.retryAfterRedisError()
+	}
+	return c.noRedisError()
+}
+
 // update for the prev known-chosen sequencer (no need to load new messages)
-func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen string) time.Duration {
+func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen string, drainFor string) time.Duration {
 	if nextChosen != "" && nextChosen != c.config.Url() {
 		// was the active sequencer, but no longer
 		// we maintain chosen status if we had it and nobody in the priorities wants the lockout
@@ -470,11 +501,21 @@ func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen strin
 			log.Warn("coordinator failed chosen one release", "err", err)
 			return c.retryAfterRedisError()
 		}
+		c.setDraining(false)
 		c.prevChosenSequencer = setPrevChosenTo
 		log.Info("released chosen-coordinator lock", "myUrl", c.config.Url(), "nextChosen", nextChosen)
 		return c.noRedisError()
 	}
 	// Was, and still is, the active sequencer
+	if drainFor != "" {
+		return c.drain(ctx, drainFor)
+	}
+	if c.setDraining(false) {
+		log.Info("planned failover withdrawn, resuming sequencing", "myUrl", c.config.Url())
+		if c.sequencer != nil {
+			c.sequencer.Activate()
+		}
+	}
 	// We leave a margin of error of either a five times the update interval or a fifth of the lockout duration, whichever is greater.
 	marginOfError := arbmath.MaxInt(c.config.LockoutDuration/5, c.config.UpdateInterval*5)
 	if time.Now().Add(marginOfError).Before(atomicTimeRead(&c.lockoutUntil)) {
@@ -500,8 +541,17 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		log.Warn("coordinator failed finding sequencer wanting lockout", "err", err)
 		return c.retryAfterRedisError()
 	}
+	drainFor, catchUp, err := c.backend.failoverRequests(ctx, c.config.Url())
+	if errors.Is(err, errPlannedFailoverUnsupported) {
+		// the backend can't be asked to fail over, so there are never any requests
+		err = nil
+	}
+	if err != nil {
+		log.Warn("coordinator failed reading planned failover requests", "err", err)
+		return c.retryAfterRedisError()
+	}
 	if c.prevChosenSequencer == c.config.Url() {
-		return c.updateWithLockout(ctx, chosenSeq)
+		return c.updateWithLockout(ctx, chosenSeq, drainFor)
 	}
 	if chosenSeq != c.config.Url() && chosenSeq != c.prevChosenSequencer {
 		var err error
@@ -518,7 +568,7 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		}
 	}
 
-	// read messages from redis
+	// read messages written by the chosen sequencer
 	localMsgCount, err := c.streamer.GetMessageCount()
 	if err != nil {
 		log.Error("cannot read message count", "err", err)
@@ -537,18 +587,14 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 	msgToRead := localMsgCount
 	var msgReadErr error
 	for msgToRead < readUntil {
-		var resString string
-		resString, msgReadErr = c.Client.Get(ctx, redisutil.MessageKeyFor(msgToRead)).Result()
+		var rsBytes, sigBytes []byte
+		rsBytes, sigBytes, msgReadErr = c.backend.message(ctx, msgToRead)
 		if msgReadErr != nil {
 			log.Warn("coordinator failed reading message", "pos", msgToRead, "err", msgReadErr)
 			break
 		}
-		rsBytes := []byte(resString)
-		var sigString string
-		var sigBytes []byte
 		sigSeparateKey := true
-		sigString, msgReadErr = c.Client.Get(ctx, redisutil.MessageSigKeyFor(msgToRead)).Result()
-		if errors.Is(msgReadErr, redis.Nil) {
+		if sigBytes == nil {
 			// no separate signature. Try reading old-style sig
 			if len(rsBytes) < 32 {
 				log.Warn("signature not found for msg", "pos", msgToRead)
@@ -558,11 +604,6 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 			sigBytes = rsBytes[:32]
 			rsBytes = rsBytes[32:]
 			sigSeparateKey = false
-		} else if msgReadErr != nil {
-			log.Warn("coordinator failed reading sig", "pos", msgToRead, "err", msgReadErr)
-			break
-		} else {
-			sigBytes = []byte(sigString)
 		}
 		msgReadErr = c.signer.VerifySignature(ctx, sigBytes, arbmath.UintToBytes(uint64(msgToRead)), rsBytes)
 		if msgReadErr != nil {
@@ -572,7 +613,7 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		var message arbostypes.MessageWithMetadata
 		err = json.Unmarshal(rsBytes, &message)
 		if err != nil {
-			log.Warn("coordinator failed to parse message from redis", "pos", msgToRead, "err", err)
+			log.Warn("coordinator failed to parse message", "pos", msgToRead, "err", err)
 			msgReadErr = fmt.Errorf("failed to parse message: %w", err)
 			// redis messages spelled "INVALID" will be parsed as invalid L1 message, but only one at a time
 			if len(messages) > 0 || string(rsBytes) != redisutil.INVALID_VAL {
@@ -607,6 +648,16 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		return c.noRedisError()
 	}
 
+	if catchUp {
+		processedMessages, err := c.streamer.GetProcessedMessageCount()
+		if err == nil {
+			err = c.backend.reportProgress(ctx, c.config.Url(), processedMessages)
+		}
+		if err != nil {
+			log.Warn("failed to report planned failover progress", "err", err)
+		}
+	}
+
 	syncProgress := c.sync.SyncProgressMap()
 	synced := len(syncProgress) == 0
 	if !synced {
@@ -730,6 +781,8 @@ func (c *SeqCoordinator) launchHealthcheckServer(ctx context.Context) {
 
 func (c *SeqCoordinator) Start(ctxIn context.Context) {
 	c.StopWaiter.Start(ctxIn, c)
+	// The backend outlives our context, to release the lockout on shutdown.
+	c.backend.start(ctxIn)
 	c.CallIteratively(c.update)
 	if c.config.ChosenHealthcheckAddr != "" {
 		c.StopWaiter.LaunchThread(c.launchHealthcheckServer)
@@ -785,7 +838,17 @@ func (c *SeqCoordinator) StopAndWait() {
 			time.Sleep(c.retryAfterRedisError())
 		}
 	}
-	_ = c.Client.Close()
+	c.backend.close()
+}
+
+// RecommendSequencerWantingLockout returns the top priority sequencer wanting the lockout
+func (c *SeqCoordinator) RecommendSequencerWantingLockout(ctx context.Context) (string, error) {
+	return c.backend.recommendSequencerWantingLockout(ctx)
+}
+
+// CurrentChosenSequencer retrieves the current chosen sequencer holding the lock
+func (c *SeqCoordinator) CurrentChosenSequencer(ctx context.Context) (string, error) {
+	return c.backend.currentChosenSequencer(ctx)
 }
 
 func (c *SeqCoordinator) CurrentlyChosen() bool {
@@ -811,7 +874,7 @@ func (c *SeqCoordinator) AvoidLockout(ctx context.Context) bool {
 	log.Info("avoiding lockout", "myUrl", c.config.Url())
 	err := c.wantsLockoutRelease(ctx)
 	if err != nil {
-		log.Error("failed to release wanting the lockout in redis", "err", err)
+		log.Error("failed to release wanting the lockout", "err", err)
 		return false
 	}
 	return true
@@ -851,7 +914,7 @@ func (c *SeqCoordinator) SeekLockout(ctx context.Context) {
 		// Even if this errors we still internally marked ourselves as wanting the lockout
 		err := c.wantsLockoutUpdateWithMutex(ctx)
 		if err != nil {
-			log.Warn("failed to set wants lockout key in redis after seeking lockout again", "err", err)
+			log.Warn("failed to set wants lockout after seeking lockout again", "err", err)
 		}
 	}
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/raftutil"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/signature"
 )
@@ -91,10 +92,10 @@ func coordinatorTestThread(ctx context.Context, coord *SeqCoordinator, data *Coo
 	}
 }
 
-func TestRedisSeqCoordinatorAtomic(t *testing.T) {
+// testSeqCoordinatorAtomic runs rounds of coordinators racing to sequence messages. newRound is called before
+// each round, while the coordinators are idle, to reset the state they coordinate through.
+func testSeqCoordinatorAtomic(t *testing.T, ctx context.Context, newRound func(coordinators []*SeqCoordinator)) {
 	NumOfThreads := 10
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	coordConfig := TestSeqCoordinatorConfig
 	coordConfig.LockoutDuration = time.Millisecond * 100
@@ -111,29 +112,25 @@ func TestRedisSeqCoordinatorAtomic(t *testing.T) {
 	nullSigner, err := signature.NewSignVerify(&coordConfig.Signer, nil, nil)
 	Require(t, err)
 
-	redisUrl := redisutil.CreateTestRedis(ctx, t)
-	coordConfig.RedisUrl = redisUrl
-	redisClient, err := redisutil.RedisClientFromURL(redisUrl)
-	Require(t, err)
-	if redisClient == nil {
-		t.Fatal("redisClient is nil")
-	}
-
+	var coordinators []*SeqCoordinator
 	for i := 0; i < NumOfThreads; i++ {
 		config := coordConfig
 		config.MyUrl = fmt.Sprint(i)
-		redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
-		Require(t, err)
-		coordinator := &SeqCoordinator{
-			RedisCoordinator: *redisCoordinator,
-			config:           config,
-			signer:           nullSigner,
-		}
-		go coordinatorTestThread(ctx, coordinator, &testData)
+		coordinators = append(coordinators, &SeqCoordinator{
+			config: config,
+			signer: nullSigner,
+		})
 	}
+	started := false
 
 	for round := int32(0); round < 10; round++ {
-		redisClient.Del(ctx, redisutil.CHOSENSEQ_KEY, redisutil.MSG_COUNT_KEY)
+		newRound(coordinators)
+		if !started {
+			for _, coordinator := range coordinators {
+				go coordinatorTestThread(ctx, coordinator, &testData)
+			}
+			started = true
+		}
 		testData.messageCount = 0
 		for i := 0; i < messagesPerRound; i++ {
 			testData.sequencer[i] = ""
@@ -154,5 +151,53 @@ func TestRedisSeqCoordinatorAtomic(t *testing.T) {
 		// wait out the current lock
 		time.Sleep(time.Millisecond * 20)
 	}
+}
+
+func TestRedisSeqCoordinatorAtomic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	redisUrl := redisutil.CreateTestRedis(ctx, t)
+	redisClient, err := redisutil.RedisClientFromURL(redisUrl)
+	Require(t, err)
+	if redisClient == nil {
+		t.Fatal("redisClient is nil")
+	}
 
+	testSeqCoordinatorAtomic(t, ctx, func(coordinators []*SeqCoordinator) {
+		for _, coordinator := range coordinators {
+			if coordinator.backend != nil {
+				continue
+			}
+			redisCoordinator, err := redisutil.NewRedisCoordinator(redisUrl)
+			Require(t, err)
+			coordinator.backend = &redisSeqCoordinatorBackend{
+				RedisCoordinator: *redisCoordinator,
+				config:           &coordinator.config,
+				signer:           coordinator.signer,
+			}
+		}
+		redisClient.Del(ctx, redisutil.CHOSENSEQ_KEY, redisutil.MSG_COUNT_KEY)
+	})
+}
+
+func TestRaftSeqCoordinatorAtomic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelCluster := func() {}
+	testSeqCoordinatorAtomic(t, ctx, func(coordinators []*SeqCoordinator) {
+		// The replicated state can't be reset, so each round gets a fresh raft cluster
+		cancelCluster()
+		var clusterCtx context.Context
+		clusterCtx, cancelCluster = context.WithCancel(ctx)
+		cluster := raftutil.CreateTestCluster(clusterCtx, t, 3, nil)
+		for i, coordinator := range coordinators {
+			coordinator.backend = &raftSeqCoordinatorBackend{
+				coordinator: cluster[i%len(cluster)],
+				config:      &coordinator.config,
+			}
+		}
+	})
+	cancelCluster()
 }
@@ -0,0 +1,151 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbnode/redislock"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/raftutil"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+// raftSeqCoordinatorBackend coordinates the sequencers via state replicated among them with raft,
+// so that no external service is needed. Each sequencer is a replica.
+type raftSeqCoordinatorBackend struct {
+	coordinator *raftutil.Coordinator
+	config      *SeqCoordinatorConfig
+}
+
+func newRaftSeqCoordinatorBackend(config *SeqCoordinatorConfig, db ethdb.KeyValueStore, signer *signature.SignVerify) (*raftSeqCoordinatorBackend, error) {
+	if db == nil {
+		return nil, errors.New("raft sequencer coordination requires a database")
+	}
+	coordinator, err := raftutil.NewCoordinator(&config.Raft, db, signer)
+	if err != nil {
+		return nil, err
+	}
+	return &raftSeqCoordinatorBackend{coordinator: coordinator, config: config}, nil
+}
+
+func retryIfConflict(err error) error {
+	if errors.Is(err, raftutil.ErrConflict) {
+		return fmt.Errorf("%w: %v", execution.ErrRetrySequencer, err)
+	}
+	return err
+}
+
+func (b *raftSeqCoordinatorBackend) acquireLockout(ctx context.Context, update *lockoutUpdate) error {
+	err := b.coordinator.AcquireLockout(ctx, &raftutil.LockoutRequest{
+		Url:              update.url,
+		ExpectedMsgCount: uint64(update.msgCountExpected),
+		MsgCount:         uint64(update.msgCount),
+		SignedMsgCount:   update.signedMsgCount,
+		Message:          update.message,
+		MessageSig:       update.messageSig,
+		LockoutUntil:     update.lockoutUntil,
+		Retention:        b.config.SeqNumDuration,
+		SetWantsLockout:  update.setWantsLockout,
+		IgnoreIfAhead:    update.ignoreIfAhead,
+	})
+	return retryIfConflict(err)
+}
+
+func (b *raftSeqCoordinatorBackend) setWantsLockout(ctx context.Context, url string, until time.Time) error {
+	return b.coordinator.SetWantsLockout(ctx, url, until)
+}
+
+func (b *raftSeqCoordinatorBackend) releaseWantsLockout(ctx context.Context, url string) error {
+	return b.coordinator.ReleaseWantsLockout(ctx, url)
+}
+
+func (b *raftSeqCoordinatorBackend) releaseChosen(ctx context.Context, url string) error {
+	return b.coordinator.ReleaseChosen(ctx, url)
+}
+
+func (b *raftSeqCoordinatorBackend) recommendSequencerWantingLockout(context.Context) (string, error) {
+	return b.coordinator.RecommendSequencerWantingLockout()
+}
+
+func (b *raftSeqCoordinatorBackend) currentChosenSequencer(context.Context) (string, error) {
+	return b.coordinator.CurrentChosenSequencer(), nil
+}
+
+func (b *raftSeqCoordinatorBackend) signedMsgCount(context.Context) ([]byte, error) {
+	return b.coordinator.SignedMsgCount(), nil
+}
+
+func (b *raftSeqCoordinatorBackend) message(_ context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error) {
+	msg, sig, ok := b.coordinator.Message(uint64(pos))
+	if !ok {
+		return nil, nil, fmt.Errorf("message %v not found", pos)
+	}
+	return msg, sig, nil
+}
+
+// errPlannedFailoverUnsupported is returned by the raft backend for planned failover, which the seq-coordinator-manager
+// drives via redis. With raft, a handoff is made by reordering the priorities instead.
+var errPlannedFailoverUnsupported = errors.New("planned failover isn't supported with raft sequencer coordination")
+
+func (b *raftSeqCoordinatorBackend) failoverRequests(context.Context, string) (string, bool, error) {
+	return "", false, errPlannedFailoverUnsupported
+}
+
+func (b *raftSeqCoordinatorBackend) reportDrained(context.Context, string, arbutil.MessageIndex) error {
+	return errPlannedFailoverUnsupported
+}
+
+func (b *raftSeqCoordinatorBackend) reportProgress(context.Context, string, arbutil.MessageIndex) error {
+	return errPlannedFailoverUnsupported
+}
+
+func (b *raftSeqCoordinatorBackend) newLock(config redislock.SimpleCfgFetcher, readyToLock func() bool) (lock, error) {
+	return &raftLock{coordinator: b.coordinator, config: config, readyToLock: readyToLock, owner: b.config.Url()}, nil
+}
+
+func (b *raftSeqCoordinatorBackend) start(ctx context.Context) {
+	b.coordinator.Start(ctx)
+}
+
+func (b *raftSeqCoordinatorBackend) close() {
+	b.coordinator.StopAndWait()
+}
+
+// raftLock is a lock held via the raft coordination state, configured like a redislock.Simple.
+type raftLock struct {
+	coordinator *raftutil.Coordinator
+	config      redislock.SimpleCfgFetcher
+	readyToLock func() bool
+	owner       string
+}
+
+func (l *raftLock) AttemptLock(ctx context.Context) bool {
+	if !l.readyToLock() {
+		return false
+	}
+	config := l.config()
+	err := l.coordinator.AcquireLock(ctx, config.Key, l.owner, time.Now().Add(config.LockoutDuration))
+	if err != nil {
+		if !errors.Is(err, raftutil.ErrConflict) {
+			log.Warn("failed to acquire raft lock", "key", config.Key, "err", err)
+		}
+		return false
+	}
+	return true
+}
+
+func (l *raftLock) Release(ctx context.Context) {
+	config := l.config()
+	if err := l.coordinator.ReleaseLock(ctx, config.Key, l.owner); err != nil {
+		log.Warn("failed to release raft lock", "key", config.Key, "err", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbnode/redislock"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+// redisSeqCoordinatorBackend coordinates the sequencers via redis.
+type redisSeqCoordinatorBackend struct {
+	redisutil.RedisCoordinator
+	config *SeqCoordinatorConfig
+	signer *signature.SignVerify
+}
+
+func newRedisSeqCoordinatorBackend(config *SeqCoordinatorConfig, signer *signature.SignVerify) (*redisSeqCoordinatorBackend, error) {
+	redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &redisSeqCoordinatorBackend{
+		RedisCoordinator: *redisCoordinator,
+		config:           config,
+		signer:           signer,
+	}, nil
+}
+
+func execTestPipe(pipe redis.Pipeliner, ctx context.Context) error {
+	cmders, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cmder := range cmders {
+		if err := cmder.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The initial expiry of keys which are then set to expire at a given time, in case that fails
+func (b *redisSeqCoordinatorBackend) initialDuration() time.Duration {
+	initialDuration := b.config.LockoutDuration
+	if initialDuration < 2*time.Second {
+		initialDuration = 2 * time.Second
+	}
+	return initialDuration
+}
+
+func (b *redisSeqCoordinatorBackend) acquireLockout(ctx context.Context, update *lockoutUpdate) error {
+	return b.Client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
+		var wasEmpty bool
+		if errors.Is(err, redis.Nil) {
+			wasEmpty = true
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		if !wasEmpty && (current != update.url) {
+			return fmt.Errorf("%w: failed to catch lock. redis shows chosen: %s", execution.ErrRetrySequencer, current)
+		}
+		remoteMsgCount, err := b.getRemoteMsgCountImpl(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if remoteMsgCount > update.msgCountExpected {
+			if update.ignoreIfAhead {
+				return nil
+			}
+			log.Info("coordinator failed to become main", "expected", update.msgCountExpected, "found", remoteMsgCount, "message is nil?", update.message == nil)
+			return fmt.Errorf("%w: failed to catch lock. expected msg %d found %d", execution.ErrRetrySequencer, update.msgCountExpected, remoteMsgCount)
+		}
+		pipe := tx.TxPipeline()
+		initialDuration := b.initialDuration()
+		if wasEmpty {
+			pipe.Set(ctx, redisutil.CHOSENSEQ_KEY, update.url, initialDuration)
+		}
+		pipe.Set(ctx, redisutil.MSG_COUNT_KEY, update.signedMsgCount, b.config.SeqNumDuration)
+		if update.message != nil {
+			pipe.Set(ctx, redisutil.MessageKeyFor(update.msgCount-1), update.message, b.config.SeqNumDuration)
+			if update.messageSig != nil {
+				pipe.Set(ctx, redisutil.MessageSigKeyFor(update.msgCount-1), update.messageSig, b.config.SeqNumDuration)
+			}
+		}
+		pipe.PExpireAt(ctx, redisutil.CHOSENSEQ_KEY, update.lockoutUntil)
+		if update.setWantsLockout {
+			myWantsLockoutKey := redisutil.WantsLockoutKeyFor(update.url)
+			pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
+			pipe.PExpireAt(ctx, myWantsLockoutKey, update.lockoutUntil)
+		}
+		err = execTestPipe(pipe, ctx)
+		if errors.Is(err, redis.TxFailedErr) {
+			return fmt.Errorf("%w: failed to catch sequencer lock", execution.ErrRetrySequencer)
+		}
+		if err != nil {
+			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
+		}
+		return nil
+	}, redisutil.CHOSENSEQ_KEY, redisutil.MSG_COUNT_KEY)
+}
+
+func (b *redisSeqCoordinatorBackend) getRemoteMsgCountImpl(ctx context.Context, r redis.Cmdable) (arbutil.MessageIndex, error) {
+	resStr, err := r.Get(ctx, redisutil.MSG_COUNT_KEY).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return signedBytesToMsgCount(ctx, b.signer, []byte(resStr))
+}
+
+func (b *redisSeqCoordinatorBackend) setWantsLockout(ctx context.Context, url string, until time.Time) error {
+	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(url)
+	pipe := b.Client.TxPipeline()
+	pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, b.initialDuration())
+	pipe.PExpireAt(ctx, myWantsLockoutKey, until)
+	err := execTestPipe(pipe, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update wants lockout key in redis: %w", err)
+	}
+	return nil
+}
+
+func (b *redisSeqCoordinatorBackend) releaseWantsLockout(ctx context.Context, url string) error {
+	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(url)
+	releaseErr := b.Client.Del(ctx, myWantsLockoutKey).Err()
+	if releaseErr != nil {
+		// got error - was it still deleted?
+		readErr := b.Client.Get(ctx, myWantsLockoutKey).Err()
+		if !errors.Is(readErr, redis.Nil) {
+			return releaseErr
+		}
+	}
+	return nil
+}
+
+func (b *redisSeqCoordinatorBackend) releaseChosen(ctx context.Context, url string) error {
+	releaseErr := b.Client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if current != url {
+			return nil
+		}
+		pipe := tx.TxPipeline()
+		pipe.Del(ctx, redisutil.CHOSENSEQ_KEY)
+		err = execTestPipe(pipe, ctx)
+		if err != nil {
+			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
+		}
+		return nil
+	}, redisutil.CHOSENSEQ_KEY)
+	if releaseErr == nil {
+		return nil
+	}
+	// got error - was it still released?
+	current, readErr := b.Client.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
+	if errors.Is(readErr, redis.Nil) {
+		return nil
+	}
+	if current != url {
+		return nil
+	}
+	return releaseErr
+}
+
+func (b *redisSeqCoordinatorBackend) recommendSequencerWantingLockout(ctx context.Context) (string, error) {
+	return b.RecommendSequencerWantingLockout(ctx)
+}
+
+func (b *redisSeqCoordinatorBackend) currentChosenSequencer(ctx context.Context) (string, error) {
+	return b.CurrentChosenSequencer(ctx)
+}
+
+func (b *redisSeqCoordinatorBackend) signedMsgCount(ctx context.Context) ([]byte, error) {
+	resStr, err := b.Client.Get(ctx, redisutil.MSG_COUNT_KEY).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resStr), nil
+}
+
+func (b *redisSeqCoordinatorBackend) message(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error) {
+	msg, err := b.Client.Get(ctx, redisutil.MessageKeyFor(pos)).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := b.Client.Get(ctx, redisutil.MessageSigKeyFor(pos)).Result()
+	if errors.Is(err, redis.Nil) {
+		return []byte(msg), nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed reading sig: %w", err)
+	}
+	return []byte(msg), []byte(sig), nil
+}
+
+func (b *redisSeqCoordinatorBackend) failoverRequests(ctx context.Context, url string) (string, bool, error) {
+	values, err := b.Client.MGet(ctx, redisutil.DrainKeyFor(url), redisutil.CatchupKeyFor(url)).Result()
+	if err != nil {
+		return "", false, err
+	}
+	drainFor, _ := values[0].(string)
+	return drainFor, values[1] != nil, nil
+}
+
+func (b *redisSeqCoordinatorBackend) reportDrained(ctx context.Context, url string, msgCount arbutil.MessageIndex) error {
+	return b.Client.Set(ctx, redisutil.DrainedKeyFor(url), uint64(msgCount), b.config.LockoutDuration).Err()
+}
+
+func (b *redisSeqCoordinatorBackend) reportProgress(ctx context.Context, url string, msgCount arbutil.MessageIndex) error {
+	return b.Client.Set(ctx, redisutil.ProgressKeyFor(url), uint64(msgCount), b.config.LockoutDuration).Err()
+}
+
+func (b *redisSeqCoordinatorBackend) newLock(config redislock.SimpleCfgFetcher, readyToLock func() bool) (lock, error) {
+	return redislock.NewSimple(b.Client, config, readyToLock)
+}
+
+func (b *redisSeqCoordinatorBackend) start(context.Context) {}
+
+func (b *redisSeqCoordinatorBackend) close() {
+	_ = b.Client.Close()
+}
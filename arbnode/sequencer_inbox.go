@@ -15,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/arbutil"
 
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
@@ -33,6 +34,7 @@ const (
 	batchDataTxInput batchDataLocation = iota
 	batchDataSeparateEvent
 	batchDataNone
+	batchDataBlobHashes
 )
 
 func init() {
@@ -149,6 +151,23 @@ func (m *SequencerInboxBatch) getSequencerData(ctx context.Context, client arbut
 	case batchDataNone:
 		// No data when in a force inclusion batch
 		return nil, nil
+	case batchDataBlobHashes:
+		tx, err := client.TransactionInBlock(ctx, m.BlockHash, m.rawLog.TxIndex)
+		if err != nil {
+			return nil, err
+		}
+		if tx.Hash() != m.rawLog.TxHash {
+			return nil, fmt.Errorf("L1 client returned unexpected transaction hash %v when looking up block %v transaction %v with expected hash %v", tx.Hash(), m.BlockHash, m.rawLog.TxIndex, m.rawLog.TxHash)
+		}
+		if len(tx.BlobHashes()) == 0 {
+			return nil, fmt.Errorf("blob batch transaction %v has no blobs", tx.Hash())
+		}
+		// The data is only the blob hashes, the inbox multiplexer reads the blobs themselves
+		data := []byte{arbstate.BlobHashesHeaderFlag}
+		for _, h := range tx.BlobHashes() {
+			data = append(data, h[:]...)
+		}
+		return data, nil
 	default:
 		return nil, fmt.Errorf("batch has invalid data location %v", m.dataLocation)
 	}
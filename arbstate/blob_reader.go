@@ -0,0 +1,25 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbstate
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// BlobReader retrieves the EIP-4844 blobs a batch was posted in.
+type BlobReader interface {
+	// GetBlobs returns the blobs with the given versioned hashes, in the same order,
+	// posted in the L1 block with hash batchBlockHash.
+	GetBlobs(
+		ctx context.Context,
+		batchBlockHash common.Hash,
+		versionedHashes []common.Hash,
+	) ([]kzg4844.Blob, error)
+
+	// Initialize must be called before GetBlobs.
+	Initialize(ctx context.Context) error
+}
@@ -37,6 +37,15 @@ const TreeDASMessageHeaderFlag byte = 0x08
 // L1AuthenticatedMessageHeaderFlag indicates that this message was authenticated by L1. Currently unused.
 const L1AuthenticatedMessageHeaderFlag byte = 0x40
 
+// BlobHashesHeaderFlag indicates that this message contains EIP-4844 versioned hashes of the blobs holding the batch data.
+// Calldata batches posted before blobs were supported may also start with it, so it's only read as such
+// for batches which InboxBackend.IsBlobBatch reports were posted in blobs.
+const BlobHashesHeaderFlag byte = L1AuthenticatedMessageHeaderFlag | 0x10
+
+// BlobHashesMinArbOSVersion is the first ArbOS version the replay binary reads blob batches in, as it can't
+// see how a batch was posted. It isn't live yet, and blob batches must not be posted before it is.
+const BlobHashesMinArbOSVersion uint64 = 20
+
 // ZeroheavyMessageHeaderFlag indicates that this message is zeroheavy-encoded.
 const ZeroheavyMessageHeaderFlag byte = 0x20
 
@@ -51,6 +60,10 @@ func IsTreeDASMessageHeaderByte(header byte) bool {
 	return (TreeDASMessageHeaderFlag & header) > 0
 }
 
+func IsBlobHashesHeaderByte(header byte) bool {
+	return (BlobHashesHeaderFlag & header) == BlobHashesHeaderFlag
+}
+
 func IsZeroheavyEncodedHeaderByte(header byte) bool {
 	return (ZeroheavyMessageHeaderFlag & header) > 0
 }
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 
@@ -21,11 +22,16 @@ import (
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/blobs"
 	"github.com/offchainlabs/nitro/zeroheavy"
 )
 
 type InboxBackend interface {
-	PeekSequencerInbox() ([]byte, error)
+	// PeekSequencerInbox returns the current sequencer message and the hash of the L1 block that posted it.
+	PeekSequencerInbox() ([]byte, common.Hash, error)
+	// IsBlobBatch returns whether the current sequencer message was posted in EIP-4844 blobs,
+	// in which case it holds the blobs' versioned hashes rather than the batch data.
+	IsBlobBatch() bool
 
 	GetSequencerInboxPosition() uint64
 	AdvanceSequencerInbox()
@@ -50,7 +56,7 @@ const maxZeroheavyDecompressedLen = 101*MaxDecompressedLen/100 + 64
 const MaxSegmentsPerSequencerMessage = 100 * 1024
 const MinLifetimeSecondsForDataAvailabilityCert = 7 * 24 * 60 * 60 // one week
 
-func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, dasReader DataAvailabilityReader, keysetValidationMode KeysetValidationMode) (*sequencerMessage, error) {
+func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash common.Hash, data []byte, isBlobBatch bool, dasReader DataAvailabilityReader, blobReader BlobReader, keysetValidationMode KeysetValidationMode) (*sequencerMessage, error) {
 	if len(data) < 40 {
 		return nil, errors.New("sequencer message missing L1 header")
 	}
@@ -79,6 +85,31 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, da
 		}
 	}
 
+	if len(payload) > 0 && isBlobBatch && IsBlobHashesHeaderByte(payload[0]) {
+		// The batch is known to be in blobs, so failing to read them is an error rather than an empty batch
+		blobHashes := payload[1:]
+		if len(blobHashes)%len(common.Hash{}) != 0 {
+			log.Warn("blob batch data is not a list of hashes", "length", len(blobHashes))
+			return parsedMsg, nil
+		}
+		versionedHashes := make([]common.Hash, len(blobHashes)/len(common.Hash{}))
+		for i := range versionedHashes {
+			copy(versionedHashes[i][:], blobHashes[i*len(common.Hash{}):])
+		}
+		if blobReader == nil {
+			return nil, errors.New("blob batch was encountered but no BlobReader was configured")
+		}
+		kzgBlobs, err := blobReader.GetBlobs(ctx, batchBlockHash, versionedHashes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blobs: %w", err)
+		}
+		payload, err = blobs.DecodeBlobs(kzgBlobs)
+		if err != nil {
+			log.Warn("Failed to decode blobs", "batchBlockHash", batchBlockHash, "versionedHashes", versionedHashes, "err", err)
+			return parsedMsg, nil
+		}
+	}
+
 	if len(payload) > 0 && IsZeroheavyEncodedHeaderByte(payload[0]) {
 		pl, err := io.ReadAll(io.LimitReader(zeroheavy.NewZeroheavyDecoder(bytes.NewReader(payload[1:])), int64(maxZeroheavyDecompressedLen)))
 		if err != nil {
@@ -242,6 +273,7 @@ type inboxMultiplexer struct {
 	backend                   InboxBackend
 	delayedMessagesRead       uint64
 	dasReader                 DataAvailabilityReader
+	blobReader                BlobReader
 	cachedSequencerMessage    *sequencerMessage
 	cachedSequencerMessageNum uint64
 	cachedSegmentNum          uint64
@@ -251,11 +283,12 @@ type inboxMultiplexer struct {
 	keysetValidationMode      KeysetValidationMode
 }
 
-func NewInboxMultiplexer(backend InboxBackend, delayedMessagesRead uint64, dasReader DataAvailabilityReader, keysetValidationMode KeysetValidationMode) arbostypes.InboxMultiplexer {
+func NewInboxMultiplexer(backend InboxBackend, delayedMessagesRead uint64, dasReader DataAvailabilityReader, blobReader BlobReader, keysetValidationMode KeysetValidationMode) arbostypes.InboxMultiplexer {
 	return &inboxMultiplexer{
 		backend:              backend,
 		delayedMessagesRead:  delayedMessagesRead,
 		dasReader:            dasReader,
+		blobReader:           blobReader,
 		keysetValidationMode: keysetValidationMode,
 	}
 }
@@ -270,13 +303,13 @@ const BatchSegmentKindAdvanceL1BlockNumber uint8 = 4
 // Note: this does *not* return parse errors, those are transformed into invalid messages
 func (r *inboxMultiplexer) Pop(ctx context.Context) (*arbostypes.MessageWithMetadata, error) {
 	if r.cachedSequencerMessage == nil {
-		bytes, realErr := r.backend.PeekSequencerInbox()
+		bytes, batchBlockHash, realErr := r.backend.PeekSequencerInbox()
 		if realErr != nil {
 			return nil, realErr
 		}
 		r.cachedSequencerMessageNum = r.backend.GetSequencerInboxPosition()
 		var err error
-		r.cachedSequencerMessage, err = parseSequencerMessage(ctx, r.cachedSequencerMessageNum, bytes, r.dasReader, r.keysetValidationMode)
+		r.cachedSequencerMessage, err = parseSequencerMessage(ctx, r.cachedSequencerMessageNum, batchBlockHash, bytes, r.backend.IsBlobBatch(), r.dasReader, r.blobReader, r.keysetValidationMode)
 		if err != nil {
 			return nil, err
 		}
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 )
 
@@ -19,11 +21,15 @@ type multiplexerBackend struct {
 	positionWithinMessage uint64
 }
 
-func (b *multiplexerBackend) PeekSequencerInbox() ([]byte, error) {
+func (b *multiplexerBackend) PeekSequencerInbox() ([]byte, common.Hash, error) {
 	if b.batchSeqNum != 0 {
-		return nil, errors.New("reading unknown sequencer batch")
+		return nil, common.Hash{}, errors.New("reading unknown sequencer batch")
 	}
-	return b.batch, nil
+	return b.batch, common.Hash{}, nil
+}
+
+func (b *multiplexerBackend) IsBlobBatch() bool {
+	return false
 }
 
 func (b *multiplexerBackend) GetSequencerInboxPosition() uint64 {
@@ -60,13 +66,17 @@ func FuzzInboxMultiplexer(f *testing.F) {
 		if len(seqMsg) < 40 {
 			return
 		}
+		if len(seqMsg) > 40 && IsBlobHashesHeaderByte(seqMsg[40]) {
+			// Blobs are fetched from L1, and failing to fetch them is a real error.
+			return
+		}
 		backend := &multiplexerBackend{
 			batchSeqNum:           0,
 			batch:                 seqMsg,
 			delayedMessage:        delayedMsg,
 			positionWithinMessage: 0,
 		}
-		multiplexer := NewInboxMultiplexer(backend, 0, nil, KeysetValidate)
+		multiplexer := NewInboxMultiplexer(backend, 0, nil, nil, KeysetValidate)
 		_, err := multiplexer.Pop(context.TODO())
 		if err != nil {
 			panic(err)
@@ -0,0 +1,101 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbstate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbcompress"
+	"github.com/offchainlabs/nitro/util/blobs"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+type memoryBlobReader struct {
+	blockHash common.Hash
+	blobs     map[common.Hash]kzg4844.Blob
+}
+
+func (r *memoryBlobReader) GetBlobs(_ context.Context, batchBlockHash common.Hash, versionedHashes []common.Hash) ([]kzg4844.Blob, error) {
+	if batchBlockHash != r.blockHash {
+		return nil, errors.New("unknown block")
+	}
+	var result []kzg4844.Blob
+	for _, hash := range versionedHashes {
+		blob, ok := r.blobs[hash]
+		if !ok {
+			return nil, errors.New("unknown blob")
+		}
+		result = append(result, blob)
+	}
+	return result, nil
+}
+
+func (r *memoryBlobReader) Initialize(context.Context) error {
+	return nil
+}
+
+func TestParseBlobSequencerMessage(t *testing.T) {
+	ctx := context.Background()
+	segments := [][]byte{{BatchSegmentKindL2Message, 1, 2, 3}, {BatchSegmentKindDelayedMessages}}
+	var encodedSegments []byte
+	for _, segment := range segments {
+		encoded, err := rlp.EncodeToBytes(segment)
+		testhelpers.RequireImpl(t, err)
+		encodedSegments = append(encodedSegments, encoded...)
+	}
+	compressed, err := arbcompress.CompressWell(encodedSegments)
+	testhelpers.RequireImpl(t, err)
+	payload := append([]byte{BrotliMessageHeaderByte}, compressed...)
+
+	kzgBlobs, err := blobs.EncodeBlobs(payload)
+	testhelpers.RequireImpl(t, err)
+	_, versionedHashes, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+	testhelpers.RequireImpl(t, err)
+	reader := &memoryBlobReader{blockHash: common.Hash{1}, blobs: make(map[common.Hash]kzg4844.Blob)}
+	for i, hash := range versionedHashes {
+		reader.blobs[hash] = kzgBlobs[i]
+	}
+
+	data := make([]byte, 40, 40+1+32*len(versionedHashes))
+	data = append(data, BlobHashesHeaderFlag)
+	for _, hash := range versionedHashes {
+		data = append(data, hash[:]...)
+	}
+	parsed, err := parseSequencerMessage(ctx, 0, reader.blockHash, data, true, nil, reader, KeysetValidate)
+	testhelpers.RequireImpl(t, err)
+	if len(parsed.segments) != len(segments) {
+		testhelpers.FailImpl(t, "expected", len(segments), "segments but got", len(parsed.segments))
+	}
+	for i := range segments {
+		if !bytes.Equal(parsed.segments[i], segments[i]) {
+			testhelpers.FailImpl(t, "segment", i, "doesn't match")
+		}
+	}
+
+	// Without a blob reader the batch can't be read.
+	_, err = parseSequencerMessage(ctx, 0, reader.blockHash, data, true, nil, nil, KeysetValidate)
+	if err == nil {
+		testhelpers.FailImpl(t, "expected an error parsing a blob batch without a blob reader")
+	}
+
+	// Nor can it if the blobs aren't found.
+	_, err = parseSequencerMessage(ctx, 0, common.Hash{2}, data, true, nil, reader, KeysetValidate)
+	if err == nil {
+		testhelpers.FailImpl(t, "expected an error parsing a blob batch whose blobs weren't found")
+	}
+
+	// A calldata batch starting with the same header isn't read from blobs.
+	parsed, err = parseSequencerMessage(ctx, 0, reader.blockHash, data, false, nil, nil, KeysetValidate)
+	testhelpers.RequireImpl(t, err)
+	if len(parsed.segments) != 0 {
+		testhelpers.FailImpl(t, "expected no segments in a calldata batch but got", len(parsed.segments))
+	}
+}
@@ -10,4 +10,7 @@ type PreimageType uint8
 const (
 	Keccak256PreimageType PreimageType = iota
 	Sha2_256PreimageType
+	// EthVersionedHashPreimageType resolves EIP-4844 blobs by their versioned hashes.
+	// The arbitrator doesn't support it yet, so blob batches can be replayed but not proven.
+	EthVersionedHashPreimageType
 )
@@ -12,6 +12,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,6 +24,7 @@ import (
 	"github.com/gobwas/ws/wsflate"
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 
@@ -37,6 +39,7 @@ import (
 var (
 	sourcesConnectedGauge    = metrics.NewRegisteredGauge("arb/feed/sources/connected", nil)
 	sourcesDisconnectedGauge = metrics.NewRegisteredGauge("arb/feed/sources/disconnected", nil)
+	filteredFeedGapCounter   = metrics.NewRegisteredCounter("arb/feed/filtered/gaps", nil)
 )
 
 type FeedConfig struct {
@@ -45,6 +48,9 @@ type FeedConfig struct {
 }
 
 func (fc *FeedConfig) Validate() error {
+	if fc.Input.Subscription.Enable {
+		return errors.New("feed.input.subscription can't be enabled on a node, which needs the whole feed")
+	}
 	return fc.Output.Validate()
 }
 
@@ -72,6 +78,52 @@ type Config struct {
 	SecondaryURL            []string                 `koanf:"secondary-url"`
 	Verify                  signature.VerifierConfig `koanf:"verify"`
 	EnableCompression       bool                     `koanf:"enable-compression" reload:"hot"`
+	EnableBinary            bool                     `koanf:"enable-binary" reload:"hot"` // reloading will affect only new connections
+	Subscription            SubscriptionConfig       `koanf:"subscription"`
+}
+
+// SubscriptionConfig configures the filter sent to the feed server when connecting.
+// A filtered feed leaves gaps, so it can't be used by a node or relay to follow the chain.
+type SubscriptionConfig struct {
+	Enable            bool     `koanf:"enable"`
+	Kinds             []int    `koanf:"kinds"`
+	Posters           []string `koanf:"posters"`
+	ConfirmationsOnly bool     `koanf:"confirmations-only"`
+}
+
+func SubscriptionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSubscriptionConfig.Enable, "only receive feed messages matching the subscription filter, instead of the whole feed (not supported by nodes and relays, which need the whole feed)")
+	f.IntSlice(prefix+".kinds", DefaultSubscriptionConfig.Kinds, "L1 incoming message kinds to receive (all kinds if empty)")
+	f.StringSlice(prefix+".posters", DefaultSubscriptionConfig.Posters, "addresses of message posters to receive messages from (all posters if empty)")
+	f.Bool(prefix+".confirmations-only", DefaultSubscriptionConfig.ConfirmationsOnly, "only receive confirmed sequence numbers, no messages")
+}
+
+var DefaultSubscriptionConfig = SubscriptionConfig{
+	Enable:            false,
+	Kinds:             []int{},
+	Posters:           []string{},
+	ConfirmationsOnly: false,
+}
+
+// Filter returns the subscription filter to send to the feed server, or nil if subscriptions aren't enabled.
+func (c *SubscriptionConfig) Filter() (*wsbroadcastserver.SubscriptionFilter, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+	filter := &wsbroadcastserver.SubscriptionFilter{
+		Kinds:             c.Kinds,
+		ConfirmationsOnly: c.ConfirmationsOnly,
+	}
+	for _, poster := range c.Posters {
+		if !common.IsHexAddress(poster) {
+			return nil, fmt.Errorf("invalid subscription poster address %s", poster)
+		}
+		filter.Posters = append(filter.Posters, common.HexToAddress(poster))
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return filter, nil
 }
 
 func (c *Config) Enable() bool {
@@ -90,6 +142,8 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.StringSlice(prefix+".secondary-url", DefaultConfig.SecondaryURL, "list of secondary URLs of sequencer feed source. Would be started in the order they appear in the list when primary feeds fails")
 	signature.FeedVerifierConfigAddOptions(prefix+".verify", f)
 	f.Bool(prefix+".enable-compression", DefaultConfig.EnableCompression, "enable per message deflate compression support")
+	f.Bool(prefix+".enable-binary", DefaultConfig.EnableBinary, "request the binary feed encoding instead of JSON, falling back to JSON if the server doesn't support it")
+	SubscriptionConfigAddOptions(prefix+".subscription", f)
 }
 
 var DefaultConfig = Config{
@@ -102,6 +156,8 @@ var DefaultConfig = Config{
 	SecondaryURL:            []string{},
 	Timeout:                 20 * time.Second,
 	EnableCompression:       true,
+	EnableBinary:            false,
+	Subscription:            DefaultSubscriptionConfig,
 }
 
 var DefaultTestConfig = Config{
@@ -114,6 +170,8 @@ var DefaultTestConfig = Config{
 	SecondaryURL:            []string{},
 	Timeout:                 200 * time.Millisecond,
 	EnableCompression:       true,
+	EnableBinary:            false,
+	Subscription:            DefaultSubscriptionConfig,
 }
 
 type TransactionStreamerInterface interface {
@@ -127,6 +185,8 @@ type BroadcastClient struct {
 	websocketUrl string
 	nextSeqNum   arbutil.MessageIndex
 	sigVerifier  *signature.Verifier
+	// optional, sent to the server after connecting
+	subscriptionFilter *wsbroadcastserver.SubscriptionFilter
 
 	chainId uint64
 
@@ -164,7 +224,12 @@ func NewBroadcastClient(
 	if err != nil {
 		return nil, err
 	}
+	subscriptionFilter, err := config().Subscription.Filter()
+	if err != nil {
+		return nil, err
+	}
 	return &BroadcastClient{
+		subscriptionFilter:              subscriptionFilter,
 		config:                          config,
 		websocketUrl:                    websocketUrl,
 		chainId:                         chainId,
@@ -219,10 +284,24 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 		return nil, nil
 	}
 
-	header := ws.HandshakeHeaderHTTP(http.Header{
-		wsbroadcastserver.HTTPHeaderFeedClientVersion:       []string{strconv.Itoa(wsbroadcastserver.FeedClientVersion)},
+	config := bc.config()
+	feedClientVersion := wsbroadcastserver.FeedClientVersion
+	if config.EnableBinary {
+		feedClientVersion = wsbroadcastserver.FeedClientVersionBinary
+	}
+	httpHeader := http.Header{
+		wsbroadcastserver.HTTPHeaderFeedClientVersion:       []string{strconv.Itoa(feedClientVersion)},
 		wsbroadcastserver.HTTPHeaderRequestedSequenceNumber: []string{strconv.FormatUint(uint64(nextSeqNum), 10)},
-	})
+	}
+	if bc.subscriptionFilter != nil {
+		// Sent in the handshake so that the catchup the server sends on connecting is filtered too
+		filter, err := json.Marshal(bc.subscriptionFilter)
+		if err != nil {
+			return nil, err
+		}
+		httpHeader[wsbroadcastserver.HTTPHeaderSubscriptionFilter] = []string{string(filter)}
+	}
+	header := ws.HandshakeHeaderHTTP(httpHeader)
 
 	log.Info("connecting to arbitrum inbox message broadcaster", "url", bc.websocketUrl)
 	var foundChainId bool
@@ -230,7 +309,6 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 	var chainId uint64
 	var feedServerVersion uint64
 
-	config := bc.config()
 	var extensions []httphead.Option
 	deflateExt := wsflate.DefaultParameters.Option()
 	if config.EnableCompression {
@@ -247,7 +325,9 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 				if err != nil {
 					return err
 				}
-				if feedServerVersion != wsbroadcastserver.FeedServerVersion {
+				// The server only answers with the binary version if this client requested it
+				binaryAccepted := config.EnableBinary && feedServerVersion == wsbroadcastserver.FeedServerVersionBinary
+				if feedServerVersion != wsbroadcastserver.FeedServerVersion && !binaryAccepted {
 					log.Error(
 						"incorrect feed server version",
 						"expectedFeedServerVersion",
@@ -382,7 +462,11 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 
 			if msg != nil {
 				res := broadcaster.BroadcastMessage{}
-				err = json.Unmarshal(msg, &res)
+				if op == ws.OpBinary {
+					err = res.UnmarshalBinary(msg)
+				} else {
+					err = json.Unmarshal(msg, &res)
+				}
 				if err != nil {
 					log.Error("error unmarshalling message", "msg", msg, "err", err)
 					continue
@@ -402,6 +486,9 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 					log.Debug("received broadcast with no messages populated", "length", len(msg))
 				}
 				if res.Version == 1 {
+					if bc.subscriptionFilter != nil {
+						bc.checkFilteredFeedGaps(&res)
+					}
 					if len(res.Messages) > 0 {
 						for _, message := range res.Messages {
 							if message == nil {
@@ -422,6 +509,12 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 							log.Error("Error adding message from Sequencer Feed", "err", err)
 						}
 					}
+					if len(res.FilteredSequenceNumbers) > 0 {
+						filteredEnd := res.FilteredSequenceNumbers[len(res.FilteredSequenceNumbers)-1].To + 1
+						if filteredEnd > bc.nextSeqNum {
+							bc.nextSeqNum = filteredEnd
+						}
+					}
 					if res.ConfirmedSequenceNumberMessage != nil && bc.confirmedSequenceNumberListener != nil {
 						bc.confirmedSequenceNumberListener <- res.ConfirmedSequenceNumberMessage.SequenceNumber
 					}
@@ -431,6 +524,26 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 	})
 }
 
+// checkFilteredFeedGaps reports sequence numbers missing from a filtered feed. The server accounts for
+// the messages it filtered in FilteredSequenceNumbers, so anything else missing is a real gap.
+func (bc *BroadcastClient) checkFilteredFeedGaps(res *broadcaster.BroadcastMessage) {
+	ranges := append([]broadcaster.SequenceNumberRange{}, res.FilteredSequenceNumbers...)
+	for _, message := range res.Messages {
+		if message != nil {
+			ranges = append(ranges, broadcaster.SequenceNumberRange{From: message.SequenceNumber, To: message.SequenceNumber})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From })
+	expected := bc.nextSeqNum
+	for _, r := range ranges {
+		if expected > 0 && r.From > expected {
+			filteredFeedGapCounter.Inc(1)
+			log.Warn("sequence number gap in filtered feed", "url", bc.websocketUrl, "expected", expected, "received", r.From)
+		}
+		expected = r.To + 1
+	}
+}
+
 func (bc *BroadcastClient) GetRetryCount() int64 {
 	return atomic.LoadInt64(&bc.retryCount)
 }
@@ -112,6 +112,58 @@ func testReceiveMessages(t *testing.T, clientCompression bool, serverCompression
 
 }
 
+func TestReceiveMessagesWithMixedEncodings(t *testing.T) {
+	t.Parallel()
+	testReceiveMessagesWithMixedEncodings(t, false)
+}
+
+func TestReceiveMessagesWithMixedEncodingsAndCompression(t *testing.T) {
+	t.Parallel()
+	testReceiveMessagesWithMixedEncodings(t, true)
+}
+
+// JSON and binary clients are served from the same broadcaster at the same time.
+func testReceiveMessagesWithMixedEncodings(t *testing.T, compression bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
+	broadcasterConfig.EnableCompression = compression
+	broadcasterConfig.EnableBinary = true
+
+	messageCount := 1000
+	chainId := uint64(9742)
+
+	privateKey, err := crypto.GenerateKey()
+	Require(t, err)
+	sequencerAddr := crypto.PubkeyToAddress(privateKey.PublicKey)
+	dataSigner := signature.DataSignerFromPrivateKey(privateKey)
+
+	feedErrChan := make(chan error, 10)
+	b := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &broadcasterConfig }, chainId, feedErrChan, dataSigner)
+
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+
+	var wg sync.WaitGroup
+	for i, binary := range []bool{false, true, false, true} {
+		config := DefaultTestConfig
+		config.EnableCompression = compression
+		config.EnableBinary = binary
+		startMakeBroadcastClient(ctx, t, config, b.ListenerAddr(), i, messageCount, chainId, &wg, &sequencerAddr)
+	}
+
+	go func() {
+		for i := 0; i < messageCount; i++ {
+			Require(t, b.BroadcastSingle(arbostypes.TestMessageWithMetadataAndRequestId, arbutil.MessageIndex(i)))
+		}
+	}()
+
+	wg.Wait()
+}
+
 func TestInvalidSignature(t *testing.T) {
 	t.Parallel()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -826,3 +878,74 @@ func Require(t *testing.T, err error, printables ...interface{}) {
 	t.Helper()
 	testhelpers.RequireImpl(t, err, printables...)
 }
+
+func TestFilteredFeedGaps(t *testing.T) {
+	config := DefaultSubscriptionConfig
+	config.Enable = true
+	config.Posters = []string{"not an address"}
+	if _, err := config.Filter(); err == nil {
+		t.Fatal("expected invalid poster address to be rejected")
+	}
+	config.Posters = []string{"0xa4b000000000000000000073657175656e636572"}
+	config.Kinds = []int{256}
+	if _, err := config.Filter(); err == nil {
+		t.Fatal("expected invalid message kind to be rejected")
+	}
+	config.Kinds = []int{3}
+	filter, err := config.Filter()
+	Require(t, err)
+
+	bc := &BroadcastClient{nextSeqNum: 10, subscriptionFilter: filter}
+	gaps := filteredFeedGapCounter.Count()
+	bc.checkFilteredFeedGaps(&broadcaster.BroadcastMessage{
+		Version:                 1,
+		Messages:                []*broadcaster.BroadcastFeedMessage{{SequenceNumber: 10}, {SequenceNumber: 13}},
+		FilteredSequenceNumbers: []broadcaster.SequenceNumberRange{{From: 11, To: 12}},
+	})
+	if filteredFeedGapCounter.Count() != gaps {
+		t.Fatal("filtered sequence numbers reported as a gap")
+	}
+	bc.checkFilteredFeedGaps(&broadcaster.BroadcastMessage{
+		Version:  1,
+		Messages: []*broadcaster.BroadcastFeedMessage{{SequenceNumber: 10}, {SequenceNumber: 13}},
+	})
+	if filteredFeedGapCounter.Count() != gaps+1 {
+		t.Fatal("missing sequence numbers not reported as a gap")
+	}
+}
+
+func TestFilteredCatchup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	settings := wsbroadcastserver.DefaultTestBroadcasterConfig
+
+	privateKey, err := crypto.GenerateKey()
+	Require(t, err)
+	sequencerAddr := crypto.PubkeyToAddress(privateKey.PublicKey)
+	dataSigner := signature.DataSignerFromPrivateKey(privateKey)
+
+	feedErrChan := make(chan error, 10)
+	chainId := uint64(8744)
+	b := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &settings }, chainId, feedErrChan, dataSigner)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+
+	Require(t, b.BroadcastSingle(arbostypes.EmptyTestMessageWithMetadata, 0))
+	Require(t, b.BroadcastSingle(arbostypes.EmptyTestMessageWithMetadata, 1))
+
+	// The filter is known when the client connects, so the cached messages aren't sent either
+	clientConfig := DefaultTestConfig
+	clientConfig.Subscription.Enable = true
+	clientConfig.Subscription.ConfirmationsOnly = true
+	var wg sync.WaitGroup
+	startMakeBroadcastClient(ctx, t, clientConfig, b.ListenerAddr(), 0, 0, chainId, &wg, &sequencerAddr)
+	wg.Wait()
+
+	// A node's transaction streamer can't follow a filtered feed
+	feedConfig := FeedConfigDefault
+	feedConfig.Input.Subscription.Enable = true
+	if err := feedConfig.Validate(); err == nil {
+		t.Fatal("expected a subscription filter on a node's feed to be rejected")
+	}
+}
@@ -5,6 +5,7 @@ package broadcastclients
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"time"
 
@@ -66,6 +67,10 @@ func NewBroadcastClients(
 	if len(config.URL) == 0 && len(config.SecondaryURL) == 0 {
 		return nil, nil
 	}
+	if config.Subscription.Enable {
+		// The messages are passed on to follow the chain, which can't skip the filtered ones
+		return nil, errors.New("feed subscription filters can't be used by a node or relay")
+	}
 	newStandardRouter := func() *Router {
 		return &Router{
 			messageChan:                 make(chan broadcaster.BroadcastFeedMessage, ROUTER_QUEUE_SIZE),
@@ -0,0 +1,141 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+// The binary feed encoding is a format byte followed by the RLP encoding of a binaryBroadcastMessage.
+// It's sent to clients which negotiated wsbroadcastserver.FeedClientVersionBinary.
+const binaryBroadcastMessageFormat byte = 0
+
+type binaryBroadcastMessage struct {
+	Version uint64
+	// Each entry is the RLP encoding of a binaryBroadcastFeedMessage
+	Messages                       []rlp.RawValue
+	HasConfirmedSequenceNumber     bool
+	ConfirmedSequenceNumberMessage uint64
+	FilteredSequenceNumbers        []SequenceNumberRange `rlp:"optional"`
+}
+
+type binaryBroadcastFeedMessage struct {
+	SequenceNumber      uint64
+	Message             *arbostypes.L1IncomingMessage `rlp:"nil"`
+	DelayedMessagesRead uint64
+	Signature           []byte
+	// RLP encodes a nil big.Int as zero, so whether the header had an L1 base fee is sent separately
+	HasL1BaseFee bool
+}
+
+// encodeBinary returns the RLP encoding of the message used within binary broadcast messages.
+func (m *BroadcastFeedMessage) encodeBinary() (rlp.RawValue, error) {
+	if m.binaryEncoded != nil {
+		return m.binaryEncoded, nil
+	}
+	encoded := binaryBroadcastFeedMessage{
+		SequenceNumber:      uint64(m.SequenceNumber),
+		Message:             m.Message.Message,
+		DelayedMessagesRead: m.Message.DelayedMessagesRead,
+		Signature:           m.Signature,
+	}
+	if m.Message.Message != nil && m.Message.Message.Header != nil {
+		encoded.HasL1BaseFee = m.Message.Message.Header.L1BaseFee != nil
+	}
+	return rlp.EncodeToBytes(&encoded)
+}
+
+// cacheBinaryEncoding stores the message's binary encoding, so that it's computed once however
+// many binary broadcast messages and subscription filters it's sent in. It must be called before
+// the message is shared with other goroutines.
+func (m *BroadcastFeedMessage) cacheBinaryEncoding() error {
+	encoded, err := m.encodeBinary()
+	if err != nil {
+		return err
+	}
+	m.binaryEncoded = encoded
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, which the feed server uses
+// to serialize messages for clients that negotiated the binary encoding.
+func (m BroadcastMessage) MarshalBinary() ([]byte, error) {
+	if m.Version < 0 {
+		return nil, fmt.Errorf("invalid broadcast message version %d", m.Version)
+	}
+	encoded := binaryBroadcastMessage{
+		Version:                 uint64(m.Version),
+		Messages:                make([]rlp.RawValue, 0, len(m.Messages)),
+		FilteredSequenceNumbers: m.FilteredSequenceNumbers,
+	}
+	for _, msg := range m.Messages {
+		msgEncoded, err := msg.encodeBinary()
+		if err != nil {
+			return nil, err
+		}
+		encoded.Messages = append(encoded.Messages, msgEncoded)
+	}
+	if m.ConfirmedSequenceNumberMessage != nil {
+		encoded.HasConfirmedSequenceNumber = true
+		encoded.ConfirmedSequenceNumberMessage = uint64(m.ConfirmedSequenceNumberMessage.SequenceNumber)
+	}
+	data, err := rlp.EncodeToBytes(&encoded)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{binaryBroadcastMessageFormat}, data...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the output of MarshalBinary.
+func (m *BroadcastMessage) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty binary broadcast message")
+	}
+	if data[0] != binaryBroadcastMessageFormat {
+		return fmt.Errorf("unknown binary broadcast message format %d", data[0])
+	}
+	var decoded binaryBroadcastMessage
+	if err := rlp.DecodeBytes(data[1:], &decoded); err != nil {
+		return fmt.Errorf("error decoding binary broadcast message: %w", err)
+	}
+	*m = BroadcastMessage{
+		Version: int(decoded.Version),
+	}
+	if len(decoded.FilteredSequenceNumbers) > 0 {
+		m.FilteredSequenceNumbers = decoded.FilteredSequenceNumbers
+	}
+	for _, msgEncoded := range decoded.Messages {
+		var msg binaryBroadcastFeedMessage
+		if err := rlp.DecodeBytes(msgEncoded, &msg); err != nil {
+			return fmt.Errorf("error decoding binary broadcast feed message: %w", err)
+		}
+		if msg.Message != nil && msg.Message.Header != nil && !msg.HasL1BaseFee {
+			msg.Message.Header.L1BaseFee = nil
+		}
+		var signature []byte
+		if len(msg.Signature) > 0 {
+			signature = msg.Signature
+		}
+		m.Messages = append(m.Messages, &BroadcastFeedMessage{
+			SequenceNumber: arbutil.MessageIndex(msg.SequenceNumber),
+			Message: arbostypes.MessageWithMetadata{
+				Message:             msg.Message,
+				DelayedMessagesRead: msg.DelayedMessagesRead,
+			},
+			Signature: signature,
+		})
+	}
+	if decoded.HasConfirmedSequenceNumber {
+		m.ConfirmedSequenceNumberMessage = &ConfirmedSequenceNumberMessage{
+			SequenceNumber: arbutil.MessageIndex(decoded.ConfirmedSequenceNumberMessage),
+		}
+	}
+	return nil
+}
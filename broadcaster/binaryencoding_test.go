@@ -0,0 +1,100 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func TestBinaryEncodingRoundTrip(t *testing.T) {
+	chainId := uint64(9742)
+	batchGasCost := uint64(12345)
+	withGasCost := *arbostypes.TestMessageWithMetadataAndRequestId.Message
+	withGasCost.BatchGasCost = &batchGasCost
+	msg := BroadcastMessage{
+		Version: 1,
+		Messages: []*BroadcastFeedMessage{
+			{
+				SequenceNumber: 10,
+				Message:        arbostypes.TestMessageWithMetadataAndRequestId,
+				Signature:      []byte{1, 2, 3},
+			},
+			{
+				SequenceNumber: 11,
+				Message:        arbostypes.EmptyTestMessageWithMetadata,
+			},
+			{
+				SequenceNumber: 12,
+				Message: arbostypes.MessageWithMetadata{
+					Message:             &withGasCost,
+					DelayedMessagesRead: 7,
+				},
+			},
+		},
+		ConfirmedSequenceNumberMessage: &ConfirmedSequenceNumberMessage{SequenceNumber: 0},
+	}
+	data, err := msg.MarshalBinary()
+	Require(t, err)
+	var decoded BroadcastMessage
+	Require(t, decoded.UnmarshalBinary(data))
+
+	if decoded.Version != msg.Version {
+		Fail(t, "unexpected version", decoded.Version)
+	}
+	if decoded.ConfirmedSequenceNumberMessage == nil || decoded.ConfirmedSequenceNumberMessage.SequenceNumber != 0 {
+		Fail(t, "unexpected confirmed sequence number", decoded.ConfirmedSequenceNumberMessage)
+	}
+	if len(decoded.Messages) != len(msg.Messages) {
+		Fail(t, "expected", len(msg.Messages), "messages, got", len(decoded.Messages))
+	}
+	for i, expected := range msg.Messages {
+		actual := decoded.Messages[i]
+		if actual.SequenceNumber != expected.SequenceNumber {
+			Fail(t, "unexpected sequence number", actual.SequenceNumber, "expected", expected.SequenceNumber)
+		}
+		if !bytes.Equal(actual.Signature, expected.Signature) {
+			Fail(t, "unexpected signature", actual.Signature, "expected", expected.Signature)
+		}
+		expectedHash, err := expected.Hash(chainId)
+		Require(t, err)
+		actualHash, err := actual.Hash(chainId)
+		Require(t, err)
+		if actualHash != expectedHash {
+			Fail(t, "message", i, "hash changed by binary encoding")
+		}
+	}
+	if decoded.Messages[0].Message.Message.Header.L1BaseFee == nil {
+		Fail(t, "zero L1 base fee decoded as nil")
+	}
+	if decoded.Messages[1].Message.Message.Header.L1BaseFee != nil {
+		Fail(t, "nil L1 base fee decoded as", decoded.Messages[1].Message.Message.Header.L1BaseFee)
+	}
+	if decoded.Messages[2].Message.Message.BatchGasCost == nil || *decoded.Messages[2].Message.Message.BatchGasCost != batchGasCost {
+		Fail(t, "batch gas cost not preserved")
+	}
+
+	for _, m := range msg.Messages {
+		Require(t, m.cacheBinaryEncoding())
+	}
+	cachedData, err := msg.MarshalBinary()
+	Require(t, err)
+	if !bytes.Equal(cachedData, data) {
+		Fail(t, "encoding with cached messages differs")
+	}
+
+	data, err = BroadcastMessage{Version: 1}.MarshalBinary()
+	Require(t, err)
+	decoded = BroadcastMessage{}
+	Require(t, decoded.UnmarshalBinary(data))
+	if len(decoded.Messages) != 0 || decoded.ConfirmedSequenceNumberMessage != nil {
+		Fail(t, "unexpected contents decoding empty message", decoded)
+	}
+
+	if err := decoded.UnmarshalBinary([]byte{binaryBroadcastMessageFormat + 1}); err == nil {
+		Fail(t, "expected error decoding unknown format")
+	}
+}
@@ -5,12 +5,14 @@ package broadcaster
 
 import (
 	"context"
+	"fmt"
 	"net"
 
 	"github.com/gobwas/ws"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
@@ -20,6 +22,7 @@ import (
 
 type Broadcaster struct {
 	server        *wsbroadcastserver.WSBroadcastServer
+	config        wsbroadcastserver.BroadcasterConfigFetcher
 	catchupBuffer *SequenceNumberCatchupBuffer
 	chainId       uint64
 	dataSigner    signature.DataSignerFunc
@@ -44,12 +47,18 @@ type BroadcastMessage struct {
 	// TODO better name than messages since there are different types of messages
 	Messages                       []*BroadcastFeedMessage         `json:"messages,omitempty"`
 	ConfirmedSequenceNumberMessage *ConfirmedSequenceNumberMessage `json:"confirmedSequenceNumberMessage,omitempty"`
+	// Only set for clients with a subscription filter, covering the messages the filter removed
+	// so the client can tell them apart from sequence number gaps.
+	FilteredSequenceNumbers []SequenceNumberRange `json:"filteredSequenceNumbers,omitempty"`
 }
 
 type BroadcastFeedMessage struct {
 	SequenceNumber arbutil.MessageIndex           `json:"sequenceNumber"`
 	Message        arbostypes.MessageWithMetadata `json:"message"`
 	Signature      []byte                         `json:"signature"`
+
+	// Set by cacheBinaryEncoding once the message is added to the catchup buffer
+	binaryEncoded rlp.RawValue
 }
 
 func (m *BroadcastFeedMessage) Hash(chainId uint64) (common.Hash, error) {
@@ -60,10 +69,17 @@ type ConfirmedSequenceNumberMessage struct {
 	SequenceNumber arbutil.MessageIndex `json:"sequenceNumber"`
 }
 
+// SequenceNumberRange covers the sequence numbers From through To, inclusive.
+type SequenceNumberRange struct {
+	From arbutil.MessageIndex `json:"from"`
+	To   arbutil.MessageIndex `json:"to"`
+}
+
 func NewBroadcaster(config wsbroadcastserver.BroadcasterConfigFetcher, chainId uint64, feedErrChan chan error, dataSigner signature.DataSignerFunc) *Broadcaster {
 	catchupBuffer := NewSequenceNumberCatchupBuffer(func() bool { return config().LimitCatchup }, func() int { return config().MaxCatchup })
 	return &Broadcaster{
 		server:        wsbroadcastserver.NewWSBroadcastServer(config, catchupBuffer, chainId, feedErrChan),
+		config:        config,
 		catchupBuffer: catchupBuffer,
 		chainId:       chainId,
 		dataSigner:    dataSigner,
@@ -143,19 +159,44 @@ func (b *Broadcaster) GetCachedMessageCount() int {
 }
 
 func (b *Broadcaster) Initialize() error {
+	if archiveConfig := b.config().Archive; archiveConfig.Enable {
+		archive, err := OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &b.config().Archive })
+		if err != nil {
+			return err
+		}
+		b.catchupBuffer.archive = archive
+		if err := b.catchupBuffer.refillFromArchive(archiveConfig.RefillMessages); err != nil {
+			_ = archive.Close()
+			return fmt.Errorf("error refilling catchup buffer from feed archive: %w", err)
+		}
+	}
 	return b.server.Initialize()
 }
 
 func (b *Broadcaster) Start(ctx context.Context) error {
+	if b.catchupBuffer.archive != nil {
+		b.catchupBuffer.archive.Start(ctx)
+	}
 	return b.server.Start(ctx)
 }
 
 func (b *Broadcaster) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
+	if b.catchupBuffer.archive != nil {
+		b.catchupBuffer.archive.Start(ctx)
+	}
 	return b.server.StartWithHeader(ctx, header)
 }
 
 func (b *Broadcaster) StopAndWait() {
 	b.server.StopAndWait()
+	if b.catchupBuffer.archive != nil {
+		if b.catchupBuffer.archive.Started() {
+			b.catchupBuffer.archive.StopAndWait()
+		}
+		if err := b.catchupBuffer.archive.Close(); err != nil {
+			log.Warn("error closing feed archive", "err", err)
+		}
+	}
 }
 
 func (b *Broadcaster) Started() bool {
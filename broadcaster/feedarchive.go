@@ -0,0 +1,366 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+const (
+	// How many archived messages to send to a client in a single BroadcastMessage
+	feedArchiveReplayChunkSize = 1000
+	feedArchivePruneInterval   = time.Minute
+	// How many broadcasts can wait to be archived before new ones are dropped
+	feedArchiveQueueSize = 1024
+)
+
+var (
+	feedArchiveFirstGauge      = metrics.NewRegisteredGauge("arb/feed/archive/first", nil)
+	feedArchiveLastGauge       = metrics.NewRegisteredGauge("arb/feed/archive/last", nil)
+	feedArchiveReplayedCounter = metrics.NewRegisteredCounter("arb/feed/archive/replayed", nil)
+	feedArchiveErrorCounter    = metrics.NewRegisteredCounter("arb/feed/archive/error", nil)
+	feedArchiveDroppedCounter  = metrics.NewRegisteredCounter("arb/feed/archive/dropped", nil)
+)
+
+// The archive keys each message by its sequence number, so iteration order is sequence number order.
+var feedArchiveMessagePrefix = []byte("m")
+
+// FeedArchive keeps broadcast feed messages on disk, so that clients can catch
+// up from sequence numbers older than the in-memory catchup buffer holds.
+//
+// Broadcast messages are queued and archived by a background thread, so that
+// writing and pruning the archive doesn't hold up the feed.
+type FeedArchive struct {
+	stopwaiter.StopWaiter
+	db        *pebble.DB
+	config    func() *wsbroadcastserver.FeedArchiveConfig
+	lastPrune time.Time
+	queue     chan []*BroadcastFeedMessage
+
+	// The last archived sequence number, only used by Put
+	last    arbutil.MessageIndex
+	hasLast bool
+}
+
+func OpenFeedArchive(config func() *wsbroadcastserver.FeedArchiveConfig) (*FeedArchive, error) {
+	db, err := pebble.Open(config().Dir, &pebble.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("error opening feed archive: %w", err)
+	}
+	a := &FeedArchive{
+		db:     db,
+		config: config,
+		queue:  make(chan []*BroadcastFeedMessage, feedArchiveQueueSize),
+	}
+	_, a.last, a.hasLast, err = a.bounds()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Start launches the thread archiving the messages passed to Enqueue.
+func (a *FeedArchive) Start(ctx context.Context) {
+	a.StopWaiter.Start(ctx, a)
+	a.LaunchThread(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case messages := <-a.queue:
+				if err := a.Put(messages); err != nil {
+					feedArchiveErrorCounter.Inc(1)
+					log.Error("error archiving feed messages", "err", err)
+				}
+				a.maybePrune()
+			}
+		}
+	})
+}
+
+// Enqueue queues messages to be archived without waiting for them to be written.
+// If the archive has fallen too far behind the messages are dropped, leaving a gap
+// that catchup from the archive won't replay across.
+func (a *FeedArchive) Enqueue(messages []*BroadcastFeedMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	select {
+	case a.queue <- messages:
+	default:
+		feedArchiveDroppedCounter.Inc(int64(len(messages)))
+		log.Error("feed archive queue full, dropping messages", "first", messages[0].SequenceNumber, "count", len(messages))
+	}
+}
+
+func feedArchiveKey(seqNum arbutil.MessageIndex) []byte {
+	key := make([]byte, len(feedArchiveMessagePrefix)+8)
+	copy(key, feedArchiveMessagePrefix)
+	binary.BigEndian.PutUint64(key[len(feedArchiveMessagePrefix):], uint64(seqNum))
+	return key
+}
+
+// The upper bound of all message keys
+func feedArchiveKeyLimit() []byte {
+	limit := append([]byte{}, feedArchiveMessagePrefix...)
+	limit[len(limit)-1]++
+	return limit
+}
+
+func feedArchiveSeqNum(key []byte) arbutil.MessageIndex {
+	return arbutil.MessageIndex(binary.BigEndian.Uint64(key[len(feedArchiveMessagePrefix):]))
+}
+
+// Values are the time the message was archived, for retention, followed by the message as JSON.
+func encodeArchivedMessage(msg *BroadcastFeedMessage, archived time.Time) ([]byte, error) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 8, 8+len(encoded))
+	binary.BigEndian.PutUint64(value, uint64(archived.Unix()))
+	return append(value, encoded...), nil
+}
+
+func decodeArchivedMessage(value []byte) (*BroadcastFeedMessage, time.Time, error) {
+	if len(value) < 8 {
+		return nil, time.Time{}, errors.New("archived feed message too short")
+	}
+	archived := time.Unix(int64(binary.BigEndian.Uint64(value)), 0)
+	var msg BroadcastFeedMessage
+	if err := json.Unmarshal(value[8:], &msg); err != nil {
+		return nil, time.Time{}, err
+	}
+	return &msg, archived, nil
+}
+
+// Put archives messages. Messages at or before the last archived sequence number
+// are a reorg, so every archived message from the first of them on is replaced.
+func (a *FeedArchive) Put(messages []*BroadcastFeedMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	batch := a.db.NewBatch()
+	defer batch.Close()
+	if first := messages[0].SequenceNumber; a.hasLast && first <= a.last {
+		if err := batch.DeleteRange(feedArchiveKey(first), feedArchiveKeyLimit(), nil); err != nil {
+			return err
+		}
+		log.Info("removing reorged messages from feed archive", "from", first, "to", a.last)
+	}
+	now := time.Now()
+	for _, msg := range messages {
+		value, err := encodeArchivedMessage(msg, now)
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(feedArchiveKey(msg.SequenceNumber), value, nil); err != nil {
+			return err
+		}
+	}
+	if err := batch.Commit(pebble.NoSync); err != nil {
+		return err
+	}
+	a.last = messages[len(messages)-1].SequenceNumber
+	a.hasLast = true
+	feedArchiveLastGauge.Update(int64(a.last))
+	return nil
+}
+
+// has returns whether the message with sequence number seqNum is archived.
+func (a *FeedArchive) has(seqNum arbutil.MessageIndex) (bool, error) {
+	_, closer, err := a.db.Get(feedArchiveKey(seqNum))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+// Replay writes the archived messages from sequence number from up to but not
+// including end, reading and writing them a page at a time. Nothing is written
+// unless the archive has both the first and the last of them.
+func (a *FeedArchive) Replay(ctx context.Context, from arbutil.MessageIndex, end arbutil.MessageIndex, write func(interface{}) error) (int, error) {
+	if end <= from {
+		return 0, nil
+	}
+	for _, seqNum := range []arbutil.MessageIndex{from, end - 1} {
+		if has, err := a.has(seqNum); err != nil || !has {
+			return 0, err
+		}
+	}
+	sent := 0
+	for next := from; next < end; {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		pageEnd := arbmath.MinInt(next+feedArchiveReplayChunkSize, end)
+		messages, err := a.Read(next, pageEnd)
+		if err != nil {
+			return sent, err
+		}
+		if len(messages) == 0 {
+			return sent, fmt.Errorf("feed archive is missing message %d", next)
+		}
+		if err := write(&BroadcastMessage{Version: 1, Messages: messages}); err != nil {
+			return sent, err
+		}
+		sent += len(messages)
+		next += arbutil.MessageIndex(len(messages))
+	}
+	return sent, nil
+}
+
+// Read returns consecutive archived messages starting from sequence number from,
+// stopping before sequence number to or the first sequence number that isn't archived.
+func (a *FeedArchive) Read(from arbutil.MessageIndex, to arbutil.MessageIndex) ([]*BroadcastFeedMessage, error) {
+	if to <= from {
+		return nil, nil
+	}
+	iter := a.db.NewIter(&pebble.IterOptions{
+		LowerBound: feedArchiveKey(from),
+		UpperBound: feedArchiveKey(to),
+	})
+	var messages []*BroadcastFeedMessage
+	expected := from
+	for iter.First(); iter.Valid(); iter.Next() {
+		if feedArchiveSeqNum(iter.Key()) != expected {
+			break
+		}
+		msg, _, err := decodeArchivedMessage(iter.Value())
+		if err != nil {
+			_ = iter.Close()
+			return nil, err
+		}
+		messages = append(messages, msg)
+		expected++
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Last returns up to count of the most recently archived messages, all consecutive, in order.
+func (a *FeedArchive) Last(count int) ([]*BroadcastFeedMessage, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	iter := a.db.NewIter(&pebble.IterOptions{
+		LowerBound: feedArchiveMessagePrefix,
+		UpperBound: feedArchiveKeyLimit(),
+	})
+	var messages []*BroadcastFeedMessage
+	for iter.Last(); iter.Valid() && len(messages) < count; iter.Prev() {
+		seqNum := feedArchiveSeqNum(iter.Key())
+		if len(messages) > 0 && seqNum+1 != messages[len(messages)-1].SequenceNumber {
+			break
+		}
+		msg, _, err := decodeArchivedMessage(iter.Value())
+		if err != nil {
+			_ = iter.Close()
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// bounds returns the first and last archived sequence numbers, and false if the archive is empty.
+func (a *FeedArchive) bounds() (arbutil.MessageIndex, arbutil.MessageIndex, bool, error) {
+	iter := a.db.NewIter(&pebble.IterOptions{
+		LowerBound: feedArchiveMessagePrefix,
+		UpperBound: feedArchiveKeyLimit(),
+	})
+	if !iter.First() {
+		return 0, 0, false, iter.Close()
+	}
+	first := feedArchiveSeqNum(iter.Key())
+	iter.Last()
+	last := feedArchiveSeqNum(iter.Key())
+	return first, last, true, iter.Close()
+}
+
+// Prune discards messages beyond the configured retention limits.
+func (a *FeedArchive) Prune(now time.Time) error {
+	config := a.config()
+	first, last, ok, err := a.bounds()
+	if err != nil || !ok {
+		return err
+	}
+	keepFrom := first
+	if config.MaxMessages > 0 && uint64(last-first)+1 > config.MaxMessages {
+		keepFrom = last + 1 - arbutil.MessageIndex(config.MaxMessages)
+	}
+	if config.MaxAge > 0 {
+		cutoff := now.Add(-config.MaxAge)
+		iter := a.db.NewIter(&pebble.IterOptions{
+			LowerBound: feedArchiveKey(keepFrom),
+			UpperBound: feedArchiveKeyLimit(),
+		})
+		for iter.First(); iter.Valid(); iter.Next() {
+			_, archived, err := decodeArchivedMessage(iter.Value())
+			if err != nil {
+				_ = iter.Close()
+				return err
+			}
+			if !archived.Before(cutoff) {
+				break
+			}
+			keepFrom = feedArchiveSeqNum(iter.Key()) + 1
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+	}
+	if keepFrom > first {
+		if err := a.db.DeleteRange(feedArchiveKey(first), feedArchiveKey(keepFrom), pebble.NoSync); err != nil {
+			return err
+		}
+		log.Debug("pruned feed archive", "from", first, "to", keepFrom)
+	}
+	feedArchiveFirstGauge.Update(int64(keepFrom))
+	return nil
+}
+
+// maybePrune prunes the archive if it hasn't been pruned recently.
+func (a *FeedArchive) maybePrune() {
+	now := time.Now()
+	if now.Sub(a.lastPrune) < feedArchivePruneInterval {
+		return
+	}
+	a.lastPrune = now
+	if err := a.Prune(now); err != nil {
+		feedArchiveErrorCounter.Inc(1)
+		log.Error("error pruning feed archive", "err", err)
+	}
+}
+
+func (a *FeedArchive) Close() error {
+	return a.db.Close()
+}
@@ -0,0 +1,182 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+func checkSequenceNumbers(t *testing.T, messages []*BroadcastFeedMessage, first arbutil.MessageIndex, count int) {
+	t.Helper()
+	if len(messages) != count {
+		Fail(t, "expected", count, "messages, got", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.SequenceNumber != first+arbutil.MessageIndex(i) {
+			Fail(t, "unexpected sequence number", msg.SequenceNumber, "at", i)
+		}
+	}
+}
+
+func TestFeedArchive(t *testing.T) {
+	config := wsbroadcastserver.DefaultFeedArchiveConfig
+	config.Enable = true
+	config.Dir = t.TempDir()
+	config.MaxAge = 0
+	archive, err := OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &config })
+	Require(t, err)
+
+	Require(t, archive.Put(createDummyBroadcastMessages([]arbutil.MessageIndex{10, 11, 12, 13, 14, 15})))
+	Require(t, archive.Put(createDummyBroadcastMessages([]arbutil.MessageIndex{20, 21, 22})))
+
+	messages, err := archive.Read(11, 30)
+	Require(t, err)
+	// Reading stops at the gap after 15.
+	checkSequenceNumbers(t, messages, 11, 5)
+	messages, err = archive.Read(12, 14)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 12, 2)
+	messages, err = archive.Read(16, 30)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 16, 0)
+
+	messages, err = archive.Last(2)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 21, 2)
+	messages, err = archive.Last(10)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 20, 3)
+
+	// Retention is by sequence number, so this keeps 19 through 22.
+	config.MaxMessages = 4
+	Require(t, archive.Prune(time.Now()))
+	messages, err = archive.Read(15, 30)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 15, 0)
+	messages, err = archive.Read(20, 30)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 20, 3)
+
+	config.MaxAge = time.Hour
+	Require(t, archive.Prune(time.Now().Add(2*time.Hour)))
+	messages, err = archive.Last(10)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 0, 0)
+
+	Require(t, archive.Close())
+}
+
+func TestFeedArchiveReorg(t *testing.T) {
+	config := wsbroadcastserver.DefaultFeedArchiveConfig
+	config.Enable = true
+	config.Dir = t.TempDir()
+	archive, err := OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &config })
+	Require(t, err)
+
+	Require(t, archive.Put(createDummyBroadcastMessages([]arbutil.MessageIndex{1, 2, 3, 4, 5, 6})))
+	// A reorg back to 4 replaces the messages from 4 on, even ones it doesn't resend
+	Require(t, archive.Put(createDummyBroadcastMessages([]arbutil.MessageIndex{4})))
+	messages, err := archive.Last(10)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 1, 4)
+	Require(t, archive.Close())
+
+	// The last archived message is remembered across restarts
+	archive, err = OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &config })
+	Require(t, err)
+	Require(t, archive.Put(createDummyBroadcastMessages([]arbutil.MessageIndex{2})))
+	messages, err = archive.Last(10)
+	Require(t, err)
+	checkSequenceNumbers(t, messages, 1, 2)
+	Require(t, archive.Close())
+}
+
+func waitForArchived(t *testing.T, archive *FeedArchive, seqNum arbutil.MessageIndex) {
+	t.Helper()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		has, err := archive.has(seqNum)
+		Require(t, err)
+		if has {
+			return
+		}
+		if time.Since(start) > 5*time.Second {
+			Fail(t, "message", seqNum, "wasn't archived")
+		}
+	}
+}
+
+func replayArchive(t *testing.T, buffer *SequenceNumberCatchupBuffer, requestedSeqNum arbutil.MessageIndex) []*BroadcastFeedMessage {
+	t.Helper()
+	from, end, ok := buffer.archiveReplayRange(requestedSeqNum)
+	if !ok {
+		return nil
+	}
+	var messages []*BroadcastFeedMessage
+	sent, err := buffer.archive.Replay(context.Background(), from, end, func(x interface{}) error {
+		bm, ok := x.(*BroadcastMessage)
+		if !ok || len(bm.Messages) > feedArchiveReplayChunkSize {
+			Fail(t, "unexpected replayed message", x)
+		}
+		messages = append(messages, bm.Messages...)
+		return nil
+	})
+	Require(t, err)
+	if sent != len(messages) {
+		Fail(t, "replay reported", sent, "messages, wrote", len(messages))
+	}
+	return messages
+}
+
+func TestCatchupBufferWithFeedArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := wsbroadcastserver.DefaultFeedArchiveConfig
+	config.Enable = true
+	config.Dir = t.TempDir()
+	archive, err := OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &config })
+	Require(t, err)
+	archive.Start(ctx)
+
+	buffer := NewSequenceNumberCatchupBuffer(func() bool { return false }, func() int { return -1 })
+	buffer.archive = archive
+	var indexes []arbutil.MessageIndex
+	for i := arbutil.MessageIndex(1); i <= 50; i++ {
+		indexes = append(indexes, i)
+	}
+	Require(t, buffer.OnDoBroadcast(BroadcastMessage{Version: 1, Messages: createDummyBroadcastMessages(indexes)}))
+	Require(t, buffer.OnDoBroadcast(BroadcastMessage{Version: 1, ConfirmedSequenceNumberMessage: &ConfirmedSequenceNumberMessage{40}}))
+	if buffer.GetMessageCount() != 10 {
+		Fail(t, "expected confirmed messages to leave the buffer, have", buffer.GetMessageCount())
+	}
+
+	waitForArchived(t, archive, 50)
+
+	// Confirmed messages are still served from the archive.
+	checkSequenceNumbers(t, replayArchive(t, buffer, 5), 5, 36)
+	checkSequenceNumbers(t, replayArchive(t, buffer, 45), 0, 0)
+	checkSequenceNumbers(t, replayArchive(t, buffer, 0), 0, 0)
+	// Clients asking for more than a full replay only get the buffer
+	config.MaxReplay = 20
+	checkSequenceNumbers(t, replayArchive(t, buffer, 5), 0, 0)
+	checkSequenceNumbers(t, replayArchive(t, buffer, 25), 25, 16)
+	archive.StopAndWait()
+	Require(t, archive.Close())
+
+	// After a restart the buffer is refilled from the archive.
+	archive, err = OpenFeedArchive(func() *wsbroadcastserver.FeedArchiveConfig { return &config })
+	Require(t, err)
+	buffer = NewSequenceNumberCatchupBuffer(func() bool { return false }, func() int { return 20 })
+	buffer.archive = archive
+	Require(t, buffer.refillFromArchive(100))
+	checkSequenceNumbers(t, buffer.messages, 31, 20)
+	if buffer.GetMessageCount() != 20 {
+		Fail(t, "unexpected message count after refill", buffer.GetMessageCount())
+	}
+	Require(t, archive.Close())
+}
@@ -4,6 +4,7 @@
 package broadcaster
 
 import (
+	"context"
 	"errors"
 	"sync/atomic"
 	"time"
@@ -30,6 +31,8 @@ type SequenceNumberCatchupBuffer struct {
 	messageCount int32
 	limitCatchup func() bool
 	maxCatchup   func() int
+	// optional, serves catchup requests for messages older than the buffer holds
+	archive *FeedArchive
 }
 
 func NewSequenceNumberCatchupBuffer(limitCatchup func() bool, maxCatchup func() int) *SequenceNumberCatchupBuffer {
@@ -79,12 +82,48 @@ func (b *SequenceNumberCatchupBuffer) getCacheMessages(requestedSeqNum arbutil.M
 	return nil
 }
 
+// archiveReplayRange returns the range of sequence numbers to replay from the archive to a
+// client requesting requestedSeqNum, which ends where the buffer starts. Catchup from the
+// archive needs a non-empty buffer, as live messages would otherwise race with the replay.
+func (b *SequenceNumberCatchupBuffer) archiveReplayRange(requestedSeqNum arbutil.MessageIndex) (arbutil.MessageIndex, arbutil.MessageIndex, bool) {
+	if b.archive == nil || requestedSeqNum == 0 || len(b.messages) == 0 {
+		return 0, 0, false
+	}
+	end := b.messages[0].SequenceNumber
+	if requestedSeqNum >= end {
+		return 0, 0, false
+	}
+	if b.limitCatchup() && end-requestedSeqNum > maxRequestedSeqNumOffset {
+		return 0, 0, false
+	}
+	if maxReplay := b.archive.config().MaxReplay; maxReplay > 0 && uint64(end-requestedSeqNum) > maxReplay {
+		return 0, 0, false
+	}
+	return requestedSeqNum, end, true
+}
+
 func (b *SequenceNumberCatchupBuffer) OnRegisterClient(clientConnection *wsbroadcastserver.ClientConnection) (error, int, time.Duration) {
 	start := time.Now()
-	bm := b.getCacheMessages(clientConnection.RequestedSeqNum())
-	var bmCount int
+	requestedSeqNum := clientConnection.RequestedSeqNum()
+	bmCount := 0
+	if from, end, ok := b.archiveReplayRange(requestedSeqNum); ok {
+		// The replay is streamed by the client's own thread before the buffered messages queued below
+		archive := b.archive
+		clientConnection.SetCatchup(func(ctx context.Context, write func(interface{}) error) error {
+			sent, err := archive.Replay(ctx, from, end, write)
+			feedArchiveReplayedCounter.Inc(int64(sent))
+			if err != nil {
+				feedArchiveErrorCounter.Inc(1)
+				log.Warn("error replaying feed archive", "err", err, "client", clientConnection.Name, "requestedSeqNum", from)
+			}
+			return err
+		})
+		requestedSeqNum = end
+	}
+
+	bm := b.getCacheMessages(requestedSeqNum)
 	if bm != nil {
-		bmCount = len(bm.Messages)
+		bmCount += len(bm.Messages)
 	}
 	if bm != nil {
 		// send the newly connected client the requested messages
@@ -149,6 +188,17 @@ func (b *SequenceNumberCatchupBuffer) OnDoBroadcast(bmi interface{}) error {
 	}
 	defer func() { atomic.StoreInt32(&b.messageCount, int32(len(b.messages))) }()
 
+	// Encode each message once here, before clients and the archive share it
+	for _, msg := range broadcastMessage.Messages {
+		if err := msg.cacheBinaryEncoding(); err != nil {
+			return err
+		}
+	}
+
+	if b.archive != nil {
+		b.archive.Enqueue(broadcastMessage.Messages)
+	}
+
 	if confirmMsg := broadcastMessage.ConfirmedSequenceNumberMessage; confirmMsg != nil {
 		b.deleteConfirmed(confirmMsg.SequenceNumber)
 		confirmedSequenceNumberGauge.Update(int64(confirmMsg.SequenceNumber))
@@ -188,6 +238,28 @@ func (b *SequenceNumberCatchupBuffer) OnDoBroadcast(bmi interface{}) error {
 
 }
 
+// refillFromArchive loads the most recently archived messages into the buffer.
+func (b *SequenceNumberCatchupBuffer) refillFromArchive(count int) error {
+	if maxCatchup := b.maxCatchup(); maxCatchup >= 0 && count > maxCatchup {
+		count = maxCatchup
+	}
+	messages, err := b.archive.Last(count)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if err := msg.cacheBinaryEncoding(); err != nil {
+			return err
+		}
+	}
+	b.messages = messages
+	atomic.StoreInt32(&b.messageCount, int32(len(b.messages)))
+	if len(messages) > 0 {
+		log.Info("refilled feed catchup buffer from archive", "first", messages[0].SequenceNumber, "last", messages[len(messages)-1].SequenceNumber)
+	}
+	return nil
+}
+
 func (b *SequenceNumberCatchupBuffer) GetMessageCount() int {
 	return int(atomic.LoadInt32(&b.messageCount))
 }
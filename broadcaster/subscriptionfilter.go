@@ -0,0 +1,55 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+func feedMessageMatches(msg *BroadcastFeedMessage, filter *wsbroadcastserver.SubscriptionFilter) bool {
+	if filter.ConfirmationsOnly {
+		return false
+	}
+	if len(filter.Kinds) == 0 && len(filter.Posters) == 0 {
+		return true
+	}
+	l1Message := msg.Message.Message
+	if l1Message == nil || l1Message.Header == nil {
+		return false
+	}
+	return filter.MatchesKind(l1Message.Header.Kind) && filter.MatchesPoster(l1Message.Header.Poster)
+}
+
+// ApplySubscriptionFilter implements wsbroadcastserver.FilterableMessage. Messages the filter
+// removes are listed in FilteredSequenceNumbers, so the client still receives an account of
+// every sequence number.
+func (m BroadcastMessage) ApplySubscriptionFilter(filter *wsbroadcastserver.SubscriptionFilter) interface{} {
+	filtered := BroadcastMessage{
+		Version:                        m.Version,
+		ConfirmedSequenceNumberMessage: m.ConfirmedSequenceNumberMessage,
+		FilteredSequenceNumbers:        append([]SequenceNumberRange{}, m.FilteredSequenceNumbers...),
+	}
+	for _, msg := range m.Messages {
+		if msg == nil {
+			continue
+		}
+		if feedMessageMatches(msg, filter) {
+			filtered.Messages = append(filtered.Messages, msg)
+			continue
+		}
+		last := len(filtered.FilteredSequenceNumbers) - 1
+		if last >= 0 && filtered.FilteredSequenceNumbers[last].To+1 == msg.SequenceNumber {
+			filtered.FilteredSequenceNumbers[last].To = msg.SequenceNumber
+		} else {
+			filtered.FilteredSequenceNumbers = append(filtered.FilteredSequenceNumbers, SequenceNumberRange{
+				From: msg.SequenceNumber,
+				To:   msg.SequenceNumber,
+			})
+		}
+	}
+	if len(filtered.Messages) == 0 && filtered.ConfirmedSequenceNumberMessage == nil && len(filtered.FilteredSequenceNumbers) == 0 {
+		return nil
+	}
+	return filtered
+}
@@ -0,0 +1,101 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+func createFilterTestMessage(seqNum arbutil.MessageIndex, kind uint8, poster common.Address) *BroadcastFeedMessage {
+	return &BroadcastFeedMessage{
+		SequenceNumber: seqNum,
+		Message: arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{
+					Kind:   kind,
+					Poster: poster,
+				},
+			},
+		},
+	}
+}
+
+func checkFilteredRanges(t *testing.T, actual []SequenceNumberRange, expected []SequenceNumberRange) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		Fail(t, "expected filtered ranges", expected, "got", actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			Fail(t, "expected filtered ranges", expected, "got", actual)
+		}
+	}
+}
+
+func TestApplySubscriptionFilter(t *testing.T) {
+	sequencer := common.HexToAddress("0xa4b000000000000000000073657175656e636572")
+	other := common.HexToAddress("0x1234")
+	msg := BroadcastMessage{
+		Version: 1,
+		Messages: []*BroadcastFeedMessage{
+			createFilterTestMessage(10, arbostypes.L1MessageType_L2Message, sequencer),
+			createFilterTestMessage(11, arbostypes.L1MessageType_EndOfBlock, sequencer),
+			createFilterTestMessage(12, arbostypes.L1MessageType_EndOfBlock, other),
+			createFilterTestMessage(13, arbostypes.L1MessageType_L2Message, other),
+			createFilterTestMessage(14, arbostypes.L1MessageType_L2Message, sequencer),
+		},
+	}
+
+	filtered, ok := msg.ApplySubscriptionFilter(&wsbroadcastserver.SubscriptionFilter{
+		Kinds: []int{int(arbostypes.L1MessageType_L2Message)},
+	}).(BroadcastMessage)
+	if !ok {
+		Fail(t, "expected a filtered message")
+	}
+	checkSequenceNumbers(t, filtered.Messages[:1], 10, 1)
+	checkSequenceNumbers(t, filtered.Messages[1:], 13, 2)
+	checkFilteredRanges(t, filtered.FilteredSequenceNumbers, []SequenceNumberRange{{11, 12}})
+
+	filtered, ok = msg.ApplySubscriptionFilter(&wsbroadcastserver.SubscriptionFilter{
+		Kinds:   []int{int(arbostypes.L1MessageType_L2Message)},
+		Posters: []common.Address{sequencer},
+	}).(BroadcastMessage)
+	if !ok {
+		Fail(t, "expected a filtered message")
+	}
+	checkSequenceNumbers(t, filtered.Messages[:1], 10, 1)
+	checkSequenceNumbers(t, filtered.Messages[1:], 14, 1)
+	checkFilteredRanges(t, filtered.FilteredSequenceNumbers, []SequenceNumberRange{{11, 13}})
+
+	// Confirmations only subscribers are still told which messages they didn't receive.
+	filtered, ok = msg.ApplySubscriptionFilter(&wsbroadcastserver.SubscriptionFilter{
+		ConfirmationsOnly: true,
+	}).(BroadcastMessage)
+	if !ok {
+		Fail(t, "expected a filtered message")
+	}
+	checkSequenceNumbers(t, filtered.Messages, 0, 0)
+	checkFilteredRanges(t, filtered.FilteredSequenceNumbers, []SequenceNumberRange{{10, 14}})
+
+	confirmation := BroadcastMessage{
+		Version:                        1,
+		ConfirmedSequenceNumberMessage: &ConfirmedSequenceNumberMessage{12},
+	}
+	filtered, ok = confirmation.ApplySubscriptionFilter(&wsbroadcastserver.SubscriptionFilter{
+		ConfirmationsOnly: true,
+	}).(BroadcastMessage)
+	if !ok || filtered.ConfirmedSequenceNumberMessage == nil || filtered.ConfirmedSequenceNumberMessage.SequenceNumber != 12 {
+		Fail(t, "expected confirmation to pass the filter")
+	}
+
+	if (BroadcastMessage{Version: 1}).ApplySubscriptionFilter(&wsbroadcastserver.SubscriptionFilter{}) != nil {
+		Fail(t, "expected nothing to send for an empty message")
+	}
+}
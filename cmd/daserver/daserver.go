@@ -41,6 +41,7 @@ type DAServerConfig struct {
 	RESTServerTimeouts genericconf.HTTPServerTimeoutConfig `koanf:"rest-server-timeouts"`
 
 	DataAvailability das.DataAvailabilityConfig `koanf:"data-availability"`
+	Backfill         BackfillConfig             `koanf:"backfill"`
 
 	Conf     genericconf.ConfConfig `koanf:"conf"`
 	LogLevel int                    `koanf:"log-level"`
@@ -62,6 +63,7 @@ var DefaultDAServerConfig = DAServerConfig{
 	RESTPort:           9877,
 	RESTServerTimeouts: genericconf.HTTPServerTimeoutConfigDefault,
 	DataAvailability:   das.DefaultDataAvailabilityConfig,
+	Backfill:           DefaultBackfillConfig,
 	Conf:               genericconf.ConfConfigDefault,
 	LogLevel:           int(log.LvlInfo),
 	LogType:            "plaintext",
@@ -71,6 +73,21 @@ var DefaultDAServerConfig = DAServerConfig{
 	PprofCfg:           genericconf.PProfDefault,
 }
 
+type BackfillConfig struct {
+	From string `koanf:"from"`
+	To   string `koanf:"to"`
+}
+
+var DefaultBackfillConfig = BackfillConfig{
+	From: "",
+	To:   "",
+}
+
+func BackfillConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".from", DefaultBackfillConfig.From, "if set, copy all data from this storage service (local-db-storage, local-file-storage, s3-storage or remote-storage) to the one named by backfill.to, then exit")
+	f.String(prefix+".to", DefaultBackfillConfig.To, "storage service to copy data into when backfilling")
+}
+
 func main() {
 	if err := startup(); err != nil {
 		log.Error("Error running DAServer", "err", err)
@@ -104,6 +121,7 @@ func parseDAServer(args []string) (*DAServerConfig, error) {
 	f.String("log-type", DefaultDAServerConfig.LogType, "log type (plaintext or json)")
 
 	das.DataAvailabilityConfigAddDaserverOptions("data-availability", f)
+	BackfillConfigAddOptions("backfill", f)
 	genericconf.ConfConfigAddOptions("conf", f)
 
 	k, err := confighelpers.BeginCommonParse(f, args)
@@ -178,7 +196,8 @@ func startup() error {
 	if err != nil {
 		confighelpers.PrintErrorAndExit(err, printSampleUsage)
 	}
-	if !(serverConfig.EnableRPC || serverConfig.EnableREST) {
+	isBackfill := serverConfig.Backfill.From != "" || serverConfig.Backfill.To != ""
+	if !(serverConfig.EnableRPC || serverConfig.EnableREST || isBackfill) {
 		confighelpers.PrintErrorAndExit(errors.New("please specify at least one of --enable-rest or --enable-rpc"), printSampleUsage)
 	}
 
@@ -195,6 +214,10 @@ func startup() error {
 		return err
 	}
 
+	if isBackfill {
+		return backfill(serverConfig)
+	}
+
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 
@@ -277,3 +300,33 @@ func startup() error {
 	}
 	return err2
 }
+
+// backfill runs a one-shot sync between two of the configured storage services.
+// If data-availability.regular-sync-storage.state-dir is set, an interrupted
+// backfill resumes from where it stopped.
+func backfill(serverConfig *DAServerConfig) error {
+	if serverConfig.Backfill.From == "" || serverConfig.Backfill.To == "" {
+		return errors.New("both --backfill.from and --backfill.to must be set to backfill")
+	}
+
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-sigint:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	syncFrom, syncTo, lifecycleManager, err := das.CreateBackfillStorageServices(ctx, &serverConfig.DataAvailability, serverConfig.Backfill.From, serverConfig.Backfill.To)
+	if err != nil {
+		return err
+	}
+	defer lifecycleManager.StopAndWaitUntil(2 * time.Second)
+
+	syncStorage := das.NewRegularlySyncStorage(nil, nil, serverConfig.DataAvailability.RegularSyncStorage)
+	return syncStorage.Backfill(ctx, syncFrom, syncTo)
+}
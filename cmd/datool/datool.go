@@ -35,7 +35,7 @@ import (
 func main() {
 	args := os.Args
 	if len(args) < 2 {
-		panic("Usage: datool [client|keygen|generatehash|dumpkeyset] ...")
+		panic("Usage: datool [client|keygen|generatehash|dumpkeyset|scrub] ...")
 	}
 
 	var err error
@@ -48,8 +48,10 @@ func main() {
 		err = generateHash(args[2])
 	case "dumpkeyset":
 		err = dumpKeyset(args[2:])
+	case "scrub":
+		err = scrub(args[2:])
 	default:
-		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash'", args[1]))
+		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash', 'dumpkeyset', 'scrub'", args[1]))
 	}
 	if err != nil {
 		panic(err)
@@ -376,3 +378,88 @@ func dumpKeyset(args []string) error {
 
 	return err
 }
+
+// datool scrub
+
+type ScrubConfig struct {
+	Storage          string                     `koanf:"storage"`
+	FromBlock        uint64                     `koanf:"from-block"`
+	ToBlock          uint64                     `koanf:"to-block"`
+	DataAvailability das.DataAvailabilityConfig `koanf:"data-availability"`
+	Conf             genericconf.ConfConfig     `koanf:"conf"`
+}
+
+func parseScrub(args []string) (*ScrubConfig, error) {
+	f := flag.NewFlagSet("datool scrub", flag.ContinueOnError)
+	f.String("storage", "local-file-storage", "storage service to scrub, configured by the matching data-availability section; one of local-db-storage, local-file-storage or s3-storage")
+	f.Uint64("from-block", 0, "if set along with to-block, check the data referenced by batches posted to the sequencer inbox in this parent chain block range instead of iterating over the storage")
+	f.Uint64("to-block", 0, "last parent chain block to check batches from, inclusive")
+	das.DataAvailabilityConfigAddDaserverOptions("data-availability", f)
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ScrubConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.ToBlock < config.FromBlock {
+		return nil, errors.New("--to-block must not be before --from-block")
+	}
+	return &config, nil
+}
+
+func scrub(args []string) error {
+	config, err := parseScrub(args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	storage, err := das.CreateStorageServiceByName(ctx, &config.DataAvailability, config.Storage)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := storage.Close(ctx); err != nil {
+			fmt.Printf("Error closing storage: %v\n", err)
+		}
+	}()
+	scrubber, err := das.NewScrubber(storage, config.DataAvailability.Scrubber)
+	if err != nil {
+		return err
+	}
+
+	var report das.ScrubReport
+	if config.ToBlock == 0 {
+		err = scrubber.ScrubIterable(ctx, &report)
+	} else {
+		seqInboxAddress, addrErr := das.OptionalAddressFromString(config.DataAvailability.SequencerInboxAddress)
+		if addrErr != nil {
+			return addrErr
+		}
+		if seqInboxAddress == nil {
+			return errors.New("--data-availability.sequencer-inbox-address must be set to scrub a block range")
+		}
+		l1Client, dialErr := das.GetL1Client(ctx, config.DataAvailability.ParentChainConnectionAttempts, config.DataAvailability.ParentChainNodeURL)
+		if dialErr != nil {
+			return dialErr
+		}
+		err = scrubber.ScrubBlockRange(ctx, l1Client, *seqInboxAddress, config.FromBlock, config.ToBlock, &report)
+	}
+
+	fmt.Printf("Scrub result: %s\n", report.String())
+	for _, hash := range report.Corrupt {
+		fmt.Printf("Corrupt: %v\n", hash)
+	}
+	for _, hash := range report.Missing {
+		fmt.Printf("Missing: %v\n", hash)
+	}
+	for _, hash := range report.RepairFailed {
+		fmt.Printf("Repair failed: %v\n", hash)
+	}
+	return err
+}
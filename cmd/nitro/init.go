@@ -377,7 +377,7 @@ func findImportantRoots(ctx context.Context, chainDb ethdb.Database, stack *node
 			return nil, fmt.Errorf("failed to get finalized block: %w", err)
 		}
 		l1BlockNum := l1Block.NumberU64()
-		tracker, err := arbnode.NewInboxTracker(arbDb, nil, nil)
+		tracker, err := arbnode.NewInboxTracker(arbDb, nil, nil, nil)
 		if err != nil {
 			return nil, err
 		}
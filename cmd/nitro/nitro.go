@@ -48,6 +48,8 @@ import (
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/cmd/util"
 	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/execution/execrpc"
 	"github.com/offchainlabs/nitro/execution/gethexec"
 	_ "github.com/offchainlabs/nitro/nodeInterface"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
@@ -457,23 +459,31 @@ func mainImpl() int {
 		}
 	}
 
-	execNode, err := gethexec.CreateExecutionNode(
-		ctx,
-		stack,
-		chainDb,
-		l2BlockChain,
-		l1Client,
-		func() *gethexec.Config { return &liveNodeConfig.Get().Execution },
-	)
-	if err != nil {
-		log.Error("failed to create execution node", "err", err)
-		return 1
+	var exec execution.FullExecutionClient
+	var execNode *gethexec.ExecutionNode
+	if nodeConfig.Node.RemoteExecution() {
+		// the execution runs in another process, with its own chain database
+		exec = execrpc.NewExecutionClient(func() *execrpc.ClientConfig { return &liveNodeConfig.Get().Node.ExecutionClient }, stack)
+	} else {
+		execNode, err = gethexec.CreateExecutionNode(
+			ctx,
+			stack,
+			chainDb,
+			l2BlockChain,
+			l1Client,
+			func() *gethexec.Config { return &liveNodeConfig.Get().Execution },
+		)
+		if err != nil {
+			log.Error("failed to create execution node", "err", err)
+			return 1
+		}
+		exec = execNode
 	}
 
 	currentNode, err := arbnode.CreateNode(
 		ctx,
 		stack,
-		execNode,
+		exec,
 		arbDb,
 		&NodeConfigFetcher{liveNodeConfig},
 		l2BlockChain.Config(),
@@ -512,6 +522,10 @@ func mainImpl() int {
 	}
 	gqlConf := nodeConfig.GraphQL
 	if gqlConf.Enable {
+		if execNode == nil {
+			log.Error("GraphQL is served by the execution, which is in another process")
+			return 1
+		}
 		if err := graphql.New(stack, execNode.Backend.APIBackend(), execNode.FilterSystem, gqlConf.CORSDomain, gqlConf.VHosts); err != nil {
 			log.Error("failed to register the GraphQL service", "err", err)
 			return 1
@@ -741,10 +755,11 @@ func ParseNode(ctx context.Context, args []string) (*NodeConfig, *genericconf.Wa
 	// Don't print wallet passwords
 	if nodeConfig.Conf.Dump {
 		err = confighelpers.DumpConfig(k, map[string]interface{}{
-			"parent-chain.wallet.password":    "",
-			"parent-chain.wallet.private-key": "",
-			"chain.dev-wallet.password":       "",
-			"chain.dev-wallet.private-key":    "",
+			"parent-chain.wallet.password":        "",
+			"parent-chain.wallet.private-key":     "",
+			"chain.dev-wallet.password":           "",
+			"chain.dev-wallet.private-key":        "",
+			"node.batch-poster.lane-private-keys": "",
 		})
 		if err != nil {
 			return nil, nil, nil, err
@@ -17,6 +17,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -60,13 +61,23 @@ func (c WavmChainContext) GetHeader(hash common.Hash, num uint64) *types.Header
 	return header
 }
 
-type WavmInbox struct{}
+type WavmInbox struct {
+	// The ArbOS version of the last block, which decides whether batches may be read from blobs
+	arbOSVersion uint64
+}
 
-func (i WavmInbox) PeekSequencerInbox() ([]byte, error) {
+func (i WavmInbox) PeekSequencerInbox() ([]byte, common.Hash, error) {
 	pos := wavmio.GetInboxPosition()
 	res := wavmio.ReadInboxMessage(pos)
 	log.Info("PeekSequencerInbox", "pos", pos, "res[:8]", res[:8])
-	return res, nil
+	// Blobs are resolved as preimages of their versioned hashes, so the block hash isn't needed
+	return res, common.Hash{}, nil
+}
+
+func (i WavmInbox) IsBlobBatch() bool {
+	// How the batch was posted isn't known here, but calldata batches can't start with the blob
+	// hashes header from this version on
+	return i.arbOSVersion >= arbstate.BlobHashesMinArbOSVersion
 }
 
 func (i WavmInbox) GetSequencerInboxPosition() uint64 {
@@ -117,6 +128,34 @@ func (dasReader *PreimageDASReader) ExpirationPolicy(ctx context.Context) (arbst
 	return arbstate.DiscardImmediately, nil
 }
 
+type BlobPreimageReader struct {
+}
+
+func (r *BlobPreimageReader) GetBlobs(
+	ctx context.Context,
+	batchBlockHash common.Hash,
+	versionedHashes []common.Hash,
+) ([]kzg4844.Blob, error) {
+	var blobs []kzg4844.Blob
+	for _, h := range versionedHashes {
+		var blob kzg4844.Blob
+		preimage, err := wavmio.ResolveTypedPreimage(arbutil.EthVersionedHashPreimageType, h)
+		if err != nil {
+			return nil, err
+		}
+		if len(preimage) != len(blob) {
+			return nil, fmt.Errorf("for blob %v got back preimage of length %v but expected blob length %v", h, len(preimage), len(blob))
+		}
+		copy(blob[:], preimage)
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+func (r *BlobPreimageReader) Initialize(ctx context.Context) error {
+	return nil
+}
+
 // To generate:
 // key, _ := crypto.HexToECDSA("0000000000000000000000000000000000000000000000000000000000000001")
 // sig, _ := crypto.Sign(make([]byte, 32), key)
@@ -168,19 +207,21 @@ func main() {
 
 	readMessage := func(dasEnabled bool) *arbostypes.MessageWithMetadata {
 		var delayedMessagesRead uint64
+		var arbOSVersion uint64
 		if lastBlockHeader != nil {
 			delayedMessagesRead = lastBlockHeader.Nonce.Uint64()
+			arbOSVersion = types.DeserializeHeaderExtraInformation(lastBlockHeader).ArbOSFormatVersion
 		}
 		var dasReader arbstate.DataAvailabilityReader
 		if dasEnabled {
 			dasReader = &PreimageDASReader{}
 		}
-		backend := WavmInbox{}
+		backend := WavmInbox{arbOSVersion: arbOSVersion}
 		var keysetValidationMode = arbstate.KeysetPanicIfInvalid
 		if backend.GetPositionWithinMessage() > 0 {
 			keysetValidationMode = arbstate.KeysetDontValidate
 		}
-		inboxMultiplexer := arbstate.NewInboxMultiplexer(backend, delayedMessagesRead, dasReader, keysetValidationMode)
+		inboxMultiplexer := arbstate.NewInboxMultiplexer(backend, delayedMessagesRead, dasReader, &BlobPreimageReader{}, keysetValidationMode)
 		ctx := context.Background()
 		message, err := inboxMultiplexer.Pop(ctx)
 		if err != nil {
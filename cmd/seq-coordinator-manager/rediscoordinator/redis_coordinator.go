@@ -3,12 +3,17 @@ package rediscoordinator
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/offchainlabs/nitro/util/redisutil"
 )
 
+const failoverPollInterval = 100 * time.Millisecond
+
 // RedisCoordinator builds upon RedisCoordinator of redisutil with additional functionality
 type RedisCoordinator struct {
 	*redisutil.RedisCoordinator
@@ -26,3 +31,138 @@ func (rc *RedisCoordinator) UpdatePriorities(ctx context.Context, priorities []s
 	}
 	return nil
 }
+
+// PlannedFailover makes target the chosen sequencer without it racing the current one: it asks the current chosen
+// sequencer to drain, waits for target to catch up to the final message count it published, then moves target to the
+// top of the priority list and waits for it to take over. Each step is passed to report as it completes.
+// If it fails or times out before target takes over, the current chosen sequencer is left to resume sequencing and the
+// priorities are restored.
+func (rc *RedisCoordinator) PlannedFailover(ctx context.Context, target string, timeout time.Duration, report func(string)) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	chosen, err := rc.CurrentChosenSequencer(ctx)
+	if err != nil {
+		return err
+	}
+	if chosen == "" {
+		// sequencers coordinating via raft never hold the lockout in redis, and can't be asked to drain through it
+		return errors.New("no chosen sequencer in redis to fail over from, planned failover isn't supported with raft sequencer coordination")
+	}
+	if chosen == target {
+		return fmt.Errorf("%v is already the chosen sequencer", target)
+	}
+	wantsLockout, err := rc.Client.Exists(ctx, redisutil.WantsLockoutKeyFor(target)).Result()
+	if err != nil {
+		return err
+	}
+	if wantsLockout == 0 {
+		return fmt.Errorf("%v doesn't want the lockout, it may be offline or not synced", target)
+	}
+	priorities, err := rc.GetPriorities(ctx)
+	if err != nil {
+		return err
+	}
+
+	switched := false
+	defer func() {
+		// ctx may be done, but cleaning up must still happen
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		resuming := chosen
+		if err != nil && switched {
+			// restoring the priorities once target took over would hand the lockout back, in a second handoff
+			current, currentErr := rc.CurrentChosenSequencer(cleanupCtx)
+			if currentErr != nil {
+				report(fmt.Sprintf("not restoring the priority list, as the chosen sequencer is unknown: %v", currentErr))
+			} else if current == target {
+				report(fmt.Sprintf("not restoring the priority list, as %v took over", target))
+				resuming = target
+			} else if restoreErr := rc.UpdatePriorities(cleanupCtx, priorities); restoreErr != nil {
+				report(fmt.Sprintf("failed to restore the priority list: %v", restoreErr))
+			}
+		}
+		keys := []string{
+			redisutil.DrainKeyFor(chosen),
+			redisutil.DrainedKeyFor(chosen),
+			redisutil.CatchupKeyFor(target),
+			redisutil.ProgressKeyFor(target),
+		}
+		if delErr := rc.Client.Del(cleanupCtx, keys...).Err(); delErr != nil {
+			report(fmt.Sprintf("failed to clear the failover requests, they expire in %v: %v", timeout, delErr))
+		}
+		if err != nil {
+			report(fmt.Sprintf("aborted with %v sequencing: %v", resuming, err))
+		}
+	}()
+
+	if err = rc.Client.Set(ctx, redisutil.DrainKeyFor(chosen), target, timeout).Err(); err != nil {
+		return err
+	}
+	report(fmt.Sprintf("asked %v to drain", chosen))
+	drainedMsgCount, err := rc.waitForMsgCount(ctx, redisutil.DrainedKeyFor(chosen), 0)
+	if err != nil {
+		return fmt.Errorf("waiting for %v to drain: %w", chosen, err)
+	}
+	report(fmt.Sprintf("%v drained at message count %v", chosen, drainedMsgCount))
+
+	if err = rc.Client.Set(ctx, redisutil.CatchupKeyFor(target), drainedMsgCount, timeout).Err(); err != nil {
+		return err
+	}
+	if _, err = rc.waitForMsgCount(ctx, redisutil.ProgressKeyFor(target), drainedMsgCount); err != nil {
+		return fmt.Errorf("waiting for %v to catch up: %w", target, err)
+	}
+	report(fmt.Sprintf("%v caught up to message count %v", target, drainedMsgCount))
+
+	newPriorities := []string{target}
+	for _, url := range priorities {
+		if url != target {
+			newPriorities = append(newPriorities, url)
+		}
+	}
+	switched = true
+	if err = rc.UpdatePriorities(ctx, newPriorities); err != nil {
+		return err
+	}
+	report(fmt.Sprintf("moved %v to the top of the priority list", target))
+	err = waitFor(ctx, func() (bool, error) {
+		current, err := rc.CurrentChosenSequencer(ctx)
+		return current == target, err
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %v to become chosen: %w", target, err)
+	}
+	report(fmt.Sprintf("%v is the chosen sequencer", target))
+	return nil
+}
+
+// waitForMsgCount waits for key to hold a message count of at least minMsgCount, and returns it.
+func (rc *RedisCoordinator) waitForMsgCount(ctx context.Context, key string, minMsgCount uint64) (uint64, error) {
+	var msgCount uint64
+	err := waitFor(ctx, func() (bool, error) {
+		value, err := rc.Client.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		msgCount, err = strconv.ParseUint(value, 10, 64)
+		return err == nil && msgCount >= minMsgCount, err
+	})
+	return msgCount, err
+}
+
+// waitFor calls check every failoverPollInterval until it returns true or an error, or ctx is done.
+func waitFor(ctx context.Context, check func() (bool, error)) error {
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(failoverPollInterval):
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/enescakir/emoji"
 	"github.com/ethereum/go-ethereum/log"
@@ -26,6 +27,12 @@ var nonPrioritySeqList = tview.NewList().ShowSecondaryText(false)
 var addSeqForm = tview.NewForm()
 var priorityForm = tview.NewForm()
 var nonPriorityForm = tview.NewForm()
+var failoverForm = tview.NewForm()
+
+// Planned failover progress
+var failoverLog = tview.NewTextView().SetScrollable(true)
+
+const defaultFailoverTimeout = time.Minute
 
 // Sequencer coordinator management UI data store
 type manager struct {
@@ -41,8 +48,9 @@ func main() {
 	defer cancelFunc()
 
 	args := os.Args[1:]
-	if len(args) != 1 {
+	if len(args) != 1 && (len(args) < 3 || len(args) > 4 || args[1] != "failover") {
 		fmt.Fprintf(os.Stderr, "Usage: seq-coordinator-manager [redis-url]\n")
+		fmt.Fprintf(os.Stderr, "       seq-coordinator-manager [redis-url] failover [target-url] [timeout, default %v]\n", defaultFailoverTimeout)
 		os.Exit(1)
 	}
 	redisURL := args[0]
@@ -59,6 +67,25 @@ func main() {
 		livelinessSet: make(map[string]bool),
 	}
 
+	if len(args) > 1 {
+		timeout := defaultFailoverTimeout
+		if len(args) == 4 {
+			timeout, err = time.ParseDuration(args[3])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid timeout: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		err = seqManager.redisCoordinator.PlannedFailover(ctx, args[2], timeout, func(step string) {
+			fmt.Println(step)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failover failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	seqManager.refreshAllLists(ctx)
 	seqManager.populateLists(ctx)
 
@@ -155,7 +182,7 @@ func main() {
 		SetText("-----Not in priority list but online-----")
 	instructions := tview.NewTextView().
 		SetTextColor(tcell.ColorYellow).
-		SetText("(r) to refresh\n(s) to save all changes\n(c) to switch between lists\n(a) to add sequencer\n(q) to quit\n(tab) to navigate")
+		SetText("(r) to refresh\n(s) to save all changes\n(c) to switch between lists\n(a) to add sequencer\n(f) to fail over to another sequencer\n(q) to quit\n(tab) to navigate")
 
 	flex.SetDirection(tview.FlexRow).
 		AddItem(priorityHeading, 0, 1, false).
@@ -195,6 +222,10 @@ func main() {
 				nonPriorityForm.Clear(true)
 				app.SetFocus(prioritySeqList)
 			}
+		} else if event.Rune() == 102 {
+			failoverForm.Clear(true)
+			seqManager.addFailoverForm(ctx)
+			pages.SwitchToPage("Failover")
 		} else if event.Rune() == 113 {
 			app.Stop()
 		}
@@ -203,6 +234,9 @@ func main() {
 
 	pages.AddPage("Menu", flex, true, true)
 	pages.AddPage("Add Sequencer", addSeqForm, true, false)
+	pages.AddPage("Failover", tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(failoverForm, 0, 1, true).
+		AddItem(failoverLog, 0, 2, false), true, false)
 
 	if err := app.SetRoot(pages, true).EnableMouse(true).Run(); err != nil {
 		panic(err)
@@ -276,6 +310,63 @@ func (sm *manager) addSeqPriorityForm(ctx context.Context) *tview.Form {
 	return addSeqForm
 }
 
+// addFailoverForm returns a form to start a planned failover to a sequencer in the priority list, whose steps are shown below it
+func (sm *manager) addFailoverForm(ctx context.Context) *tview.Form {
+	target := ""
+	if len(sm.priorityList) > 0 {
+		target = sm.priorityList[0]
+	}
+	timeoutString := defaultFailoverTimeout.String()
+	failoverLog.Clear()
+	failoverForm.AddDropDown("Fail over to", sm.priorityList, 0, func(url string, selection int) {
+		target = url
+	})
+	failoverForm.AddInputField("Timeout", timeoutString, 0, nil, func(timeout string) {
+		timeoutString = timeout
+	})
+	failoverForm.AddButton("Cancel", func() {
+		failoverForm.Clear(true)
+		pages.SwitchToPage("Menu")
+		app.SetFocus(prioritySeqList)
+	})
+	failoverForm.AddButton("Fail over", func() {
+		timeout, err := time.ParseDuration(timeoutString)
+		if err != nil {
+			fmt.Fprintf(failoverLog, "invalid timeout: %v\n", err)
+			return
+		}
+		if target == "" {
+			fmt.Fprintln(failoverLog, "no sequencer to fail over to")
+			return
+		}
+		failoverForm.Clear(true)
+		fmt.Fprintf(failoverLog, "failing over to %v, timeout %v\n", target, timeout)
+		go func() {
+			err := sm.redisCoordinator.PlannedFailover(ctx, target, timeout, func(step string) {
+				app.QueueUpdateDraw(func() {
+					fmt.Fprintln(failoverLog, step)
+				})
+			})
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					fmt.Fprintf(failoverLog, "failover failed: %v\n", err)
+				} else {
+					fmt.Fprintln(failoverLog, "failover done")
+				}
+				sm.refreshAllLists(ctx)
+				sm.populateLists(ctx)
+				failoverForm.AddButton("Back", func() {
+					failoverForm.Clear(true)
+					pages.SwitchToPage("Menu")
+					app.SetFocus(prioritySeqList)
+				})
+				app.SetFocus(failoverForm)
+			})
+		}()
+	})
+	return failoverForm
+}
+
 // pushUpdates pushes the local changes to the redis server
 func (sm *manager) pushUpdates(ctx context.Context) {
 	err := sm.redisCoordinator.UpdatePriorities(ctx, sm.priorityList)
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"math/bits"
+	"strings"
 	"time"
 
 	flag "github.com/spf13/pflag"
@@ -27,14 +28,20 @@ import (
 )
 
 type AggregatorConfig struct {
-	Enable        bool   `koanf:"enable"`
-	AssumedHonest int    `koanf:"assumed-honest"`
-	Backends      string `koanf:"backends"`
+	Enable              bool          `koanf:"enable"`
+	AssumedHonest       int           `koanf:"assumed-honest"`
+	Backends            string        `koanf:"backends"`
+	RetryDeadline       time.Duration `koanf:"retry-deadline"`
+	RetryInitialBackoff time.Duration `koanf:"retry-initial-backoff"`
+	RetryMaxBackoff     time.Duration `koanf:"retry-max-backoff"`
 }
 
 var DefaultAggregatorConfig = AggregatorConfig{
-	AssumedHonest: 0,
-	Backends:      "",
+	AssumedHonest:       0,
+	Backends:            "",
+	RetryDeadline:       30 * time.Second,
+	RetryInitialBackoff: 500 * time.Millisecond,
+	RetryMaxBackoff:     5 * time.Second,
 }
 
 var BatchToDasFailed = errors.New("unable to batch to DAS")
@@ -43,6 +50,9 @@ func AggregatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultAggregatorConfig.Enable, "enable storage/retrieval of sequencer batch data from a list of RPC endpoints; this should only be used by the batch poster and not in combination with other DAS storage types")
 	f.Int(prefix+".assumed-honest", DefaultAggregatorConfig.AssumedHonest, "Number of assumed honest backends (H). If there are N backends, K=N+1-H valid responses are required to consider an Store request to be successful.")
 	f.String(prefix+".backends", DefaultAggregatorConfig.Backends, "JSON RPC backend configuration")
+	f.Duration(prefix+".retry-deadline", DefaultAggregatorConfig.RetryDeadline, "how long to keep retrying backends that failed a Store request while not enough have succeeded; 0 disables retries")
+	f.Duration(prefix+".retry-initial-backoff", DefaultAggregatorConfig.RetryInitialBackoff, "time to wait before the first retry of a backend that failed a Store request")
+	f.Duration(prefix+".retry-max-backoff", DefaultAggregatorConfig.RetryMaxBackoff, "maximum time to wait between retries of a backend that failed a Store request")
 }
 
 type Aggregator struct {
@@ -117,6 +127,9 @@ func NewAggregatorWithSeqInboxCaller(
 	services []ServiceDetails,
 	seqInboxCaller *bridgegen.SequencerInboxCaller,
 ) (*Aggregator, error) {
+	if config.RPCAggregator.RetryDeadline > 0 && (config.RPCAggregator.RetryInitialBackoff <= 0 || config.RPCAggregator.RetryMaxBackoff < config.RPCAggregator.RetryInitialBackoff) {
+		return nil, errors.New("rpc-aggregator.retry-initial-backoff must be positive and no greater than rpc-aggregator.retry-max-backoff when retries are enabled")
+	}
 
 	keysetHash, keysetBytes, err := KeysetHashFromServices(services, uint64(config.RPCAggregator.AssumedHonest))
 	if err != nil {
@@ -141,9 +154,63 @@ func NewAggregatorWithSeqInboxCaller(
 }
 
 type storeResponse struct {
-	details ServiceDetails
-	sig     blsSignatures.Signature
-	err     error
+	index    int
+	details  ServiceDetails
+	sig      blsSignatures.Signature
+	err      error
+	reason   string
+	attempts int
+}
+
+// BackendStoreResult describes how a single committee member responded to a store request.
+type BackendStoreResult struct {
+	Backend     string
+	SignersMask uint64
+	Attempts    int
+	// Reason is one of "timeout", "client" or "bad_response" when the backend failed.
+	Reason string
+	Err    error
+}
+
+// StoreResult describes which committee members signed a store request, which
+// failed and why, and which hadn't responded yet when the result was decided.
+type StoreResult struct {
+	Signed  []BackendStoreResult
+	Failed  []BackendStoreResult
+	Pending []BackendStoreResult
+}
+
+func (r *StoreResult) SignersMask() uint64 {
+	var mask uint64
+	for _, signed := range r.Signed {
+		mask |= signed.SignersMask
+	}
+	return mask
+}
+
+func (r *StoreResult) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("signed %d, failed %d, pending %d", len(r.Signed), len(r.Failed), len(r.Pending)))
+	for _, failed := range r.Failed {
+		b.WriteString(fmt.Sprintf("; %s failed after %d attempts (%s): %v", failed.Backend, failed.Attempts, failed.Reason, failed.Err))
+	}
+	return b.String()
+}
+
+// StoreFailedError is returned when too few committee members signed a store
+// request for a certificate to be generated. It wraps BatchToDasFailed, and the
+// cause, which is the context's error if the store was canceled or timed out.
+type StoreFailedError struct {
+	Result *StoreResult
+	cause  error
+}
+
+func (e *StoreFailedError) Error() string {
+	return fmt.Sprintf("%v. %v (%s)", e.cause, BatchToDasFailed, e.Result.String())
+}
+
+func (e *StoreFailedError) Unwrap() []error {
+	return []error{BatchToDasFailed, e.cause}
 }
 
 // Store calls Store on each backend DAS in parallel and collects responses.
@@ -152,10 +219,15 @@ type storeResponse struct {
 // then Store returns immediately. If there were any backend Store subroutines
 // that were still running when Aggregator.Store returns, they are allowed to
 // continue running until the context is canceled (eg via TimeoutWrapper),
-// with their results discarded.
+// and their late responses are logged and counted in metrics.
 //
-// If Store gets enough errors that K successes is impossible, then it stops early
-// and returns an error.
+// If rpc-aggregator.retry-deadline is set, a backend that fails is retried with
+// exponential backoff until it succeeds, K successes have been collected, or
+// the deadline passes. Successful responses are kept while other backends are
+// retried, so only the failing backends are asked to store the data again.
+//
+// If Store gets enough final errors that K successes is impossible, then it stops
+// early and returns an error.
 //
 // If Store gets not enough successful responses by the time its context is canceled
 // (eg via TimeoutWrapper) then it also returns an error.
@@ -165,82 +237,72 @@ type storeResponse struct {
 // is from the batch poster. If the contract details are not provided, then the
 // signature is not checked, which is useful for testing.
 func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64, sig []byte) (*arbstate.DataAvailabilityCertificate, error) {
+	cert, _, err := a.StoreWithResult(ctx, message, timeout, sig)
+	return cert, err
+}
+
+// StoreWithResult is like Store, but also describes how each committee member
+// responded. If too few signed, the error is a *StoreFailedError holding the result.
+func (a *Aggregator) StoreWithResult(ctx context.Context, message []byte, timeout uint64, sig []byte) (*arbstate.DataAvailabilityCertificate, *StoreResult, error) {
 	log.Trace("das.Aggregator.Store", "message", pretty.FirstFewBytes(message), "timeout", time.Unix(int64(timeout), 0), "sig", pretty.FirstFewBytes(sig))
 	if a.addrVerifier != nil {
 		actualSigner, err := DasRecoverSigner(message, timeout, sig)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		isBatchPosterOrSequencer, err := a.addrVerifier.IsBatchPosterOrSequencer(ctx, actualSigner)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if !isBatchPosterOrSequencer {
-			return nil, errors.New("store request not properly signed")
+			return nil, nil, errors.New("store request not properly signed")
+		}
+	}
+
+	var retryDeadline time.Time
+	if a.config.RetryDeadline > 0 {
+		retryDeadline = time.Now().Add(a.config.RetryDeadline)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(retryDeadline) {
+			retryDeadline = ctxDeadline
 		}
 	}
 
 	responses := make(chan storeResponse, len(a.services))
+	// Closed once the outcome is known, to stop retrying failed backends.
+	decided := make(chan struct{})
 
 	expectedHash := dastree.Hash(message)
-	for _, d := range a.services {
-		go func(ctx context.Context, d ServiceDetails) {
-			storeCtx, cancel := context.WithTimeout(ctx, a.requestTimeout)
-			const metricBase string = "arb/das/rpc/aggregator/store"
-			var metricWithServiceName = metricBase + "/" + d.metricName
-			defer cancel()
-			incFailureMetric := func() {
-				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/total", nil).Inc(1)
-				metrics.GetOrRegisterCounter(metricBase+"/error/all/total", nil).Inc(1)
-			}
-
-			cert, err := d.service.Store(storeCtx, message, timeout, sig)
-			if err != nil {
-				incFailureMetric()
-				if errors.Is(err, context.DeadlineExceeded) {
-					metrics.GetOrRegisterCounter(metricWithServiceName+"/error/timeout/total", nil).Inc(1)
-				} else {
-					metrics.GetOrRegisterCounter(metricWithServiceName+"/error/client/total", nil).Inc(1)
+	for i, d := range a.services {
+		go func(ctx context.Context, index int, d ServiceDetails) {
+			backoff := a.config.RetryInitialBackoff
+			for attempt := 1; ; attempt++ {
+				blsSig, reason, err := a.storeToBackend(ctx, d, message, timeout, sig, expectedHash)
+				if err == nil {
+					responses <- storeResponse{index, d, blsSig, nil, "", attempt}
+					return
+				}
+				if retryDeadline.IsZero() || time.Now().Add(backoff).After(retryDeadline) {
+					responses <- storeResponse{index, d, nil, err, reason, attempt}
+					return
+				}
+				log.Debug("das.Aggregator: retrying backend", "backend", d.service, "signerMask", d.signersMask, "attempt", attempt, "backoff", backoff, "reason", reason, "err", err)
+				metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/"+d.metricName+"/retry/total", nil).Inc(1)
+				metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/retry/all/total", nil).Inc(1)
+				select {
+				case <-ctx.Done():
+					responses <- storeResponse{index, d, nil, err, reason, attempt}
+					return
+				case <-decided:
+					responses <- storeResponse{index, d, nil, err, reason, attempt}
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+				if backoff > a.config.RetryMaxBackoff {
+					backoff = a.config.RetryMaxBackoff
 				}
-				responses <- storeResponse{d, nil, err}
-				return
-			}
-
-			verified, err := blsSignatures.VerifySignature(
-				cert.Sig, cert.SerializeSignableFields(), d.pubKey,
-			)
-			if err != nil {
-				incFailureMetric()
-				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, err}
-				return
-			}
-			if !verified {
-				incFailureMetric()
-				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, errors.New("signature verification failed")}
-				return
-			}
-
-			// SignersMask from backend DAS is ignored.
-
-			if cert.DataHash != expectedHash {
-				incFailureMetric()
-				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, errors.New("hash verification failed")}
-				return
-			}
-			if cert.Timeout != timeout {
-				incFailureMetric()
-				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, fmt.Errorf("timeout was %d, expected %d", cert.Timeout, timeout)}
-				return
 			}
-
-			metrics.GetOrRegisterCounter(metricWithServiceName+"/success/total", nil).Inc(1)
-			metrics.GetOrRegisterCounter(metricBase+"/success/all/total", nil).Inc(1)
-			responses <- storeResponse{d, cert.Sig, nil}
-		}(ctx, d)
+		}(ctx, i, d)
 	}
 
 	var aggCert arbstate.DataAvailabilityCertificate
@@ -249,6 +311,7 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 		pubKeys        []blsSignatures.PublicKey
 		sigs           []blsSignatures.Signature
 		aggSignersMask uint64
+		result         *StoreResult
 		err            error
 	}
 
@@ -260,16 +323,51 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 		var aggSignersMask uint64
 		var storeFailures, successfullyStoredCount int
 		var returned bool
+		results := make([]*BackendStoreResult, len(a.services))
+		snapshot := func() *StoreResult {
+			result := &StoreResult{}
+			for i, r := range results {
+				switch {
+				case r == nil:
+					result.Pending = append(result.Pending, BackendStoreResult{Backend: a.services[i].metricName, SignersMask: a.services[i].signersMask})
+				case r.Err == nil:
+					result.Signed = append(result.Signed, *r)
+				default:
+					result.Failed = append(result.Failed, *r)
+				}
+			}
+			return result
+		}
+		decide := func(cd certDetails) {
+			cd.result = snapshot()
+			certDetailsChan <- cd
+			close(decided)
+			returned = true
+		}
 		for i := 0; i < len(a.services); i++ {
 
 			select {
 			case <-ctx.Done():
-				break
+				if !returned {
+					decide(certDetails{err: fmt.Errorf("aggregator store canceled with %d of %d required DASes stored: %w", successfullyStoredCount, a.requiredServicesForStore, ctx.Err())})
+				}
+				log.Warn("das.Aggregator: store finished", "dataHash", expectedHash, "result", snapshot().String(), "err", ctx.Err())
+				return
 			case r := <-responses:
+				results[r.index] = &BackendStoreResult{
+					Backend:     r.details.metricName,
+					SignersMask: r.details.signersMask,
+					Attempts:    r.attempts,
+					Reason:      r.reason,
+					Err:         r.err,
+				}
 				if r.err != nil {
 					storeFailures++
-					log.Warn("das.Aggregator: Error from backend", "backend", r.details.service, "signerMask", r.details.signersMask, "err", r.err)
+					log.Warn("das.Aggregator: Error from backend", "backend", r.details.service, "signerMask", r.details.signersMask, "attempts", r.attempts, "reason", r.reason, "late", returned, "err", r.err)
 				} else {
+					if returned {
+						log.Info("das.Aggregator: late response from backend", "backend", r.details.service, "signerMask", r.details.signersMask, "attempts", r.attempts)
+					}
 					pubKeys = append(pubKeys, r.details.pubKey)
 					sigs = append(sigs, r.sig)
 					aggSignersMask |= r.details.signersMask
@@ -288,27 +386,33 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 					cd.pubKeys = append(cd.pubKeys, pubKeys...)
 					cd.sigs = append(cd.sigs, sigs...)
 					cd.aggSignersMask = aggSignersMask
-					certDetailsChan <- cd
-					returned = true
+					decide(cd)
+					metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/quorum/success/total", nil).Inc(1)
 					if a.maxAllowedServiceStoreFailures > 0 && // Ignore the case where AssumedHonest = 1, probably a testnet
 						storeFailures+1 > a.maxAllowedServiceStoreFailures {
 						log.Error("das.Aggregator: storing the batch data succeeded to enough DAS commitee members to generate the Data Availability Cert, but if one more had failed then the cert would not have been able to be generated. Look for preceding logs with \"Error from backend\"")
 					}
 				} else if storeFailures > a.maxAllowedServiceStoreFailures {
 					cd := certDetails{}
-					cd.err = fmt.Errorf("aggregator failed to store message to at least %d out of %d DASes (assuming %d are honest). %w", a.requiredServicesForStore, len(a.services), a.config.AssumedHonest, BatchToDasFailed)
-					certDetailsChan <- cd
-					returned = true
+					cd.err = fmt.Errorf("aggregator failed to store message to at least %d out of %d DASes (assuming %d are honest)", a.requiredServicesForStore, len(a.services), a.config.AssumedHonest)
+					if ctx.Err() != nil {
+						// the backends may have failed because the store was canceled
+						cd.err = fmt.Errorf("%v: %w", cd.err, ctx.Err())
+					}
+					decide(cd)
+					metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/quorum/failure/total", nil).Inc(1)
 				}
 			}
 
 		}
+		metrics.GetOrRegisterGauge(aggregatorStoreMetricBase+"/signed/last", nil).Update(int64(successfullyStoredCount))
+		log.Info("das.Aggregator: store finished", "dataHash", expectedHash, "result", snapshot().String())
 	}()
 
 	cd := <-certDetailsChan
 
 	if cd.err != nil {
-		return nil, cd.err
+		return nil, cd.result, &StoreFailedError{Result: cd.result, cause: cd.err}
 	}
 
 	aggCert.Sig = blsSignatures.AggregateSignatures(cd.sigs)
@@ -323,12 +427,66 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 	verified, err := blsSignatures.VerifySignature(aggCert.Sig, aggCert.SerializeSignableFields(), aggPubKey)
 	if err != nil {
 		//nolint:errorlint
-		return nil, fmt.Errorf("%s. %w", err.Error(), BatchToDasFailed)
+		return nil, cd.result, fmt.Errorf("%s. %w", err.Error(), BatchToDasFailed)
 	}
 	if !verified {
-		return nil, fmt.Errorf("failed aggregate signature check. %w", BatchToDasFailed)
+		return nil, cd.result, fmt.Errorf("failed aggregate signature check. %w", BatchToDasFailed)
 	}
-	return &aggCert, nil
+	return &aggCert, cd.result, nil
+}
+
+const aggregatorStoreMetricBase string = "arb/das/rpc/aggregator/store"
+
+// storeToBackend makes a single store request to a backend and checks its
+// response, returning the backend's signature or the error and its category.
+func (a *Aggregator) storeToBackend(
+	ctx context.Context, d ServiceDetails, message []byte, timeout uint64, sig []byte, expectedHash common.Hash,
+) (blsSignatures.Signature, string, error) {
+	storeCtx, cancel := context.WithTimeout(ctx, a.requestTimeout)
+	var metricWithServiceName = aggregatorStoreMetricBase + "/" + d.metricName
+	defer cancel()
+	incFailureMetric := func(reason string) {
+		metrics.GetOrRegisterCounter(metricWithServiceName+"/error/total", nil).Inc(1)
+		metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/error/all/total", nil).Inc(1)
+		metrics.GetOrRegisterCounter(metricWithServiceName+"/error/"+reason+"/total", nil).Inc(1)
+	}
+
+	cert, err := d.service.Store(storeCtx, message, timeout, sig)
+	if err != nil {
+		reason := "client"
+		if errors.Is(err, context.DeadlineExceeded) {
+			reason = "timeout"
+		}
+		incFailureMetric(reason)
+		return nil, reason, err
+	}
+
+	verified, err := blsSignatures.VerifySignature(
+		cert.Sig, cert.SerializeSignableFields(), d.pubKey,
+	)
+	if err != nil {
+		incFailureMetric("bad_response")
+		return nil, "bad_response", err
+	}
+	if !verified {
+		incFailureMetric("bad_response")
+		return nil, "bad_response", errors.New("signature verification failed")
+	}
+
+	// SignersMask from backend DAS is ignored.
+
+	if cert.DataHash != expectedHash {
+		incFailureMetric("bad_response")
+		return nil, "bad_response", errors.New("hash verification failed")
+	}
+	if cert.Timeout != timeout {
+		incFailureMetric("bad_response")
+		return nil, "bad_response", fmt.Errorf("timeout was %d, expected %d", cert.Timeout, timeout)
+	}
+
+	metrics.GetOrRegisterCounter(metricWithServiceName+"/success/total", nil).Inc(1)
+	metrics.GetOrRegisterCounter(aggregatorStoreMetricBase+"/success/all/total", nil).Inc(1)
+	return cert.Sig, "", nil
 }
 
 func (a *Aggregator) String() string {
@@ -288,3 +288,97 @@ func TestDAS_AtLeastHStorageFailures(t *testing.T) {
 		})
 	}
 }
+
+// Fails each backend's first few Store requests, then succeeds.
+type failFirstAttempts struct {
+	mutex    sync.Mutex
+	failures int
+}
+
+func (f *failFirstAttempts) shouldFail() failureType {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return immediateError
+	}
+	return success
+}
+
+func TestDAS_AggregatorRetriesFailingBackends(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numBackendDAS := 3
+	failures := []int{0, 2, 1000}
+	var backends []ServiceDetails
+	for i := 0; i < numBackendDAS; i++ {
+		privKey, err := blsSignatures.GeneratePrivKeyString()
+		Require(t, err)
+		config := DataAvailabilityConfig{
+			Enable: true,
+			Key: KeyConfig{
+				PrivKey: privKey,
+			},
+			ParentChainNodeURL: "none",
+		}
+		das, err := NewSignAfterStoreDASWriter(ctx, config, NewMemoryBackedStorageService(ctx))
+		Require(t, err)
+		injector := &failFirstAttempts{failures: failures[i]}
+		details, err := NewServiceDetails(&WrapStore{t, injector, das}, *das.pubKey, uint64(1<<i), "service"+strconv.Itoa(i))
+		Require(t, err)
+		backends = append(backends, *details)
+	}
+
+	newAggregator := func(assumedHonest int) *Aggregator {
+		aggregator, err := NewAggregator(
+			ctx,
+			DataAvailabilityConfig{
+				RPCAggregator: AggregatorConfig{
+					AssumedHonest:       assumedHonest,
+					RetryDeadline:       500 * time.Millisecond,
+					RetryInitialBackoff: 10 * time.Millisecond,
+					RetryMaxBackoff:     50 * time.Millisecond,
+				},
+				ParentChainNodeURL: "none",
+				RequestTimeout:     time.Second,
+			}, backends)
+		Require(t, err)
+		return aggregator
+	}
+
+	// K=2, so the retried second backend makes up the quorum.
+	rawMsg := []byte("It's time for you to see the fnords.")
+	cert, result, err := newAggregator(2).StoreWithResult(ctx, rawMsg, 0, []byte{})
+	Require(t, err, "Error storing message")
+	if cert.SignersMask != 3 || result.SignersMask() != 3 {
+		Fail(t, "unexpected signers", cert.SignersMask, result.String())
+	}
+	for _, signed := range result.Signed {
+		if signed.Backend == "service1" && signed.Attempts != 3 {
+			Fail(t, "expected the second backend to sign on its third attempt, got", signed.Attempts)
+		}
+	}
+
+	// K=3 can't be reached because the last backend never succeeds.
+	_, result, err = newAggregator(1).StoreWithResult(ctx, rawMsg, 0, []byte{})
+	if !errors.Is(err, BatchToDasFailed) {
+		Fail(t, "expected BatchToDasFailed, got", err)
+	}
+	var storeErr *StoreFailedError
+	if !errors.As(err, &storeErr) || storeErr.Result != result {
+		Fail(t, "expected a StoreFailedError holding the result, got", err)
+	}
+	if len(result.Signed) != 2 || len(result.Failed) != 1 || result.Failed[0].Backend != "service2" || result.Failed[0].Reason != "client" || result.Failed[0].Attempts < 2 {
+		Fail(t, "unexpected store result", result.String())
+	}
+}
+
+func TestDAS_StoreFailedErrorKeepsCause(t *testing.T) {
+	for _, cause := range []error{context.Canceled, context.DeadlineExceeded} {
+		err := error(&StoreFailedError{Result: &StoreResult{}, cause: fmt.Errorf("aggregator store canceled: %w", cause)})
+		if !errors.Is(err, BatchToDasFailed) || !errors.Is(err, cause) {
+			Fail(t, "store failure doesn't wrap both BatchToDasFailed and", cause, err)
+		}
+	}
+}
@@ -43,11 +43,14 @@ type DataAvailabilityConfig struct {
 	LocalCache BigCacheConfig `koanf:"local-cache"`
 	RedisCache RedisConfig    `koanf:"redis-cache"`
 
-	LocalDBStorage     LocalDBStorageConfig     `koanf:"local-db-storage"`
-	LocalFileStorage   LocalFileStorageConfig   `koanf:"local-file-storage"`
-	S3Storage          S3StorageServiceConfig   `koanf:"s3-storage"`
-	IpfsStorage        IpfsStorageServiceConfig `koanf:"ipfs-storage"`
-	RegularSyncStorage RegularSyncStorageConfig `koanf:"regular-sync-storage"`
+	LocalDBStorage      LocalDBStorageConfig      `koanf:"local-db-storage"`
+	LocalFileStorage    LocalFileStorageConfig    `koanf:"local-file-storage"`
+	S3Storage           S3StorageServiceConfig    `koanf:"s3-storage"`
+	RemoteStorage       RemoteStorageConfig       `koanf:"remote-storage"`
+	IpfsStorage         IpfsStorageServiceConfig  `koanf:"ipfs-storage"`
+	ErasureCodedStorage ErasureCodedStorageConfig `koanf:"erasure-coded-storage"`
+	RegularSyncStorage  RegularSyncStorageConfig  `koanf:"regular-sync-storage"`
+	Scrubber            ScrubberConfig            `koanf:"scrubber"`
 
 	Key KeyConfig `koanf:"key"`
 
@@ -70,6 +73,8 @@ var DefaultDataAvailabilityConfig = DataAvailabilityConfig{
 	ParentChainConnectionAttempts: 15,
 	PanicOnError:                  false,
 	IpfsStorage:                   DefaultIpfsStorageServiceConfig,
+	Scrubber:                      DefaultScrubberConfig,
+	ErasureCodedStorage:           DefaultErasureCodedStorageConfig,
 }
 
 func OptionalAddressFromString(s string) (*common.Address, error) {
@@ -116,7 +121,10 @@ func dataAvailabilityConfigAddOptions(prefix string, f *flag.FlagSet, r role) {
 		LocalDBStorageConfigAddOptions(prefix+".local-db-storage", f)
 		LocalFileStorageConfigAddOptions(prefix+".local-file-storage", f)
 		S3ConfigAddOptions(prefix+".s3-storage", f)
+		RemoteStorageConfigAddOptions(prefix+".remote-storage", f)
+		ErasureCodedStorageConfigAddOptions(prefix+".erasure-coded-storage", f)
 		RegularSyncStorageConfigAddOptions(prefix+".regular-sync-storage", f)
+		ScrubberConfigAddOptions(prefix+".scrubber", f)
 
 		// Key config for storage
 		KeyConfigAddOptions(prefix+".key", f)
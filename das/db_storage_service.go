@@ -110,9 +110,12 @@ func (dbs *DBStorageService) GetByHash(ctx context.Context, key common.Hash) ([]
 
 func (dbs *DBStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.DBStorageService.Put", data, timeout, dbs)
+	return dbs.putKeyValueWithExpiry(ctx, dastree.Hash(data), data, timeout)
+}
 
+func (dbs *DBStorageService) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, timeout uint64) error {
 	return dbs.db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry(dastree.HashBytes(data), data)
+		e := badger.NewEntry(key.Bytes(), value)
 		if dbs.discardAfterTimeout {
 			e = e.WithTTL(time.Until(time.Unix(int64(timeout), 0)))
 		}
@@ -0,0 +1,385 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/klauspost/reedsolomon"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/pretty"
+)
+
+var erasureReachableShardsGauge = metrics.NewRegisteredGauge("arb/das/erasure/shards/reachable", nil)
+
+type ErasureCodedStorageConfig struct {
+	Enable       bool     `koanf:"enable"`
+	DataShards   int      `koanf:"data-shards"`
+	ParityShards int      `koanf:"parity-shards"`
+	WriteQuorum  int      `koanf:"write-quorum"`
+	LocalDirs    []string `koanf:"local-dirs"`
+	S3Buckets    []string `koanf:"s3-buckets"`
+}
+
+var DefaultErasureCodedStorageConfig = ErasureCodedStorageConfig{
+	Enable:       false,
+	DataShards:   4,
+	ParityShards: 2,
+	WriteQuorum:  0,
+	LocalDirs:    []string{},
+	S3Buckets:    []string{},
+}
+
+// erasureMaxShards is the most shards a header can describe, as shard counts are stored in single bytes.
+const erasureMaxShards = 255
+
+func (c *ErasureCodedStorageConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.DataShards <= 0 || c.ParityShards < 0 {
+		return fmt.Errorf("erasure coded storage needs a positive number of data shards and no negative parity shards, got %d and %d", c.DataShards, c.ParityShards)
+	}
+	if c.DataShards+c.ParityShards > erasureMaxShards {
+		return fmt.Errorf("erasure coded storage supports at most %d shards, got %d", erasureMaxShards, c.DataShards+c.ParityShards)
+	}
+	if len(c.LocalDirs)+len(c.S3Buckets) == 0 {
+		return errors.New("erasure coded storage requires at least one of local-dirs or s3-buckets")
+	}
+	return nil
+}
+
+func ErasureCodedStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultErasureCodedStorageConfig.Enable, "enable storage/retrieval of sequencer batch data as Reed-Solomon shards spread over several backends, any data-shards of which are enough to recover the data")
+	f.Int(prefix+".data-shards", DefaultErasureCodedStorageConfig.DataShards, "number of shards the data is split into; this many shards are needed to recover it")
+	f.Int(prefix+".parity-shards", DefaultErasureCodedStorageConfig.ParityShards, "number of extra parity shards; this many shards can be lost without losing data")
+	f.Int(prefix+".write-quorum", DefaultErasureCodedStorageConfig.WriteQuorum, "number of shards that must be written for a store to succeed, must be at least data-shards (0 means all shards)")
+	f.StringSlice(prefix+".local-dirs", DefaultErasureCodedStorageConfig.LocalDirs, "directories to store shards in, one backend per directory, using the expiry settings of local-file-storage")
+	f.StringSlice(prefix+".s3-buckets", DefaultErasureCodedStorageConfig.S3Buckets, "S3 buckets to store shards in, one backend per bucket, using the credentials, region and expiry settings of s3-storage")
+}
+
+const erasureShardKeyPrefix = "erasure_shard_"
+
+// Every shard is stored with a header so that it can be checked against the others.
+//
+//	version (1 byte) | index (1 byte) | data shards (1 byte) | parity shards (1 byte) | data size (8 bytes)
+const (
+	erasureShardVersion    = 1
+	erasureShardHeaderSize = 12
+)
+
+// ErasureCodedStorageService splits each piece of data into DataShards shards,
+// adds ParityShards parity shards, and stores shard i on backend i modulo the
+// number of backends. Data can be recovered from any DataShards shards.
+//
+// Shards are stored under keys derived from the data hash, so the backends
+// must be able to store a value under a given key with an expiry, which they
+// then honor according to their own expiration policy.
+type ErasureCodedStorageService struct {
+	backends     []expiringKeyValueStorageService
+	encoder      reedsolomon.Encoder
+	dataShards   int
+	parityShards int
+	writeQuorum  int
+}
+
+func NewErasureCodedStorageService(backends []StorageService, dataShards, parityShards, writeQuorum int) (*ErasureCodedStorageService, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("erasure coded storage requires at least one backend")
+	}
+	if dataShards+parityShards > erasureMaxShards {
+		return nil, fmt.Errorf("erasure coded storage supports at most %d shards", erasureMaxShards)
+	}
+	totalShards := dataShards + parityShards
+	if writeQuorum == 0 {
+		writeQuorum = totalShards
+	}
+	if writeQuorum < dataShards || writeQuorum > totalShards {
+		return nil, fmt.Errorf("erasure coded storage write quorum must be between %d and %d, got %d", dataShards, totalShards, writeQuorum)
+	}
+	encoder, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	keyValueBackends := make([]expiringKeyValueStorageService, 0, len(backends))
+	for _, backend := range backends {
+		keyValueBackend, ok := backend.(expiringKeyValueStorageService)
+		if !ok {
+			return nil, fmt.Errorf("%v can't be used as an erasure coded storage backend", backend)
+		}
+		keyValueBackends = append(keyValueBackends, keyValueBackend)
+	}
+	if len(backends) < totalShards {
+		log.Warn("fewer erasure coded storage backends than shards, so losing one backend loses several shards", "backends", len(backends), "shards", totalShards)
+	}
+	return &ErasureCodedStorageService{
+		backends:     keyValueBackends,
+		encoder:      encoder,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		writeQuorum:  writeQuorum,
+	}, nil
+}
+
+func (e *ErasureCodedStorageService) totalShards() int {
+	return e.dataShards + e.parityShards
+}
+
+func (e *ErasureCodedStorageService) backendFor(shard int) expiringKeyValueStorageService {
+	return e.backends[shard%len(e.backends)]
+}
+
+func erasureShardKey(key common.Hash, shard int) common.Hash {
+	return dastree.Hash([]byte(erasureShardKeyPrefix + strconv.Itoa(shard) + "_" + EncodeStorageServiceKey(key)))
+}
+
+func (e *ErasureCodedStorageService) encodeShards(data []byte) ([][]byte, error) {
+	// The encoder can't split empty data, the size in the header lets us drop the padding.
+	padded := data
+	if len(padded) == 0 {
+		padded = []byte{0}
+	}
+	shards, err := e.encoder.Split(padded)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.encoder.Encode(shards); err != nil {
+		return nil, err
+	}
+	encoded := make([][]byte, len(shards))
+	for i, shard := range shards {
+		buf := make([]byte, erasureShardHeaderSize, erasureShardHeaderSize+len(shard))
+		buf[0] = erasureShardVersion
+		buf[1] = byte(i)
+		buf[2] = byte(e.dataShards)
+		buf[3] = byte(e.parityShards)
+		binary.BigEndian.PutUint64(buf[4:12], uint64(len(data)))
+		encoded[i] = append(buf, shard...)
+	}
+	return encoded, nil
+}
+
+// decodeShard checks a stored shard's header and returns its contents and the size of the original data.
+func (e *ErasureCodedStorageService) decodeShard(shard int, stored []byte) ([]byte, uint64, error) {
+	if len(stored) < erasureShardHeaderSize {
+		return nil, 0, fmt.Errorf("erasure coded shard %d is too short", shard)
+	}
+	if stored[0] != erasureShardVersion || int(stored[1]) != shard || int(stored[2]) != e.dataShards || int(stored[3]) != e.parityShards {
+		return nil, 0, fmt.Errorf("erasure coded shard %d has an unexpected header %v", shard, stored[:4])
+	}
+	return stored[erasureShardHeaderSize:], binary.BigEndian.Uint64(stored[4:12]), nil
+}
+
+func (e *ErasureCodedStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	log.Trace("das.ErasureCodedStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", e)
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type shardResponse struct {
+		shard int
+		data  []byte
+		err   error
+	}
+	responses := make(chan shardResponse, e.totalShards())
+	for i := 0; i < e.totalShards(); i++ {
+		go func(shard int) {
+			data, err := e.backendFor(shard).GetByHash(subCtx, erasureShardKey(key, shard))
+			responses <- shardResponse{shard, data, err}
+		}(i)
+	}
+
+	shards := make([][]byte, e.totalShards())
+	var dataSize uint64
+	found := 0
+	var anyError error
+	for pending := e.totalShards(); pending > 0 && found < e.dataShards; pending-- {
+		var resp shardResponse
+		select {
+		case resp = <-responses:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if resp.err != nil {
+			anyError = resp.err
+			continue
+		}
+		shard, size, err := e.decodeShard(resp.shard, resp.data)
+		if err != nil {
+			log.Warn("ignoring bad erasure coded shard", "key", pretty.PrettyHash(key), "err", err)
+			anyError = err
+			continue
+		}
+		if found > 0 && size != dataSize {
+			log.Warn("ignoring erasure coded shard with inconsistent size", "key", pretty.PrettyHash(key), "shard", resp.shard)
+			continue
+		}
+		shards[resp.shard] = shard
+		dataSize = size
+		found++
+	}
+	if found < e.dataShards {
+		if found == 0 && (anyError == nil || errors.Is(anyError, ErrNotFound)) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("only found %d of the %d erasure coded shards needed for %v: %w", found, e.dataShards, key, anyError)
+	}
+
+	if err := e.encoder.ReconstructData(shards); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := e.encoder.Join(&buf, shards, int(dataSize)); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	if dastree.Hash(data) != key {
+		return nil, fmt.Errorf("erasure coded data for %v failed hash verification", key)
+	}
+	return data, nil
+}
+
+func (e *ErasureCodedStorageService) Put(ctx context.Context, data []byte, expirationTime uint64) error {
+	logPut("das.ErasureCodedStorageService.Store", data, expirationTime, e)
+	return e.putShards(ctx, dastree.Hash(data), data, func(backend expiringKeyValueStorageService, shardKey common.Hash, shard []byte) error {
+		return backend.putKeyValueWithExpiry(ctx, shardKey, shard, expirationTime)
+	})
+}
+
+func (e *ErasureCodedStorageService) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error {
+	return e.putShards(ctx, key, value, func(backend expiringKeyValueStorageService, shardKey common.Hash, shard []byte) error {
+		return backend.putKeyValueWithExpiry(ctx, shardKey, shard, expirationTime)
+	})
+}
+
+func (e *ErasureCodedStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	return e.putShards(ctx, key, value, func(backend expiringKeyValueStorageService, shardKey common.Hash, shard []byte) error {
+		return backend.putKeyValue(ctx, shardKey, shard)
+	})
+}
+
+func (e *ErasureCodedStorageService) putShards(ctx context.Context, key common.Hash, value []byte, put func(expiringKeyValueStorageService, common.Hash, []byte) error) error {
+	shards, err := e.encodeShards(value)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	var errorMutex sync.Mutex
+	var errs []error
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard []byte) {
+			defer wg.Done()
+			if err := put(e.backendFor(i), erasureShardKey(key, i), shard); err != nil {
+				errorMutex.Lock()
+				errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+				errorMutex.Unlock()
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+	written := len(shards) - len(errs)
+	if written < e.writeQuorum {
+		return fmt.Errorf("only wrote %d of the %d erasure coded shards required: %w", written, e.writeQuorum, errors.Join(errs...))
+	}
+	if len(errs) > 0 {
+		log.Warn("failed to write some erasure coded shards", "key", pretty.PrettyHash(key), "written", written, "err", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (e *ErasureCodedStorageService) Sync(ctx context.Context) error {
+	for _, backend := range e.backends {
+		if err := backend.Sync(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ErasureCodedStorageService) Close(ctx context.Context) error {
+	var errs []error
+	for _, backend := range e.backends {
+		if err := backend.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExpirationPolicy is the policy under which DataShards shards are still kept,
+// since the data can be recovered until fewer than that are left.
+func (e *ErasureCodedStorageService) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	// Count the shards kept for at least as long as each policy, from the longest lived down.
+	longestFirst := []arbstate.ExpirationPolicy{arbstate.KeepForever, arbstate.DiscardAfterArchiveTimeout, arbstate.DiscardAfterDataTimeout}
+	kept := make(map[arbstate.ExpirationPolicy]int)
+	for shard := 0; shard < e.totalShards(); shard++ {
+		policy, err := e.backendFor(shard).ExpirationPolicy(ctx)
+		if err != nil {
+			return -1, err
+		}
+		kept[policy]++
+	}
+	shards := 0
+	for _, policy := range longestFirst {
+		shards += kept[policy]
+		if shards >= e.dataShards {
+			return policy, nil
+		}
+	}
+	return -1, errors.New("unknown expiration policy")
+}
+
+func (e *ErasureCodedStorageService) String() string {
+	backendNames := make([]string, 0, len(e.backends))
+	for _, backend := range e.backends {
+		backendNames = append(backendNames, backend.String())
+	}
+	return fmt.Sprintf("ErasureCodedStorageService(%d+%d:%s)", e.dataShards, e.parityShards, strings.Join(backendNames, ","))
+}
+
+// HealthCheck fails if fewer than DataShards shards are on healthy backends,
+// since then nothing could be read back. Losing only parity shards is logged.
+func (e *ErasureCodedStorageService) HealthCheck(ctx context.Context) error {
+	healthy := make([]bool, len(e.backends))
+	var wg sync.WaitGroup
+	for i, backend := range e.backends {
+		wg.Add(1)
+		go func(i int, backend StorageService) {
+			defer wg.Done()
+			if err := backend.HealthCheck(ctx); err != nil {
+				log.Warn("erasure coded storage backend failed health check", "backend", backend, "err", err)
+				return
+			}
+			healthy[i] = true
+		}(i, backend)
+	}
+	wg.Wait()
+	reachable := 0
+	for shard := 0; shard < e.totalShards(); shard++ {
+		if healthy[shard%len(e.backends)] {
+			reachable++
+		}
+	}
+	erasureReachableShardsGauge.Update(int64(reachable))
+	if reachable < e.dataShards {
+		return fmt.Errorf("only %d of %d erasure coded shards are reachable, %d are needed", reachable, e.totalShards(), e.dataShards)
+	}
+	if reachable < e.totalShards() {
+		log.Warn("erasure coded storage is degraded", "reachable", reachable, "shards", e.totalShards())
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestErasureCodedStorageService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backends := make([]StorageService, 6)
+	for i := range backends {
+		backends[i] = NewMemoryBackedStorageService(ctx)
+	}
+	storageService, err := NewErasureCodedStorageService(backends, 4, 2, 0)
+	Require(t, err)
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	values := [][]byte{
+		[]byte("The first value, which is long enough to be split over several shards"),
+		[]byte("x"),
+		{},
+	}
+	for _, value := range values {
+		Require(t, storageService.Put(ctx, value, timeout))
+	}
+
+	_, err = storageService.GetByHash(ctx, dastree.Hash([]byte("absent value")))
+	if !errors.Is(err, ErrNotFound) {
+		Fail(t, "expected ErrNotFound, got", err)
+	}
+
+	checkValues := func() {
+		t.Helper()
+		for _, value := range values {
+			res, err := storageService.GetByHash(ctx, dastree.Hash(value))
+			Require(t, err)
+			if !bytes.Equal(res, value) {
+				Fail(t, "unexpected value", res, value)
+			}
+		}
+	}
+	checkValues()
+
+	// Losing as many backends as there are parity shards still leaves enough shards.
+	Require(t, backends[0].Close(ctx))
+	Require(t, backends[4].Close(ctx))
+	checkValues()
+
+	Require(t, backends[2].Close(ctx))
+	if _, err := storageService.GetByHash(ctx, dastree.Hash(values[0])); err == nil {
+		Fail(t, "expected an error with too few shards available")
+	}
+	if err := storageService.Put(ctx, []byte("new value"), timeout); err == nil {
+		Fail(t, "expected store to fail without a full write quorum")
+	}
+}
+
+func TestErasureCodedStorageServiceExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var backends []StorageService
+	var localBackends []*LocalFileStorageService
+	for i := 0; i < 3; i++ {
+		s, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+			DataDir:       t.TempDir(),
+			EnableExpiry:  true,
+			PruneInterval: time.Hour,
+		})
+		Require(t, err)
+		defer s.Close(ctx)
+		backends = append(backends, s)
+		localBackends = append(localBackends, s.(*LocalFileStorageService))
+	}
+	storageService, err := NewErasureCodedStorageService(backends, 2, 1, 0)
+	Require(t, err)
+	policy, err := storageService.ExpirationPolicy(ctx)
+	Require(t, err)
+	if policy != arbstate.DiscardAfterDataTimeout {
+		Fail(t, "unexpected expiration policy", policy)
+	}
+
+	now := uint64(time.Now().Unix())
+	value := []byte("erasure coded value that expires")
+	Require(t, storageService.Put(ctx, value, now+10))
+	_, err = storageService.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+
+	for _, s := range localBackends {
+		Require(t, s.pruneExpired(ctx, now+2*expiryBucketSeconds))
+	}
+	if _, err := storageService.GetByHash(ctx, dastree.Hash(value)); !errors.Is(err, ErrNotFound) {
+		Fail(t, "expected expired shards to be pruned, got", err)
+	}
+}
+
+func TestErasureCodedStorageConfigValidate(t *testing.T) {
+	config := DefaultErasureCodedStorageConfig
+	config.Enable = true
+	config.LocalDirs = []string{t.TempDir()}
+	Require(t, config.Validate())
+
+	config.DataShards = 200
+	config.ParityShards = 56
+	if err := config.Validate(); err == nil {
+		Fail(t, "accepted more shards than fit in a shard header")
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
@@ -34,28 +36,30 @@ func CreatePersistentStorageService(
 		}
 		if config.LocalDBStorage.SyncFromStorageService {
 			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+			iterableStorageService.stateKey = "local-db-storage:" + config.LocalDBStorage.DataDir
 			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
 			s = iterableStorageService
 		}
 		if config.LocalDBStorage.SyncToStorageService {
-			*syncToStorageServices = append(*syncToStorageServices, s)
+			*syncToStorageServices = append(*syncToStorageServices, withSyncStateKey(s, "local-db-storage:"+config.LocalDBStorage.DataDir))
 		}
 		lifecycleManager.Register(s)
 		storageServices = append(storageServices, s)
 	}
 
 	if config.LocalFileStorage.Enable {
-		s, err := NewLocalFileStorageService(config.LocalFileStorage.DataDir)
+		s, err := NewLocalFileStorageService(ctx, config.LocalFileStorage)
 		if err != nil {
 			return nil, nil, err
 		}
 		if config.LocalFileStorage.SyncFromStorageService {
 			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+			iterableStorageService.stateKey = "local-file-storage:" + config.LocalFileStorage.DataDir
 			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
 			s = iterableStorageService
 		}
 		if config.LocalFileStorage.SyncToStorageService {
-			*syncToStorageServices = append(*syncToStorageServices, s)
+			*syncToStorageServices = append(*syncToStorageServices, withSyncStateKey(s, "local-file-storage:"+config.LocalFileStorage.DataDir))
 		}
 		lifecycleManager.Register(s)
 		storageServices = append(storageServices, s)
@@ -69,15 +73,44 @@ func CreatePersistentStorageService(
 		lifecycleManager.Register(s)
 		if config.S3Storage.SyncFromStorageService {
 			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+			iterableStorageService.stateKey = s3SyncStateKey(&config.S3Storage)
 			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
 			s = iterableStorageService
 		}
 		if config.S3Storage.SyncToStorageService {
-			*syncToStorageServices = append(*syncToStorageServices, s)
+			*syncToStorageServices = append(*syncToStorageServices, withSyncStateKey(s, s3SyncStateKey(&config.S3Storage)))
 		}
 		storageServices = append(storageServices, s)
 	}
 
+	if config.RemoteStorage.Enable {
+		remote, err := NewRemoteStorageService(config.RemoteStorage)
+		if err != nil {
+			return nil, nil, err
+		}
+		lifecycleManager.Register(remote)
+		var s StorageService = remote
+		if config.RemoteStorage.SyncFromStorageService {
+			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+			iterableStorageService.stateKey = "remote-storage:" + config.RemoteStorage.URL
+			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
+			s = iterableStorageService
+		}
+		if config.RemoteStorage.SyncToStorageService {
+			*syncToStorageServices = append(*syncToStorageServices, withSyncStateKey(s, "remote-storage:"+config.RemoteStorage.URL))
+		}
+		storageServices = append(storageServices, s)
+	}
+
+	if config.ErasureCodedStorage.Enable {
+		s, err := createErasureCodedStorageService(ctx, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		lifecycleManager.Register(s)
+		storageServices = append(storageServices, s)
+	}
+
 	if config.IpfsStorage.Enable {
 		s, err := NewIpfsStorageService(ctx, config.IpfsStorage)
 		if err != nil {
@@ -101,6 +134,47 @@ func CreatePersistentStorageService(
 	return nil, &lifecycleManager, nil
 }
 
+func createErasureCodedStorageService(ctx context.Context, config *DataAvailabilityConfig) (StorageService, error) {
+	ecConfig := &config.ErasureCodedStorage
+	if err := ecConfig.Validate(); err != nil {
+		return nil, err
+	}
+	backends := make([]StorageService, 0, len(ecConfig.LocalDirs)+len(ecConfig.S3Buckets))
+	closeBackends := func() {
+		for _, backend := range backends {
+			if err := backend.Close(ctx); err != nil {
+				log.Warn("Failed to close erasure coded storage backend", "backend", backend, "err", err)
+			}
+		}
+	}
+	for _, dir := range ecConfig.LocalDirs {
+		localFileConfig := config.LocalFileStorage
+		localFileConfig.DataDir = dir
+		s, err := NewLocalFileStorageService(ctx, localFileConfig)
+		if err != nil {
+			closeBackends()
+			return nil, err
+		}
+		backends = append(backends, s)
+	}
+	for _, bucket := range ecConfig.S3Buckets {
+		s3Config := config.S3Storage
+		s3Config.Bucket = bucket
+		s, err := NewS3StorageService(s3Config)
+		if err != nil {
+			closeBackends()
+			return nil, err
+		}
+		backends = append(backends, s)
+	}
+	s, err := NewErasureCodedStorageService(backends, ecConfig.DataShards, ecConfig.ParityShards, ecConfig.WriteQuorum)
+	if err != nil {
+		closeBackends()
+		return nil, err
+	}
+	return s, nil
+}
+
 func WrapStorageWithCache(
 	ctx context.Context,
 	config *DataAvailabilityConfig,
@@ -122,11 +196,12 @@ func WrapStorageWithCache(
 		}
 		if config.RedisCache.SyncFromStorageService {
 			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(storageService))
+			iterableStorageService.stateKey = "redis-cache:" + config.RedisCache.Url
 			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
 			storageService = iterableStorageService
 		}
 		if config.RedisCache.SyncToStorageService {
-			*syncToStorageServices = append(*syncToStorageServices, storageService)
+			*syncToStorageServices = append(*syncToStorageServices, withSyncStateKey(storageService, "redis-cache:"+config.RedisCache.Url))
 		}
 	}
 	if config.LocalCache.Enable {
@@ -203,8 +278,10 @@ func CreateDAComponentsForDaserver(
 	if !config.LocalDBStorage.Enable &&
 		!config.LocalFileStorage.Enable &&
 		!config.S3Storage.Enable &&
+		!config.RemoteStorage.Enable &&
+		!config.ErasureCodedStorage.Enable &&
 		!config.IpfsStorage.Enable {
-		return nil, nil, nil, nil, errors.New("At least one of --data-availability.(local-db-storage|local-file-storage|s3-storage|ipfs-storage) must be enabled.")
+		return nil, nil, nil, nil, errors.New("At least one of --data-availability.(local-db-storage|local-file-storage|s3-storage|remote-storage|erasure-coded-storage|ipfs-storage) must be enabled.")
 	}
 	// Done checking config requirements
 
@@ -299,6 +376,23 @@ func CreateDAComponentsForDaserver(
 	if config.RegularSyncStorage.Enable && len(syncFromStorageServices) != 0 && len(syncToStorageServices) != 0 {
 		regularlySyncStorage := NewRegularlySyncStorage(syncFromStorageServices, syncToStorageServices, config.RegularSyncStorage)
 		regularlySyncStorage.Start(ctx)
+		dasLifecycleManager.Register(regularlySyncStorage)
+	}
+
+	if config.Scrubber.Enable {
+		if len(syncFromStorageServices) == 0 {
+			return nil, nil, nil, nil, errors.New("--data-availability.scrubber.enable requires at least one storage type with sync-from-storage-service enabled")
+		}
+		for _, syncFrom := range syncFromStorageServices {
+			scrubber, err := NewScrubber(syncFrom, config.Scrubber)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			if err := scrubber.Start(ctx); err != nil {
+				return nil, nil, nil, nil, err
+			}
+			dasLifecycleManager.Register(scrubber)
+		}
 	}
 
 	if seqInboxAddress != nil {
@@ -383,3 +477,66 @@ func CreateDAReaderForNode(
 
 	return daReader, dasLifecycleManager, nil
 }
+
+// CreateBackfillStorageServices creates the two persistent storage services named by
+// fromName and toName (eg "local-db-storage"), using their sections of config, so that
+// data can be copied from one to the other once with RegularlySyncStorage.Backfill.
+func CreateBackfillStorageServices(
+	ctx context.Context,
+	config *DataAvailabilityConfig,
+	fromName string,
+	toName string,
+) (*IterableStorageService, StorageService, *LifecycleManager, error) {
+	if fromName == toName {
+		return nil, nil, nil, fmt.Errorf("can't backfill %s into itself", fromName)
+	}
+	var lifecycleManager LifecycleManager
+	syncFrom, err := CreateStorageServiceByName(ctx, config, fromName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lifecycleManager.Register(syncFrom)
+	syncTo, err := CreateStorageServiceByName(ctx, config, toName)
+	if err != nil {
+		lifecycleManager.StopAndWaitUntil(time.Second)
+		return nil, nil, nil, err
+	}
+	lifecycleManager.Register(syncTo)
+	return syncFrom, syncTo, &lifecycleManager, nil
+}
+
+// CreateStorageServiceByName creates the persistent storage service configured by the
+// named section of config, wrapped so that it can be iterated over.
+func CreateStorageServiceByName(ctx context.Context, config *DataAvailabilityConfig, name string) (*IterableStorageService, error) {
+	var s StorageService
+	var stateKey string
+	var err error
+	switch name {
+	case "local-db-storage":
+		s, err = NewDBStorageService(ctx, config.LocalDBStorage.DataDir, config.LocalDBStorage.DiscardAfterTimeout)
+		stateKey = "local-db-storage:" + config.LocalDBStorage.DataDir
+	case "local-file-storage":
+		s, err = NewLocalFileStorageService(ctx, config.LocalFileStorage)
+		stateKey = "local-file-storage:" + config.LocalFileStorage.DataDir
+	case "s3-storage":
+		s, err = NewS3StorageService(config.S3Storage)
+		stateKey = s3SyncStateKey(&config.S3Storage)
+	case "remote-storage":
+		s, err = NewRemoteStorageService(config.RemoteStorage)
+		stateKey = "remote-storage:" + config.RemoteStorage.URL
+	default:
+		return nil, fmt.Errorf("unknown storage service %#v, expected one of local-db-storage, local-file-storage, s3-storage or remote-storage", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Wrapping the destination too keeps it iterable, so it can be synced from later.
+	iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+	iterableStorageService.stateKey = stateKey
+	return iterableStorageService, nil
+}
+
+// s3SyncStateKey names an S3 storage service in the persisted sync state by where it stores its objects.
+func s3SyncStateKey(config *S3StorageServiceConfig) string {
+	return "s3-storage:" + config.Region + "/" + config.Bucket + "/" + config.ObjectPrefix
+}
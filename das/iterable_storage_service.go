@@ -26,6 +26,12 @@ type IterationCompatibleStorageService interface {
 	StorageService
 }
 
+// expiringKeyValueStorageService can also write a value under any key with an expiration time, like Put does under its hash.
+type expiringKeyValueStorageService interface {
+	IterationCompatibleStorageService
+	putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error
+}
+
 // IterationCompatibleStorageServiceAdaptor is an adaptor used to covert iteration incompatible StorageService
 // to IterationCompatibleStorageService (basically adds an empty putKeyValue to the StorageService)
 type IterationCompatibleStorageServiceAdaptor struct {
@@ -50,6 +56,8 @@ type IterableStorageService struct {
 	// Local copy of iterator end. End can also be accessed by getByHash for iteratorEnd.
 	end atomic.Value // atomic access to common.Hash
 	IterationCompatibleStorageService
+	// Names the service in the persisted state of RegularlySyncStorage, if set
+	stateKey string
 
 	mutex sync.Mutex
 }
@@ -114,6 +122,10 @@ func (i *IterableStorageService) GetExpirationTime(ctx context.Context, hash com
 	return expirationTime, nil
 }
 
+func (i *IterableStorageService) syncStateKey() string {
+	return i.stateKey
+}
+
 func (i *IterableStorageService) DefaultBegin() common.Hash {
 	return dastree.Hash([]byte(iteratorBegin))
 }
@@ -10,106 +10,249 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/pretty"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/sys/unix"
 )
 
 type LocalFileStorageConfig struct {
-	Enable                 bool   `koanf:"enable"`
-	DataDir                string `koanf:"data-dir"`
-	SyncFromStorageService bool   `koanf:"sync-from-storage-service"`
-	SyncToStorageService   bool   `koanf:"sync-to-storage-service"`
+	Enable                 bool          `koanf:"enable"`
+	DataDir                string        `koanf:"data-dir"`
+	EnableExpiry           bool          `koanf:"enable-expiry"`
+	PruneInterval          time.Duration `koanf:"prune-interval"`
+	MigrateLegacyLayout    bool          `koanf:"migrate-legacy-layout"`
+	SyncFromStorageService bool          `koanf:"sync-from-storage-service"`
+	SyncToStorageService   bool          `koanf:"sync-to-storage-service"`
 }
 
 var DefaultLocalFileStorageConfig = LocalFileStorageConfig{
-	DataDir: "",
+	DataDir:             "",
+	PruneInterval:       5 * time.Minute,
+	MigrateLegacyLayout: true,
 }
 
 func LocalFileStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultLocalFileStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from a directory of files, one per batch")
 	f.String(prefix+".data-dir", DefaultLocalFileStorageConfig.DataDir, "local data directory")
+	f.Bool(prefix+".enable-expiry", DefaultLocalFileStorageConfig.EnableExpiry, "discard data after its expiry timeout")
+	f.Duration(prefix+".prune-interval", DefaultLocalFileStorageConfig.PruneInterval, "interval between scans for expired data when enable-expiry is set")
+	f.Bool(prefix+".migrate-legacy-layout", DefaultLocalFileStorageConfig.MigrateLegacyLayout, "on startup, move files stored in the legacy flat layout into the sharded layout")
 	f.Bool(prefix+".sync-from-storage-service", DefaultLocalFileStorageConfig.SyncFromStorageService, "enable local storage to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultLocalFileStorageConfig.SyncToStorageService, "enable local storage to be used as a sink for regular sync storage")
 }
 
+// The data directory is laid out as follows:
+//
+//	by-data-hash/ab/cd/abcd...       the data itself, sharded on the first two bytes of its key
+//	by-expiry-timestamp/<ts>/abcd... hard links to the data, bucketed by expiry rounded up to the hour
+//
+// Each expiry link holds a reference to the data file, so the link count of a
+// file tells us whether an entry is still wanted by a later expiry bucket.
+const (
+	byDataHashDir        = "by-data-hash"
+	byExpiryTimestampDir = "by-expiry-timestamp"
+	expiryBucketSeconds  = 60 * 60
+)
+
 type LocalFileStorageService struct {
-	dataDir string
+	stopWaiter   stopwaiter.StopWaiterSafe
+	dataDir      string
+	enableExpiry bool
+
+	// Held for writing while pruning so that a concurrent Put can't add an
+	// expiry link to a file that is about to be removed.
+	pruneMutex sync.RWMutex
 }
 
-func NewLocalFileStorageService(dataDir string) (StorageService, error) {
-	if unix.Access(dataDir, unix.W_OK|unix.R_OK) != nil {
-		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", dataDir)
+func NewLocalFileStorageService(ctx context.Context, config LocalFileStorageConfig) (StorageService, error) {
+	if unix.Access(config.DataDir, unix.W_OK|unix.R_OK) != nil {
+		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", config.DataDir)
+	}
+	s := &LocalFileStorageService{
+		dataDir:      config.DataDir,
+		enableExpiry: config.EnableExpiry,
+	}
+	if config.MigrateLegacyLayout {
+		if err := s.migrateLegacyLayout(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.stopWaiter.Start(ctx, s); err != nil {
+		return nil, err
+	}
+	if config.EnableExpiry {
+		if config.PruneInterval <= 0 {
+			return nil, errors.New("local-file-storage.prune-interval must be positive when expiry is enabled")
+		}
+		err := s.stopWaiter.CallIterativelySafe(func(ctx context.Context) time.Duration {
+			if err := s.pruneExpired(ctx, uint64(time.Now().Unix())); err != nil {
+				log.Error("error pruning expired data from LocalFileStorageService", "dir", s.dataDir, "err", err)
+			}
+			return config.PruneInterval
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *LocalFileStorageService) dataPath(key common.Hash) string {
+	encodedKey := EncodeStorageServiceKey(key)
+	return filepath.Join(s.dataDir, byDataHashDir, encodedKey[:2], encodedKey[2:4], encodedKey)
+}
+
+func expiryBucket(expiry uint64) uint64 {
+	return arbmath.SaturatingUAdd(expiry, expiryBucketSeconds-1) / expiryBucketSeconds * expiryBucketSeconds
+}
+
+func (s *LocalFileStorageService) expiryPath(key common.Hash, expiry uint64) string {
+	bucket := strconv.FormatUint(expiryBucket(expiry), 10)
+	return filepath.Join(s.dataDir, byExpiryTimestampDir, bucket, EncodeStorageServiceKey(key))
+}
+
+func (s *LocalFileStorageService) legacyPaths(key common.Hash) []string {
+	return []string{
+		filepath.Join(s.dataDir, EncodeStorageServiceKey(key)),
+		// Just for backward compatability.
+		filepath.Join(s.dataDir, base32.StdEncoding.EncodeToString(key.Bytes())),
 	}
-	return &LocalFileStorageService{dataDir: dataDir}, nil
 }
 
 func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
 	log.Trace("das.LocalFileStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", s)
-	pathname := s.dataDir + "/" + EncodeStorageServiceKey(key)
-	data, err := os.ReadFile(pathname)
-	if err != nil {
-		// Just for backward compatability.
-		pathname = s.dataDir + "/" + base32.StdEncoding.EncodeToString(key.Bytes())
+	data, err := os.ReadFile(s.dataPath(key))
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, pathname := range s.legacyPaths(key) {
 		data, err = os.ReadFile(pathname)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				return nil, ErrNotFound
-			}
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
 			return nil, err
 		}
-		return data, nil
 	}
-	return data, nil
+	return nil, ErrNotFound
 }
 
 func (s *LocalFileStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.LocalFileStorageService.Store", data, timeout, s)
-	fileName := EncodeStorageServiceKey(dastree.Hash(data))
-	finalPath := s.dataDir + "/" + fileName
+	key := dastree.Hash(data)
+	finalPath := s.dataPath(key)
 
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
-	if err != nil {
+	s.pruneMutex.RLock()
+	defer s.pruneMutex.RUnlock()
+
+	// The data is content addressed, so an existing file already holds it.
+	// Replacing it would also detach it from its existing expiry links.
+	if _, err := os.Stat(finalPath); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomically(finalPath, data); err != nil {
+			return err
+		}
+	} else if err != nil {
 		return err
 	}
-	err = f.Chmod(0o600)
-	if err != nil {
-		return err
+	return s.linkExpiry(key, timeout)
+}
+
+// linkExpiry adds an expiry link to the data file for key. The caller must hold pruneMutex.
+func (s *LocalFileStorageService) linkExpiry(key common.Hash, timeout uint64) error {
+	if !s.enableExpiry {
+		return nil
 	}
-	_, err = f.Write(data)
-	if err != nil {
+	expiryPath := s.expiryPath(key, timeout)
+	if err := os.MkdirAll(filepath.Dir(expiryPath), 0o700); err != nil {
 		return err
 	}
-	err = f.Close()
-	if err != nil {
-		return err
+	err := os.Link(s.dataPath(key), expiryPath)
+	if errors.Is(err, os.ErrExist) {
+		return nil
 	}
+	return err
+}
 
-	return os.Rename(f.Name(), finalPath)
+func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	s.pruneMutex.RLock()
+	defer s.pruneMutex.RUnlock()
+	return s.writeKeyValue(key, value)
+}
 
+func (s *LocalFileStorageService) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, timeout uint64) error {
+	s.pruneMutex.RLock()
+	defer s.pruneMutex.RUnlock()
+	if err := s.writeKeyValue(key, value); err != nil {
+		return err
+	}
+	return s.linkExpiry(key, timeout)
 }
 
-func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
-	fileName := EncodeStorageServiceKey(key)
-	finalPath := s.dataDir + "/" + fileName
+// writeKeyValue writes the data file for key. The caller must hold pruneMutex.
+func (s *LocalFileStorageService) writeKeyValue(key common.Hash, value []byte) error {
+	finalPath := s.dataPath(key)
+	// An existing file is overwritten in place rather than renamed over, as a new
+	// inode would be detached from the expiry links of the old one and never pruned.
+	if _, err := os.Stat(finalPath); err == nil {
+		return writeFileInPlace(finalPath, value)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return writeFileAtomically(finalPath, value)
+}
 
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
+func writeFileInPlace(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomically uses a temp file and rename to achieve atomic writes.
+func writeFileAtomically(finalPath string, data []byte) error {
+	dir := filepath.Dir(finalPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(finalPath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
 	err = f.Chmod(0o600)
 	if err != nil {
 		return err
 	}
-	_, err = f.Write(value)
+	_, err = f.Write(data)
 	if err != nil {
 		return err
 	}
@@ -117,9 +260,131 @@ func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Ha
 	if err != nil {
 		return err
 	}
+	err = os.Rename(f.Name(), finalPath)
+	return err
+}
 
-	return os.Rename(f.Name(), finalPath)
+// pruneExpired removes every entry whose latest expiry is at or before now.
+func (s *LocalFileStorageService) pruneExpired(ctx context.Context, now uint64) error {
+	bucketsDir := filepath.Join(s.dataDir, byExpiryTimestampDir)
+	buckets, err := os.ReadDir(bucketsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pruned := 0
+	for _, bucket := range buckets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		bucketTimestamp, err := strconv.ParseUint(bucket.Name(), 10, 64)
+		if err != nil || !bucket.IsDir() {
+			log.Warn("unexpected entry in LocalFileStorageService expiry index", "name", bucket.Name())
+			continue
+		}
+		if bucketTimestamp > now {
+			continue
+		}
+		n, err := s.pruneBucket(filepath.Join(bucketsDir, bucket.Name()))
+		pruned += n
+		if err != nil {
+			return err
+		}
+	}
+	if pruned > 0 {
+		log.Info("pruned expired data from LocalFileStorageService", "dir", s.dataDir, "count", pruned)
+	}
+	return nil
+}
+
+func (s *LocalFileStorageService) pruneBucket(bucketDir string) (int, error) {
+	s.pruneMutex.Lock()
+	defer s.pruneMutex.Unlock()
 
+	entries, err := os.ReadDir(bucketDir)
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, entry := range entries {
+		key, err := DecodeStorageServiceKey(entry.Name())
+		if err != nil {
+			log.Warn("unexpected entry in LocalFileStorageService expiry index", "bucket", bucketDir, "name", entry.Name())
+			continue
+		}
+		linkPath := filepath.Join(bucketDir, entry.Name())
+		linkInfo, err := os.Stat(linkPath)
+		if err != nil {
+			return pruned, err
+		}
+		dataPath := s.dataPath(key)
+		dataInfo, err := os.Stat(dataPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return pruned, err
+		}
+		// A link count above two means a later expiry bucket still references the data.
+		stat, ok := linkInfo.Sys().(*syscall.Stat_t)
+		if err == nil && ok && stat.Nlink <= 2 && os.SameFile(linkInfo, dataInfo) {
+			if err := os.Remove(dataPath); err != nil {
+				return pruned, err
+			}
+			pruned++
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, os.Remove(bucketDir)
+}
+
+// migrateLegacyLayout moves files written to the top level of the data directory,
+// named either by hex or base32 encoded key, into the sharded layout.
+// Migrated data has no recorded expiry so it is kept forever.
+func (s *LocalFileStorageService) migrateLegacyLayout() error {
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		key, ok := decodeLegacyFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		finalPath := s.dataPath(key)
+		if err := os.MkdirAll(filepath.Dir(finalPath), 0o700); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(s.dataDir, entry.Name()), finalPath); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Info("migrated LocalFileStorageService data to sharded layout", "dir", s.dataDir, "count", migrated)
+	}
+	return nil
+}
+
+func decodeLegacyFileName(name string) (common.Hash, bool) {
+	if len(name) == 2*common.HashLength {
+		key, err := DecodeStorageServiceKey(name)
+		if err == nil {
+			return key, true
+		}
+	}
+	if len(name) == base32.StdEncoding.EncodedLen(common.HashLength) {
+		keyBytes, err := base32.StdEncoding.DecodeString(name)
+		if err == nil && len(keyBytes) == common.HashLength {
+			return common.BytesToHash(keyBytes), true
+		}
+	}
+	return common.Hash{}, false
 }
 
 func (s *LocalFileStorageService) Sync(ctx context.Context) error {
@@ -127,10 +392,13 @@ func (s *LocalFileStorageService) Sync(ctx context.Context) error {
 }
 
 func (s *LocalFileStorageService) Close(ctx context.Context) error {
-	return nil
+	return s.stopWaiter.StopAndWait()
 }
 
 func (s *LocalFileStorageService) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	if s.enableExpiry {
+		return arbstate.DiscardAfterDataTimeout, nil
+	}
 	return arbstate.KeepForever, nil
 }
 
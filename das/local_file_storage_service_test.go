@@ -0,0 +1,152 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestLocalFileStorageServiceExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storageService, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+		DataDir:       t.TempDir(),
+		EnableExpiry:  true,
+		PruneInterval: time.Hour,
+	})
+	Require(t, err)
+	defer storageService.Close(ctx)
+	s, ok := storageService.(*LocalFileStorageService)
+	if !ok {
+		t.Fatal("unexpected storage service type", storageService)
+	}
+
+	policy, err := s.ExpirationPolicy(ctx)
+	Require(t, err)
+	if policy != arbstate.DiscardAfterDataTimeout {
+		t.Fatal("unexpected expiration policy", policy)
+	}
+
+	now := uint64(time.Now().Unix())
+	shortLived := []byte("short lived value")
+	longLived := []byte("long lived value")
+	extended := []byte("value stored twice with different timeouts")
+	Require(t, s.Put(ctx, shortLived, now+10))
+	Require(t, s.Put(ctx, longLived, now+10*expiryBucketSeconds))
+	Require(t, s.Put(ctx, extended, now+10))
+	Require(t, s.Put(ctx, extended, now+10*expiryBucketSeconds))
+
+	Require(t, s.pruneExpired(ctx, now+2*expiryBucketSeconds))
+	if _, err := s.GetByHash(ctx, dastree.Hash(shortLived)); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected short lived value to be pruned, got", err)
+	}
+	for _, value := range [][]byte{longLived, extended} {
+		res, err := s.GetByHash(ctx, dastree.Hash(value))
+		Require(t, err)
+		if !bytes.Equal(res, value) {
+			t.Fatal(res, value)
+		}
+	}
+
+	Require(t, s.pruneExpired(ctx, now+11*expiryBucketSeconds))
+	for _, value := range [][]byte{longLived, extended} {
+		if _, err := s.GetByHash(ctx, dastree.Hash(value)); !errors.Is(err, ErrNotFound) {
+			t.Fatal("expected value to be pruned, got", err)
+		}
+	}
+	buckets, err := os.ReadDir(filepath.Join(s.dataDir, byExpiryTimestampDir))
+	Require(t, err)
+	if len(buckets) != 0 {
+		t.Fatal("expected all expiry buckets to be removed, found", len(buckets))
+	}
+}
+
+func TestLocalFileStorageServiceRewriteKeepsExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storageService, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+		DataDir:       t.TempDir(),
+		EnableExpiry:  true,
+		PruneInterval: time.Hour,
+	})
+	Require(t, err)
+	defer storageService.Close(ctx)
+	s, ok := storageService.(*LocalFileStorageService)
+	if !ok {
+		t.Fatal("unexpected storage service type", storageService)
+	}
+
+	now := uint64(time.Now().Unix())
+	value := []byte("value repaired after being stored")
+	key := dastree.Hash(value)
+	Require(t, s.Put(ctx, value, now+10))
+	// Overwriting the data, as a repair does, must leave it attached to its expiry link
+	Require(t, os.WriteFile(s.dataPath(key), []byte("corrupted"), 0o600))
+	Require(t, s.putKeyValue(ctx, key, value))
+	res, err := s.GetByHash(ctx, key)
+	Require(t, err)
+	if !bytes.Equal(res, value) {
+		t.Fatal(res, value)
+	}
+
+	Require(t, s.pruneExpired(ctx, now+2*expiryBucketSeconds))
+	if _, err := s.GetByHash(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected rewritten value to be pruned, got", err)
+	}
+}
+
+func TestLocalFileStorageServiceLegacyLayout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dataDir := t.TempDir()
+
+	hexValue := []byte("stored under a hex file name")
+	base32Value := []byte("stored under a base32 file name")
+	hexName := EncodeStorageServiceKey(dastree.Hash(hexValue))
+	base32Name := base32.StdEncoding.EncodeToString(dastree.Hash(base32Value).Bytes())
+	Require(t, os.WriteFile(filepath.Join(dataDir, hexName), hexValue, 0o600))
+	Require(t, os.WriteFile(filepath.Join(dataDir, base32Name), base32Value, 0o600))
+	Require(t, os.WriteFile(filepath.Join(dataDir, "das_bls"), []byte("unrelated"), 0o600))
+
+	checkValues := func(s StorageService) {
+		t.Helper()
+		for _, value := range [][]byte{hexValue, base32Value} {
+			res, err := s.GetByHash(ctx, dastree.Hash(value))
+			Require(t, err)
+			if !bytes.Equal(res, value) {
+				t.Fatal(res, value)
+			}
+		}
+	}
+
+	unmigrated, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{DataDir: dataDir})
+	Require(t, err)
+	checkValues(unmigrated)
+	Require(t, unmigrated.Close(ctx))
+
+	migrated, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{DataDir: dataDir, MigrateLegacyLayout: true})
+	Require(t, err)
+	defer migrated.Close(ctx)
+	checkValues(migrated)
+	for _, name := range []string{hexName, base32Name} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("expected legacy file to be migrated", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "das_bls")); err != nil {
+		t.Fatal("unrelated file should be left in place", err)
+	}
+}
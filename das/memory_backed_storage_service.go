@@ -63,6 +63,10 @@ func (m *MemoryBackedStorageService) putKeyValue(ctx context.Context, key common
 	return nil
 }
 
+func (m *MemoryBackedStorageService) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error {
+	return m.putKeyValue(ctx, key, value)
+}
+
 func (m *MemoryBackedStorageService) Sync(ctx context.Context) error {
 	m.rwmutex.RLock()
 	defer m.rwmutex.RUnlock()
@@ -5,12 +5,18 @@ package das
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
+	"net/url"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
 
 	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/util/metricsutil"
 )
 
 var ErrNoReadersResponded = errors.New("no DAS readers responded successfully")
@@ -20,6 +26,12 @@ type aggregatorStrategy interface {
 	update([]arbstate.DataAvailabilityReader, map[arbstate.DataAvailabilityReader]readerStats)
 }
 
+// Strategies that implement statObserver are told about each completed request
+// as it is recorded, rather than only at each update.
+type statObserver interface {
+	observe(arbstate.DataAvailabilityReader, readerStat)
+}
+
 type abstractAggregatorStrategy struct {
 	sync.RWMutex
 	readers []arbstate.DataAvailabilityReader
@@ -80,6 +92,210 @@ func (s *simpleExploreExploitStrategy) newInstance() aggregatorStrategyInstance
 	return &basicStrategyInstance{readerSets: readerSets}
 }
 
+// Latency Aware Strategy
+//
+// Readers are tried one at a time in order of their latency percentile, and if
+// a reader hasn't responded within that percentile of its usual latency the
+// next reader is tried alongside it (a hedged request). Readers whose error
+// ratio spikes have their circuit breaker opened and are skipped until the
+// breaker has been open for breakerOpenDuration, after which a single probe
+// request decides whether to close it again.
+type latencyAwareStrategy struct {
+	hedgePercentile     int
+	minHedgeDelay       time.Duration
+	breakerErrorRatio   float64
+	breakerMinRequests  int
+	breakerWindow       int
+	breakerOpenDuration time.Duration
+
+	latencies map[arbstate.DataAvailabilityReader]time.Duration
+
+	breakersMutex sync.Mutex
+	breakers      map[arbstate.DataAvailabilityReader]*readerBreaker
+
+	abstractAggregatorStrategy
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type readerBreaker struct {
+	state   breakerState
+	since   time.Time
+	probing bool
+	recent  []bool
+}
+
+func newLatencyAwareStrategy(config *LatencyAwareStrategyConfig) (*latencyAwareStrategy, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &latencyAwareStrategy{
+		hedgePercentile:     config.HedgePercentile,
+		minHedgeDelay:       config.MinHedgeDelay,
+		breakerErrorRatio:   config.BreakerErrorRatio,
+		breakerMinRequests:  config.BreakerMinRequests,
+		breakerWindow:       config.BreakerWindow,
+		breakerOpenDuration: config.BreakerOpenDuration,
+		latencies:           make(map[arbstate.DataAvailabilityReader]time.Duration),
+		breakers:            make(map[arbstate.DataAvailabilityReader]*readerBreaker),
+	}, nil
+}
+
+func (s *latencyAwareStrategy) update(readers []arbstate.DataAvailabilityReader, stats map[arbstate.DataAvailabilityReader]readerStats) {
+	s.abstractAggregatorStrategy.update(readers, stats)
+
+	latencies := make(map[arbstate.DataAvailabilityReader]time.Duration)
+	for _, reader := range readers {
+		history := stats[reader]
+		latencies[reader] = history.latencyPercentile(s.hedgePercentile)
+
+		metricName := "arb/das/reader/" + readerMetricName(reader)
+		metrics.GetOrRegisterGauge(metricName+"/latency/percentile", nil).Update(latencies[reader].Milliseconds())
+		metrics.GetOrRegisterGauge(metricName+"/error/percent", nil).Update(int64(history.errorRatio() * 100))
+		metrics.GetOrRegisterGauge(metricName+"/breaker/state", nil).Update(int64(s.breakerState(reader)))
+	}
+
+	s.breakersMutex.Lock()
+	for reader := range s.breakers {
+		if _, ok := latencies[reader]; !ok {
+			delete(s.breakers, reader)
+		}
+	}
+	s.breakersMutex.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	s.latencies = latencies
+}
+
+// Called for every request outcome, so breakers react faster than the strategy update interval.
+func (s *latencyAwareStrategy) observe(reader arbstate.DataAvailabilityReader, stat readerStat) {
+	s.breakersMutex.Lock()
+	defer s.breakersMutex.Unlock()
+	b := s.breakerLocked(reader, time.Now())
+	switch b.state {
+	case breakerClosed:
+		b.recent = append(b.recent, stat.success)
+		if len(b.recent) > s.breakerWindow {
+			b.recent = b.recent[len(b.recent)-s.breakerWindow:]
+		}
+		failures := 0
+		for _, success := range b.recent {
+			if !success {
+				failures++
+			}
+		}
+		if len(b.recent) >= s.breakerMinRequests && float64(failures) >= s.breakerErrorRatio*float64(len(b.recent)) {
+			b.state, b.since, b.recent = breakerOpen, time.Now(), nil
+			metrics.GetOrRegisterCounter("arb/das/reader/"+readerMetricName(reader)+"/breaker/opened", nil).Inc(1)
+		}
+	case breakerHalfOpen:
+		if stat.success {
+			b.state, b.since, b.recent = breakerClosed, time.Now(), nil
+		} else {
+			b.state, b.since = breakerOpen, time.Now()
+		}
+	case breakerOpen:
+		// Late responses to requests made before the breaker opened don't count.
+	}
+}
+
+// Returns the breaker for reader, moving it from open to half-open if it has been open for long enough.
+func (s *latencyAwareStrategy) breakerLocked(reader arbstate.DataAvailabilityReader, now time.Time) *readerBreaker {
+	b, ok := s.breakers[reader]
+	if !ok {
+		b = &readerBreaker{state: breakerClosed, since: now}
+		s.breakers[reader] = b
+	}
+	if b.state == breakerOpen && now.Sub(b.since) >= s.breakerOpenDuration {
+		b.state, b.since, b.probing = breakerHalfOpen, now, false
+	}
+	if b.state == breakerHalfOpen && b.probing && now.Sub(b.since) >= s.breakerOpenDuration {
+		// The probe's outcome was never observed, so allow another one.
+		b.since, b.probing = now, false
+	}
+	return b
+}
+
+func (s *latencyAwareStrategy) breakerState(reader arbstate.DataAvailabilityReader) breakerState {
+	s.breakersMutex.Lock()
+	defer s.breakersMutex.Unlock()
+	return s.breakerLocked(reader, time.Now()).state
+}
+
+func (s *latencyAwareStrategy) newInstance() aggregatorStrategyInstance {
+	s.RLock()
+	defer s.RUnlock()
+
+	var closed, open, probes []arbstate.DataAvailabilityReader
+	s.breakersMutex.Lock()
+	now := time.Now()
+	for _, reader := range s.readers {
+		b := s.breakerLocked(reader, now)
+		switch {
+		case b.state == breakerClosed:
+			closed = append(closed, reader)
+		case b.state == breakerHalfOpen && !b.probing:
+			b.probing = true
+			probes = append(probes, reader)
+		default:
+			open = append(open, reader)
+		}
+	}
+	s.breakersMutex.Unlock()
+
+	if len(closed) == 0 && len(probes) == 0 {
+		// Trying readers with open breakers beats failing outright.
+		closed = open
+	}
+	// Readers we know nothing about yet sort first so that their latency gets measured.
+	sort.SliceStable(closed, func(i, j int) bool {
+		return s.latencies[closed[i]] < s.latencies[closed[j]]
+	})
+
+	si := &hedgedStrategyInstance{}
+	for i, reader := range closed {
+		readerSet := []arbstate.DataAvailabilityReader{reader}
+		if i == 0 {
+			readerSet = append(readerSet, probes...)
+		}
+		si.readerSets = append(si.readerSets, readerSet)
+		si.waits = append(si.waits, s.hedgeDelay(reader))
+	}
+	if len(closed) == 0 {
+		si.readerSets = append(si.readerSets, probes)
+		si.waits = append(si.waits, 0)
+	}
+	return si
+}
+
+// Returns how long to wait for reader before hedging, or 0 if there is no latency data for it.
+func (s *latencyAwareStrategy) hedgeDelay(reader arbstate.DataAvailabilityReader) time.Duration {
+	latency := s.latencies[reader]
+	if latency == 0 {
+		return 0
+	}
+	if latency < s.minHedgeDelay {
+		return s.minHedgeDelay
+	}
+	return latency
+}
+
+func readerMetricName(reader arbstate.DataAvailabilityReader) string {
+	if client, ok := reader.(*RestfulDasClient); ok {
+		if parsed, err := url.Parse(client.url); err == nil {
+			return metricsutil.CanonicalizeMetricName(parsed.Host)
+		}
+	}
+	return metricsutil.CanonicalizeMetricName(fmt.Sprintf("%v", reader))
+}
+
 // Sequential Strategy for Testing
 type testingSequentialStrategy struct {
 	abstractAggregatorStrategy
@@ -102,6 +318,12 @@ type aggregatorStrategyInstance interface {
 	nextReaders() []arbstate.DataAvailabilityReader
 }
 
+// Strategy instances that implement hedgingStrategyInstance choose how long to
+// wait for each set of readers instead of always using wait-before-try-next.
+type hedgingStrategyInstance interface {
+	waitBeforeTryNext(maxWait time.Duration) time.Duration
+}
+
 type basicStrategyInstance struct {
 	readerSets [][]arbstate.DataAvailabilityReader
 }
@@ -114,3 +336,28 @@ func (si *basicStrategyInstance) nextReaders() []arbstate.DataAvailabilityReader
 	si.readerSets = si.readerSets[1:]
 	return next
 }
+
+// A strategy instance that also chooses how long to wait on each set of readers
+// before trying the next set.
+type hedgedStrategyInstance struct {
+	basicStrategyInstance
+	waits []time.Duration
+	wait  time.Duration
+}
+
+func (si *hedgedStrategyInstance) nextReaders() []arbstate.DataAvailabilityReader {
+	if len(si.waits) == 0 {
+		return nil
+	}
+	si.wait = si.waits[0]
+	si.waits = si.waits[1:]
+	return si.basicStrategyInstance.nextReaders()
+}
+
+// Returns how long to wait on the readers last returned by nextReaders, capped at maxWait.
+func (si *hedgedStrategyInstance) waitBeforeTryNext(maxWait time.Duration) time.Duration {
+	if si.wait == 0 || si.wait > maxWait {
+		return maxWait
+	}
+	return si.wait
+}
@@ -114,3 +114,108 @@ func TestDAS_SimpleExploreExploit(t *testing.T) {
 	}
 
 }
+
+func TestDAS_LatencyAware(t *testing.T) {
+	readers := []arbstate.DataAvailabilityReader{&dummyReader{0}, &dummyReader{1}, &dummyReader{2}}
+	stats := make(map[arbstate.DataAvailabilityReader]readerStats)
+	stats[readers[0]] = []readerStat{ // p95 3s
+		{1 * time.Second, true},
+		{3 * time.Second, true},
+	}
+	stats[readers[1]] = []readerStat{ // p95 20ms, below the minimum hedge delay
+		{10 * time.Millisecond, true},
+		{20 * time.Millisecond, true},
+		{time.Second, false},
+	}
+	stats[readers[2]] = []readerStat{ // p95 2s
+		{2 * time.Second, true},
+	}
+
+	config := DefaultLatencyAwareStrategyConfig
+	config.BreakerMinRequests = 2
+	config.BreakerOpenDuration = 50 * time.Millisecond
+	strategy, err := newLatencyAwareStrategy(&config)
+	Require(t, err)
+	strategy.update(readers, stats)
+
+	checkInstance := func(expected [][]int, expectedWaits []time.Duration) {
+		t.Helper()
+		si := strategy.newInstance()
+		hedging := si.(hedgingStrategyInstance)
+		for i, expectedSet := range expected {
+			was := si.nextReaders()
+			if len(was) != len(expectedSet) {
+				Fail(t, fmt.Sprintf("set %d has %d readers, expected %d", i, len(was), len(expectedSet)))
+			}
+			for j := range was {
+				if was[j].(*dummyReader).int != expectedSet[j] {
+					Fail(t, fmt.Sprintf("set %d: expected %d, was %d", i, expectedSet[j], was[j].(*dummyReader).int))
+				}
+			}
+			if wait := hedging.waitBeforeTryNext(time.Minute); wait != expectedWaits[i] {
+				Fail(t, fmt.Sprintf("set %d: expected wait %v, was %v", i, expectedWaits[i], wait))
+			}
+		}
+		if next := si.nextReaders(); len(next) != 0 {
+			Fail(t, "unexpected extra readers", next)
+		}
+	}
+
+	checkInstance([][]int{{1}, {2}, {0}}, []time.Duration{config.MinHedgeDelay, 2 * time.Second, 3 * time.Second})
+
+	// Enough failures open reader 1's breaker so it's skipped.
+	strategy.observe(readers[1], readerStat{time.Second, false})
+	strategy.observe(readers[1], readerStat{time.Second, false})
+	checkInstance([][]int{{2}, {0}}, []time.Duration{2 * time.Second, 3 * time.Second})
+
+	// Once the breaker has been open long enough, reader 1 is probed exactly once alongside the best reader.
+	time.Sleep(config.BreakerOpenDuration)
+	checkInstance([][]int{{2, 1}, {0}}, []time.Duration{2 * time.Second, 3 * time.Second})
+	checkInstance([][]int{{2}, {0}}, []time.Duration{2 * time.Second, 3 * time.Second})
+
+	// A successful probe closes the breaker.
+	strategy.observe(readers[1], readerStat{10 * time.Millisecond, true})
+	checkInstance([][]int{{1}, {2}, {0}}, []time.Duration{config.MinHedgeDelay, 2 * time.Second, 3 * time.Second})
+
+	// With every breaker open, all readers are still tried rather than none.
+	for _, reader := range readers {
+		strategy.observe(reader, readerStat{time.Second, false})
+		strategy.observe(reader, readerStat{time.Second, false})
+	}
+	checkInstance([][]int{{1}, {2}, {0}}, []time.Duration{config.MinHedgeDelay, 2 * time.Second, 3 * time.Second})
+}
+
+func TestDAS_LatencyAwareConfigValidation(t *testing.T) {
+	config := DefaultLatencyAwareStrategyConfig
+	Require(t, config.Validate())
+	for _, invalid := range []func(*LatencyAwareStrategyConfig){
+		func(c *LatencyAwareStrategyConfig) { c.BreakerWindow = 0 },
+		func(c *LatencyAwareStrategyConfig) { c.BreakerMinRequests = 0 },
+		func(c *LatencyAwareStrategyConfig) { c.BreakerMinRequests = -1 },
+		func(c *LatencyAwareStrategyConfig) { c.BreakerMinRequests = c.BreakerWindow + 1 },
+	} {
+		config := DefaultLatencyAwareStrategyConfig
+		invalid(&config)
+		if err := config.Validate(); err == nil {
+			Fail(t, "invalid config accepted", config)
+		}
+	}
+}
+
+func TestDAS_CanceledRequestsNotRecorded(t *testing.T) {
+	aggregator := &SimpleDASReaderAggregator{statMessages: make(chan readerStatMessage, 2)}
+	reader := &dummyReader{0}
+
+	// A hedged request abandoned because another reader answered first
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = aggregator.tryGetByHash(ctx, common.Hash{}, reader)
+	if len(aggregator.statMessages) != 0 {
+		Fail(t, "canceled request recorded as a failure")
+	}
+
+	_, _ = aggregator.tryGetByHash(context.Background(), common.Hash{}, reader)
+	if len(aggregator.statMessages) != 1 {
+		Fail(t, "failed request not recorded")
+	}
+}
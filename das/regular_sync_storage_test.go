@@ -6,6 +6,7 @@ package das
 import (
 	"bytes"
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -77,3 +78,114 @@ func TestRegularSyncStorage(t *testing.T) {
 		}
 	}
 }
+
+func TestRegularSyncStorageResumesFromState(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	conf := RegularSyncStorageConfig{
+		Enable:       true,
+		SyncInterval: time.Hour,
+		StateDir:     t.TempDir(),
+	}
+	syncFrom := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(NewMemoryBackedStorageService(ctx)))
+
+	val := [][]byte{
+		[]byte("The first value"),
+		[]byte("The second value"),
+		[]byte("The third value"),
+	}
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	for _, v := range val[:2] {
+		Require(t, syncFrom.Put(ctx, v, timeout))
+	}
+
+	firstSyncTo := NewMemoryBackedStorageService(ctx)
+	firstSync := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{firstSyncTo}, conf)
+	firstSync.syncAllStorages(ctx)
+	for _, v := range val[:2] {
+		_, err := firstSyncTo.GetByHash(ctx, dastree.Hash(v))
+		Require(t, err)
+	}
+
+	Require(t, syncFrom.Put(ctx, val[2], timeout))
+
+	// The second sink has the same name as the first, so it picks up the persisted
+	// position and only receives data added after the first sync.
+	secondSyncTo := NewMemoryBackedStorageService(ctx)
+	secondSync := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{secondSyncTo}, conf)
+	secondSync.syncAllStorages(ctx)
+	for _, v := range val[:2] {
+		if _, err := secondSyncTo.GetByHash(ctx, dastree.Hash(v)); !errors.Is(err, ErrNotFound) {
+			t.Fatal("expected already synced value to be skipped, got", err)
+		}
+	}
+	res, err := secondSyncTo.GetByHash(ctx, dastree.Hash(val[2]))
+	Require(t, err)
+	if !bytes.Equal(res, val[2]) {
+		t.Fatal(res, val[2])
+	}
+}
+
+func TestRegularSyncStorageKeysStateByConfig(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	conf := RegularSyncStorageConfig{
+		Enable:       true,
+		SyncInterval: time.Hour,
+		StateDir:     t.TempDir(),
+	}
+	syncFrom := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(NewMemoryBackedStorageService(ctx)))
+	value := []byte("The value")
+	Require(t, syncFrom.Put(ctx, value, uint64(time.Now().Add(time.Hour).Unix())))
+
+	// Sinks of the same type, which describe themselves the same way, keep separate positions
+	firstSync := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{withSyncStateKey(NewMemoryBackedStorageService(ctx), "first")}, conf)
+	firstSync.syncAllStorages(ctx)
+	secondSyncTo := NewMemoryBackedStorageService(ctx)
+	secondSync := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{withSyncStateKey(secondSyncTo, "second")}, conf)
+	secondSync.syncAllStorages(ctx)
+	_, err := secondSyncTo.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+}
+
+func TestRegularSyncStorageResetsLostPosition(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	syncFrom := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(NewMemoryBackedStorageService(ctx)))
+	value := []byte("The value")
+	Require(t, syncFrom.Put(ctx, value, uint64(time.Now().Add(time.Hour).Unix())))
+	syncTo := NewMemoryBackedStorageService(ctx)
+	regularSyncStorage := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{syncTo}, DefaultRegularSyncStorageConfig)
+
+	// The position was recorded against an entry the source no longer has
+	regularSyncStorage.lastSyncedHashes[regularSyncPair{syncFrom, syncTo}] = common.HexToHash("0x1234")
+	synced, err := regularSyncStorage.syncPair(ctx, syncFrom, syncTo)
+	Require(t, err)
+	if synced != 1 {
+		t.Fatal("expected the source to be synced from the beginning, synced", synced)
+	}
+	_, err = syncTo.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+}
+
+func TestRegularSyncStorageLagsBeforeCatchingUp(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	syncFrom := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(NewMemoryBackedStorageService(ctx)))
+	Require(t, syncFrom.Put(ctx, []byte("The value"), uint64(time.Now().Add(time.Hour).Unix())))
+	syncTo := NewMemoryBackedStorageService(ctx)
+	regularSyncStorage := NewRegularlySyncStorage([]*IterableStorageService{syncFrom}, []StorageService{syncTo}, DefaultRegularSyncStorageConfig)
+	pair := regularSyncPair{syncFrom, syncTo}
+	started, ok := regularSyncStorage.lastCaughtUp[pair]
+	if !ok {
+		t.Fatal("pair which hasn't caught up yet doesn't count towards the lag")
+	}
+
+	// A pass which is stopped before copying anything doesn't catch the pair up
+	stoppedCtx, stop := context.WithCancel(ctx)
+	stop()
+	regularSyncStorage.syncAllStorages(stoppedCtx)
+	if caughtUp := regularSyncStorage.lastCaughtUp[pair]; caughtUp != started {
+		t.Fatal("pair caught up without syncing, at", caughtUp)
+	}
+}
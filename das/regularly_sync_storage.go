@@ -5,91 +5,310 @@ package das
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 
+	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 
 	flag "github.com/spf13/pflag"
 )
 
+var (
+	regularSyncSyncedCounter    = metrics.NewRegisteredCounter("arb/das/regularsync/synced", nil)
+	regularSyncErrorCounter     = metrics.NewRegisteredCounter("arb/das/regularsync/error", nil)
+	regularSyncPassEntriesGauge = metrics.NewRegisteredGauge("arb/das/regularsync/pass/entries", nil)
+	regularSyncLagSecondsGauge  = metrics.NewRegisteredGauge("arb/das/regularsync/lag/seconds", nil)
+)
+
 type RegularSyncStorageConfig struct {
 	Enable       bool          `koanf:"enable"`
 	SyncInterval time.Duration `koanf:"sync-interval"`
+	StateDir     string        `koanf:"state-dir"`
 }
 
 var DefaultRegularSyncStorageConfig = RegularSyncStorageConfig{
 	Enable:       false,
 	SyncInterval: 5 * time.Minute,
+	StateDir:     "",
 }
 
 func RegularSyncStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultRegularSyncStorageConfig.Enable, "enable regular storage syncing")
 	f.Duration(prefix+".sync-interval", DefaultRegularSyncStorageConfig.SyncInterval, "interval for running regular storage sync")
+	f.String(prefix+".state-dir", DefaultRegularSyncStorageConfig.StateDir, "directory to store the sync state in, ie the last hash synced from each source to each sink, so that we don't sync from scratch each time")
 }
 
+const regularSyncStateFilename = "regularSyncState.json"
+
+// How many entries to copy between writes of the sync state during a long sync.
+const regularSyncStateFlushEntries = 1000
+
 // A RegularlySyncStorage is used to sync data from syncFromStorageServices to
 // all the syncToStorageServices at regular intervals.
 // (Only newly added data since the last sync is copied over.)
+//
+// The position reached in each source's iteration list is tracked separately
+// for every sink, and persisted to StateDir if one is configured.
 type RegularlySyncStorage struct {
 	stopwaiter.StopWaiter
-	syncFromStorageServices                    []*IterableStorageService
-	syncToStorageServices                      []StorageService
-	lastSyncedHashOfEachSyncFromStorageService map[*IterableStorageService]common.Hash
-	syncInterval                               time.Duration
+	syncFromStorageServices []*IterableStorageService
+	syncToStorageServices   []StorageService
+	syncInterval            time.Duration
+	stateDir                string
+
+	stateMutex       sync.Mutex
+	persistedHashes  map[string]common.Hash
+	lastSyncedHashes map[regularSyncPair]common.Hash
+	lastCaughtUp     map[regularSyncPair]time.Time
+}
+
+type regularSyncPair struct {
+	syncFrom *IterableStorageService
+	syncTo   StorageService
+}
+
+// stateKey names the pair in the persisted sync state, so it must be stable across restarts.
+func (p regularSyncPair) stateKey() string {
+	return syncStateKeyOf(p.syncFrom) + " -> " + syncStateKeyOf(p.syncTo)
+}
+
+// legacyStateKey is what the pair used to be named by in the persisted sync state, to resume from.
+func (p regularSyncPair) legacyStateKey() string {
+	return p.syncFrom.String() + " -> " + p.syncTo.String()
+}
+
+// syncStateKeyed storage services are named in the persisted sync state after their configuration,
+// as two services of the same type may describe themselves the same way.
+type syncStateKeyed interface {
+	syncStateKey() string
+}
+
+type syncStateKeyedStorageService struct {
+	StorageService
+	key string
+}
+
+func (s *syncStateKeyedStorageService) syncStateKey() string {
+	return s.key
+}
+
+// withSyncStateKey names s by key in the persisted sync state.
+func withSyncStateKey(s StorageService, key string) StorageService {
+	if iterable, ok := s.(*IterableStorageService); ok {
+		iterable.stateKey = key
+		return iterable
+	}
+	return &syncStateKeyedStorageService{s, key}
+}
+
+func syncStateKeyOf(s StorageService) string {
+	if keyed, ok := s.(syncStateKeyed); ok && keyed.syncStateKey() != "" {
+		return keyed.syncStateKey()
+	}
+	return s.String()
 }
 
 func NewRegularlySyncStorage(syncFromStorageServices []*IterableStorageService, syncToStorageServices []StorageService, conf RegularSyncStorageConfig) *RegularlySyncStorage {
-	lastSyncedHashOfEachSyncFromStorageService := make(map[*IterableStorageService]common.Hash)
+	// Pairs which haven't caught up yet are as far behind as they've been since now, so that they count towards the lag.
+	now := time.Now()
+	lastCaughtUp := make(map[regularSyncPair]time.Time)
 	for _, syncFrom := range syncFromStorageServices {
-		lastSyncedHashOfEachSyncFromStorageService[syncFrom] = syncFrom.DefaultBegin()
+		for _, syncTo := range syncToStorageServices {
+			lastCaughtUp[regularSyncPair{syncFrom, syncTo}] = now
+		}
 	}
 	return &RegularlySyncStorage{
-		syncFromStorageServices:                    syncFromStorageServices,
-		syncToStorageServices:                      syncToStorageServices,
-		lastSyncedHashOfEachSyncFromStorageService: lastSyncedHashOfEachSyncFromStorageService,
-		syncInterval:                               conf.SyncInterval,
+		syncFromStorageServices: syncFromStorageServices,
+		syncToStorageServices:   syncToStorageServices,
+		syncInterval:            conf.SyncInterval,
+		stateDir:                conf.StateDir,
+		persistedHashes:         readRegularSyncStateOrDefault(conf.StateDir),
+		lastSyncedHashes:        make(map[regularSyncPair]common.Hash),
+		lastCaughtUp:            lastCaughtUp,
 	}
 }
 
+func readRegularSyncStateOrDefault(stateDir string) map[string]common.Hash {
+	state := make(map[string]common.Hash)
+	if stateDir == "" {
+		return state
+	}
+	path := filepath.Join(stateDir, regularSyncStateFilename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Info("Couldn't open regular sync state file, syncing from the beginning", "err", err, "path", path)
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Warn("Invalid data in regular sync state file, syncing from the beginning", "err", err, "path", path)
+		return make(map[string]common.Hash)
+	}
+	return state
+}
+
+func (r *RegularlySyncStorage) writeState() error {
+	if r.stateDir == "" {
+		return nil
+	}
+	r.stateMutex.Lock()
+	for pair, hash := range r.lastSyncedHashes {
+		r.persistedHashes[pair.stateKey()] = hash
+	}
+	data, err := json.Marshal(r.persistedHashes)
+	r.stateMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(filepath.Join(r.stateDir, regularSyncStateFilename), data)
+}
+
 func (r *RegularlySyncStorage) Start(ctx context.Context) {
 	// Start thread for regular sync
 	r.StopWaiter.Start(ctx, r)
 	r.CallIteratively(r.syncAllStorages)
 }
 
+func (r *RegularlySyncStorage) Close(ctx context.Context) error {
+	r.StopAndWait()
+	return r.writeState()
+}
+
+func (r *RegularlySyncStorage) String() string {
+	return "RegularlySyncStorage"
+}
+
 func (r *RegularlySyncStorage) syncAllStorages(ctx context.Context) time.Duration {
-	for syncFrom, lastSyncedHash := range r.lastSyncedHashOfEachSyncFromStorageService {
-		end := syncFrom.End(ctx)
-		if (end == common.Hash{}) {
+	var passEntries int64
+	for _, syncFrom := range r.syncFromStorageServices {
+		for _, syncTo := range r.syncToStorageServices {
+			synced, _ := r.syncPair(ctx, syncFrom, syncTo)
+			passEntries += int64(synced)
+		}
+	}
+	regularSyncPassEntriesGauge.Update(passEntries)
+
+	var lagSeconds int64
+	r.stateMutex.Lock()
+	for _, caughtUp := range r.lastCaughtUp {
+		lagSeconds = arbmath.MaxInt(lagSeconds, int64(time.Since(caughtUp).Seconds()))
+	}
+	r.stateMutex.Unlock()
+	regularSyncLagSecondsGauge.Update(lagSeconds)
+
+	if err := r.writeState(); err != nil {
+		log.Warn("regular sync storage failed to write sync state", "err", err, "dir", r.stateDir)
+	}
+	return r.syncInterval
+}
+
+// syncPair copies everything added to syncFrom since the last sync into syncTo.
+// It returns the number of entries walked, and an error if it had to stop before
+// reaching the end of syncFrom, in which case the next call resumes from where it stopped.
+func (r *RegularlySyncStorage) syncPair(ctx context.Context, syncFrom *IterableStorageService, syncTo StorageService) (int, error) {
+	pair := regularSyncPair{syncFrom, syncTo}
+	end := syncFrom.End(ctx)
+
+	r.stateMutex.Lock()
+	syncHash, ok := r.lastSyncedHashes[pair]
+	if !ok {
+		syncHash, ok = r.persistedHashes[pair.stateKey()]
+	}
+	if !ok {
+		syncHash, ok = r.persistedHashes[pair.legacyStateKey()]
+	}
+	if !ok {
+		syncHash = syncFrom.DefaultBegin()
+	}
+	if (end == common.Hash{}) || syncHash == end {
+		r.lastCaughtUp[pair] = time.Now()
+	}
+	r.stateMutex.Unlock()
+
+	synced := 0
+	for (end != common.Hash{}) && syncHash != end {
+		if ctx.Err() != nil {
+			return synced, ctx.Err()
+		}
+		nextHash := syncFrom.Next(ctx, syncHash)
+		if (nextHash == common.Hash{}) && syncHash != syncFrom.DefaultBegin() {
+			// The entry synced last is gone from the source, eg because the source was wiped,
+			// so there's no way to tell what follows it. Syncing again from the beginning
+			// only copies the entries which the sink is missing.
+			log.Warn("regular sync storage lost its position in the source, syncing from the beginning", "from", syncFrom, "to", syncTo, "lost", syncHash)
+			syncHash = syncFrom.DefaultBegin()
+			r.stateMutex.Lock()
+			r.lastSyncedHashes[pair] = syncHash
+			r.stateMutex.Unlock()
 			continue
 		}
+		if (nextHash == common.Hash{}) {
+			regularSyncErrorCounter.Inc(1)
+			return synced, fmt.Errorf("couldn't find the entry after %v in %v", syncHash, syncFrom)
+		}
+		if err := syncEntry(ctx, syncFrom, syncTo, nextHash); err != nil {
+			regularSyncErrorCounter.Inc(1)
+			log.Error("Error while running regular storage sync", "from", syncFrom, "to", syncTo, "err", err)
+			return synced, err
+		}
+		syncHash = nextHash
+		synced++
+		regularSyncSyncedCounter.Inc(1)
 
-		syncHash := lastSyncedHash
-		for syncHash != end {
-			syncHash = syncFrom.Next(ctx, syncHash)
-			data, err := syncFrom.GetByHash(ctx, syncHash)
-			if err != nil {
-				continue
-			}
-			expirationTime, err := syncFrom.GetExpirationTime(ctx, syncHash)
-			if err != nil {
-				continue
-			}
-			for _, syncTo := range r.syncToStorageServices {
-				_, err = syncTo.GetByHash(ctx, syncHash)
-				if err == nil {
-					continue
-				}
-
-				if err = syncTo.Put(ctx, data, expirationTime); err != nil {
-					log.Error("Error while running regular storage sync", "err", err)
-				}
+		r.stateMutex.Lock()
+		r.lastSyncedHashes[pair] = syncHash
+		if syncHash == end {
+			r.lastCaughtUp[pair] = time.Now()
+		}
+		r.stateMutex.Unlock()
+		if synced%regularSyncStateFlushEntries == 0 {
+			if err := r.writeState(); err != nil {
+				log.Warn("regular sync storage failed to write sync state", "err", err, "dir", r.stateDir)
 			}
 		}
-		r.lastSyncedHashOfEachSyncFromStorageService[syncFrom] = end
 	}
-	return r.syncInterval
+	return synced, nil
+}
+
+func syncEntry(ctx context.Context, syncFrom *IterableStorageService, syncTo StorageService, hash common.Hash) error {
+	data, err := syncFrom.GetByHash(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		// The entry has expired from the source; there is nothing left to copy.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	expirationTime, err := syncFrom.GetExpirationTime(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		log.Warn("regular sync storage skipping entry with no expiration time", "hash", hash, "from", syncFrom)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = syncTo.GetByHash(ctx, hash); err == nil {
+		return nil
+	}
+	return syncTo.Put(ctx, data, expirationTime)
+}
+
+// Backfill copies everything in syncFrom into syncTo once, resuming from the
+// position recorded in the sync state if there is one.
+func (r *RegularlySyncStorage) Backfill(ctx context.Context, syncFrom *IterableStorageService, syncTo StorageService) error {
+	synced, err := r.syncPair(ctx, syncFrom, syncTo)
+	log.Info("DAS storage backfill finished", "from", syncFrom, "to", syncTo, "synced", synced, "err", err)
+	if writeErr := r.writeState(); writeErr != nil {
+		log.Warn("regular sync storage failed to write sync state", "err", writeErr, "dir", r.stateDir)
+	}
+	return err
 }
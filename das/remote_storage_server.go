@@ -0,0 +1,118 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+// RemoteStorageServer is a reference implementation of the remote storage
+// protocol described on RemoteStorageService, backed by any StorageService.
+type RemoteStorageServer struct {
+	storage StorageService
+}
+
+func NewRemoteStorageServer(storage StorageService) *RemoteStorageServer {
+	return &RemoteStorageServer{storage: storage}
+}
+
+func (s *RemoteStorageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, remoteStorageDataPath):
+		s.serveData(w, r)
+	case r.URL.Path == remoteStorageHealthPath && r.Method == http.MethodGet:
+		if err := s.storage.HealthCheck(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == remoteStorageExpirationPolicyPath && r.Method == http.MethodGet:
+		policy, err := s.storage.ExpirationPolicy(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		policyString, err := policy.String()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(policyString))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *RemoteStorageServer) serveData(w http.ResponseWriter, r *http.Request) {
+	key, err := DecodeStorageServiceKey(strings.TrimPrefix(r.URL.Path, remoteStorageDataPath))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		data, err := s.storage.GetByHash(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Warn("remote storage server failed to get data", "key", key, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var expirationTime uint64
+		if param := r.URL.Query().Get(remoteStorageExpirationParam); param != "" {
+			expirationTime, err = strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.put(r, key, data, expirationTime); err != nil {
+			log.Warn("remote storage server failed to put data", "key", key, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *RemoteStorageServer) put(r *http.Request, key common.Hash, data []byte, expirationTime uint64) error {
+	// Put skips data that's already present, so values are replaced by key wherever the storage allows it.
+	if writer, ok := s.storage.(expiringKeyValueStorageService); ok && expirationTime != 0 {
+		return writer.putKeyValueWithExpiry(r.Context(), key, data, expirationTime)
+	}
+	if dastree.Hash(data) == key && expirationTime != 0 {
+		// Only Put can honor the expiration time.
+		return s.storage.Put(r.Context(), data, expirationTime)
+	}
+	writer, ok := s.storage.(IterationCompatibleStorageService)
+	if _, isAdaptor := s.storage.(*IterationCompatibleStorageServiceAdaptor); ok && !isAdaptor {
+		return writer.putKeyValue(r.Context(), key, data)
+	}
+	if dastree.Hash(data) == key {
+		return s.storage.Put(r.Context(), data, expirationTime)
+	}
+	return fmt.Errorf("%v only accepts data stored under its hash", s.storage)
+}
@@ -0,0 +1,188 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/pretty"
+)
+
+type RemoteStorageConfig struct {
+	Enable                 bool          `koanf:"enable"`
+	URL                    string        `koanf:"url"`
+	Timeout                time.Duration `koanf:"timeout"`
+	SyncFromStorageService bool          `koanf:"sync-from-storage-service"`
+	SyncToStorageService   bool          `koanf:"sync-to-storage-service"`
+}
+
+var DefaultRemoteStorageConfig = RemoteStorageConfig{
+	Enable:  false,
+	URL:     "",
+	Timeout: 10 * time.Second,
+}
+
+func RemoteStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRemoteStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from an external process implementing the remote storage protocol")
+	f.String(prefix+".url", DefaultRemoteStorageConfig.URL, "URL including 'http://' or 'https://' prefix of the remote storage process")
+	f.Duration(prefix+".timeout", DefaultRemoteStorageConfig.Timeout, "timeout for requests to the remote storage process")
+	f.Bool(prefix+".sync-from-storage-service", DefaultRemoteStorageConfig.SyncFromStorageService, "enable remote storage to be used as a source for regular sync storage")
+	f.Bool(prefix+".sync-to-storage-service", DefaultRemoteStorageConfig.SyncToStorageService, "enable remote storage to be used as a sink for regular sync storage")
+}
+
+const (
+	remoteStorageDataPath             = "/data/"
+	remoteStorageHealthPath           = "/health"
+	remoteStorageExpirationPolicyPath = "/expiration-policy"
+	remoteStorageExpirationParam      = "expiration"
+)
+
+// RemoteStorageService stores data in an external process that implements the
+// remote storage protocol, so that operators can plug in their own storage
+// without building it into the daserver. All paths are relative to the
+// configured URL:
+//
+//	PUT /data/<key>?expiration=<timestamp>
+//	    Store the request body under key, a 64 character hex string.
+//	    The timestamp is in unix seconds; the data may be discarded after it.
+//	    The expiration is optional, and omitted for the iteration index, which must
+//	    be kept forever. Storing to an existing key replaces its value.
+//	    Responds 200 on success.
+//	GET /data/<key>
+//	    Responds 200 with exactly the bytes stored under key, or 404 if there are none.
+//	GET /health
+//	    Responds 200 if the storage is able to serve requests.
+//	GET /expiration-policy
+//	    Responds 200 with one of KeepForever, DiscardAfterArchiveTimeout or
+//	    DiscardAfterDataTimeout as the body.
+//
+// Any other status is treated as an error. RemoteStorageServer is a reference
+// implementation of the protocol.
+type RemoteStorageService struct {
+	url    string
+	client *http.Client
+}
+
+func NewRemoteStorageService(config RemoteStorageConfig) (*RemoteStorageService, error) {
+	if !(strings.HasPrefix(config.URL, "http://") || strings.HasPrefix(config.URL, "https://")) {
+		return nil, fmt.Errorf("protocol prefix 'http://' or 'https://' must be specified for remote-storage.url; got '%s'", config.URL)
+	}
+	return &RemoteStorageService{
+		url:    strings.TrimSuffix(config.URL, "/"),
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (r *RemoteStorageService) do(ctx context.Context, method string, path string, body []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resBody, res.StatusCode, nil
+}
+
+func remoteStorageStatusError(status int, body []byte) error {
+	return fmt.Errorf("HTTP error with status %d returned by remote storage: %s %s", status, http.StatusText(status), pretty.FirstFewChars(string(body)))
+}
+
+func (r *RemoteStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	log.Trace("das.RemoteStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", r)
+	body, status, err := r.do(ctx, http.MethodGet, remoteStorageDataPath+EncodeStorageServiceKey(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, remoteStorageStatusError(status, body)
+	}
+}
+
+func (r *RemoteStorageService) Put(ctx context.Context, data []byte, expirationTime uint64) error {
+	logPut("das.RemoteStorageService.Store", data, expirationTime, r)
+	return r.put(ctx, dastree.Hash(data), data, expirationTime)
+}
+
+func (r *RemoteStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	return r.put(ctx, key, value, 0)
+}
+
+func (r *RemoteStorageService) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error {
+	return r.put(ctx, key, value, expirationTime)
+}
+
+func (r *RemoteStorageService) put(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error {
+	path := remoteStorageDataPath + EncodeStorageServiceKey(key)
+	if expirationTime != 0 {
+		path += "?" + remoteStorageExpirationParam + "=" + strconv.FormatUint(expirationTime, 10)
+	}
+	body, status, err := r.do(ctx, http.MethodPut, path, value)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return remoteStorageStatusError(status, body)
+	}
+	return nil
+}
+
+func (r *RemoteStorageService) Sync(ctx context.Context) error {
+	return nil
+}
+
+func (r *RemoteStorageService) Close(ctx context.Context) error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+func (r *RemoteStorageService) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	body, status, err := r.do(ctx, http.MethodGet, remoteStorageExpirationPolicyPath, nil)
+	if err != nil {
+		return -1, err
+	}
+	if status != http.StatusOK {
+		return -1, remoteStorageStatusError(status, body)
+	}
+	return arbstate.StringToExpirationPolicy(strings.TrimSpace(string(body)))
+}
+
+func (r *RemoteStorageService) String() string {
+	return "RemoteStorageService(" + r.url + ")"
+}
+
+func (r *RemoteStorageService) HealthCheck(ctx context.Context) error {
+	body, status, err := r.do(ctx, http.MethodGet, remoteStorageHealthPath, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return remoteStorageStatusError(status, body)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestRemoteStorageService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backing := NewMemoryBackedStorageService(ctx)
+	server := httptest.NewServer(NewRemoteStorageServer(backing))
+	defer server.Close()
+
+	config := DefaultRemoteStorageConfig
+	config.URL = server.URL
+	remote, err := NewRemoteStorageService(config)
+	Require(t, err)
+	Require(t, remote.HealthCheck(ctx))
+
+	policy, err := remote.ExpirationPolicy(ctx)
+	Require(t, err)
+	if policy != arbstate.KeepForever {
+		Fail(t, "unexpected expiration policy", policy)
+	}
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	value := []byte("data stored through the remote storage protocol")
+	Require(t, remote.Put(ctx, value, timeout))
+	res, err := remote.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+	if !bytes.Equal(res, value) {
+		Fail(t, "unexpected value", res, value)
+	}
+	res, err = backing.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+	if !bytes.Equal(res, value) {
+		Fail(t, "value not stored in backing storage", res)
+	}
+
+	_, err = remote.GetByHash(ctx, dastree.Hash([]byte("absent value")))
+	if !errors.Is(err, ErrNotFound) {
+		Fail(t, "expected ErrNotFound, got", err)
+	}
+
+	// The remote storage can hold the iteration list, so it can be synced from.
+	iterable := NewIterableStorageService(remote)
+	values := [][]byte{[]byte("first"), []byte("second")}
+	for _, value := range values {
+		Require(t, iterable.Put(ctx, value, timeout))
+	}
+	hash := iterable.DefaultBegin()
+	for _, value := range values {
+		hash = iterable.Next(ctx, hash)
+		if hash != dastree.Hash(value) {
+			Fail(t, "unexpected iteration order", hash)
+		}
+	}
+	if iterable.End(ctx) != hash {
+		Fail(t, "unexpected end of iteration", iterable.End(ctx))
+	}
+
+	server.Close()
+	if err := remote.HealthCheck(ctx); err == nil {
+		Fail(t, "expected health check to fail once the server is gone")
+	}
+}
+
+func TestRemoteStorageServerReplacesData(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Local file storage skips data that's already present when it's Put
+	backing, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+		DataDir:       t.TempDir(),
+		EnableExpiry:  true,
+		PruneInterval: time.Hour,
+	})
+	Require(t, err)
+	defer backing.Close(ctx)
+	server := httptest.NewServer(NewRemoteStorageServer(backing))
+	defer server.Close()
+	config := DefaultRemoteStorageConfig
+	config.URL = server.URL
+	remote, err := NewRemoteStorageService(config)
+	Require(t, err)
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	value := []byte("data which gets corrupted")
+	Require(t, backing.(*LocalFileStorageService).putKeyValue(ctx, dastree.Hash(value), []byte("garbage")))
+	Require(t, remote.Put(ctx, value, timeout))
+	res, err := backing.GetByHash(ctx, dastree.Hash(value))
+	Require(t, err)
+	if !bytes.Equal(res, value) {
+		Fail(t, "value not replaced in backing storage", res)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	scrubberCheckedCounter      = metrics.NewRegisteredCounter("arb/das/scrubber/checked", nil)
+	scrubberCorruptCounter      = metrics.NewRegisteredCounter("arb/das/scrubber/corrupt", nil)
+	scrubberMissingCounter      = metrics.NewRegisteredCounter("arb/das/scrubber/missing", nil)
+	scrubberRepairedCounter     = metrics.NewRegisteredCounter("arb/das/scrubber/repaired", nil)
+	scrubberRepairFailedCounter = metrics.NewRegisteredCounter("arb/das/scrubber/repair/failed", nil)
+)
+
+type ScrubberConfig struct {
+	Enable     bool          `koanf:"enable"`
+	Interval   time.Duration `koanf:"interval"`
+	Repair     bool          `koanf:"repair"`
+	RepairUrls []string      `koanf:"repair-urls"`
+}
+
+var DefaultScrubberConfig = ScrubberConfig{
+	Enable:     false,
+	Interval:   24 * time.Hour,
+	Repair:     false,
+	RepairUrls: []string{},
+}
+
+func ScrubberConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultScrubberConfig.Enable, "enable periodically checking that the data held by the sync-from storage services still matches its hash")
+	f.Duration(prefix+".interval", DefaultScrubberConfig.Interval, "interval between full scrubs of storage")
+	f.Bool(prefix+".repair", DefaultScrubberConfig.Repair, "replace corrupt or missing data with a copy fetched from repair-urls")
+	f.StringSlice(prefix+".repair-urls", DefaultScrubberConfig.RepairUrls, "list of URLs including 'http://' or 'https://' prefixes and port numbers to REST DAS endpoints to fetch good copies of data from")
+}
+
+// ScrubReport describes the outcome of a scrub.
+type ScrubReport struct {
+	Checked      int
+	Corrupt      []common.Hash
+	Missing      []common.Hash
+	Repaired     []common.Hash
+	RepairFailed []common.Hash
+}
+
+func (r *ScrubReport) String() string {
+	return fmt.Sprintf(
+		"checked %d, corrupt %d, missing %d, repaired %d, repair failed %d",
+		r.Checked, len(r.Corrupt), len(r.Missing), len(r.Repaired), len(r.RepairFailed),
+	)
+}
+
+// A Scrubber checks that the data in a StorageService still hashes to its key,
+// and optionally repairs anything corrupt or missing from other DAS REST endpoints.
+type Scrubber struct {
+	stopwaiter.StopWaiter
+	storage       StorageService
+	repairSources []*RestfulDasClient
+	interval      time.Duration
+	repair        bool
+}
+
+func NewScrubber(storage StorageService, config ScrubberConfig) (*Scrubber, error) {
+	var repairSources []*RestfulDasClient
+	for _, url := range config.RepairUrls {
+		client, err := NewRestfulDasClientFromURL(url)
+		if err != nil {
+			return nil, err
+		}
+		repairSources = append(repairSources, client)
+	}
+	if config.Repair && len(repairSources) == 0 {
+		return nil, errors.New("scrubber.repair requires at least one of scrubber.repair-urls")
+	}
+	return &Scrubber{
+		storage:       storage,
+		repairSources: repairSources,
+		interval:      config.Interval,
+		repair:        config.Repair,
+	}, nil
+}
+
+// Start periodically scrubs the scrubber's storage, which must be iterable.
+func (s *Scrubber) Start(ctx context.Context) error {
+	if _, ok := s.storage.(*IterableStorageService); !ok {
+		return fmt.Errorf("periodic scrubbing requires an iterable storage service, got %v", s.storage)
+	}
+	s.StopWaiter.Start(ctx, s)
+	s.CallIteratively(func(ctx context.Context) time.Duration {
+		var report ScrubReport
+		err := s.ScrubIterable(ctx, &report)
+		if err != nil {
+			log.Error("error scrubbing DAS storage", "storage", s.storage, "err", err)
+		}
+		log.Info("scrubbed DAS storage", "storage", s.storage, "report", report.String())
+		return s.interval
+	})
+	return nil
+}
+
+func (s *Scrubber) Close(ctx context.Context) error {
+	s.StopAndWait()
+	return nil
+}
+
+func (s *Scrubber) String() string {
+	return "Scrubber(" + s.storage.String() + ")"
+}
+
+// ScrubIterable checks every unexpired entry in the iteration list of the scrubber's storage.
+func (s *Scrubber) ScrubIterable(ctx context.Context, report *ScrubReport) error {
+	iterable, ok := s.storage.(*IterableStorageService)
+	if !ok {
+		return fmt.Errorf("can't iterate over %v", s.storage)
+	}
+	end := iterable.End(ctx)
+	hash := iterable.DefaultBegin()
+	for (end != common.Hash{}) && hash != end {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		nextHash := iterable.Next(ctx, hash)
+		if (nextHash == common.Hash{}) {
+			return fmt.Errorf("couldn't find the entry after %v in %v", hash, iterable)
+		}
+		hash = nextHash
+		expirationTime, err := iterable.GetExpirationTime(ctx, hash)
+		if err != nil {
+			// Left as 0, so that missing data isn't repaired only to be pruned straight away
+			log.Warn("scrubber couldn't read expiration time", "hash", hash, "err", err)
+			expirationTime = 0
+		} else if expirationTime < uint64(time.Now().Unix()) {
+			// Expired data may legitimately be gone.
+			continue
+		}
+		if err := s.scrubHash(ctx, hash, expirationTime, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScrubBlockRange checks the keyset and batch data referenced by every DAS batch
+// posted to the sequencer inbox between fromBlock and toBlock inclusive.
+func (s *Scrubber) ScrubBlockRange(
+	ctx context.Context,
+	l1Client arbutil.L1Interface,
+	inboxAddr common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+	report *ScrubReport,
+) error {
+	inboxContract, err := bridgegen.NewSequencerInbox(inboxAddr, l1Client)
+	if err != nil {
+		return err
+	}
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{inboxAddr},
+		Topics:    [][]common.Hash{{BatchDeliveredID}},
+	}
+	logs, err := l1Client.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, deliveredLog := range logs {
+		deliveredEvent, err := inboxContract.ParseSequencerBatchDelivered(deliveredLog)
+		if err != nil {
+			return err
+		}
+		data, err := FindDASDataFromLog(ctx, inboxContract, deliveredEvent, inboxAddr, l1Client, deliveredLog)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		cert, err := arbstate.DeserializeDASCertFrom(bytes.NewReader(data))
+		if err != nil {
+			log.Warn("scrubber couldn't deserialize DAS certificate", "batch", deliveredEvent.BatchSequenceNumber, "err", err)
+			continue
+		}
+		if cert.Timeout < uint64(time.Now().Unix()) {
+			continue
+		}
+		for _, hash := range []common.Hash{cert.KeysetHash, cert.DataHash} {
+			if cert.Version == 0 {
+				// Old certificates may refer to data stored under either style of hash.
+				treeHash := dastree.FlatHashToTreeHash(hash)
+				if _, err := s.storage.GetByHash(ctx, treeHash); err == nil {
+					hash = treeHash
+				}
+			}
+			if err := s.scrubHash(ctx, hash, cert.Timeout, report); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validPreimage(hash common.Hash, data []byte) bool {
+	return dastree.ValidHash(hash, data) || crypto.Keccak256Hash(data) == hash
+}
+
+func (s *Scrubber) scrubHash(ctx context.Context, hash common.Hash, expirationTime uint64, report *ScrubReport) error {
+	report.Checked++
+	scrubberCheckedCounter.Inc(1)
+	data, err := s.storage.GetByHash(ctx, hash)
+	corrupt := false
+	switch {
+	case errors.Is(err, ErrNotFound):
+		log.Warn("scrubber found missing DAS data", "hash", hash, "storage", s.storage)
+		report.Missing = append(report.Missing, hash)
+		scrubberMissingCounter.Inc(1)
+	case err != nil:
+		return err
+	case !validPreimage(hash, data):
+		log.Error("scrubber found corrupt DAS data", "hash", hash, "storage", s.storage)
+		report.Corrupt = append(report.Corrupt, hash)
+		scrubberCorruptCounter.Inc(1)
+		corrupt = true
+	default:
+		return nil
+	}
+	if !s.repair {
+		return nil
+	}
+	if err := s.repairHash(ctx, hash, expirationTime, corrupt); err != nil {
+		log.Error("scrubber failed to repair DAS data", "hash", hash, "err", err)
+		report.RepairFailed = append(report.RepairFailed, hash)
+		scrubberRepairFailedCounter.Inc(1)
+		return nil
+	}
+	report.Repaired = append(report.Repaired, hash)
+	scrubberRepairedCounter.Inc(1)
+	return nil
+}
+
+// repairHash replaces the data of hash with a valid copy from the repair sources.
+// An expirationTime of 0 means it's unknown, in which case the data can't be put back, as it would never expire.
+func (s *Scrubber) repairHash(ctx context.Context, hash common.Hash, expirationTime uint64, corrupt bool) error {
+	if expirationTime == 0 {
+		return errors.New("can't repair data without knowing its expiration time")
+	}
+	var data []byte
+	for _, source := range s.repairSources {
+		candidate, err := source.GetByHash(ctx, hash)
+		if err != nil {
+			log.Debug("scrubber repair source failed", "source", source.url, "hash", hash, "err", err)
+			continue
+		}
+		if validPreimage(hash, candidate) {
+			data = candidate
+			break
+		}
+		log.Warn("scrubber repair source returned bad data", "source", source.url, "hash", hash)
+	}
+	if data == nil {
+		return errors.New("no repair source had a valid copy")
+	}
+	target := s.storage
+	if iterable, ok := target.(*IterableStorageService); ok {
+		// The entry is already in the iteration list, so bypass it to avoid adding it twice.
+		target = iterable.IterationCompatibleStorageService
+	}
+	var err error
+	if corrupt {
+		// Put won't replace data that's already present, so overwrite corrupt data directly.
+		writer, ok := target.(expiringKeyValueStorageService)
+		if !ok {
+			return fmt.Errorf("can't overwrite corrupt data in %v", target)
+		}
+		err = writer.putKeyValueWithExpiry(ctx, hash, data, expirationTime)
+	} else {
+		err = target.Put(ctx, data, expirationTime)
+	}
+	if err != nil {
+		return err
+	}
+	repaired, err := s.storage.GetByHash(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("couldn't read back repaired data: %w", err)
+	}
+	if !validPreimage(hash, repaired) {
+		return errors.New("repaired data still doesn't match its hash")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestScrubberFindsAndRepairsCorruption(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	good := []byte("data that stays intact")
+	corrupted := []byte("data that gets corrupted")
+
+	backing := NewMemoryBackedStorageService(ctx)
+	storage := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(backing))
+	Require(t, storage.Put(ctx, good, timeout))
+	Require(t, storage.Put(ctx, corrupted, timeout))
+	Require(t, storage.putKeyValue(ctx, dastree.Hash(corrupted), []byte("garbage")))
+
+	scrubber, err := NewScrubber(storage, DefaultScrubberConfig)
+	Require(t, err)
+	var report ScrubReport
+	Require(t, scrubber.ScrubIterable(ctx, &report))
+	if report.Checked != 2 || len(report.Corrupt) != 1 || report.Corrupt[0] != dastree.Hash(corrupted) {
+		Fail(t, "unexpected scrub report", report.String())
+	}
+
+	goodCopies := NewMemoryBackedStorageService(ctx)
+	Require(t, goodCopies.Put(ctx, corrupted, timeout))
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, goodCopies)
+	Require(t, err)
+	defer func() {
+		Require(t, server.Shutdown())
+	}()
+
+	repairConfig := DefaultScrubberConfig
+	repairConfig.Repair = true
+	repairConfig.RepairUrls = []string{fmt.Sprintf("http://%s:%d", LocalServerAddressForTest, port)}
+	scrubber, err = NewScrubber(storage, repairConfig)
+	Require(t, err)
+	report = ScrubReport{}
+	Require(t, scrubber.ScrubIterable(ctx, &report))
+	if len(report.Repaired) != 1 || len(report.RepairFailed) != 0 {
+		Fail(t, "unexpected scrub report", report.String())
+	}
+
+	repaired, err := storage.GetByHash(ctx, dastree.Hash(corrupted))
+	Require(t, err)
+	if !bytes.Equal(repaired, corrupted) {
+		Fail(t, "data wasn't repaired", repaired)
+	}
+	report = ScrubReport{}
+	Require(t, scrubber.ScrubIterable(ctx, &report))
+	if len(report.Corrupt) != 0 || len(report.Missing) != 0 {
+		Fail(t, "unexpected scrub report after repair", report.String())
+	}
+}
+
+// withoutKeyValue hides putKeyValue, so that the storage service can only be wrapped in an adaptor.
+type withoutKeyValue struct {
+	StorageService
+}
+
+func TestScrubberRepairFailsWhenCorruptDataCantBeOverwritten(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	corrupted := []byte("data that gets corrupted")
+	backing := NewMemoryBackedStorageService(ctx)
+	storage := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(withoutKeyValue{backing}))
+	Require(t, storage.Put(ctx, corrupted, timeout))
+	Require(t, backing.putKeyValue(ctx, dastree.Hash(corrupted), []byte("garbage")))
+
+	goodCopies := NewMemoryBackedStorageService(ctx)
+	Require(t, goodCopies.Put(ctx, corrupted, timeout))
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, goodCopies)
+	Require(t, err)
+	defer func() {
+		Require(t, server.Shutdown())
+	}()
+
+	repairConfig := DefaultScrubberConfig
+	repairConfig.Repair = true
+	repairConfig.RepairUrls = []string{fmt.Sprintf("http://%s:%d", LocalServerAddressForTest, port)}
+	scrubber, err := NewScrubber(storage, repairConfig)
+	Require(t, err)
+	// The adaptor doesn't keep an iteration list, so the data is scrubbed by hash as for a block range
+	var report ScrubReport
+	Require(t, scrubber.scrubHash(ctx, dastree.Hash(corrupted), timeout, &report))
+	if len(report.Corrupt) != 1 || len(report.Repaired) != 0 || len(report.RepairFailed) != 1 {
+		Fail(t, "unexpected scrub report", report.String())
+	}
+}
+
+// recordingExpiry remembers the expiration times values are written with by key.
+type recordingExpiry struct {
+	*MemoryBackedStorageService
+	expirations map[common.Hash]uint64
+}
+
+func (r *recordingExpiry) putKeyValueWithExpiry(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error {
+	r.expirations[key] = expirationTime
+	return r.MemoryBackedStorageService.putKeyValueWithExpiry(ctx, key, value, expirationTime)
+}
+
+func TestScrubberRepairKeepsExpiry(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	corrupted := []byte("data that gets corrupted")
+	backing := &recordingExpiry{NewMemoryBackedStorageService(ctx), make(map[common.Hash]uint64)}
+	storage := NewIterableStorageService(backing)
+	Require(t, storage.Put(ctx, corrupted, timeout))
+	Require(t, backing.putKeyValue(ctx, dastree.Hash(corrupted), []byte("garbage")))
+
+	goodCopies := NewMemoryBackedStorageService(ctx)
+	Require(t, goodCopies.Put(ctx, corrupted, timeout))
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, goodCopies)
+	Require(t, err)
+	defer func() {
+		Require(t, server.Shutdown())
+	}()
+
+	repairConfig := DefaultScrubberConfig
+	repairConfig.Repair = true
+	repairConfig.RepairUrls = []string{fmt.Sprintf("http://%s:%d", LocalServerAddressForTest, port)}
+	scrubber, err := NewScrubber(storage, repairConfig)
+	Require(t, err)
+	var report ScrubReport
+	Require(t, scrubber.ScrubIterable(ctx, &report))
+	if len(report.Repaired) != 1 {
+		Fail(t, "unexpected scrub report", report.String())
+	}
+	if expiration, ok := backing.expirations[dastree.Hash(corrupted)]; !ok || expiration != timeout {
+		Fail(t, "repaired data was written with expiration", expiration, "instead of", timeout)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +35,7 @@ type RestfulClientAggregatorConfig struct {
 	WaitBeforeTryNext            time.Duration                      `koanf:"wait-before-try-next"`
 	MaxPerEndpointStats          int                                `koanf:"max-per-endpoint-stats"`
 	SimpleExploreExploitStrategy SimpleExploreExploitStrategyConfig `koanf:"simple-explore-exploit-strategy"`
+	LatencyAwareStrategy         LatencyAwareStrategyConfig         `koanf:"latency-aware-strategy"`
 	SyncToStorage                SyncToStorageConfig                `koanf:"sync-to-storage"`
 }
 
@@ -46,6 +48,7 @@ var DefaultRestfulClientAggregatorConfig = RestfulClientAggregatorConfig{
 	WaitBeforeTryNext:            2 * time.Second,
 	MaxPerEndpointStats:          20,
 	SimpleExploreExploitStrategy: DefaultSimpleExploreExploitStrategyConfig,
+	LatencyAwareStrategy:         DefaultLatencyAwareStrategyConfig,
 	SyncToStorage:                DefaultSyncToStorageConfig,
 }
 
@@ -59,16 +62,48 @@ var DefaultSimpleExploreExploitStrategyConfig = SimpleExploreExploitStrategyConf
 	ExploitIterations: 1000,
 }
 
+type LatencyAwareStrategyConfig struct {
+	HedgePercentile     int           `koanf:"hedge-percentile"`
+	MinHedgeDelay       time.Duration `koanf:"min-hedge-delay"`
+	BreakerErrorRatio   float64       `koanf:"breaker-error-ratio"`
+	BreakerMinRequests  int           `koanf:"breaker-min-requests"`
+	BreakerWindow       int           `koanf:"breaker-window"`
+	BreakerOpenDuration time.Duration `koanf:"breaker-open-duration"`
+}
+
+var DefaultLatencyAwareStrategyConfig = LatencyAwareStrategyConfig{
+	HedgePercentile:     95,
+	MinHedgeDelay:       50 * time.Millisecond,
+	BreakerErrorRatio:   0.5,
+	BreakerMinRequests:  5,
+	BreakerWindow:       20,
+	BreakerOpenDuration: 30 * time.Second,
+}
+
+func (c *LatencyAwareStrategyConfig) Validate() error {
+	if c.HedgePercentile <= 0 || c.HedgePercentile > 100 {
+		return fmt.Errorf("latency-aware-strategy.hedge-percentile must be between 1 and 100, got %d", c.HedgePercentile)
+	}
+	if c.BreakerWindow <= 0 {
+		return fmt.Errorf("latency-aware-strategy.breaker-window must be positive, got %d", c.BreakerWindow)
+	}
+	if c.BreakerMinRequests <= 0 || c.BreakerMinRequests > c.BreakerWindow {
+		return fmt.Errorf("latency-aware-strategy.breaker-min-requests must be between 1 and breaker-window, got %d", c.BreakerMinRequests)
+	}
+	return nil
+}
+
 func RestfulClientAggregatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultRestfulClientAggregatorConfig.Enable, "enable retrieval of sequencer batch data from a list of remote REST endpoints; if other DAS storage types are enabled, this mode is used as a fallback")
 	f.StringSlice(prefix+".urls", DefaultRestfulClientAggregatorConfig.Urls, "list of URLs including 'http://' or 'https://' prefixes and port numbers to REST DAS endpoints; additive with the online-url-list option")
 	f.String(prefix+".online-url-list", DefaultRestfulClientAggregatorConfig.OnlineUrlList, "a URL to a list of URLs of REST das endpoints that is checked at startup; additive with the url option")
 	f.Duration(prefix+".online-url-list-fetch-interval", DefaultRestfulClientAggregatorConfig.OnlineUrlListFetchInterval, "time interval to periodically fetch url list from online-url-list")
-	f.String(prefix+".strategy", DefaultRestfulClientAggregatorConfig.Strategy, "strategy to use to determine order and parallelism of calling REST endpoint URLs; valid options are 'simple-explore-exploit'")
+	f.String(prefix+".strategy", DefaultRestfulClientAggregatorConfig.Strategy, "strategy to use to determine order and parallelism of calling REST endpoint URLs; valid options are 'simple-explore-exploit' and 'latency-aware'")
 	f.Duration(prefix+".strategy-update-interval", DefaultRestfulClientAggregatorConfig.StrategyUpdateInterval, "how frequently to update the strategy with endpoint latency and error rate data")
 	f.Duration(prefix+".wait-before-try-next", DefaultRestfulClientAggregatorConfig.WaitBeforeTryNext, "time to wait until trying the next set of REST endpoints while waiting for a response; the next set of REST endpoints is determined by the strategy selected")
 	f.Int(prefix+".max-per-endpoint-stats", DefaultRestfulClientAggregatorConfig.MaxPerEndpointStats, "number of stats entries (latency and success rate) to keep for each REST endpoint; controls whether strategy is faster or slower to respond to changing conditions")
 	SimpleExploreExploitStrategyConfigAddOptions(prefix+".simple-explore-exploit-strategy", f)
+	LatencyAwareStrategyConfigAddOptions(prefix+".latency-aware-strategy", f)
 	SyncToStorageConfigAddOptions(prefix+".sync-to-storage", f)
 }
 
@@ -77,6 +112,15 @@ func SimpleExploreExploitStrategyConfigAddOptions(prefix string, f *flag.FlagSet
 	f.Int(prefix+".exploit-iterations", DefaultSimpleExploreExploitStrategyConfig.ExploitIterations, "number of consecutive GetByHash calls to the aggregator where each call will cause it to select from REST endpoints in order of best latency and success rate, before switching to explore mode")
 }
 
+func LatencyAwareStrategyConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Int(prefix+".hedge-percentile", DefaultLatencyAwareStrategyConfig.HedgePercentile, "percentile of a REST endpoint's recent latencies to wait for before also requesting from the next best endpoint")
+	f.Duration(prefix+".min-hedge-delay", DefaultLatencyAwareStrategyConfig.MinHedgeDelay, "minimum time to wait for a REST endpoint before also requesting from the next best endpoint")
+	f.Float64(prefix+".breaker-error-ratio", DefaultLatencyAwareStrategyConfig.BreakerErrorRatio, "ratio of failed to total recent requests at which a REST endpoint stops being used")
+	f.Int(prefix+".breaker-min-requests", DefaultLatencyAwareStrategyConfig.BreakerMinRequests, "minimum number of recent requests to a REST endpoint before its error ratio can cause it to stop being used")
+	f.Int(prefix+".breaker-window", DefaultLatencyAwareStrategyConfig.BreakerWindow, "number of recent requests to a REST endpoint used to calculate its error ratio")
+	f.Duration(prefix+".breaker-open-duration", DefaultLatencyAwareStrategyConfig.BreakerOpenDuration, "time a REST endpoint stops being used for after its error ratio gets too high, before a single request is made to check whether it has recovered")
+}
+
 func NewRestfulClientAggregator(ctx context.Context, config *RestfulClientAggregatorConfig) (*SimpleDASReaderAggregator, error) {
 	a := SimpleDASReaderAggregator{
 		config: config,
@@ -123,6 +167,12 @@ func NewRestfulClientAggregator(ctx context.Context, config *RestfulClientAggreg
 			exploreIterations: uint32(config.SimpleExploreExploitStrategy.ExploreIterations),
 			exploitIterations: uint32(config.SimpleExploreExploitStrategy.ExploitIterations),
 		}
+	case "latency-aware":
+		strategy, err := newLatencyAwareStrategy(&config.LatencyAwareStrategy)
+		if err != nil {
+			return nil, err
+		}
+		a.strategy = strategy
 	case "testing-sequential":
 		a.strategy = &testingSequentialStrategy{}
 	default:
@@ -153,6 +203,39 @@ func (s *readerStats) successRatioWeightedMeanLatency() time.Duration {
 	return time.Duration(avgLatency / successRatio)
 }
 
+// Return the given percentile of the latencies of successful requests, or 0 if there were none
+func (s *readerStats) latencyPercentile(percentile int) time.Duration {
+	var latencies []time.Duration
+	for _, stat := range *s {
+		if stat.success {
+			latencies = append(latencies, stat.latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	index := (len(latencies)*percentile+99)/100 - 1
+	if index < 0 {
+		index = 0
+	}
+	return latencies[index]
+}
+
+// Return the ratio of failed requests to total requests
+func (s *readerStats) errorRatio() float64 {
+	if len(*s) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, stat := range *s {
+		if !stat.success {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(*s))
+}
+
 type readerStat struct {
 	latency time.Duration
 	success bool
@@ -194,6 +277,7 @@ func (a *SimpleDASReaderAggregator) GetByHash(ctx context.Context, hash common.H
 
 	go func() {
 		si := a.strategy.newInstance()
+		hedging, _ := si.(hedgingStrategyInstance)
 		for readers := si.nextReaders(); len(readers) != 0 && subCtx.Err() == nil; readers = si.nextReaders() {
 			wg := sync.WaitGroup{}
 			waitChan := make(chan interface{})
@@ -214,10 +298,14 @@ func (a *SimpleDASReaderAggregator) GetByHash(ctx context.Context, hash common.H
 				wg.Wait()
 				close(waitChan)
 			}()
+			wait := a.config.WaitBeforeTryNext
+			if hedging != nil {
+				wait = hedging.waitBeforeTryNext(wait)
+			}
 			select {
 			case <-subCtx.Done():
 				return
-			case <-time.After(a.config.WaitBeforeTryNext):
+			case <-time.After(wait):
 			case <-waitChan:
 				// Yield to give the collector a chance to run in case a request succeeded
 				time.Sleep(10 * time.Millisecond)
@@ -258,6 +346,10 @@ func (a *SimpleDASReaderAggregator) tryGetByHash(
 		}
 	}
 	stat.latency = time.Since(start)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// The request was abandoned because another reader answered first, which isn't this reader's failure.
+		return result, err
+	}
 
 	select {
 	case a.statMessages <- stat:
@@ -313,6 +405,9 @@ func (a *SimpleDASReaderAggregator) Start(ctx context.Context) {
 				return
 			case stat := <-a.statMessages:
 				a.stats[stat.reader] = append(a.stats[stat.reader], stat.readerStat)
+				if observer, ok := a.strategy.(statObserver); ok {
+					observer.observe(stat.reader, stat.readerStat)
+				}
 				statsLen := len(a.stats[stat.reader])
 				if statsLen > a.config.MaxPerEndpointStats {
 					a.stats[stat.reader] = a.stats[stat.reader][statsLen-a.config.MaxPerEndpointStats:]
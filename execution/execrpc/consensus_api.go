@@ -0,0 +1,111 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/node"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+const ConsensusNamespace string = "consensus"
+
+// The errors the two sides check for with errors.Is, which only their messages survive RPC of
+var sentinelErrors = []error{
+	execution.ErrRetrySequencer,
+	execution.ErrSequencerInsertLockTaken,
+}
+
+// fromRpcError wraps the sentinel error an error returned over RPC was created from, if any.
+func fromRpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, sentinel := range sentinelErrors {
+		if strings.Contains(err.Error(), sentinel.Error()) {
+			return fmt.Errorf("%w: %v", sentinel, err)
+		}
+	}
+	return err
+}
+
+// ConsensusServerAPI serves the transaction streamer of a consensus node to its remote execution,
+// for the sequencer to write the messages it creates.
+type ConsensusServerAPI struct {
+	streamer execution.TransactionStreamer
+}
+
+func NewConsensusServerAPI(streamer execution.TransactionStreamer) *ConsensusServerAPI {
+	return &ConsensusServerAPI{streamer}
+}
+
+func (a *ConsensusServerAPI) FetchBatch(batchNum uint64) ([]byte, error) {
+	return a.streamer.FetchBatch(batchNum)
+}
+
+func (a *ConsensusServerAPI) WriteMessageFromSequencer(pos arbutil.MessageIndex, msgWithMeta arbostypes.MessageWithMetadata) error {
+	return a.streamer.WriteMessageFromSequencer(pos, msgWithMeta)
+}
+
+func (a *ConsensusServerAPI) ExpectChosenSequencer() error {
+	return a.streamer.ExpectChosenSequencer()
+}
+
+// ConsensusClient is the transaction streamer of a remote consensus node, as seen by the execution.
+type ConsensusClient struct {
+	stopwaiter.StopWaiter
+	client *rpcclient.RpcClient
+}
+
+var _ execution.TransactionStreamer = (*ConsensusClient)(nil)
+
+func NewConsensusClient(config rpcclient.ClientConfigFetcher, stack *node.Node) *ConsensusClient {
+	return &ConsensusClient{
+		client: rpcclient.NewRpcClient(config, stack),
+	}
+}
+
+func (c *ConsensusClient) Start(ctxIn context.Context) error {
+	c.StopWaiter.Start(ctxIn, c)
+	return c.client.Start(c.GetContext())
+}
+
+func (c *ConsensusClient) StopAndWait() {
+	c.StopWaiter.StopAndWait()
+	c.client.Close()
+}
+
+func (c *ConsensusClient) FetchBatch(batchNum uint64) ([]byte, error) {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return nil, err
+	}
+	var res []byte
+	err = c.client.CallContext(ctx, &res, ConsensusNamespace+"_fetchBatch", batchNum)
+	return res, err
+}
+
+func (c *ConsensusClient) WriteMessageFromSequencer(pos arbutil.MessageIndex, msgWithMeta arbostypes.MessageWithMetadata) error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	return fromRpcError(c.client.CallContext(ctx, nil, ConsensusNamespace+"_writeMessageFromSequencer", pos, msgWithMeta))
+}
+
+func (c *ConsensusClient) ExpectChosenSequencer() error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	return fromRpcError(c.client.CallContext(ctx, nil, ConsensusNamespace+"_expectChosenSequencer"))
+}
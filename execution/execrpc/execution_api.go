@@ -0,0 +1,122 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package execrpc lets the consensus side of a node run in a different process or machine from its execution
+// side, by serving the execution over RPC to the consensus, and the transaction streamer back to the execution.
+package execrpc
+
+import (
+	"context"
+	"crypto/rand"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+)
+
+const Namespace string = "execution"
+
+type ServerConfig struct {
+	Enable    bool                   `koanf:"enable"`
+	Consensus rpcclient.ClientConfig `koanf:"consensus"`
+}
+
+type ServerConfigFetcher func() *ServerConfig
+
+var DefaultServerConfig = ServerConfig{
+	Enable: false,
+	Consensus: rpcclient.ClientConfig{
+		URL:         "",
+		ArgLogLimit: 2048,
+	},
+}
+
+func ServerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultServerConfig.Enable, "serve the execution over authenticated RPC to a consensus node in another process, instead of to one in this process")
+	rpcclient.RPCClientAddOptions(prefix+".consensus", f, &DefaultServerConfig.Consensus)
+}
+
+// ExecutionServerAPI serves an execution node to a remote ExecutionClient.
+type ExecutionServerAPI struct {
+	exec       execution.FullExecutionClient
+	instanceId string
+}
+
+func NewExecutionServerAPI(exec execution.FullExecutionClient) *ExecutionServerAPI {
+	var id common.Hash
+	_, _ = rand.Read(id[:])
+	return &ExecutionServerAPI{
+		exec:       exec,
+		instanceId: id.Hex(),
+	}
+}
+
+// InstanceId is random for each run of the execution, so that clients can tell it restarted.
+func (a *ExecutionServerAPI) InstanceId() string {
+	return a.instanceId
+}
+
+// DigestMessage returns the result of the message, which the client records to check the execution against if it restarts.
+func (a *ExecutionServerAPI) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (*execution.MessageResult, error) {
+	if err := a.exec.DigestMessage(num, msg); err != nil {
+		return nil, err
+	}
+	return a.exec.ResultAtPos(num)
+}
+
+func (a *ExecutionServerAPI) Reorg(count arbutil.MessageIndex, newMessages []arbostypes.MessageWithMetadata, oldMessages []*arbostypes.MessageWithMetadata) error {
+	return a.exec.Reorg(count, newMessages, oldMessages)
+}
+
+func (a *ExecutionServerAPI) HeadMessageNumber() (arbutil.MessageIndex, error) {
+	return a.exec.HeadMessageNumber()
+}
+
+func (a *ExecutionServerAPI) ResultAtPos(pos arbutil.MessageIndex) (*execution.MessageResult, error) {
+	return a.exec.ResultAtPos(pos)
+}
+
+func (a *ExecutionServerAPI) RecordBlockCreation(ctx context.Context, pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (*execution.RecordResult, error) {
+	return a.exec.RecordBlockCreation(ctx, pos, msg)
+}
+
+func (a *ExecutionServerAPI) MarkValid(pos arbutil.MessageIndex, resultHash common.Hash) {
+	a.exec.MarkValid(pos, resultHash)
+}
+
+func (a *ExecutionServerAPI) PrepareForRecord(ctx context.Context, start, end arbutil.MessageIndex) error {
+	return a.exec.PrepareForRecord(ctx, start, end)
+}
+
+func (a *ExecutionServerAPI) Pause() {
+	a.exec.Pause()
+}
+
+func (a *ExecutionServerAPI) Activate() {
+	a.exec.Activate()
+}
+
+func (a *ExecutionServerAPI) ForwardTo(url string) error {
+	return a.exec.ForwardTo(url)
+}
+
+func (a *ExecutionServerAPI) SequenceDelayedMessage(message *arbostypes.L1IncomingMessage, delayedSeqNum uint64) error {
+	return a.exec.SequenceDelayedMessage(message, delayedSeqNum)
+}
+
+func (a *ExecutionServerAPI) NextDelayedMessageNumber() (uint64, error) {
+	return a.exec.NextDelayedMessageNumber()
+}
+
+func (a *ExecutionServerAPI) Maintenance() error {
+	return a.exec.Maintenance()
+}
+
+func (a *ExecutionServerAPI) MessageIndexToBlockNumber(messageNum arbutil.MessageIndex) uint64 {
+	return a.exec.MessageIndexToBlockNumber(messageNum)
+}
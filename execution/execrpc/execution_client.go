@@ -0,0 +1,433 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+type ClientConfig struct {
+	RPC                  rpcclient.ClientConfig `koanf:"rpc"`
+	RestartCheckInterval time.Duration          `koanf:"restart-check-interval" reload:"hot"`
+}
+
+func (c *ClientConfig) Validate() error {
+	return c.RPC.Validate()
+}
+
+type ClientConfigFetcher func() *ClientConfig
+
+var DefaultClientConfig = ClientConfig{
+	RPC: rpcclient.ClientConfig{
+		URL:         "",
+		ArgLogLimit: 2048,
+	},
+	RestartCheckInterval: time.Second,
+}
+
+var TestClientConfig = ClientConfig{
+	RPC:                  rpcclient.TestClientConfig,
+	RestartCheckInterval: 50 * time.Millisecond,
+}
+
+func ClientConfigAddOptions(prefix string, f *flag.FlagSet) {
+	rpcclient.RPCClientAddOptions(prefix+".rpc", f, &DefaultClientConfig.RPC)
+	f.Duration(prefix+".restart-check-interval", DefaultClientConfig.RestartCheckInterval, "how often to check whether the execution restarted, to replay the messages it lost and restore its sequencer state")
+}
+
+// MessageSource is what an ExecutionClient replays messages from when the execution restarts.
+// The transaction streamer is one.
+type MessageSource interface {
+	GetMessageCount() (arbutil.MessageIndex, error)
+	GetMessage(pos arbutil.MessageIndex) (*arbostypes.MessageWithMetadata, error)
+}
+
+// The most message results recorded to check a restarted execution against
+const maxRecordedResults = 4096
+
+type recordedResult struct {
+	pos       arbutil.MessageIndex
+	blockHash common.Hash
+}
+
+type sequencerMode int
+
+const (
+	sequencerModeUnset sequencerMode = iota
+	sequencerModePaused
+	sequencerModeActive
+	sequencerModeForwarding
+)
+
+// ExecutionClient is an execution served over RPC by another process, which it reconnects to if that restarts.
+// Once it notices the execution restarted, it replays every message the consensus has to it before anything else is
+// digested, then restores the sequencer to the last mode it was set to.
+type ExecutionClient struct {
+	stopwaiter.StopWaiter
+	config ClientConfigFetcher
+	client *rpcclient.RpcClient
+
+	source          MessageSource
+	genesisBlockNum uint64
+
+	// Held while digesting or reorging messages and while replaying them, so they don't interleave
+	digestMutex sync.Mutex
+	instanceId  string
+	// The block hashes the execution reported for recent messages, by increasing position,
+	// which a restarted execution is checked against. Guarded by the digestMutex.
+	results []recordedResult
+
+	sequencerMutex     sync.Mutex
+	sequencerMode      sequencerMode
+	forwardTo          string
+	sequencerModeDirty bool
+}
+
+var _ execution.FullExecutionClient = (*ExecutionClient)(nil)
+
+func NewExecutionClient(config ClientConfigFetcher, stack *node.Node) *ExecutionClient {
+	return &ExecutionClient{
+		config: config,
+		client: rpcclient.NewRpcClient(func() *rpcclient.ClientConfig { return &config().RPC }, stack),
+	}
+}
+
+func (c *ExecutionClient) Start(ctxIn context.Context) error {
+	c.StopWaiter.Start(ctxIn, c)
+	ctx := c.GetContext()
+	if err := c.client.Start(ctx); err != nil {
+		return err
+	}
+	if err := c.client.CallContext(ctx, &c.instanceId, Namespace+"_instanceId"); err != nil {
+		return err
+	}
+	if err := c.client.CallContext(ctx, &c.genesisBlockNum, Namespace+"_messageIndexToBlockNumber", 0); err != nil {
+		return err
+	}
+	c.CallIteratively(c.checkRestart)
+	return nil
+}
+
+func (c *ExecutionClient) StopAndWait() {
+	c.StopWaiter.StopAndWait()
+	c.client.Close()
+}
+
+func (c *ExecutionClient) checkRestart(ctx context.Context) time.Duration {
+	interval := c.config().RestartCheckInterval
+	var instanceId string
+	if err := c.client.CallContext(ctx, &instanceId, Namespace+"_instanceId"); err != nil {
+		log.Warn("failed to reach execution", "err", err)
+		return interval
+	}
+	c.digestMutex.Lock()
+	restarted := instanceId != c.instanceId
+	if !restarted {
+		// messages the execution's own sequencer created aren't digested through this, so sample its head
+		if err := c.recordHead(ctx); err != nil {
+			log.Warn("failed to record execution head", "err", err)
+		}
+	}
+	if restarted {
+		log.Warn("execution restarted, replaying messages", "instanceId", instanceId)
+		if err := c.replay(ctx); err != nil {
+			c.digestMutex.Unlock()
+			log.Error("failed to replay messages to restarted execution", "err", err)
+			return interval
+		}
+		c.instanceId = instanceId
+	}
+	c.digestMutex.Unlock()
+
+	c.sequencerMutex.Lock()
+	defer c.sequencerMutex.Unlock()
+	if restarted {
+		c.sequencerModeDirty = true
+	}
+	if c.sequencerModeDirty {
+		if err := c.applySequencerMode(ctx); err != nil {
+			log.Error("failed to restore execution sequencer mode", "err", err)
+		}
+	}
+	return interval
+}
+
+// record keeps the result the execution reported for a message, if it's after every result recorded so far.
+// The digestMutex must be held.
+func (c *ExecutionClient) record(pos arbutil.MessageIndex, result *execution.MessageResult) {
+	if result == nil || (len(c.results) > 0 && pos <= c.results[len(c.results)-1].pos) {
+		return
+	}
+	c.results = append(c.results, recordedResult{pos: pos, blockHash: result.BlockHash})
+	if len(c.results) > maxRecordedResults {
+		c.results = c.results[len(c.results)-maxRecordedResults:]
+	}
+}
+
+// forgetResults drops the results recorded for messages from count on, which were reorged out.
+// The digestMutex must be held.
+func (c *ExecutionClient) forgetResults(count arbutil.MessageIndex) {
+	for len(c.results) > 0 && c.results[len(c.results)-1].pos >= count {
+		c.results = c.results[:len(c.results)-1]
+	}
+}
+
+// The digestMutex must be held.
+func (c *ExecutionClient) recordHead(ctx context.Context) error {
+	var head arbutil.MessageIndex
+	if err := c.client.CallContext(ctx, &head, Namespace+"_headMessageNumber"); err != nil {
+		return err
+	}
+	var result execution.MessageResult
+	if err := c.client.CallContext(ctx, &result, Namespace+"_resultAtPos", head); err != nil {
+		return err
+	}
+	c.record(head, &result)
+	return nil
+}
+
+// checkHead compares the results of a restarted execution with those recorded before it restarted, from its head
+// back, and reorgs it to the last message they match at if they don't at its head, returning its new head.
+// The digestMutex must be held.
+func (c *ExecutionClient) checkHead(ctx context.Context, head arbutil.MessageIndex) (arbutil.MessageIndex, error) {
+	checked := 0
+	for i := len(c.results) - 1; i >= 0; i-- {
+		recorded := c.results[i]
+		if recorded.pos > head {
+			continue
+		}
+		var result execution.MessageResult
+		if err := c.client.CallContext(ctx, &result, Namespace+"_resultAtPos", recorded.pos); err != nil {
+			return 0, err
+		}
+		if result.BlockHash == recorded.blockHash {
+			if checked == 0 {
+				return head, nil
+			}
+			if err := c.client.CallContext(ctx, nil, Namespace+"_reorg", recorded.pos+1, []arbostypes.MessageWithMetadata{}, []*arbostypes.MessageWithMetadata{}); err != nil {
+				return 0, fmt.Errorf("failed to reorg restarted execution: %w", err)
+			}
+			c.forgetResults(recorded.pos + 1)
+			log.Warn("restarted execution diverged from consensus, reorged it", "head", head, "to", recorded.pos)
+			return recorded.pos, nil
+		}
+		log.Warn("restarted execution result mismatch", "pos", recorded.pos, "expected", recorded.blockHash, "got", result.BlockHash)
+		checked++
+	}
+	if checked > 0 {
+		return 0, fmt.Errorf("restarted execution at head %d doesn't match any of the %d recorded results before it", head, checked)
+	}
+	return head, nil
+}
+
+// replay checks the execution's head against consensus, and digests every message the source has which
+// the execution doesn't. The digestMutex must be held.
+func (c *ExecutionClient) replay(ctx context.Context) error {
+	if c.source == nil {
+		return errors.New("no message source to replay from")
+	}
+	msgCount, err := c.source.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	var head arbutil.MessageIndex
+	if err := c.client.CallContext(ctx, &head, Namespace+"_headMessageNumber"); err != nil {
+		return err
+	}
+	head, err = c.checkHead(ctx, head)
+	if err != nil {
+		return err
+	}
+	for pos := head + 1; pos < msgCount; pos++ {
+		msg, err := c.source.GetMessage(pos)
+		if err != nil {
+			return err
+		}
+		var result execution.MessageResult
+		if err := c.client.CallContext(ctx, &result, Namespace+"_digestMessage", pos, msg); err != nil {
+			return err
+		}
+		c.record(pos, &result)
+	}
+	if head+1 < msgCount {
+		log.Info("replayed messages to restarted execution", "from", head+1, "to", msgCount-1)
+	}
+	return nil
+}
+
+// The sequencerMutex must be held.
+func (c *ExecutionClient) applySequencerMode(ctx context.Context) error {
+	var err error
+	switch c.sequencerMode {
+	case sequencerModeUnset:
+	case sequencerModePaused:
+		err = c.client.CallContext(ctx, nil, Namespace+"_pause")
+	case sequencerModeActive:
+		err = c.client.CallContext(ctx, nil, Namespace+"_activate")
+	case sequencerModeForwarding:
+		err = c.client.CallContext(ctx, nil, Namespace+"_forwardTo", c.forwardTo)
+	}
+	c.sequencerModeDirty = err != nil
+	return err
+}
+
+func (c *ExecutionClient) setSequencerMode(mode sequencerMode, forwardTo string) error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	c.sequencerMutex.Lock()
+	defer c.sequencerMutex.Unlock()
+	c.sequencerMode = mode
+	c.forwardTo = forwardTo
+	return c.applySequencerMode(ctx)
+}
+
+func (c *ExecutionClient) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	c.digestMutex.Lock()
+	defer c.digestMutex.Unlock()
+	var result execution.MessageResult
+	if err := c.client.CallContext(ctx, &result, Namespace+"_digestMessage", num, msg); err != nil {
+		return fromRpcError(err)
+	}
+	c.record(num, &result)
+	return nil
+}
+
+func (c *ExecutionClient) Reorg(count arbutil.MessageIndex, newMessages []arbostypes.MessageWithMetadata, oldMessages []*arbostypes.MessageWithMetadata) error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	c.digestMutex.Lock()
+	defer c.digestMutex.Unlock()
+	if err := c.client.CallContext(ctx, nil, Namespace+"_reorg", count, newMessages, oldMessages); err != nil {
+		return fromRpcError(err)
+	}
+	c.forgetResults(count)
+	return nil
+}
+
+func (c *ExecutionClient) HeadMessageNumber() (arbutil.MessageIndex, error) {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return 0, err
+	}
+	var res arbutil.MessageIndex
+	err = c.client.CallContext(ctx, &res, Namespace+"_headMessageNumber")
+	return res, err
+}
+
+func (c *ExecutionClient) HeadMessageNumberSync(t *testing.T) (arbutil.MessageIndex, error) {
+	return c.HeadMessageNumber()
+}
+
+func (c *ExecutionClient) ResultAtPos(pos arbutil.MessageIndex) (*execution.MessageResult, error) {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return nil, err
+	}
+	var res execution.MessageResult
+	if err := c.client.CallContext(ctx, &res, Namespace+"_resultAtPos", pos); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *ExecutionClient) RecordBlockCreation(ctx context.Context, pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (*execution.RecordResult, error) {
+	var res execution.RecordResult
+	if err := c.client.CallContext(ctx, &res, Namespace+"_recordBlockCreation", pos, msg); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *ExecutionClient) MarkValid(pos arbutil.MessageIndex, resultHash common.Hash) {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return
+	}
+	if err := c.client.CallContext(ctx, nil, Namespace+"_markValid", pos, resultHash); err != nil {
+		log.Warn("failed to mark message valid in execution", "pos", pos, "err", err)
+	}
+}
+
+func (c *ExecutionClient) PrepareForRecord(ctx context.Context, start, end arbutil.MessageIndex) error {
+	return c.client.CallContext(ctx, nil, Namespace+"_prepareForRecord", start, end)
+}
+
+func (c *ExecutionClient) Pause() {
+	if err := c.setSequencerMode(sequencerModePaused, ""); err != nil {
+		log.Error("failed to pause execution sequencer, will retry", "err", err)
+	}
+}
+
+func (c *ExecutionClient) Activate() {
+	if err := c.setSequencerMode(sequencerModeActive, ""); err != nil {
+		log.Error("failed to activate execution sequencer, will retry", "err", err)
+	}
+}
+
+func (c *ExecutionClient) ForwardTo(url string) error {
+	return c.setSequencerMode(sequencerModeForwarding, url)
+}
+
+func (c *ExecutionClient) SequenceDelayedMessage(message *arbostypes.L1IncomingMessage, delayedSeqNum uint64) error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	return fromRpcError(c.client.CallContext(ctx, nil, Namespace+"_sequenceDelayedMessage", message, delayedSeqNum))
+}
+
+func (c *ExecutionClient) NextDelayedMessageNumber() (uint64, error) {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return 0, err
+	}
+	var res uint64
+	err = c.client.CallContext(ctx, &res, Namespace+"_nextDelayedMessageNumber")
+	return res, err
+}
+
+// SetTransactionStreamer keeps the streamer to replay messages from, if it's a MessageSource.
+// The execution reaches the streamer through the consensus RPC API instead.
+func (c *ExecutionClient) SetTransactionStreamer(streamer execution.TransactionStreamer) {
+	if source, ok := streamer.(MessageSource); ok {
+		c.source = source
+	}
+}
+
+func (c *ExecutionClient) Maintenance() error {
+	ctx, err := c.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	return c.client.CallContext(ctx, nil, Namespace+"_maintenance")
+}
+
+func (c *ExecutionClient) MessageIndexToBlockNumber(messageNum arbutil.MessageIndex) uint64 {
+	return uint64(messageNum) + c.genesisBlockNum
+}
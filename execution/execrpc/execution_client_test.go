@@ -0,0 +1,341 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func Require(t *testing.T, err error, printables ...interface{}) {
+	t.Helper()
+	testhelpers.RequireImpl(t, err, printables...)
+}
+
+func Fail(t *testing.T, printables ...interface{}) {
+	t.Helper()
+	testhelpers.FailImpl(t, printables...)
+}
+
+// fakeExecution digests messages by remembering them, and starts with only the genesis message.
+type fakeExecution struct {
+	mutex    sync.Mutex
+	messages []*arbostypes.MessageWithMetadata
+	active   bool
+}
+
+func newFakeExecution() *fakeExecution {
+	return &fakeExecution{messages: []*arbostypes.MessageWithMetadata{&arbostypes.EmptyTestMessageWithMetadata}}
+}
+
+func (e *fakeExecution) head() arbutil.MessageIndex {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return arbutil.MessageIndex(len(e.messages) - 1)
+}
+
+func (e *fakeExecution) isActive() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.active
+}
+
+func (e *fakeExecution) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if int(num) != len(e.messages) {
+		return fmt.Errorf("wrong message number in digest got %d expected %d", num, len(e.messages))
+	}
+	e.messages = append(e.messages, msg)
+	return nil
+}
+
+func (e *fakeExecution) Reorg(count arbutil.MessageIndex, newMessages []arbostypes.MessageWithMetadata, oldMessages []*arbostypes.MessageWithMetadata) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.messages = e.messages[:count]
+	for i := range newMessages {
+		e.messages = append(e.messages, &newMessages[i])
+	}
+	return nil
+}
+
+func (e *fakeExecution) HeadMessageNumber() (arbutil.MessageIndex, error) {
+	return e.head(), nil
+}
+
+func (e *fakeExecution) HeadMessageNumberSync(t *testing.T) (arbutil.MessageIndex, error) {
+	return e.head(), nil
+}
+
+func (e *fakeExecution) ResultAtPos(pos arbutil.MessageIndex) (*execution.MessageResult, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if int(pos) >= len(e.messages) {
+		return nil, errors.New("message not digested")
+	}
+	// the block hash depends on every message up to the position, as a real one does
+	var blockHash common.Hash
+	for _, msg := range e.messages[:pos+1] {
+		blockHash = crypto.Keccak256Hash(blockHash[:], new(big.Int).SetUint64(msg.DelayedMessagesRead).Bytes())
+	}
+	return &execution.MessageResult{BlockHash: blockHash, SendRoot: common.Hash{byte(pos)}}, nil
+}
+
+func (e *fakeExecution) RecordBlockCreation(ctx context.Context, pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (*execution.RecordResult, error) {
+	return nil, errors.New("not supported")
+}
+
+func (e *fakeExecution) MarkValid(pos arbutil.MessageIndex, resultHash common.Hash) {}
+
+func (e *fakeExecution) PrepareForRecord(ctx context.Context, start, end arbutil.MessageIndex) error {
+	return nil
+}
+
+func (e *fakeExecution) Pause() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.active = false
+}
+
+func (e *fakeExecution) Activate() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.active = true
+}
+
+func (e *fakeExecution) ForwardTo(url string) error {
+	e.Pause()
+	return nil
+}
+
+func (e *fakeExecution) SequenceDelayedMessage(message *arbostypes.L1IncomingMessage, delayedSeqNum uint64) error {
+	return fmt.Errorf("%w: not main sequencer", execution.ErrRetrySequencer)
+}
+
+func (e *fakeExecution) NextDelayedMessageNumber() (uint64, error) {
+	return 0, nil
+}
+
+func (e *fakeExecution) SetTransactionStreamer(streamer execution.TransactionStreamer) {}
+
+func (e *fakeExecution) Start(ctx context.Context) error {
+	return nil
+}
+
+func (e *fakeExecution) StopAndWait() {}
+
+func (e *fakeExecution) Maintenance() error {
+	return nil
+}
+
+func (e *fakeExecution) MessageIndexToBlockNumber(messageNum arbutil.MessageIndex) uint64 {
+	return uint64(messageNum) + 10
+}
+
+type testMessageSource struct {
+	count arbutil.MessageIndex
+}
+
+func (s *testMessageSource) GetMessageCount() (arbutil.MessageIndex, error) {
+	return s.count, nil
+}
+
+func (s *testMessageSource) GetMessage(pos arbutil.MessageIndex) (*arbostypes.MessageWithMetadata, error) {
+	if pos >= s.count {
+		return nil, errors.New("message not found")
+	}
+	return &arbostypes.MessageWithMetadata{Message: &arbostypes.EmptyTestIncomingMessage, DelayedMessagesRead: uint64(pos)}, nil
+}
+
+func (s *testMessageSource) FetchBatch(batchNum uint64) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *testMessageSource) WriteMessageFromSequencer(pos arbutil.MessageIndex, msgWithMeta arbostypes.MessageWithMetadata) error {
+	return errors.New("not supported")
+}
+
+func (s *testMessageSource) ExpectChosenSequencer() error {
+	return nil
+}
+
+func createTestExecutionServer(t *testing.T, exec execution.FullExecutionClient, wsPort int) *node.Node {
+	stackConf := node.DefaultConfig
+	stackConf.HTTPPort = 0
+	stackConf.DataDir = ""
+	stackConf.WSHost = "127.0.0.1"
+	stackConf.WSPort = wsPort
+	stackConf.WSModules = []string{Namespace}
+	stackConf.P2P.NoDiscovery = true
+	stackConf.P2P.ListenAddr = ""
+
+	stack, err := node.New(&stackConf)
+	Require(t, err)
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: Namespace,
+		Version:   "1.0",
+		Service:   NewExecutionServerAPI(exec),
+		Public:    true,
+	}})
+	Require(t, stack.Start())
+	return stack
+}
+
+func wsPort(t *testing.T, stack *node.Node) int {
+	endpoint, err := url.Parse(stack.WSEndpoint())
+	Require(t, err)
+	port, err := strconv.Atoi(endpoint.Port())
+	Require(t, err)
+	return port
+}
+
+func TestExecutionClientCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec := newFakeExecution()
+	stack := createTestExecutionServer(t, exec, 0)
+	defer stack.Close()
+
+	config := TestClientConfig
+	config.RPC.URL = stack.WSEndpoint()
+	client := NewExecutionClient(func() *ClientConfig { return &config }, nil)
+	Require(t, client.Start(ctx))
+	defer client.StopAndWait()
+
+	Require(t, client.DigestMessage(1, &arbostypes.EmptyTestMessageWithMetadata))
+	if err := client.DigestMessage(3, &arbostypes.EmptyTestMessageWithMetadata); err == nil {
+		Fail(t, "digested a message out of order")
+	}
+	head, err := client.HeadMessageNumber()
+	Require(t, err)
+	if head != 1 {
+		Fail(t, "unexpected head", head)
+	}
+	result, err := client.ResultAtPos(1)
+	Require(t, err)
+	if result.SendRoot != (common.Hash{1}) {
+		Fail(t, "unexpected result", result)
+	}
+	if blockNum := client.MessageIndexToBlockNumber(5); blockNum != 15 {
+		Fail(t, "unexpected block number", blockNum)
+	}
+	if err := client.SequenceDelayedMessage(&arbostypes.EmptyTestIncomingMessage, 0); !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "sequencer error not kept over rpc", err)
+	}
+	client.Activate()
+	if !exec.isActive() {
+		Fail(t, "sequencer not activated")
+	}
+}
+
+func TestExecutionClientReplaysAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := &testMessageSource{count: 5}
+	exec := newFakeExecution()
+	stack := createTestExecutionServer(t, exec, 0)
+	port := wsPort(t, stack)
+
+	config := TestClientConfig
+	config.RPC.URL = stack.WSEndpoint()
+	config.RPC.Timeout = time.Second
+	client := NewExecutionClient(func() *ClientConfig { return &config }, nil)
+	client.SetTransactionStreamer(source)
+	Require(t, client.Start(ctx))
+	defer client.StopAndWait()
+	for pos := arbutil.MessageIndex(1); pos < source.count; pos++ {
+		msg, err := source.GetMessage(pos)
+		Require(t, err)
+		Require(t, client.DigestMessage(pos, msg))
+	}
+	client.Activate()
+
+	// The execution restarts, having lost every message but genesis.
+	stack.Close()
+	restarted := newFakeExecution()
+	stack = createTestExecutionServer(t, restarted, port)
+	defer stack.Close()
+
+	for start := time.Now(); restarted.head() != source.count-1 || !restarted.isActive(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			Fail(t, "restarted execution not restored", "head", restarted.head(), "active", restarted.isActive())
+		}
+	}
+	restarted.mutex.Lock()
+	defer restarted.mutex.Unlock()
+	for pos, msg := range restarted.messages[1:] {
+		if msg.DelayedMessagesRead != uint64(pos+1) {
+			Fail(t, "replayed the wrong message", pos+1, msg.DelayedMessagesRead)
+		}
+	}
+}
+
+func TestExecutionClientReorgsDivergedRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := &testMessageSource{count: 5}
+	exec := newFakeExecution()
+	stack := createTestExecutionServer(t, exec, 0)
+	port := wsPort(t, stack)
+
+	config := TestClientConfig
+	config.RPC.URL = stack.WSEndpoint()
+	config.RPC.Timeout = time.Second
+	client := NewExecutionClient(func() *ClientConfig { return &config }, nil)
+	client.SetTransactionStreamer(source)
+	Require(t, client.Start(ctx))
+	defer client.StopAndWait()
+	for pos := arbutil.MessageIndex(1); pos < source.count; pos++ {
+		msg, err := source.GetMessage(pos)
+		Require(t, err)
+		Require(t, client.DigestMessage(pos, msg))
+	}
+
+	// The execution restarts with the same head, but with different messages from position 3 on.
+	stack.Close()
+	restarted := newFakeExecution()
+	for pos := arbutil.MessageIndex(1); pos < source.count; pos++ {
+		msg, err := source.GetMessage(pos)
+		Require(t, err)
+		if pos >= 3 {
+			msg.DelayedMessagesRead += 100
+		}
+		Require(t, restarted.DigestMessage(pos, msg))
+	}
+	stack = createTestExecutionServer(t, restarted, port)
+	defer stack.Close()
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		restarted.mutex.Lock()
+		matches := len(restarted.messages) == int(source.count)
+		for pos, msg := range restarted.messages[1:] {
+			matches = matches && msg.DelayedMessagesRead == uint64(pos+1)
+		}
+		restarted.mutex.Unlock()
+		if matches {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			Fail(t, "diverged execution not reorged to consensus")
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/time/rate"
+
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	senderRateLimitedCounter = metrics.NewRegisteredCounter("arb/sequencer/admission/ratelimited/sender", nil)
+	ipRateLimitedCounter     = metrics.NewRegisteredCounter("arb/sequencer/admission/ratelimited/ip", nil)
+	blockedSenderCounter     = metrics.NewRegisteredCounter("arb/sequencer/admission/blocked", nil)
+)
+
+// AdmissionErrorCode is the JSON-RPC error code of transactions rejected by the sequencer's admission control.
+const AdmissionErrorCode = -32090
+
+// AdmissionError is returned for transactions rejected by the sequencer's admission control.
+// It's returned to the client with the AdmissionErrorCode, which is kept when it's forwarded.
+type AdmissionError struct {
+	msg string
+}
+
+func (e *AdmissionError) Error() string  { return e.msg }
+func (e *AdmissionError) ErrorCode() int { return AdmissionErrorCode }
+
+var (
+	ErrSenderRateLimited = &AdmissionError{"transaction sender rate limit exceeded"}
+	ErrClientRateLimited = &AdmissionError{"client rate limit exceeded"}
+	ErrSenderBlocked     = &AdmissionError{"transaction sender is blocked"}
+)
+
+type AdmissionConfig struct {
+	SenderRateLimit   float64  `koanf:"sender-rate-limit" reload:"hot"`
+	SenderBurst       int      `koanf:"sender-burst" reload:"hot"`
+	IPRateLimit       float64  `koanf:"ip-rate-limit" reload:"hot"`
+	IPBurst           int      `koanf:"ip-burst" reload:"hot"`
+	TrackedKeys       int      `koanf:"tracked-keys" reload:"hot"`
+	Blocklist         []string `koanf:"blocklist" reload:"hot"`
+	TrustedForwarders []string `koanf:"trusted-forwarders" reload:"hot"`
+}
+
+var DefaultAdmissionConfig = AdmissionConfig{
+	SenderRateLimit:   0,
+	SenderBurst:       10,
+	IPRateLimit:       0,
+	IPBurst:           100,
+	TrackedKeys:       100_000,
+	Blocklist:         []string{},
+	TrustedForwarders: []string{},
+}
+
+func AdmissionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Float64(prefix+".sender-rate-limit", DefaultAdmissionConfig.SenderRateLimit, "maximum sustained transactions per second accepted from each sender (0 = unlimited)")
+	f.Int(prefix+".sender-burst", DefaultAdmissionConfig.SenderBurst, "number of transactions a sender can submit at once before being rate limited")
+	f.Float64(prefix+".ip-rate-limit", DefaultAdmissionConfig.IPRateLimit, "maximum sustained transactions per second accepted from each client IP (0 = unlimited)")
+	f.Int(prefix+".ip-burst", DefaultAdmissionConfig.IPBurst, "number of transactions a client IP can submit at once before being rate limited")
+	f.Int(prefix+".tracked-keys", DefaultAdmissionConfig.TrackedKeys, "maximum number of senders and of client IPs to keep rate limits for, least recently seen first forgotten")
+	f.StringSlice(prefix+".blocklist", DefaultAdmissionConfig.Blocklist, "addresses whose transactions are rejected")
+	f.StringSlice(prefix+".trusted-forwarders", DefaultAdmissionConfig.TrustedForwarders, "IPs or CIDRs of nodes forwarding transactions to the sequencer, which are rate limited by the client IP they report rather than their own")
+}
+
+func (c *AdmissionConfig) Validate() error {
+	if c.SenderRateLimit < 0 || c.IPRateLimit < 0 {
+		return errors.New("sequencer admission rate limits must not be negative")
+	}
+	if c.SenderRateLimit > 0 && c.SenderBurst < 1 {
+		return errors.New("sequencer admission sender burst must be at least 1")
+	}
+	if c.IPRateLimit > 0 && c.IPBurst < 1 {
+		return errors.New("sequencer admission IP burst must be at least 1")
+	}
+	if (c.SenderRateLimit > 0 || c.IPRateLimit > 0) && c.TrackedKeys < 1 {
+		return errors.New("sequencer admission rate limits require tracking at least one key")
+	}
+	if _, err := parseBlocklist(c.Blocklist); err != nil {
+		return err
+	}
+	if _, err := parseTrustedForwarders(c.TrustedForwarders); err != nil {
+		return err
+	}
+	return nil
+}
+
+// enabled returns whether config requires transactions to be checked, which requires recovering their sender.
+func (c *AdmissionConfig) enabled() bool {
+	return len(c.Blocklist) > 0 || c.SenderRateLimit > 0 || c.IPRateLimit > 0
+}
+
+func parseBlocklist(entries []string) (map[common.Address]struct{}, error) {
+	blocklist := make(map[common.Address]struct{}, len(entries))
+	for _, entry := range entries {
+		if !common.IsHexAddress(entry) {
+			return nil, fmt.Errorf("sequencer blocklist entry \"%v\" is not a valid address", entry)
+		}
+		blocklist[common.HexToAddress(entry)] = struct{}{}
+	}
+	return blocklist, nil
+}
+
+func parseTrustedForwarders(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("sequencer trusted forwarder \"%v\" is not a valid IP or CIDR", entry)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+type forwardedClientIPKey struct{}
+
+// withForwardedClientIP records the client IP a forwarding node reported a transaction as coming from.
+// It's only used if the forwarding node is a trusted forwarder.
+func withForwardedClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, forwardedClientIPKey{}, ip)
+}
+
+// peerIP returns the IP of the RPC client the request came from, or nil if it wasn't an RPC request.
+func peerIP(ctx context.Context) net.IP {
+	addr := rpc.PeerInfoFromContext(ctx).RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
+
+// attributedClientIP returns the IP a transaction should be attributed to, given the IP of the RPC client it came
+// from and the client IP reported by that RPC client if any, or "" if it can't be attributed to a single client.
+// Reported client IPs are only used from trusted forwarders.
+func attributedClientIP(trustedForwarders []*net.IPNet, peer net.IP, forwarded string) string {
+	if peer == nil {
+		return ""
+	}
+	for _, trusted := range trustedForwarders {
+		if trusted.Contains(peer) {
+			// A trusted forwarder which doesn't report client IPs forwards for many clients
+			return forwarded
+		}
+	}
+	return peer.String()
+}
+
+// admissionControl rate limits transactions by sender and client IP, and rejects those from blocked senders.
+type admissionControl struct {
+	mutex sync.Mutex
+	// The config the parsed fields below are from, which is replaced when the config is reloaded
+	config            *AdmissionConfig
+	blocklist         map[common.Address]struct{}
+	trustedForwarders []*net.IPNet
+	senderLimiters    *containers.LruCache[common.Address, *rate.Limiter]
+	ipLimiters        *containers.LruCache[string, *rate.Limiter]
+}
+
+func newAdmissionControl() *admissionControl {
+	return &admissionControl{
+		senderLimiters: containers.NewLruCache[common.Address, *rate.Limiter](0),
+		ipLimiters:     containers.NewLruCache[string, *rate.Limiter](0),
+	}
+}
+
+// update applies config if it's changed since the last call. The mutex must be held by the caller.
+func (a *admissionControl) update(config *AdmissionConfig) {
+	if a.config == config {
+		return
+	}
+	blocklist, err := parseBlocklist(config.Blocklist)
+	if err != nil {
+		log.Error("invalid sequencer blocklist, keeping the previous one", "err", err)
+		blocklist = a.blocklist
+	}
+	trustedForwarders, err := parseTrustedForwarders(config.TrustedForwarders)
+	if err != nil {
+		log.Error("invalid sequencer trusted forwarders, keeping the previous ones", "err", err)
+		trustedForwarders = a.trustedForwarders
+	}
+	previous := a.config
+	if previous == nil || previous.SenderRateLimit != config.SenderRateLimit || previous.SenderBurst != config.SenderBurst {
+		a.senderLimiters.Clear()
+	}
+	if previous == nil || previous.IPRateLimit != config.IPRateLimit || previous.IPBurst != config.IPBurst {
+		a.ipLimiters.Clear()
+	}
+	a.senderLimiters.Resize(config.TrackedKeys)
+	a.ipLimiters.Resize(config.TrackedKeys)
+	a.config = config
+	a.blocklist = blocklist
+	a.trustedForwarders = trustedForwarders
+}
+
+// clientIP returns the IP to rate limit a transaction by, or "" if it shouldn't be rate limited by IP.
+// The mutex must be held by the caller.
+func (a *admissionControl) clientIP(peer net.IP, forwarded string) string {
+	return attributedClientIP(a.trustedForwarders, peer, forwarded)
+}
+
+func allow[K comparable](limiters *containers.LruCache[K, *rate.Limiter], key K, limit float64, burst int, now time.Time) bool {
+	limiter, ok := limiters.Get(key)
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+		limiters.Add(key, limiter)
+	}
+	return limiter.AllowN(now, 1)
+}
+
+// check returns an AdmissionError if a transaction from sender submitted with the given request context
+// should be rejected, and otherwise takes it into account for rate limiting.
+func (a *admissionControl) check(ctx context.Context, config *AdmissionConfig, sender common.Address, now time.Time) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.update(config)
+	if _, blocked := a.blocklist[sender]; blocked {
+		blockedSenderCounter.Inc(1)
+		return ErrSenderBlocked
+	}
+	if config.IPRateLimit > 0 {
+		forwarded, _ := ctx.Value(forwardedClientIPKey{}).(string)
+		if ip := a.clientIP(peerIP(ctx), forwarded); ip != "" && !allow(a.ipLimiters, ip, config.IPRateLimit, config.IPBurst, now) {
+			ipRateLimitedCounter.Inc(1)
+			return ErrClientRateLimited
+		}
+	}
+	if config.SenderRateLimit > 0 && !allow(a.senderLimiters, sender, config.SenderRateLimit, config.SenderBurst, now) {
+		senderRateLimitedCounter.Inc(1)
+		return ErrSenderRateLimited
+	}
+	return nil
+}
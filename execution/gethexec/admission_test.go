@@ -0,0 +1,116 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestAdmissionSenderRateLimit(t *testing.T) {
+	config := DefaultAdmissionConfig
+	config.SenderRateLimit = 1
+	config.SenderBurst = 2
+	Require(t, config.Validate())
+	admission := newAdmissionControl()
+	ctx := context.Background()
+	sender := common.HexToAddress("0x1")
+	other := common.HexToAddress("0x2")
+	now := time.Unix(1_000_000, 0)
+
+	for i := 0; i < config.SenderBurst; i++ {
+		Require(t, admission.check(ctx, &config, sender, now))
+	}
+	err := admission.check(ctx, &config, sender, now)
+	if !errors.Is(err, ErrSenderRateLimited) {
+		Fail(t, "expected sender to be rate limited, got", err)
+	}
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != AdmissionErrorCode {
+		Fail(t, "rate limit error doesn't have the admission error code:", err)
+	}
+	// Other senders have their own limits
+	Require(t, admission.check(ctx, &config, other, now))
+	// The bucket refills over time
+	Require(t, admission.check(ctx, &config, sender, now.Add(time.Second)))
+
+	// Changing the limits resets them
+	reloaded := config
+	reloaded.SenderRateLimit = 2
+	Require(t, admission.check(ctx, &reloaded, sender, now.Add(time.Second)))
+}
+
+func TestAdmissionBlocklistReload(t *testing.T) {
+	blocked := common.HexToAddress("0xb10c")
+	config := DefaultAdmissionConfig
+	config.Blocklist = []string{blocked.Hex()}
+	Require(t, config.Validate())
+	if !config.enabled() {
+		Fail(t, "admission control with a blocklist isn't enabled")
+	}
+	admission := newAdmissionControl()
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := admission.check(ctx, &config, blocked, now); !errors.Is(err, ErrSenderBlocked) {
+		Fail(t, "expected blocked sender to be rejected, got", err)
+	}
+	Require(t, admission.check(ctx, &config, common.HexToAddress("0x1"), now))
+
+	invalid := config
+	invalid.Blocklist = []string{"not an address"}
+	if invalid.Validate() == nil {
+		Fail(t, "invalid blocklist entry passed validation")
+	}
+	// An invalid blocklist keeps the previous one rather than unblocking everyone
+	if err := admission.check(ctx, &invalid, blocked, now); !errors.Is(err, ErrSenderBlocked) {
+		Fail(t, "expected blocked sender to stay blocked after an invalid reload, got", err)
+	}
+
+	reloaded := config
+	reloaded.Blocklist = []string{}
+	Require(t, admission.check(ctx, &reloaded, blocked, now))
+}
+
+func TestAdmissionClientIP(t *testing.T) {
+	config := DefaultAdmissionConfig
+	config.TrustedForwarders = []string{"10.0.0.0/8", "192.168.1.1"}
+	Require(t, config.Validate())
+	admission := newAdmissionControl()
+	admission.update(&config)
+
+	for _, tc := range []struct {
+		peer      string
+		forwarded string
+		want      string
+	}{
+		{peer: "1.2.3.4", forwarded: "", want: "1.2.3.4"},
+		// Reported client IPs are ignored from untrusted peers
+		{peer: "1.2.3.4", forwarded: "5.6.7.8", want: "1.2.3.4"},
+		{peer: "10.1.2.3", forwarded: "5.6.7.8", want: "5.6.7.8"},
+		{peer: "192.168.1.1", forwarded: "5.6.7.8", want: "5.6.7.8"},
+		{peer: "192.168.1.2", forwarded: "5.6.7.8", want: "192.168.1.2"},
+		// Trusted forwarders which don't report a client IP aren't rate limited
+		{peer: "10.1.2.3", forwarded: "", want: ""},
+	} {
+		if got := admission.clientIP(net.ParseIP(tc.peer), tc.forwarded); got != tc.want {
+			Fail(t, "peer", tc.peer, "forwarded", tc.forwarded, "got client IP", got, "expected", tc.want)
+		}
+	}
+	if got := admission.clientIP(nil, "5.6.7.8"); got != "" {
+		Fail(t, "got client IP", got, "without a peer")
+	}
+
+	invalid := config
+	invalid.TrustedForwarders = []string{"10.0.0.0/33"}
+	if invalid.Validate() == nil {
+		Fail(t, "invalid trusted forwarder passed validation")
+	}
+}
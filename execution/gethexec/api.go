@@ -14,9 +14,12 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/arbitrum"
+	"github.com/ethereum/go-ethereum/arbitrum_types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/retryables"
@@ -24,17 +27,163 @@ import (
 )
 
 type ArbAPI struct {
-	txPublisher TransactionPublisher
+	txPublisher         TransactionPublisher
+	txFeeCap            float64 // in ether, 0 for no cap
+	allowUnprotectedTxs bool
 }
 
-func NewArbAPI(publisher TransactionPublisher) *ArbAPI {
-	return &ArbAPI{publisher}
+func NewArbAPI(publisher TransactionPublisher, txFeeCap float64, allowUnprotectedTxs bool) *ArbAPI {
+	return &ArbAPI{publisher, txFeeCap, allowUnprotectedTxs}
+}
+
+// checkTransaction applies the checks eth_sendRawTransaction makes before publishing a transaction.
+func (a *ArbAPI) checkTransaction(tx *types.Transaction) error {
+	if a.txFeeCap != 0 {
+		fee := new(big.Float).SetInt(new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas())))
+		feeEth, _ := fee.Quo(fee, big.NewFloat(params.Ether)).Float64()
+		if feeEth > a.txFeeCap {
+			return fmt.Errorf("tx fee (%.2f ether) exceeds the configured cap (%.2f ether)", feeEth, a.txFeeCap)
+		}
+	}
+	if !a.allowUnprotectedTxs && !tx.Protected() {
+		return errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	return nil
 }
 
 func (a *ArbAPI) CheckPublisherHealth(ctx context.Context) error {
 	return a.txPublisher.CheckHealth(ctx)
 }
 
+// SendForwardedRawTransaction is used by forwarding nodes to publish a transaction along with the IP of the
+// client they received it from, which is only used if the forwarding node is a trusted forwarder.
+// It checks the transaction like eth_sendRawTransaction, as any client can call it.
+func (a *ArbAPI) SendForwardedRawTransaction(ctx context.Context, input hexutil.Bytes, options *arbitrum_types.ConditionalOptions, clientIP string) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := a.checkTransaction(tx); err != nil {
+		return common.Hash{}, err
+	}
+	if clientIP != "" {
+		ctx = withForwardedClientIP(ctx, clientIP)
+	}
+	return tx.Hash(), a.txPublisher.PublishTransaction(ctx, tx, options)
+}
+
+// SendRawTransactionSync is like eth_sendRawTransaction, except that it returns once the transaction is in a block,
+// with a soft confirmation holding its receipt, signed by the sequencer if it signs its feed.
+// Forwarding nodes pass the IP of the client they received the transaction from, as for arb_sendForwardedRawTransaction.
+func (a *ArbAPI) SendRawTransactionSync(ctx context.Context, input hexutil.Bytes, options *arbitrum_types.ConditionalOptions, clientIP *string) (*SoftConfirmation, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	if err := a.checkTransaction(tx); err != nil {
+		return nil, err
+	}
+	if clientIP != nil && *clientIP != "" {
+		ctx = withForwardedClientIP(ctx, *clientIP)
+	}
+	confirmation := new(SoftConfirmation)
+	if err := a.txPublisher.PublishTransaction(withSoftConfirmation(ctx, confirmation), tx, options); err != nil {
+		return nil, err
+	}
+	if confirmation.Receipt == nil {
+		return nil, errors.New("transaction published, but the publisher doesn't support soft confirmations")
+	}
+	return confirmation, nil
+}
+
+// SequencerAPI lets users find out what's happening to the transactions they've submitted to the sequencer.
+type SequencerAPI struct {
+	sequencer *Sequencer
+}
+
+func NewSequencerAPI(sequencer *Sequencer) *SequencerAPI {
+	return &SequencerAPI{sequencer}
+}
+
+type SequencerQueueStatus struct {
+	// Whether the sequencer is sequencing, rather than paused or forwarding transactions
+	Active           bool   `json:"active"`
+	ForwardingTarget string `json:"forwardingTarget,omitempty"`
+	// The number of transactions waiting in the queue to be picked up for a block
+	QueueDepth    hexutil.Uint64 `json:"queueDepth"`
+	QueueCapacity hexutil.Uint64 `json:"queueCapacity"`
+	// The number of submitted transactions which are queued or being sequenced, and which are parked in the
+	// nonce failure cache waiting for a transaction with a lower nonce
+	Queued hexutil.Uint64 `json:"queued"`
+	Parked hexutil.Uint64 `json:"parked"`
+}
+
+func (a *SequencerAPI) QueueStatus(ctx context.Context) *SequencerQueueStatus {
+	pauseChan, forwarder := a.sequencer.GetPauseAndForwarder()
+	queued, parked := a.sequencer.txTracker.counts()
+	return &SequencerQueueStatus{
+		Active:           pauseChan == nil && forwarder == nil,
+		ForwardingTarget: a.sequencer.ForwardTarget(),
+		QueueDepth:       hexutil.Uint64(len(a.sequencer.txQueue)),
+		QueueCapacity:    hexutil.Uint64(cap(a.sequencer.txQueue)),
+		Queued:           hexutil.Uint64(queued),
+		Parked:           hexutil.Uint64(parked),
+	}
+}
+
+// TxStatus returns whether a transaction is queued and its position in the queue, parked waiting for a
+// transaction with a lower nonce, or was recently rejected and why. Transactions which have been sequenced
+// are no longer tracked, and their receipts should be used instead.
+func (a *SequencerAPI) TxStatus(ctx context.Context, hash common.Hash) *SequencerTxStatus {
+	return a.sequencer.txTracker.status(hash)
+}
+
+// RecentRejections returns the most recently rejected transactions and why, most recent first.
+func (a *SequencerAPI) RecentRejections(ctx context.Context, count *hexutil.Uint64) []TxRejection {
+	limit := recentRejectionsSize
+	if count != nil && uint64(*count) < uint64(limit) {
+		limit = int(*count)
+	}
+	return a.sequencer.txTracker.recentRejections(limit)
+}
+
+// TxLifecycle subscribes to transactions being queued, parked, included, forwarded and rejected,
+// optionally only for the given transaction hashes.
+func (a *SequencerAPI) TxLifecycle(ctx context.Context, hashes *[]common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var filter map[common.Hash]struct{}
+	if hashes != nil && len(*hashes) > 0 {
+		filter = make(map[common.Hash]struct{}, len(*hashes))
+		for _, hash := range *hashes {
+			filter[hash] = struct{}{}
+		}
+	}
+	rpcSub := notifier.CreateSubscription()
+	events, unsubscribe := a.sequencer.txTracker.subscribe()
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case event := <-events:
+				if filter != nil {
+					if _, ok := filter[event.Hash]; !ok {
+						continue
+					}
+				}
+				if err := notifier.Notify(rpcSub.ID, event); err != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 type ArbDebugAPI struct {
 	blockchain        *core.BlockChain
 	blockRangeBound   uint64
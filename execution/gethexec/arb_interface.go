@@ -24,12 +24,15 @@ type ArbInterface struct {
 	exec        *ExecutionEngine
 	txPublisher TransactionPublisher
 	arbNode     interface{}
+	// Whether eth_sendRawTransaction waits for transactions to be in a block
+	syncSendRawTransaction func() bool
 }
 
-func NewArbInterface(exec *ExecutionEngine, txPublisher TransactionPublisher) (*ArbInterface, error) {
+func NewArbInterface(exec *ExecutionEngine, txPublisher TransactionPublisher, syncSendRawTransaction func() bool) (*ArbInterface, error) {
 	return &ArbInterface{
-		exec:        exec,
-		txPublisher: txPublisher,
+		exec:                   exec,
+		txPublisher:            txPublisher,
+		syncSendRawTransaction: syncSendRawTransaction,
 	}, nil
 }
 
@@ -37,7 +40,12 @@ func (a *ArbInterface) Initialize(arbnode interface{}) {
 	a.arbNode = arbnode
 }
 
+// PublishTransaction publishes transactions submitted with eth_sendRawTransaction. If configured to, it returns
+// once the transaction is in a block, as arb_sendRawTransactionSync does, if the publisher supports that.
 func (a *ArbInterface) PublishTransaction(ctx context.Context, tx *types.Transaction, options *arbitrum_types.ConditionalOptions) error {
+	if a.syncSendRawTransaction != nil && a.syncSendRawTransaction() && requestedSoftConfirmation(ctx) == nil {
+		ctx = withSoftConfirmation(ctx, new(SoftConfirmation))
+	}
 	return a.txPublisher.PublishTransaction(ctx, tx, options)
 }
 
@@ -9,23 +9,43 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
 
 	"github.com/ethereum/go-ethereum/arbitrum"
 	"github.com/ethereum/go-ethereum/arbitrum_types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	forwarderRetryCounter       = metrics.NewRegisteredCounter("arb/forwarder/retry", nil)
+	forwarderBreakerOpenCounter = metrics.NewRegisteredCounter("arb/forwarder/breaker/open", nil)
+)
+
+const (
+	// Try the targets in the order they're configured, publishing to the first one that's reachable
+	ForwardingModeSequential = "sequential"
+	// Publish to every target at once, succeeding if any of them accepts the transaction
+	ForwardingModeBroadcast = "broadcast"
+	// Try the targets from the lowest to the highest recent latency
+	ForwardingModeLowestLatency = "lowest-latency"
+)
+
 type ForwarderConfig struct {
 	ConnectionTimeout     time.Duration `koanf:"connection-timeout"`
 	IdleConnectionTimeout time.Duration `koanf:"idle-connection-timeout"`
@@ -33,6 +53,31 @@ type ForwarderConfig struct {
 	RedisUrl              string        `koanf:"redis-url"`
 	UpdateInterval        time.Duration `koanf:"update-interval"`
 	RetryInterval         time.Duration `koanf:"retry-interval"`
+	ForwardClientIP       bool          `koanf:"forward-client-ip"`
+	TrustedForwarders     []string      `koanf:"trusted-forwarders"`
+	Mode                  string        `koanf:"mode"`
+	MaxRetries            int           `koanf:"max-retries"`
+	RetryBackoff          time.Duration `koanf:"retry-backoff"`
+	BreakerThreshold      int           `koanf:"breaker-threshold"`
+	BreakerCooldown       time.Duration `koanf:"breaker-cooldown"`
+}
+
+func (c *ForwarderConfig) Validate() error {
+	switch c.Mode {
+	case ForwardingModeSequential, ForwardingModeBroadcast, ForwardingModeLowestLatency:
+	default:
+		return fmt.Errorf("unknown forwarder mode \"%v\", expected one of %v, %v or %v", c.Mode, ForwardingModeSequential, ForwardingModeBroadcast, ForwardingModeLowestLatency)
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("forwarder max retries can't be negative")
+	}
+	if c.BreakerThreshold < 0 {
+		return errors.New("forwarder breaker threshold can't be negative")
+	}
+	if _, err := parseTrustedForwarders(c.TrustedForwarders); err != nil {
+		return err
+	}
+	return nil
 }
 
 var DefaultTestForwarderConfig = ForwarderConfig{
@@ -42,6 +87,13 @@ var DefaultTestForwarderConfig = ForwarderConfig{
 	RedisUrl:              "",
 	UpdateInterval:        time.Millisecond * 10,
 	RetryInterval:         time.Millisecond * 3,
+	ForwardClientIP:       false,
+	TrustedForwarders:     []string{},
+	Mode:                  ForwardingModeSequential,
+	MaxRetries:            3,
+	RetryBackoff:          time.Millisecond * 3,
+	BreakerThreshold:      3,
+	BreakerCooldown:       time.Millisecond * 100,
 }
 
 var DefaultNodeForwarderConfig = ForwarderConfig{
@@ -51,6 +103,13 @@ var DefaultNodeForwarderConfig = ForwarderConfig{
 	RedisUrl:              "",
 	UpdateInterval:        time.Second,
 	RetryInterval:         100 * time.Millisecond,
+	ForwardClientIP:       false,
+	TrustedForwarders:     []string{},
+	Mode:                  ForwardingModeSequential,
+	MaxRetries:            3,
+	RetryBackoff:          100 * time.Millisecond,
+	BreakerThreshold:      5,
+	BreakerCooldown:       10 * time.Second,
 }
 
 var DefaultSequencerForwarderConfig = ForwarderConfig{
@@ -60,6 +119,13 @@ var DefaultSequencerForwarderConfig = ForwarderConfig{
 	RedisUrl:              "",
 	UpdateInterval:        time.Second,
 	RetryInterval:         100 * time.Millisecond,
+	ForwardClientIP:       false,
+	TrustedForwarders:     []string{},
+	Mode:                  ForwardingModeSequential,
+	MaxRetries:            3,
+	RetryBackoff:          100 * time.Millisecond,
+	BreakerThreshold:      5,
+	BreakerCooldown:       10 * time.Second,
 }
 
 func AddOptionsForNodeForwarderConfig(prefix string, f *flag.FlagSet) {
@@ -77,23 +143,117 @@ func AddOptionsForForwarderConfigImpl(prefix string, defaultConfig *ForwarderCon
 	f.String(prefix+".redis-url", defaultConfig.RedisUrl, "the Redis URL to recomend target via")
 	f.Duration(prefix+".update-interval", defaultConfig.UpdateInterval, "forwarding target update interval")
 	f.Duration(prefix+".retry-interval", defaultConfig.RetryInterval, "minimal time between update retries")
+	f.Bool(prefix+".forward-client-ip", defaultConfig.ForwardClientIP, "forward transactions with the IP of the client they came from, for the sequencer to rate limit by (requires the target to serve the arb namespace)")
+	f.StringSlice(prefix+".trusted-forwarders", defaultConfig.TrustedForwarders, "IPs or CIDRs of nodes forwarding transactions to this one, whose reported client IPs are forwarded rather than their own")
+	f.String(prefix+".mode", defaultConfig.Mode, "how to pick the target to publish to: "+ForwardingModeSequential+" (the first reachable one in order), "+ForwardingModeBroadcast+" (all of them at once) or "+ForwardingModeLowestLatency+" (the reachable one with the lowest recent latency)")
+	f.Int(prefix+".max-retries", defaultConfig.MaxRetries, "how many times to resend a transaction the target asked to retry, unless it turns out to already have it")
+	f.Duration(prefix+".retry-backoff", defaultConfig.RetryBackoff, "time to wait before resending a transaction, multiplied by the number of the retry")
+	f.Int(prefix+".breaker-threshold", defaultConfig.BreakerThreshold, "number of consecutive failures to reach a target after which it's skipped while others are reachable (0 to never skip targets)")
+	f.Duration(prefix+".breaker-cooldown", defaultConfig.BreakerCooldown, "time to skip a target for once it reached the breaker threshold, after which a single failure skips it again")
+}
+
+// Each call moves the latency estimate of a target this fraction of the way to its own latency
+const forwardingLatencyDecay = 5
+
+var metricNameUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// forwardingTarget is one of the targets of a TxForwarder, with its own circuit breaker, latency estimate and metrics.
+type forwardingTarget struct {
+	url       string
+	rpcClient *rpc.Client
+	ethClient *ethclient.Client
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	latencyTimer     metrics.Timer
+	errorCounter     metrics.Counter
+
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	latency   time.Duration
+}
+
+func newForwardingTarget(url string, rpcClient *rpc.Client, config *ForwarderConfig) *forwardingTarget {
+	// Only the host goes in the metric names, as the rest of the url may hold credentials
+	name := url
+	if parsed, err := neturl.Parse(url); err == nil && parsed.Host != "" {
+		name = parsed.Host
+	}
+	name = strings.Trim(metricNameUnsafeChars.ReplaceAllString(name, "_"), "_")
+	return &forwardingTarget{
+		url:              url,
+		rpcClient:        rpcClient,
+		ethClient:        ethclient.NewClient(rpcClient),
+		breakerThreshold: config.BreakerThreshold,
+		breakerCooldown:  config.BreakerCooldown,
+		latencyTimer:     metrics.GetOrRegisterTimer("arb/forwarder/target/"+name+"/latency", nil),
+		errorCounter:     metrics.GetOrRegisterCounter("arb/forwarder/target/"+name+"/errors", nil),
+	}
+}
+
+// available is false while the breaker of the target is open
+func (t *forwardingTarget) available(now time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return !now.Before(t.openUntil)
+}
+
+// estimatedLatency is zero until a call to the target succeeds, so that untried targets get tried
+func (t *forwardingTarget) estimatedLatency() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.latency
+}
+
+// record updates the target with the outcome of a call to it started at start.
+// failed is whether the target couldn't be reached, not whether it rejected the call.
+func (t *forwardingTarget) record(start time.Time, failed bool) {
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !failed {
+		elapsed := now.Sub(start)
+		t.latencyTimer.Update(elapsed)
+		if t.latency == 0 {
+			t.latency = elapsed
+		} else {
+			t.latency += (elapsed - t.latency) / forwardingLatencyDecay
+		}
+		t.failures = 0
+		return
+	}
+	t.errorCounter.Inc(1)
+	t.failures++
+	// The failures aren't reset when the breaker opens, so once it closes again a single failure reopens it
+	if t.breakerThreshold > 0 && t.failures >= t.breakerThreshold && !now.Before(t.openUntil) {
+		log.Warn("skipping unreachable forwarding target", "target", t.url, "failures", t.failures, "for", t.breakerCooldown)
+		forwarderBreakerOpenCounter.Inc(1)
+		t.openUntil = now.Add(t.breakerCooldown)
+	}
 }
 
 type TxForwarder struct {
 	ctx context.Context
 
-	enabled   atomic.Bool
-	timeout   time.Duration
-	transport *http.Transport
+	enabled         atomic.Bool
+	timeout         time.Duration
+	transport       *http.Transport
+	forwardClientIP bool
+	config          *ForwarderConfig
+	// Nodes forwarding to us whose reported client IPs we forward
+	trustedForwarders []*net.IPNet
 
 	healthMutex   sync.Mutex
 	healthErr     error
 	healthChecked time.Time
 
 	targets               []string
-	rpcClients            []*rpc.Client
-	ethClients            []*ethclient.Client
+	clients               []*forwardingTarget
 	tryNewForwarderErrors *regexp.Regexp
+	retryErrors           *regexp.Regexp
+	// Errors a target returns when resent a transaction it already has
+	alreadyPublishedErrors *regexp.Regexp
 }
 
 func NewForwarder(targets []string, config *ForwarderConfig) *TxForwarder {
@@ -120,11 +280,21 @@ func NewForwarder(targets []string, config *ForwarderConfig) *TxForwarder {
 		TLSHandshakeTimeout:   5 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
+	trustedForwarders, err := parseTrustedForwarders(config.TrustedForwarders)
+	if err != nil {
+		log.Error("invalid forwarder trusted forwarders, not forwarding client IPs they report", "err", err)
+	}
 	return &TxForwarder{
 		targets:               targets,
 		timeout:               config.ConnectionTimeout,
 		transport:             transport,
-		tryNewForwarderErrors: regexp.MustCompile(`(?i)(^http:|^json:|^i/0|timeout exceeded|no such host)`),
+		forwardClientIP:       config.ForwardClientIP,
+		config:                config,
+		trustedForwarders:     trustedForwarders,
+		tryNewForwarderErrors: regexp.MustCompile(`(?i)(^http:|^json:|^i/0|timeout exceeded|no such host|connection refused)`),
+		retryErrors:           regexp.MustCompile(regexp.QuoteMeta(execution.ErrRetrySequencer.Error()) + "|" + regexp.QuoteMeta(ErrNoSequencer.Error())),
+		// The transaction may also have been included already
+		alreadyPublishedErrors: regexp.MustCompile(`(?i)(already known|nonce too low)`),
 	}
 }
 
@@ -139,19 +309,165 @@ func (f *TxForwarder) PublishTransaction(inctx context.Context, tx *types.Transa
 	if !f.enabled.Load() {
 		return ErrNoSequencer
 	}
+	var ip string
+	var txBytes hexutil.Bytes
+	if f.forwardClientIP {
+		forwarded, _ := inctx.Value(forwardedClientIPKey{}).(string)
+		ip = attributedClientIP(f.trustedForwarders, peerIP(inctx), forwarded)
+	}
+	confirmation := requestedSoftConfirmation(inctx)
+	if ip != "" || confirmation != nil {
+		var err error
+		txBytes, err = tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+	}
 	ctx, cancelFunc := f.ctxWithTimeout()
 	defer cancelFunc()
-	for pos, rpcClient := range f.rpcClients {
+	for attempt := 0; ; attempt++ {
 		var err error
-		if options == nil {
-			err = f.ethClients[pos].SendTransaction(ctx, tx)
+		if f.config.Mode == ForwardingModeBroadcast {
+			err = f.broadcast(tx, options, ip, txBytes, confirmation)
 		} else {
-			err = arbitrum.SendConditionalTransactionRPC(ctx, rpcClient, tx, options)
+			err = f.publishInOrder(ctx, tx, options, ip, txBytes, confirmation)
+		}
+		if err != nil && attempt > 0 && f.alreadyPublishedErrors.MatchString(err.Error()) {
+			// The target took the transaction on an earlier attempt despite asking to retry it
+			if confirmation != nil {
+				return errors.New("transaction was published, but its soft confirmation was lost, its receipt has to be polled for")
+			}
+			return nil
+		}
+		if err == nil || attempt >= f.config.MaxRetries || !f.retryErrors.MatchString(err.Error()) {
+			return err
+		}
+		forwarderRetryCounter.Inc(1)
+		log.Debug("retrying forwarded transaction", "tx", tx.Hash(), "attempt", attempt+1, "err", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(f.config.RetryBackoff * time.Duration(attempt+1)):
 		}
+	}
+}
+
+// candidates returns the targets to publish to in the order to try them. Those whose breaker is open are left out,
+// unless all of them are.
+func (f *TxForwarder) candidates() []*forwardingTarget {
+	now := time.Now()
+	var candidates []*forwardingTarget
+	for _, target := range f.clients {
+		if target.available(now) {
+			candidates = append(candidates, target)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, f.clients...)
+	}
+	if f.config.Mode == ForwardingModeLowestLatency {
+		latencies := make(map[*forwardingTarget]time.Duration, len(candidates))
+		for _, target := range candidates {
+			latencies[target] = target.estimatedLatency()
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return latencies[candidates[i]] < latencies[candidates[j]]
+		})
+	}
+	return candidates
+}
+
+// send publishes the transaction to target, filling in confirmation if it isn't nil
+func (f *TxForwarder) send(ctx context.Context, target *forwardingTarget, tx *types.Transaction, options *arbitrum_types.ConditionalOptions, ip string, txBytes hexutil.Bytes, confirmation *SoftConfirmation) error {
+	start := time.Now()
+	var err error
+	if confirmation != nil {
+		var clientIP *string
+		if ip != "" {
+			clientIP = &ip
+		}
+		err = target.rpcClient.CallContext(ctx, confirmation, "arb_sendRawTransactionSync", txBytes, options, clientIP)
+	} else if ip != "" {
+		err = target.rpcClient.CallContext(ctx, nil, "arb_sendForwardedRawTransaction", txBytes, options, ip)
+	} else if options == nil {
+		err = target.ethClient.SendTransaction(ctx, tx)
+	} else {
+		err = arbitrum.SendConditionalTransactionRPC(ctx, target.rpcClient, tx, options)
+	}
+	target.record(start, err != nil && f.tryNewForwarderErrors.MatchString(err.Error()))
+	return err
+}
+
+func (f *TxForwarder) publishInOrder(ctx context.Context, tx *types.Transaction, options *arbitrum_types.ConditionalOptions, ip string, txBytes hexutil.Bytes, confirmation *SoftConfirmation) error {
+	for _, target := range f.candidates() {
+		err := f.send(ctx, target, tx, options, ip, txBytes, confirmation)
 		if err == nil || !f.tryNewForwarderErrors.MatchString(err.Error()) {
 			return err
 		}
-		log.Info("error forwarding transaction to a backup target", "target", f.targets[pos], "err", err)
+		log.Info("error forwarding transaction to a backup target", "target", target.url, "err", err)
+	}
+	return errors.New("failed to publish transaction to any of the forwarding targets")
+}
+
+// broadcastTargets returns the targets whose breaker is closed, or if there are none, only the first candidate,
+// so that broadcasts don't keep going to targets known to be unreachable.
+func (f *TxForwarder) broadcastTargets() []*forwardingTarget {
+	now := time.Now()
+	var targets []*forwardingTarget
+	for _, target := range f.clients {
+		if target.available(now) {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 && len(f.clients) > 0 {
+		targets = append(targets, f.clients[0])
+	}
+	return targets
+}
+
+// broadcast returns as soon as a target accepts the transaction, leaving the others to finish in the background.
+// If none does, it returns the first error a reachable target rejected it with.
+func (f *TxForwarder) broadcast(tx *types.Transaction, options *arbitrum_types.ConditionalOptions, ip string, txBytes hexutil.Bytes, confirmation *SoftConfirmation) error {
+	type result struct {
+		err          error
+		confirmation *SoftConfirmation
+	}
+	candidates := f.broadcastTargets()
+	results := make(chan result, len(candidates))
+	for _, target := range candidates {
+		target := target
+		go func() {
+			// Not tied to the caller, which may stop waiting after another target accepted the transaction
+			ctx, cancelFunc := f.ctxWithTimeout()
+			defer cancelFunc()
+			// Each target fills in its own confirmation, and only the first accepting one is kept
+			var targetConfirmation *SoftConfirmation
+			if confirmation != nil {
+				targetConfirmation = new(SoftConfirmation)
+			}
+			err := f.send(ctx, target, tx, options, ip, txBytes, targetConfirmation)
+			if err != nil {
+				log.Info("error broadcasting transaction to a forwarding target", "target", target.url, "err", err)
+			}
+			results <- result{err, targetConfirmation}
+		}()
+	}
+	var rejection error
+	for range candidates {
+		res := <-results
+		err := res.err
+		if err == nil {
+			if confirmation != nil {
+				*confirmation = *res.confirmation
+			}
+			return nil
+		}
+		if rejection == nil && !f.tryNewForwarderErrors.MatchString(err.Error()) {
+			rejection = err
+		}
+	}
+	if rejection != nil {
+		return rejection
 	}
 	return errors.New("failed to publish transaction to any of the forwarding targets")
 }
@@ -159,7 +475,8 @@ func (f *TxForwarder) PublishTransaction(inctx context.Context, tx *types.Transa
 const cacheUpstreamHealth = 2 * time.Second
 const maxHealthTimeout = 10 * time.Second
 
-// CheckHealth returns health of the highest priority forwarding target
+// CheckHealth returns nil if any of the targets that would be published to is healthy, checking them in the order
+// they would be tried. Otherwise it returns the error of the last one.
 func (f *TxForwarder) CheckHealth(inctx context.Context) error {
 	if !f.enabled.Load() {
 		return ErrNoSequencer
@@ -173,7 +490,15 @@ func (f *TxForwarder) CheckHealth(inctx context.Context) error {
 		}
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
-		f.healthErr = f.rpcClients[0].CallContext(ctx, nil, "arb_checkPublisherHealth")
+		for _, target := range f.candidates() {
+			start := time.Now()
+			f.healthErr = target.rpcClient.CallContext(ctx, nil, "arb_checkPublisherHealth")
+			// an unhealthy sequencer is reachable, so only errors reaching it count against the target
+			target.record(start, f.healthErr != nil && f.tryNewForwarderErrors.MatchString(f.healthErr.Error()))
+			if f.healthErr == nil {
+				break
+			}
+		}
 		f.healthChecked = time.Now()
 	}
 	return f.healthErr
@@ -198,12 +523,10 @@ func (f *TxForwarder) Initialize(inctx context.Context) error {
 			continue
 		}
 		targets = append(targets, target)
-		ethClient := ethclient.NewClient(rpcClient)
-		f.rpcClients = append(f.rpcClients, rpcClient)
-		f.ethClients = append(f.ethClients, ethClient)
+		f.clients = append(f.clients, newForwardingTarget(target, rpcClient, f.config))
 	}
 	f.targets = targets
-	if len(f.rpcClients) > 0 {
+	if len(f.clients) > 0 {
 		f.enabled.Store(true)
 	} else {
 		return lastError
@@ -221,8 +544,8 @@ func (f *TxForwarder) Start(ctx context.Context) error {
 }
 
 func (f *TxForwarder) StopAndWait() {
-	for _, ethClient := range f.ethClients {
-		ethClient.Close() // internally closes also the rpc client
+	for _, target := range f.clients {
+		target.ethClient.Close() // internally closes also the rpc client
 	}
 }
 
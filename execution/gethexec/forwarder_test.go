@@ -0,0 +1,264 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/execution"
+)
+
+// fakeForwardingTarget serves the eth methods the forwarder calls, remembering the transactions sent to it.
+type fakeForwardingTarget struct {
+	delay time.Duration
+
+	mutex sync.Mutex
+	// Rejects this many sends asking to retry them, remembering the transaction if keepRetried
+	retries     int
+	keepRetried bool
+	sends       int
+	txs         map[common.Hash]*types.Transaction
+}
+
+func (t *fakeForwardingTarget) SendRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	time.Sleep(t.delay)
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sends++
+	if _, ok := t.txs[tx.Hash()]; ok {
+		return common.Hash{}, errors.New("already known")
+	}
+	if t.retries > 0 {
+		t.retries--
+		if t.keepRetried {
+			t.txs[tx.Hash()] = tx
+		}
+		return common.Hash{}, execution.ErrRetrySequencer
+	}
+	t.txs[tx.Hash()] = tx
+	return tx.Hash(), nil
+}
+
+func (t *fakeForwardingTarget) received() (int, int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.sends, len(t.txs)
+}
+
+func startFakeForwardingTarget(t *testing.T, target *fakeForwardingTarget) *httptest.Server {
+	target.txs = make(map[common.Hash]*types.Transaction)
+	server := rpc.NewServer()
+	Require(t, server.RegisterName("eth", target))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func createTestForwarder(t *testing.T, ctx context.Context, config *ForwarderConfig, targets ...string) *TxForwarder {
+	Require(t, config.Validate())
+	forwarder := NewForwarder(targets, config)
+	Require(t, forwarder.Initialize(ctx))
+	t.Cleanup(forwarder.StopAndWait)
+	return forwarder
+}
+
+func testTransactions(t *testing.T, count int) []*types.Transaction {
+	key, err := crypto.GenerateKey()
+	Require(t, err)
+	signer := types.LatestSignerForChainID(common.Big1)
+	var txs []*types.Transaction
+	for i := 0; i < count; i++ {
+		tx, err := types.SignNewTx(key, signer, &types.LegacyTx{Nonce: uint64(i), Gas: 21000, GasPrice: common.Big1})
+		Require(t, err)
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func TestForwarderBreakerSkipsUnreachableTarget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unreachable := startFakeForwardingTarget(t, &fakeForwardingTarget{})
+	unreachable.Close()
+	backup := &fakeForwardingTarget{}
+	config := DefaultTestForwarderConfig
+	forwarder := createTestForwarder(t, ctx, &config, unreachable.URL, startFakeForwardingTarget(t, backup).URL)
+
+	for _, tx := range testTransactions(t, config.BreakerThreshold+1) {
+		Require(t, forwarder.PublishTransaction(ctx, tx, nil))
+	}
+	if _, received := backup.received(); received != config.BreakerThreshold+1 {
+		Fail(t, "backup target got", received, "transactions")
+	}
+	candidates := forwarder.candidates()
+	if len(candidates) != 1 || candidates[0].url == unreachable.URL {
+		Fail(t, "unreachable target not skipped")
+	}
+
+	time.Sleep(config.BreakerCooldown)
+	if len(forwarder.candidates()) != 2 {
+		Fail(t, "unreachable target still skipped after the cooldown")
+	}
+}
+
+func TestForwarderLowestLatency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := &fakeForwardingTarget{delay: 50 * time.Millisecond}
+	fast := &fakeForwardingTarget{}
+	config := DefaultTestForwarderConfig
+	config.Mode = ForwardingModeLowestLatency
+	forwarder := createTestForwarder(t, ctx, &config, startFakeForwardingTarget(t, slow).URL, startFakeForwardingTarget(t, fast).URL)
+
+	// The first transactions probe the targets, which have no latency estimate yet
+	for _, tx := range testTransactions(t, 10) {
+		Require(t, forwarder.PublishTransaction(ctx, tx, nil))
+	}
+	if slowReceived, _ := slow.received(); slowReceived != 1 {
+		Fail(t, "slow target got", slowReceived, "transactions")
+	}
+	if _, fastReceived := fast.received(); fastReceived != 9 {
+		Fail(t, "fast target got", fastReceived, "transactions")
+	}
+}
+
+func TestForwarderBroadcast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	targets := []*fakeForwardingTarget{{}, {}, {delay: 50 * time.Millisecond}}
+	var urls []string
+	for _, target := range targets {
+		urls = append(urls, startFakeForwardingTarget(t, target).URL)
+	}
+	config := DefaultTestForwarderConfig
+	config.Mode = ForwardingModeBroadcast
+	forwarder := createTestForwarder(t, ctx, &config, urls...)
+
+	tx := testTransactions(t, 1)[0]
+	Require(t, forwarder.PublishTransaction(ctx, tx, nil))
+	// The slow target still gets the transaction after it was accepted elsewhere
+	for i, target := range targets {
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			if _, received := target.received(); received == 1 {
+				break
+			}
+			if time.Since(start) > 5*time.Second {
+				Fail(t, "target", i, "didn't get the transaction")
+			}
+		}
+	}
+}
+
+func TestForwarderBroadcastSkipsTrippedTarget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unreachable := startFakeForwardingTarget(t, &fakeForwardingTarget{})
+	unreachable.Close()
+	reachable := &fakeForwardingTarget{}
+	config := DefaultTestForwarderConfig
+	config.Mode = ForwardingModeBroadcast
+	config.BreakerCooldown = time.Minute
+	forwarder := createTestForwarder(t, ctx, &config, unreachable.URL, startFakeForwardingTarget(t, reachable).URL)
+	txs := testTransactions(t, config.BreakerThreshold+2)
+
+	for _, tx := range txs[:config.BreakerThreshold] {
+		Require(t, forwarder.PublishTransaction(ctx, tx, nil))
+	}
+	// The unreachable target's failures are recorded in the background
+	for start := time.Now(); forwarder.clients[0].available(time.Now()); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			Fail(t, "unreachable target's breaker didn't open")
+		}
+	}
+	for _, tx := range txs[config.BreakerThreshold:] {
+		Require(t, forwarder.PublishTransaction(ctx, tx, nil))
+	}
+	time.Sleep(50 * time.Millisecond)
+	forwarder.clients[0].mutex.Lock()
+	failures := forwarder.clients[0].failures
+	forwarder.clients[0].mutex.Unlock()
+	if failures != config.BreakerThreshold {
+		Fail(t, "tripped target was still broadcast to, failures", failures)
+	}
+	if _, received := reachable.received(); received != len(txs) {
+		Fail(t, "reachable target got", received, "transactions")
+	}
+}
+
+func TestForwarderRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target := &fakeForwardingTarget{retries: 2}
+	config := DefaultTestForwarderConfig
+	forwarder := createTestForwarder(t, ctx, &config, startFakeForwardingTarget(t, target).URL)
+	txs := testTransactions(t, 2)
+
+	Require(t, forwarder.PublishTransaction(ctx, txs[0], nil))
+	if sends, received := target.received(); sends != 3 || received != 1 {
+		Fail(t, "unexpected sends", sends, "or transactions", received)
+	}
+
+	// Published once the target says it already has it
+	target.mutex.Lock()
+	target.retries = 1
+	target.keepRetried = true
+	target.mutex.Unlock()
+	Require(t, forwarder.PublishTransaction(ctx, txs[1], nil))
+	if sends, received := target.received(); sends != 5 || received != 2 {
+		Fail(t, "unexpected sends", sends, "or transactions", received)
+	}
+
+	target.mutex.Lock()
+	target.retries = config.MaxRetries + 1
+	target.keepRetried = false
+	target.mutex.Unlock()
+	if err := forwarder.PublishTransaction(ctx, testTransactions(t, 1)[0], nil); err == nil {
+		Fail(t, "published a transaction the target always asked to retry")
+	}
+}
+
+// unhealthyPublisher is reachable, but reports its publisher unhealthy.
+type unhealthyPublisher struct{}
+
+func (unhealthyPublisher) CheckPublisherHealth(ctx context.Context) error {
+	return errors.New("sequencer not synced")
+}
+
+func TestForwarderBreakerIgnoresUnhealthyTarget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := rpc.NewServer()
+	Require(t, server.RegisterName("arb", unhealthyPublisher{}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	config := DefaultTestForwarderConfig
+	forwarder := createTestForwarder(t, ctx, &config, httpServer.URL)
+
+	for i := 0; i <= config.BreakerThreshold; i++ {
+		if err := forwarder.CheckHealth(ctx); err == nil {
+			Fail(t, "unhealthy target reported healthy")
+		}
+		forwarder.healthMutex.Lock()
+		forwarder.healthChecked = time.Time{}
+		forwarder.healthMutex.Unlock()
+	}
+	if !forwarder.clients[0].available(time.Now()) {
+		Fail(t, "reachable but unhealthy target skipped")
+	}
+}
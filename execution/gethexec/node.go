@@ -21,8 +21,10 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/execution/execrpc"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
 	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/rpcclient"
 	flag "github.com/spf13/pflag"
 )
 
@@ -50,6 +52,8 @@ type Config struct {
 	RPC                       arbitrum.Config                  `koanf:"rpc"`
 	TxLookupLimit             uint64                           `koanf:"tx-lookup-limit"`
 	Dangerous                 DangerousConfig                  `koanf:"dangerous"`
+	RPCServer                 execrpc.ServerConfig             `koanf:"rpc-server"`
+	SyncSendRawTransaction    bool                             `koanf:"sync-send-raw-transaction" reload:"hot"`
 
 	forwardingTarget string
 }
@@ -69,6 +73,12 @@ func (c *Config) Validate() error {
 	if c.forwardingTarget != "" && c.Sequencer.Enable {
 		return errors.New("ForwardingTarget set and sequencer enabled")
 	}
+	if err := c.Forwarder.Validate(); err != nil {
+		return err
+	}
+	if c.RPCServer.Enable {
+		return c.RPCServer.Consensus.Validate()
+	}
 	return nil
 }
 
@@ -84,6 +94,8 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	CachingConfigAddOptions(prefix+".caching", f)
 	f.Uint64(prefix+".tx-lookup-limit", ConfigDefault.TxLookupLimit, "retain the ability to lookup transactions by hash for the past N blocks (0 = all blocks)")
 	DangerousConfigAddOptions(prefix+".dangerous", f)
+	execrpc.ServerConfigAddOptions(prefix+".rpc-server", f)
+	f.Bool(prefix+".sync-send-raw-transaction", ConfigDefault.SyncSendRawTransaction, "make eth_sendRawTransaction return only once the transaction is in a block, like arb_sendRawTransactionSync (forwarding targets must serve arb_sendRawTransactionSync)")
 }
 
 var ConfigDefault = Config{
@@ -98,6 +110,8 @@ var ConfigDefault = Config{
 	Caching:                   DefaultCachingConfig,
 	Dangerous:                 DefaultDangerousConfig,
 	Forwarder:                 DefaultNodeForwarderConfig,
+	RPCServer:                 execrpc.DefaultServerConfig,
+	SyncSendRawTransaction:    false,
 }
 
 func ConfigDefaultNonSequencerTest() *Config {
@@ -136,6 +150,7 @@ type ExecutionNode struct {
 	TxPublisher       TransactionPublisher
 	ConfigFetcher     ConfigFetcher
 	ParentChainReader *headerreader.HeaderReader
+	ConsensusClient   *execrpc.ConsensusClient // nil unless the consensus is in another process
 	started           atomic.Bool
 }
 
@@ -186,7 +201,7 @@ func CreateExecutionNode(
 	txprecheckConfigFetcher := func() *TxPreCheckerConfig { return &configFetcher().TxPreChecker }
 
 	txPublisher = NewTxPreChecker(txPublisher, l2BlockChain, txprecheckConfigFetcher)
-	arbInterface, err := NewArbInterface(execEngine, txPublisher)
+	arbInterface, err := NewArbInterface(execEngine, txPublisher, func() bool { return configFetcher().SyncSendRawTransaction })
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +217,7 @@ func CreateExecutionNode(
 	apis := []rpc.API{{
 		Namespace: "arb",
 		Version:   "1.0",
-		Service:   NewArbAPI(txPublisher),
+		Service:   NewArbAPI(txPublisher, config.RPC.RPCTxFeeCap, stack.Config().AllowUnprotectedTxs),
 		Public:    false,
 	}}
 	apis = append(apis, rpc.API{
@@ -224,15 +239,21 @@ func CreateExecutionNode(
 		),
 		Public: false,
 	})
+	if sequencer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "sequencer",
+			Version:   "1.0",
+			Service:   NewSequencerAPI(sequencer),
+			Public:    false,
+		})
+	}
 	apis = append(apis, rpc.API{
 		Namespace: "debug",
 		Service:   eth.NewDebugAPI(eth.NewArbEthereum(l2BlockChain, chainDB)),
 		Public:    false,
 	})
 
-	stack.RegisterAPIs(apis)
-
-	return &ExecutionNode{
+	execNode := &ExecutionNode{
 		ChainDB:           chainDB,
 		Backend:           backend,
 		FilterSystem:      filterSystem,
@@ -243,7 +264,22 @@ func CreateExecutionNode(
 		TxPublisher:       txPublisher,
 		ConfigFetcher:     configFetcher,
 		ParentChainReader: parentChainReader,
-	}, nil
+	}
+
+	if config.RPCServer.Enable {
+		apis = append(apis, rpc.API{
+			Namespace:     execrpc.Namespace,
+			Version:       "1.0",
+			Service:       execrpc.NewExecutionServerAPI(execNode),
+			Public:        false,
+			Authenticated: true,
+		})
+		execNode.ConsensusClient = execrpc.NewConsensusClient(func() *rpcclient.ClientConfig { return &configFetcher().RPCServer.Consensus }, stack)
+	}
+
+	stack.RegisterAPIs(apis)
+
+	return execNode, nil
 
 }
 
@@ -274,6 +310,12 @@ func (n *ExecutionNode) Start(ctx context.Context) error {
 	// if err != nil {
 	// 	return fmt.Errorf("error starting geth stack: %w", err)
 	// }
+	if n.ConsensusClient != nil {
+		if err := n.ConsensusClient.Start(ctx); err != nil {
+			return fmt.Errorf("error connecting to consensus: %w", err)
+		}
+		n.SetTransactionStreamer(n.ConsensusClient)
+	}
 	n.ExecEngine.Start(ctx)
 	err := n.TxPublisher.Start(ctx)
 	if err != nil {
@@ -301,6 +343,9 @@ func (n *ExecutionNode) StopAndWait() {
 	if n.ExecEngine.Started() {
 		n.ExecEngine.StopAndWait()
 	}
+	if n.ConsensusClient != nil && n.ConsensusClient.Started() {
+		n.ConsensusClient.StopAndWait()
+	}
 	n.ArbInterface.BlockChain().Stop() // does nothing if not running
 	if err := n.Backend.Stop(); err != nil {
 		log.Error("backend stop", "err", err)
@@ -20,11 +20,13 @@ import (
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/containers"
 	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/signature"
 	flag "github.com/spf13/pflag"
 
 	"github.com/ethereum/go-ethereum/arbitrum"
 	"github.com/ethereum/go-ethereum/arbitrum_types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -66,6 +68,9 @@ type SequencerConfig struct {
 	MaxTxDataSize               int             `koanf:"max-tx-data-size" reload:"hot"`
 	NonceFailureCacheSize       int             `koanf:"nonce-failure-cache-size" reload:"hot"`
 	NonceFailureCacheExpiry     time.Duration   `koanf:"nonce-failure-cache-expiry" reload:"hot"`
+	OrderingPolicy              string          `koanf:"ordering-policy" reload:"hot"`
+	OrderingWindow              time.Duration   `koanf:"ordering-window" reload:"hot"`
+	Admission                   AdmissionConfig `koanf:"admission" reload:"hot"`
 }
 
 func (c *SequencerConfig) Validate() error {
@@ -78,7 +83,13 @@ func (c *SequencerConfig) Validate() error {
 			return fmt.Errorf("sequencer sender whitelist entry \"%v\" is not a valid address", address)
 		}
 	}
-	return nil
+	if _, err := newTxOrderingPolicy(c); err != nil {
+		return err
+	}
+	if err := c.Forwarder.Validate(); err != nil {
+		return err
+	}
+	return c.Admission.Validate()
 }
 
 type SequencerConfigFetcher func() *SequencerConfig
@@ -97,6 +108,9 @@ var DefaultSequencerConfig = SequencerConfig{
 	MaxTxDataSize:           95000,
 	NonceFailureCacheSize:   1024,
 	NonceFailureCacheExpiry: time.Second,
+	OrderingPolicy:          FCFSOrderingPolicy,
+	OrderingWindow:          time.Millisecond * 50,
+	Admission:               DefaultAdmissionConfig,
 }
 
 var TestSequencerConfig = SequencerConfig{
@@ -112,6 +126,9 @@ var TestSequencerConfig = SequencerConfig{
 	MaxTxDataSize:               95000,
 	NonceFailureCacheSize:       1024,
 	NonceFailureCacheExpiry:     time.Second,
+	OrderingPolicy:              FCFSOrderingPolicy,
+	OrderingWindow:              time.Millisecond * 10,
+	Admission:                   DefaultAdmissionConfig,
 }
 
 func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -127,6 +144,9 @@ func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Int(prefix+".max-tx-data-size", DefaultSequencerConfig.MaxTxDataSize, "maximum transaction size the sequencer will accept")
 	f.Int(prefix+".nonce-failure-cache-size", DefaultSequencerConfig.NonceFailureCacheSize, "number of transactions with too high of a nonce to keep in memory while waiting for their predecessor")
 	f.Duration(prefix+".nonce-failure-cache-expiry", DefaultSequencerConfig.NonceFailureCacheExpiry, "maximum amount of time to wait for a predecessor before rejecting a tx with nonce too high")
+	f.String(prefix+".ordering-policy", DefaultSequencerConfig.OrderingPolicy, "order to sequence the transactions collected for a block in (\"fcfs\" for first come first served, \"priority-fee\" for highest effective tip first, or \"round-robin\" for one transaction per sender in turn)")
+	f.Duration(prefix+".ordering-window", DefaultSequencerConfig.OrderingWindow, "how long to collect transactions for a block before ordering them, when using an ordering policy other than fcfs")
+	AdmissionConfigAddOptions(prefix+".admission", f)
 }
 
 type txQueueItem struct {
@@ -136,6 +156,15 @@ type txQueueItem struct {
 	returnedResult  bool
 	ctx             context.Context
 	firstAppearance time.Time
+	// Set if a soft confirmation was requested, for the block the transaction is included in to be recorded
+	inclusion *txInclusion
+}
+
+type txInclusion struct {
+	included    bool
+	blockHash   common.Hash
+	blockNumber uint64
+	txIndex     int
 }
 
 func (i *txQueueItem) returnResult(err error) {
@@ -242,6 +271,7 @@ type nonceFailure struct {
 type nonceFailureCache struct {
 	*containers.LruCache[addressAndNonce, *nonceFailure]
 	getExpiry func() time.Duration
+	tracker   *txTracker
 }
 
 func (c nonceFailureCache) Contains(err NonceError) bool {
@@ -266,6 +296,7 @@ func (c nonceFailureCache) Add(err NonceError, queueItem txQueueItem) {
 	if evicted {
 		nonceFailureCacheOverflowCounter.Inc(1)
 	}
+	c.tracker.parked(queueItem.tx.Hash(), err, expiry)
 }
 
 type Sequencer struct {
@@ -277,6 +308,8 @@ type Sequencer struct {
 	l1Reader        *headerreader.HeaderReader
 	config          SequencerConfigFetcher
 	senderWhitelist map[common.Address]struct{}
+	admission       *admissionControl
+	txTracker       *txTracker
 	nonceCache      *nonceCache
 	nonceFailures   *nonceFailureCache
 	onForwarderSet  chan struct{}
@@ -291,6 +324,9 @@ type Sequencer struct {
 	activeMutex sync.Mutex
 	pauseChan   chan struct{}
 	forwarder   *TxForwarder
+
+	// Signs soft confirmations if set, with the feed signing key
+	commitmentSigner signature.DataSignerFunc
 }
 
 func NewSequencer(execEngine *ExecutionEngine, l1Reader *headerreader.HeaderReader, configFetcher SequencerConfigFetcher) (*Sequencer, error) {
@@ -312,6 +348,8 @@ func NewSequencer(execEngine *ExecutionEngine, l1Reader *headerreader.HeaderRead
 		l1Reader:        l1Reader,
 		config:          configFetcher,
 		senderWhitelist: senderWhitelist,
+		admission:       newAdmissionControl(),
+		txTracker:       newTxTracker(),
 		nonceCache:      newNonceCache(config.NonceCacheSize),
 		l1BlockNumber:   0,
 		l1Timestamp:     0,
@@ -321,6 +359,7 @@ func NewSequencer(execEngine *ExecutionEngine, l1Reader *headerreader.HeaderRead
 	s.nonceFailures = &nonceFailureCache{
 		containers.NewLruCacheWithOnEvict(config.NonceCacheSize, s.onNonceFailureEvict),
 		func() time.Duration { return configFetcher().NonceFailureCacheExpiry },
+		s.txTracker,
 	}
 	s.Pause()
 	execEngine.EnableReorgSequencing()
@@ -375,24 +414,13 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		}
 	}
 
-	if len(s.senderWhitelist) > 0 {
-		signer := types.LatestSigner(s.execEngine.bc.Config())
-		sender, err := types.Sender(signer, tx)
-		if err != nil {
-			return err
-		}
-		_, authorized := s.senderWhitelist[sender]
-		if !authorized {
-			return errors.New("transaction sender is not on the whitelist")
-		}
-	}
-	if tx.Type() >= types.ArbitrumDepositTxType || tx.Type() == types.BlobTxType {
-		// Should be unreachable for Arbitrum types due to UnmarshalBinary not accepting Arbitrum internal txs
-		// and we want to disallow BlobTxType since Arbitrum doesn't support EIP-4844 txs yet.
-		return types.ErrTxTypeNotSupported
+	config := s.config()
+	if err := s.admit(parentCtx, config, tx); err != nil {
+		s.txTracker.rejected(tx.Hash(), err)
+		return err
 	}
 
-	queueTimeout := s.config().QueueTimeout
+	queueTimeout := config.QueueTimeout
 	queueCtx, cancelFunc := ctxWithTimeout(parentCtx, queueTimeout)
 	defer cancelFunc()
 
@@ -408,15 +436,28 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		false,
 		queueCtx,
 		time.Now(),
+		nil,
+	}
+	confirmation := requestedSoftConfirmation(parentCtx)
+	if confirmation != nil {
+		queueItem.inclusion = &txInclusion{}
 	}
+	// Track the transaction before it's queued, so that it's tracked by the time it's sequenced
+	trackingSeq := s.txTracker.queued(tx.Hash(), queueItem.firstAppearance)
 	select {
 	case s.txQueue <- queueItem:
 	case <-queueCtx.Done():
+		s.txTracker.finished(tx.Hash(), trackingSeq, queueCtx.Err())
 		return queueCtx.Err()
 	}
 
 	select {
 	case res := <-resultChan:
+		s.txTracker.finished(tx.Hash(), trackingSeq, res)
+		// If another sequencer took over and the transaction was forwarded to it, the forwarder filled in its confirmation
+		if res == nil && confirmation != nil && queueItem.inclusion.included {
+			res = s.softConfirm(queueItem.inclusion, confirmation)
+		}
 		return res
 	case <-abortCtx.Done():
 		// We use abortCtx here and not queueCtx, because the QueueTimeout only applies to the background queue.
@@ -426,10 +467,70 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 			// If we've hit the abort deadline (as opposed to parentCtx being canceled), something went wrong.
 			log.Warn("Transaction sequencing hit abort deadline", "err", err, "submittedAt", queueItem.firstAppearance, "queueTimeout", queueTimeout, "txHash", tx.Hash())
 		}
+		s.txTracker.finished(tx.Hash(), trackingSeq, err)
 		return err
 	}
 }
 
+// softConfirm fills in confirmation for a transaction included in a block, signing it if there's a commitment signer.
+func (s *Sequencer) softConfirm(inclusion *txInclusion, confirmation *SoftConfirmation) error {
+	receipts := s.execEngine.bc.GetReceiptsByHash(inclusion.blockHash)
+	if inclusion.txIndex >= len(receipts) {
+		return fmt.Errorf("receipt of sequenced transaction not found in block %v", inclusion.blockHash)
+	}
+	msgIdx, err := s.execEngine.BlockNumberToMessageIndex(inclusion.blockNumber)
+	if err != nil {
+		return err
+	}
+	*confirmation = SoftConfirmation{
+		BlockHash:    inclusion.blockHash,
+		BlockNumber:  hexutil.Uint64(inclusion.blockNumber),
+		MessageIndex: hexutil.Uint64(msgIdx),
+		TxIndex:      hexutil.Uint64(inclusion.txIndex),
+		Receipt:      receipts[inclusion.txIndex],
+	}
+	if s.commitmentSigner != nil {
+		chainId := s.execEngine.bc.Config().ChainID.Uint64()
+		confirmation.Signature, err = s.commitmentSigner(confirmation.CommitmentHash(chainId).Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to sign soft confirmation: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetCommitmentSigner sets the key to sign soft confirmations with, which should be the feed signing key.
+// It must be called before the sequencer is started.
+func (s *Sequencer) SetCommitmentSigner(signer signature.DataSignerFunc) {
+	s.commitmentSigner = signer
+}
+
+// admit returns an error if the transaction mustn't be queued.
+func (s *Sequencer) admit(ctx context.Context, config *SequencerConfig, tx *types.Transaction) error {
+	if len(s.senderWhitelist) > 0 || config.Admission.enabled() {
+		signer := types.LatestSigner(s.execEngine.bc.Config())
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		if len(s.senderWhitelist) > 0 {
+			_, authorized := s.senderWhitelist[sender]
+			if !authorized {
+				return errors.New("transaction sender is not on the whitelist")
+			}
+		}
+		if err := s.admission.check(ctx, &config.Admission, sender, time.Now()); err != nil {
+			return err
+		}
+	}
+	if tx.Type() >= types.ArbitrumDepositTxType || tx.Type() == types.BlobTxType {
+		// Should be unreachable for Arbitrum types due to UnmarshalBinary not accepting Arbitrum internal txs
+		// and we want to disallow BlobTxType since Arbitrum doesn't support EIP-4844 txs yet.
+		return types.ErrTxTypeNotSupported
+	}
+	return nil
+}
+
 func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, statedb *state.StateDB, _ *arbosState.ArbosState, tx *types.Transaction, options *arbitrum_types.ConditionalOptions, sender common.Address, l1Info *arbos.L1Info) error {
 	if s.nonceCache.Caching() {
 		stateNonce := s.nonceCache.Get(header, statedb, sender)
@@ -461,6 +562,7 @@ func (s *Sequencer) postTxFilter(header *types.Header, _ *arbosState.ArbosState,
 	if haveNonceFailure {
 		nonceFailure.revived = true // prevent the expiry hook from taking effect
 		s.nonceFailures.Remove(newAddrAndNonce)
+		s.txTracker.revived(nonceFailure.queueItem.tx.Hash())
 		// Immediately check if the transaction submission has been canceled
 		err := nonceFailure.queueItem.ctx.Err()
 		if err != nil {
@@ -627,6 +729,48 @@ func (s *Sequencer) expireNonceFailures() *time.Timer {
 	}
 }
 
+// orderQueueItems orders the queue items as the ordering policy says to.
+// Items whose sender can't be recovered are left at the end, for precheckNonces to reject.
+func (s *Sequencer) orderQueueItems(policy TxOrderingPolicy, queueItems []txQueueItem) []txQueueItem {
+	if _, fcfs := policy.(fcfsOrdering); fcfs || len(queueItems) < 2 {
+		return queueItems
+	}
+	bc := s.execEngine.bc
+	latestHeader := bc.CurrentBlock()
+	signer := types.MakeSigner(bc.Config(), arbmath.BigAdd(latestHeader.Number, common.Big1), latestHeader.Time)
+	txs := make([]QueuedTx, 0, len(queueItems))
+	ordered := make([]txQueueItem, 0, len(queueItems))
+	var invalid []txQueueItem
+	for _, item := range queueItems {
+		sender, err := types.Sender(signer, item.tx)
+		if err != nil {
+			invalid = append(invalid, item)
+			continue
+		}
+		ordered = append(ordered, item)
+		txs = append(txs, QueuedTx{Tx: item.tx, Sender: sender, Arrival: item.firstAppearance})
+	}
+	order := policy.Order(txs, latestHeader.BaseFee)
+	if len(order) != len(txs) {
+		log.Error("sequencer ordering policy returned the wrong number of transactions", "policy", policy.Name(), "expected", len(txs), "got", len(order))
+		return queueItems
+	}
+	seen := make([]bool, len(txs))
+	result := make([]txQueueItem, 0, len(queueItems))
+	for position, index := range order {
+		if index < 0 || index >= len(txs) || seen[index] {
+			log.Error("sequencer ordering policy returned an invalid order", "policy", policy.Name(), "index", index)
+			return queueItems
+		}
+		seen[index] = true
+		if position != index {
+			reorderedTxsCounter.Inc(1)
+		}
+		result = append(result, ordered[index])
+	}
+	return append(result, invalid...)
+}
+
 // There's no guarantee that returned tx nonces will be correct
 func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 	bc := s.execEngine.bc
@@ -674,6 +818,7 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 				// Re-enqueue the tx whose nonce should now be correct, unless it expired
 				revivingFailure.revived = true
 				s.nonceFailures.Remove(nextKey)
+				s.txTracker.revived(revivingFailure.queueItem.tx.Hash())
 				err := revivingFailure.queueItem.ctx.Err()
 				if err != nil {
 					revivingFailure.queueItem.returnResult(err)
@@ -733,6 +878,13 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	defer nonceFailureCacheSizeGauge.Update(int64(s.nonceFailures.Len()))
 
 	config := s.config()
+	orderingPolicy, err := newTxOrderingPolicy(config)
+	if err != nil {
+		log.Error("invalid sequencer ordering policy, falling back to first come first served", "err", err)
+		orderingPolicy = fcfsOrdering{}
+	}
+	updateOrderingPolicyGauges(orderingPolicy.Name())
+	var collectUntil time.Time
 
 	// Clear out old nonceFailures
 	s.nonceFailures.Resize(config.NonceFailureCacheSize)
@@ -771,10 +923,23 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			}
 		} else {
 			done := false
-			select {
-			case queueItem = <-s.txQueue:
-			default:
-				done = true
+			if wait := time.Until(collectUntil); wait > 0 {
+				// Keep collecting transactions for the ordering policy to order
+				timer := time.NewTimer(wait)
+				select {
+				case queueItem = <-s.txQueue:
+				case <-timer.C:
+					done = true
+				case <-ctx.Done():
+					done = true
+				}
+				timer.Stop()
+			} else {
+				select {
+				case queueItem = <-s.txQueue:
+				default:
+					done = true
+				}
 			}
 			if done {
 				break
@@ -802,11 +967,15 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			break
 		}
 		totalBatchSize += len(txBytes)
+		if len(queueItems) == 0 {
+			collectUntil = time.Now().Add(orderingPolicy.CollectionWindow())
+		}
 		queueItems = append(queueItems, queueItem)
 	}
 
 	s.nonceCache.Resize(config.NonceCacheSize) // Would probably be better in a config hook but this is basically free
 	s.nonceCache.BeginNewBlock()
+	queueItems = s.orderQueueItems(orderingPolicy, queueItems)
 	queueItems = s.precheckNonces(queueItems)
 	txes := make([]*types.Transaction, len(queueItems))
 	hooks := s.makeSequencingHooks()
@@ -893,6 +1062,8 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	}
 
 	madeBlock := false
+	// The positions of the transactions in the block, only computed if a soft confirmation was requested
+	var txIndexes map[common.Hash]int
 	for i, err := range hooks.TxErrors {
 		if err == nil {
 			madeBlock = true
@@ -915,6 +1086,23 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			s.nonceFailures.Add(nonceError, queueItem)
 			continue
 		}
+		if err == nil && block != nil {
+			s.txTracker.included(queueItem.tx.Hash(), block.NumberU64())
+			if queueItem.inclusion != nil {
+				if txIndexes == nil {
+					txIndexes = make(map[common.Hash]int, len(block.Transactions()))
+					for index, tx := range block.Transactions() {
+						txIndexes[tx.Hash()] = index
+					}
+				}
+				*queueItem.inclusion = txInclusion{
+					included:    true,
+					blockHash:   block.Hash(),
+					blockNumber: block.NumberU64(),
+					txIndex:     txIndexes[queueItem.tx.Hash()],
+				}
+			}
+		}
 		queueItem.returnResult(err)
 	}
 	return madeBlock
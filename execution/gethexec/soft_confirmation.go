@@ -0,0 +1,65 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var softConfirmationPrefix = []byte("Arbitrum Nitro Soft Confirmation:")
+
+// SoftConfirmation is returned by the sequencer for a transaction it included in a block, before that block is posted
+// to the parent chain. If the sequencer signs its feed, it signs the confirmation with the same key, committing to the
+// block at the message index. A signed confirmation for a block that isn't the one eventually posted at that message
+// index, or two of them for different blocks, prove that the sequencer equivocated.
+type SoftConfirmation struct {
+	BlockHash    common.Hash    `json:"blockHash"`
+	BlockNumber  hexutil.Uint64 `json:"blockNumber"`
+	MessageIndex hexutil.Uint64 `json:"messageIndex"`
+	TxIndex      hexutil.Uint64 `json:"transactionIndex"`
+	Receipt      *types.Receipt `json:"receipt"`
+	// The signature of CommitmentHash, if the sequencer signs its feed
+	Signature hexutil.Bytes `json:"signature,omitempty"`
+}
+
+// CommitmentHash is the hash the sequencer signs. It leaves out the receipt, which follows from the block.
+func (c *SoftConfirmation) CommitmentHash(chainId uint64) common.Hash {
+	data := make([]byte, 24)
+	binary.BigEndian.PutUint64(data[:8], chainId)
+	binary.BigEndian.PutUint64(data[8:16], uint64(c.MessageIndex))
+	binary.BigEndian.PutUint64(data[16:], uint64(c.TxIndex))
+	return crypto.Keccak256Hash(softConfirmationPrefix, data, c.BlockHash.Bytes())
+}
+
+// Signer recovers the address that signed the confirmation, to check it against the feed signer of the chain.
+func (c *SoftConfirmation) Signer(chainId uint64) (common.Address, error) {
+	if len(c.Signature) == 0 {
+		return common.Address{}, errors.New("soft confirmation isn't signed")
+	}
+	pubkey, err := crypto.SigToPub(c.CommitmentHash(chainId).Bytes(), c.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+type softConfirmationKey struct{}
+
+// withSoftConfirmation asks the publisher of a transaction to fill in confirmation once it's included in a block.
+func withSoftConfirmation(ctx context.Context, confirmation *SoftConfirmation) context.Context {
+	return context.WithValue(ctx, softConfirmationKey{}, confirmation)
+}
+
+// requestedSoftConfirmation returns the confirmation to fill in for the transaction being published, if any.
+func requestedSoftConfirmation(ctx context.Context) *SoftConfirmation {
+	confirmation, _ := ctx.Value(softConfirmationKey{}).(*SoftConfirmation)
+	return confirmation
+}
@@ -0,0 +1,97 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arbitrum_types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+func TestSoftConfirmationSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	Require(t, err)
+	signer := signature.DataSignerFromPrivateKey(key)
+	const chainId = 412346
+	confirmation := SoftConfirmation{
+		BlockHash:    common.HexToHash("0x1234"),
+		BlockNumber:  10,
+		MessageIndex: 9,
+		TxIndex:      1,
+	}
+	confirmation.Signature, err = signer(confirmation.CommitmentHash(chainId).Bytes())
+	Require(t, err)
+
+	address, err := confirmation.Signer(chainId)
+	Require(t, err)
+	if address != crypto.PubkeyToAddress(key.PublicKey) {
+		Fail(t, "recovered the wrong signer", address)
+	}
+
+	// A confirmation for another block at the same message index doesn't carry the signature over
+	equivocation := confirmation
+	equivocation.BlockHash = common.HexToHash("0x5678")
+	address, err = equivocation.Signer(chainId)
+	if err == nil && address == crypto.PubkeyToAddress(key.PublicKey) {
+		Fail(t, "signature valid for another block")
+	}
+	if _, err := (&SoftConfirmation{}).Signer(chainId); err == nil {
+		Fail(t, "recovered a signer without a signature")
+	}
+}
+
+// fakeSyncTarget serves arb_sendRawTransactionSync, confirming every transaction in block 7.
+type fakeSyncTarget struct{}
+
+func (t *fakeSyncTarget) SendRawTransactionSync(ctx context.Context, input hexutil.Bytes, options *arbitrum_types.ConditionalOptions, clientIP *string) (*SoftConfirmation, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	return &SoftConfirmation{
+		BlockHash:    common.HexToHash("0x07"),
+		BlockNumber:  7,
+		MessageIndex: 6,
+		TxIndex:      1,
+		Receipt:      &types.Receipt{TxHash: tx.Hash(), Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}},
+	}, nil
+}
+
+func TestForwarderSoftConfirmation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target := &fakeForwardingTarget{txs: make(map[common.Hash]*types.Transaction)}
+	server := rpc.NewServer()
+	Require(t, server.RegisterName("eth", target))
+	Require(t, server.RegisterName("arb", &fakeSyncTarget{}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	for _, mode := range []string{ForwardingModeSequential, ForwardingModeBroadcast} {
+		config := DefaultTestForwarderConfig
+		config.Mode = mode
+		forwarder := createTestForwarder(t, ctx, &config, httpServer.URL)
+
+		tx := testTransactions(t, 1)[0]
+		confirmation := new(SoftConfirmation)
+		Require(t, forwarder.PublishTransaction(withSoftConfirmation(ctx, confirmation), tx, nil))
+		if confirmation.BlockNumber != 7 || confirmation.Receipt == nil || confirmation.Receipt.TxHash != tx.Hash() {
+			Fail(t, mode, "unexpected soft confirmation", confirmation)
+		}
+		// Without a soft confirmation requested, the transaction is sent as usual
+		Require(t, forwarder.PublishTransaction(ctx, testTransactions(t, 1)[0], nil))
+	}
+	if sends, _ := target.received(); sends != 2 {
+		Fail(t, "target got", sends, "plain transactions")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	FCFSOrderingPolicy        = "fcfs"
+	PriorityFeeOrderingPolicy = "priority-fee"
+	RoundRobinOrderingPolicy  = "round-robin"
+)
+
+var orderingPolicyGauges = map[string]metrics.Gauge{
+	FCFSOrderingPolicy:        metrics.NewRegisteredGauge("arb/sequencer/ordering/"+FCFSOrderingPolicy, nil),
+	PriorityFeeOrderingPolicy: metrics.NewRegisteredGauge("arb/sequencer/ordering/"+PriorityFeeOrderingPolicy, nil),
+	RoundRobinOrderingPolicy:  metrics.NewRegisteredGauge("arb/sequencer/ordering/"+RoundRobinOrderingPolicy, nil),
+}
+
+var reorderedTxsCounter = metrics.NewRegisteredCounter("arb/sequencer/ordering/reordered", nil)
+
+// QueuedTx is a transaction collected for the next block, as seen by a TxOrderingPolicy.
+type QueuedTx struct {
+	Tx      *types.Transaction
+	Sender  common.Address
+	Arrival time.Time
+}
+
+// TxOrderingPolicy decides the order the transactions collected for a block are sequenced in.
+type TxOrderingPolicy interface {
+	Name() string
+	// CollectionWindow is how long to keep collecting transactions for a block after the first one is taken
+	// from the queue, so that later ones can be ordered before it.
+	CollectionWindow() time.Duration
+	// Order returns the order to sequence txs in, as indices into txs, each exactly once.
+	// Each sender's transactions must stay in the order they're given in, which is the order they arrived in,
+	// as otherwise later nonces would be sequenced before earlier ones.
+	Order(txs []QueuedTx, baseFee *big.Int) []int
+}
+
+func newTxOrderingPolicy(config *SequencerConfig) (TxOrderingPolicy, error) {
+	switch config.OrderingPolicy {
+	case "", FCFSOrderingPolicy:
+		return fcfsOrdering{}, nil
+	case PriorityFeeOrderingPolicy:
+		return priorityFeeOrdering{window: config.OrderingWindow}, nil
+	case RoundRobinOrderingPolicy:
+		return roundRobinOrdering{window: config.OrderingWindow}, nil
+	default:
+		return nil, fmt.Errorf("unknown sequencer ordering policy \"%v\" (must be %v, %v or %v)", config.OrderingPolicy, FCFSOrderingPolicy, PriorityFeeOrderingPolicy, RoundRobinOrderingPolicy)
+	}
+}
+
+func updateOrderingPolicyGauges(active string) {
+	for name, gauge := range orderingPolicyGauges {
+		if name == active {
+			gauge.Update(1)
+		} else {
+			gauge.Update(0)
+		}
+	}
+}
+
+// fcfsOrdering sequences transactions in the order they arrived in.
+type fcfsOrdering struct{}
+
+func (fcfsOrdering) Name() string                    { return FCFSOrderingPolicy }
+func (fcfsOrdering) CollectionWindow() time.Duration { return 0 }
+
+func (fcfsOrdering) Order(txs []QueuedTx, _ *big.Int) []int {
+	order := make([]int, len(txs))
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// senderQueues groups txs by sender, keeping each sender's in order, and returns the senders in the order
+// their first transaction arrived in.
+func senderQueues(txs []QueuedTx) ([]common.Address, map[common.Address][]int) {
+	var senders []common.Address
+	queues := make(map[common.Address][]int)
+	for i, tx := range txs {
+		if _, ok := queues[tx.Sender]; !ok {
+			senders = append(senders, tx.Sender)
+		}
+		queues[tx.Sender] = append(queues[tx.Sender], i)
+	}
+	return senders, queues
+}
+
+// priorityFeeOrdering sequences the transactions collected within its window by their effective tip,
+// highest first, while keeping each sender's transactions in order. Ties go to the earliest arrival.
+type priorityFeeOrdering struct {
+	window time.Duration
+}
+
+func (priorityFeeOrdering) Name() string                      { return PriorityFeeOrderingPolicy }
+func (p priorityFeeOrdering) CollectionWindow() time.Duration { return p.window }
+
+func effectiveTip(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return tx.GasTipCap()
+	}
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		// The fee cap is below the base fee, so the transaction will fail anyway.
+		return new(big.Int)
+	}
+	return tip
+}
+
+// tipHeap holds the next transaction of each sender, ordered by effective tip and then by arrival.
+type tipHeap struct {
+	heads []int
+	tips  []*big.Int
+}
+
+func (h *tipHeap) Len() int { return len(h.heads) }
+func (h *tipHeap) Less(i, j int) bool {
+	if cmp := h.tips[h.heads[i]].Cmp(h.tips[h.heads[j]]); cmp != 0 {
+		return cmp > 0
+	}
+	return h.heads[i] < h.heads[j]
+}
+func (h *tipHeap) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *tipHeap) Push(x any)    { h.heads = append(h.heads, x.(int)) }
+func (h *tipHeap) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+func (priorityFeeOrdering) Order(txs []QueuedTx, baseFee *big.Int) []int {
+	senders, queues := senderQueues(txs)
+	h := &tipHeap{tips: make([]*big.Int, len(txs))}
+	for i, tx := range txs {
+		h.tips[i] = effectiveTip(tx.Tx, baseFee)
+	}
+	for _, sender := range senders {
+		h.heads = append(h.heads, queues[sender][0])
+	}
+	heap.Init(h)
+	order := make([]int, 0, len(txs))
+	for h.Len() > 0 {
+		next := heap.Pop(h).(int)
+		order = append(order, next)
+		sender := txs[next].Sender
+		queues[sender] = queues[sender][1:]
+		if len(queues[sender]) > 0 {
+			heap.Push(h, queues[sender][0])
+		}
+	}
+	return order
+}
+
+// roundRobinOrdering sequences one transaction from each sender in turn, so that a sender with many
+// transactions can't delay everyone else's until all of theirs are sequenced.
+type roundRobinOrdering struct {
+	window time.Duration
+}
+
+func (roundRobinOrdering) Name() string                      { return RoundRobinOrderingPolicy }
+func (r roundRobinOrdering) CollectionWindow() time.Duration { return r.window }
+
+func (roundRobinOrdering) Order(txs []QueuedTx, _ *big.Int) []int {
+	senders, queues := senderQueues(txs)
+	order := make([]int, 0, len(txs))
+	for len(order) < len(txs) {
+		for _, sender := range senders {
+			if len(queues[sender]) > 0 {
+				order = append(order, queues[sender][0])
+				queues[sender] = queues[sender][1:]
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,114 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	orderingAlice = common.HexToAddress("0xa")
+	orderingBob   = common.HexToAddress("0xb")
+	orderingCarol = common.HexToAddress("0xc")
+)
+
+func queuedTx(sender common.Address, nonce uint64, tipCap int64, feeCap int64) QueuedTx {
+	return QueuedTx{
+		Tx: types.NewTx(&types.DynamicFeeTx{
+			Nonce:     nonce,
+			GasTipCap: big.NewInt(tipCap),
+			GasFeeCap: big.NewInt(feeCap),
+		}),
+		Sender:  sender,
+		Arrival: time.Unix(int64(nonce), 0),
+	}
+}
+
+// checkSenderOrder fails if any sender's transactions aren't in the order they were given in.
+func checkSenderOrder(t *testing.T, txs []QueuedTx, order []int) {
+	t.Helper()
+	last := make(map[common.Address]int)
+	for _, index := range order {
+		if previous, ok := last[txs[index].Sender]; ok && previous > index {
+			Fail(t, "sender", txs[index].Sender, "transactions reordered:", order)
+		}
+		last[txs[index].Sender] = index
+	}
+}
+
+func TestTxOrderingPolicies(t *testing.T) {
+	baseFee := big.NewInt(100)
+	txs := []QueuedTx{
+		queuedTx(orderingAlice, 0, 1, 200),
+		queuedTx(orderingAlice, 1, 50, 200),
+		queuedTx(orderingAlice, 2, 1, 200),
+		queuedTx(orderingBob, 0, 10, 200),
+		queuedTx(orderingCarol, 0, 30, 200),
+		// The fee cap only leaves a tip of 5
+		queuedTx(orderingCarol, 1, 40, 105),
+		queuedTx(orderingBob, 1, 10, 200),
+	}
+	for _, tc := range []struct {
+		policy string
+		want   []int
+	}{
+		{
+			policy: FCFSOrderingPolicy,
+			want:   []int{0, 1, 2, 3, 4, 5, 6},
+		},
+		{
+			// Carol's first transaction has the highest tip of the senders' first transactions, then Bob's,
+			// after which Alice's low tip first transaction has to go before her high tip second one.
+			policy: PriorityFeeOrderingPolicy,
+			want:   []int{4, 3, 6, 5, 0, 1, 2},
+		},
+		{
+			policy: RoundRobinOrderingPolicy,
+			want:   []int{0, 3, 4, 1, 6, 5, 2},
+		},
+	} {
+		config := TestSequencerConfig
+		config.OrderingPolicy = tc.policy
+		policy, err := newTxOrderingPolicy(&config)
+		Require(t, err)
+		if policy.Name() != tc.policy {
+			Fail(t, "got policy", policy.Name(), "expected", tc.policy)
+		}
+		order := policy.Order(txs, baseFee)
+		if !reflect.DeepEqual(order, tc.want) {
+			Fail(t, tc.policy, "ordering", order, "expected", tc.want)
+		}
+		checkSenderOrder(t, txs, order)
+		// Ordering must be deterministic
+		if again := policy.Order(txs, baseFee); !reflect.DeepEqual(order, again) {
+			Fail(t, tc.policy, "ordering", order, "then", again)
+		}
+	}
+}
+
+func TestPriorityFeeOrderingTies(t *testing.T) {
+	txs := []QueuedTx{
+		queuedTx(orderingCarol, 0, 5, 200),
+		queuedTx(orderingAlice, 0, 5, 200),
+		queuedTx(orderingBob, 0, 5, 200),
+	}
+	order := priorityFeeOrdering{}.Order(txs, big.NewInt(100))
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(order, want) {
+		Fail(t, "equal tips ordered", order, "expected arrival order", want)
+	}
+}
+
+func TestInvalidOrderingPolicy(t *testing.T) {
+	config := TestSequencerConfig
+	config.OrderingPolicy = "random"
+	if err := config.Validate(); err == nil {
+		Fail(t, "unknown ordering policy passed validation")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var droppedLifecycleEventsCounter = metrics.NewRegisteredCounter("arb/sequencer/lifecycle/dropped", nil)
+
+const (
+	TxStatusQueued    = "queued"
+	TxStatusParked    = "parked"
+	TxStatusIncluded  = "included"
+	TxStatusForwarded = "forwarded"
+	TxStatusRejected  = "rejected"
+	TxStatusUnknown   = "unknown"
+)
+
+// The number of recent rejections kept for introspection
+const recentRejectionsSize = 1024
+
+// The number of lifecycle events buffered for each subscriber, beyond which events are dropped for it
+const lifecycleSubscriberBuffer = 1024
+
+// TxLifecycleEvent is sent to subscribers when a transaction is queued, parked waiting for a transaction with a
+// lower nonce, included in a block, forwarded to another sequencer, or rejected.
+type TxLifecycleEvent struct {
+	Hash        common.Hash     `json:"hash"`
+	Status      string          `json:"status"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	Time        time.Time       `json:"time"`
+}
+
+type TxRejection struct {
+	Hash   common.Hash `json:"hash"`
+	Reason string      `json:"reason"`
+	Time   time.Time   `json:"time"`
+}
+
+type trackedTx struct {
+	seq      uint64
+	queuedAt time.Time
+	// Set if the transaction is in the nonce failure cache, waiting for its predecessor
+	parked      bool
+	nonceErr    NonceError
+	parkedUntil time.Time
+	included    bool
+}
+
+// txTracker keeps track of what's happening to the transactions submitted to the sequencer, from when they're
+// queued until their result is returned, for introspection. It's safe to use concurrently.
+type txTracker struct {
+	mutex   sync.Mutex
+	nextSeq uint64
+	pending map[common.Hash]*trackedTx
+	// A ring buffer of the most recent rejections
+	rejections    []TxRejection
+	nextRejection int
+	subscribers   map[chan TxLifecycleEvent]struct{}
+}
+
+func newTxTracker() *txTracker {
+	return &txTracker{
+		pending:     make(map[common.Hash]*trackedTx),
+		rejections:  make([]TxRejection, 0, recentRejectionsSize),
+		subscribers: make(map[chan TxLifecycleEvent]struct{}),
+	}
+}
+
+// notify sends an event to every subscriber, dropping it for those which aren't keeping up,
+// so that slow subscribers never hold up sequencing. The mutex must be held by the caller.
+func (t *txTracker) notify(event TxLifecycleEvent) {
+	for sub := range t.subscribers {
+		select {
+		case sub <- event:
+		default:
+			droppedLifecycleEventsCounter.Inc(1)
+		}
+	}
+}
+
+// subscribe returns a channel lifecycle events are sent to, and a function to stop the subscription.
+func (t *txTracker) subscribe() (<-chan TxLifecycleEvent, func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	sub := make(chan TxLifecycleEvent, lifecycleSubscriberBuffer)
+	t.subscribers[sub] = struct{}{}
+	return sub, func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		delete(t.subscribers, sub)
+	}
+}
+
+// queued records a transaction being added to the queue, and returns the sequence number to finish it with.
+func (t *txTracker) queued(hash common.Hash, now time.Time) uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.nextSeq++
+	t.pending[hash] = &trackedTx{seq: t.nextSeq, queuedAt: now}
+	t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusQueued, Time: now})
+	return t.nextSeq
+}
+
+// parked records a transaction being added to the nonce failure cache, to wait for its predecessor until expiry.
+func (t *txTracker) parked(hash common.Hash, nonceErr NonceError, expiry time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.pending[hash]
+	if !ok || tracked.parked {
+		return
+	}
+	tracked.parked = true
+	tracked.nonceErr = nonceErr
+	tracked.parkedUntil = expiry
+	t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusParked, Time: time.Now()})
+}
+
+// revived records a parked transaction being taken out of the nonce failure cache to be sequenced again.
+func (t *txTracker) revived(hash common.Hash) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if tracked, ok := t.pending[hash]; ok && tracked.parked {
+		tracked.parked = false
+		t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusQueued, Time: time.Now()})
+	}
+}
+
+// included records a transaction being sequenced in a block. Its result is returned afterwards.
+func (t *txTracker) included(hash common.Hash, blockNumber uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if tracked, ok := t.pending[hash]; ok {
+		tracked.included = true
+	}
+	number := hexutil.Uint64(blockNumber)
+	t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusIncluded, BlockNumber: &number, Time: time.Now()})
+}
+
+// finished records the result of a transaction being returned, after which it's no longer tracked.
+// A successful result for a transaction which wasn't included means it was forwarded to another sequencer.
+func (t *txTracker) finished(hash common.Hash, seq uint64, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.pending[hash]
+	if !ok || tracked.seq != seq {
+		// The same transaction was submitted again, and its latest submission is still tracked
+		return
+	}
+	delete(t.pending, hash)
+	if err != nil {
+		t.recordRejection(hash, err)
+	} else if !tracked.included {
+		t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusForwarded, Time: time.Now()})
+	}
+}
+
+// rejected records a transaction being rejected before it was queued.
+func (t *txTracker) rejected(hash common.Hash, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordRejection(hash, err)
+}
+
+// The mutex must be held by the caller.
+func (t *txTracker) recordRejection(hash common.Hash, err error) {
+	rejection := TxRejection{Hash: hash, Reason: err.Error(), Time: time.Now()}
+	if len(t.rejections) < recentRejectionsSize {
+		t.rejections = append(t.rejections, rejection)
+	} else {
+		t.rejections[t.nextRejection] = rejection
+	}
+	t.nextRejection = (t.nextRejection + 1) % recentRejectionsSize
+	t.notify(TxLifecycleEvent{Hash: hash, Status: TxStatusRejected, Reason: rejection.Reason, Time: rejection.Time})
+}
+
+// recentRejections returns up to count of the most recent rejections, most recent first.
+func (t *txTracker) recentRejections(count int) []TxRejection {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if count > len(t.rejections) {
+		count = len(t.rejections)
+	}
+	res := make([]TxRejection, 0, count)
+	for i := 1; i <= count; i++ {
+		index := (t.nextRejection - i + recentRejectionsSize) % recentRejectionsSize
+		res = append(res, t.rejections[index])
+	}
+	return res
+}
+
+// SequencerTxStatus is what the sequencer knows about a transaction submitted to it.
+type SequencerTxStatus struct {
+	Hash   common.Hash `json:"hash"`
+	Status string      `json:"status"`
+	// The number of queued transactions ahead of it, if it's queued
+	Position *hexutil.Uint64 `json:"position,omitempty"`
+	QueuedAt *time.Time      `json:"queuedAt,omitempty"`
+	// If it's parked, its sender and nonce, and the sender's nonce, as it's waiting for the transactions in between
+	Sender       *common.Address `json:"sender,omitempty"`
+	Nonce        *hexutil.Uint64 `json:"nonce,omitempty"`
+	AccountNonce *hexutil.Uint64 `json:"accountNonce,omitempty"`
+	ParkedUntil  *time.Time      `json:"parkedUntil,omitempty"`
+	// The reason it was rejected, if it was recently rejected
+	Reason     string     `json:"reason,omitempty"`
+	RejectedAt *time.Time `json:"rejectedAt,omitempty"`
+}
+
+func (t *txTracker) status(hash common.Hash) *SequencerTxStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	res := &SequencerTxStatus{Hash: hash, Status: TxStatusUnknown}
+	if tracked, ok := t.pending[hash]; ok {
+		queuedAt := tracked.queuedAt
+		res.QueuedAt = &queuedAt
+		if tracked.parked {
+			res.Status = TxStatusParked
+			sender := tracked.nonceErr.sender
+			nonce := hexutil.Uint64(tracked.nonceErr.txNonce)
+			accountNonce := hexutil.Uint64(tracked.nonceErr.stateNonce)
+			until := tracked.parkedUntil
+			res.Sender = &sender
+			res.Nonce = &nonce
+			res.AccountNonce = &accountNonce
+			res.ParkedUntil = &until
+		} else {
+			res.Status = TxStatusQueued
+			var position hexutil.Uint64
+			for _, other := range t.pending {
+				if !other.parked && other.seq < tracked.seq {
+					position++
+				}
+			}
+			res.Position = &position
+		}
+		return res
+	}
+	for i := 1; i <= len(t.rejections); i++ {
+		rejection := t.rejections[(t.nextRejection-i+recentRejectionsSize)%recentRejectionsSize]
+		if rejection.Hash == hash {
+			res.Status = TxStatusRejected
+			res.Reason = rejection.Reason
+			rejectedAt := rejection.Time
+			res.RejectedAt = &rejectedAt
+			break
+		}
+	}
+	return res
+}
+
+// counts returns the number of tracked transactions which are queued and which are parked.
+func (t *txTracker) counts() (int, int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var parked int
+	for _, tracked := range t.pending {
+		if tracked.parked {
+			parked++
+		}
+	}
+	return len(t.pending) - parked, parked
+}
@@ -0,0 +1,136 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func expectLifecycleEvent(t *testing.T, events <-chan TxLifecycleEvent, hash common.Hash, status string) TxLifecycleEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.Hash != hash || event.Status != status {
+			Fail(t, "got event", event.Status, "for", event.Hash, "expected", status, "for", hash)
+		}
+		return event
+	default:
+		Fail(t, "no event, expected", status, "for", hash)
+	}
+	return TxLifecycleEvent{}
+}
+
+func TestTxTrackerLifecycle(t *testing.T) {
+	tracker := newTxTracker()
+	events, unsubscribe := tracker.subscribe()
+	defer unsubscribe()
+
+	first := common.HexToHash("0x1")
+	second := common.HexToHash("0x2")
+	third := common.HexToHash("0x3")
+	now := time.Now()
+	firstSeq := tracker.queued(first, now)
+	secondSeq := tracker.queued(second, now)
+	thirdSeq := tracker.queued(third, now)
+	for _, hash := range []common.Hash{first, second, third} {
+		expectLifecycleEvent(t, events, hash, TxStatusQueued)
+	}
+
+	status := tracker.status(third)
+	if status.Status != TxStatusQueued || status.Position == nil || *status.Position != 2 {
+		Fail(t, "unexpected status of third transaction", status)
+	}
+
+	nonceErr := NonceError{sender: common.HexToAddress("0xa"), txNonce: 5, stateNonce: 3}
+	tracker.parked(second, nonceErr, now.Add(time.Second))
+	expectLifecycleEvent(t, events, second, TxStatusParked)
+	status = tracker.status(second)
+	if status.Status != TxStatusParked || status.Nonce == nil || *status.Nonce != 5 || *status.AccountNonce != 3 {
+		Fail(t, "unexpected status of parked transaction", status)
+	}
+	// Parked transactions aren't ahead of anyone in the queue
+	if status := tracker.status(third); *status.Position != 1 {
+		Fail(t, "third transaction at position", *status.Position, "with second parked")
+	}
+	if queued, parked := tracker.counts(); queued != 2 || parked != 1 {
+		Fail(t, "counted", queued, "queued and", parked, "parked")
+	}
+
+	tracker.included(first, 100)
+	event := expectLifecycleEvent(t, events, first, TxStatusIncluded)
+	if event.BlockNumber == nil || *event.BlockNumber != 100 {
+		Fail(t, "unexpected inclusion block", event.BlockNumber)
+	}
+	tracker.finished(first, firstSeq, nil)
+	if status := tracker.status(first); status.Status != TxStatusUnknown {
+		Fail(t, "sequenced transaction still tracked as", status.Status)
+	}
+
+	tracker.revived(second)
+	expectLifecycleEvent(t, events, second, TxStatusQueued)
+	rejection := errors.New("nonce too high")
+	tracker.finished(second, secondSeq, rejection)
+	expectLifecycleEvent(t, events, second, TxStatusRejected)
+	status = tracker.status(second)
+	if status.Status != TxStatusRejected || status.Reason != rejection.Error() {
+		Fail(t, "unexpected status of rejected transaction", status)
+	}
+
+	// A successful result without inclusion means the transaction was forwarded
+	tracker.finished(third, thirdSeq, nil)
+	expectLifecycleEvent(t, events, third, TxStatusForwarded)
+
+	select {
+	case event := <-events:
+		Fail(t, "unexpected event", event)
+	default:
+	}
+}
+
+func TestTxTrackerResubmission(t *testing.T) {
+	tracker := newTxTracker()
+	hash := common.HexToHash("0x1")
+	firstSeq := tracker.queued(hash, time.Now())
+	tracker.queued(hash, time.Now())
+	// The result of the first submission doesn't stop the second being tracked
+	tracker.finished(hash, firstSeq, errors.New("already known"))
+	if status := tracker.status(hash); status.Status != TxStatusQueued {
+		Fail(t, "resubmitted transaction has status", status.Status)
+	}
+}
+
+func TestTxTrackerRecentRejections(t *testing.T) {
+	tracker := newTxTracker()
+	total := recentRejectionsSize + 10
+	for i := 0; i < total; i++ {
+		tracker.rejected(common.BigToHash(big.NewInt(int64(i))), errors.New("rejected"))
+	}
+	recent := tracker.recentRejections(total)
+	if len(recent) != recentRejectionsSize {
+		Fail(t, "kept", len(recent), "rejections")
+	}
+	for i, rejection := range recent {
+		if expected := common.BigToHash(big.NewInt(int64(total - 1 - i))); rejection.Hash != expected {
+			Fail(t, "rejection", i, "is", rejection.Hash, "expected", expected)
+		}
+	}
+	if status := tracker.status(common.BigToHash(big.NewInt(0))); status.Status != TxStatusUnknown {
+		Fail(t, "oldest rejection should have been forgotten")
+	}
+}
+
+func TestTxTrackerSlowSubscriber(t *testing.T) {
+	tracker := newTxTracker()
+	_, unsubscribe := tracker.subscribe()
+	defer unsubscribe()
+	// Must not block once the subscriber's buffer is full
+	for i := 0; i < lifecycleSubscriberBuffer*2; i++ {
+		tracker.rejected(common.Hash{}, errors.New("rejected"))
+	}
+}
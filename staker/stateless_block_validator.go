@@ -19,6 +19,7 @@ import (
 	"github.com/offchainlabs/nitro/validator"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -68,6 +69,7 @@ type TransactionStreamerInterface interface {
 
 type InboxReaderInterface interface {
 	GetSequencerMessageBytes(ctx context.Context, seqNum uint64) ([]byte, error)
+	GetSequencerMessageBlobs(ctx context.Context, seqNum uint64) ([]common.Hash, []kzg4844.Blob, error)
 }
 
 type GlobalStatePosition struct {
@@ -298,6 +300,23 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 			}
 		}
 	}
+	if len(e.BatchInfo) > 0 {
+		// The first batch is the one the message is read from, whose data may be in blobs
+		batch := e.BatchInfo[0]
+		if len(batch.Data) > 40 && arbstate.IsBlobHashesHeaderByte(batch.Data[40]) {
+			versionedHashes, kzgBlobs, err := v.inboxReader.GetSequencerMessageBlobs(ctx, batch.Number)
+			if err != nil {
+				return fmt.Errorf("error reading blobs of batch %d for proving: %w", batch.Number, err)
+			}
+			if len(kzgBlobs) > 0 {
+				blobPreimages := make(map[common.Hash][]byte)
+				for i := range kzgBlobs {
+					blobPreimages[versionedHashes[i]] = kzgBlobs[i][:]
+				}
+				e.Preimages[arbutil.EthVersionedHashPreimageType] = blobPreimages
+			}
+		}
+	}
 
 	e.msg = nil // no longer needed
 	e.Stage = Ready
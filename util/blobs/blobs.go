@@ -0,0 +1,107 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package blobs encodes batch data into EIP-4844 blobs and decodes it back.
+package blobs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	fieldElementsPerBlob = 4096
+	bytesPerFieldElement = 32
+	// The top byte of each field element is left zero so that it's always less than the BLS modulus.
+	usableBytesPerFieldElement = bytesPerFieldElement - 1
+	// BlobEncodableData is the number of bytes of data a single blob holds.
+	BlobEncodableData = fieldElementsPerBlob * usableBytesPerFieldElement
+	// MaxBlobsPerTransaction is the most blobs a single transaction may carry.
+	MaxBlobsPerTransaction = 6
+	// versionedHashVersionKzg is the version byte of versioned hashes of KZG commitments.
+	versionedHashVersionKzg = 0x01
+)
+
+// EncodeBlobs RLP encodes data, so its length is known when decoding, and fills as many blobs as needed with it.
+func EncodeBlobs(data []byte) ([]kzg4844.Blob, error) {
+	encoded, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]kzg4844.Blob, (len(encoded)+BlobEncodableData-1)/BlobEncodableData)
+	for i := range blobs {
+		for fieldElement := 0; fieldElement < fieldElementsPerBlob && len(encoded) > 0; fieldElement++ {
+			start := fieldElement*bytesPerFieldElement + 1
+			copied := copy(blobs[i][start:start+usableBytesPerFieldElement], encoded)
+			encoded = encoded[copied:]
+		}
+	}
+	return blobs, nil
+}
+
+// DecodeBlobs returns the data encoded into blobs by EncodeBlobs.
+func DecodeBlobs(blobs []kzg4844.Blob) ([]byte, error) {
+	encoded := make([]byte, 0, len(blobs)*BlobEncodableData)
+	for i := range blobs {
+		for fieldElement := 0; fieldElement < fieldElementsPerBlob; fieldElement++ {
+			start := fieldElement * bytesPerFieldElement
+			if blobs[i][start] != 0 {
+				return nil, fmt.Errorf("blob %v field element %v has a non-zero top byte", i, fieldElement)
+			}
+			encoded = append(encoded, blobs[i][start+1:start+bytesPerFieldElement]...)
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, errors.New("no blobs to decode")
+	}
+	var data []byte
+	// rlp.Decode ignores the zero padding after the encoded data
+	if err := rlp.Decode(bytes.NewReader(encoded), &data); err != nil {
+		return nil, fmt.Errorf("error decoding blob data: %w", err)
+	}
+	return data, nil
+}
+
+// VersionedHash returns the hash of a KZG commitment that blob transactions reference blobs by.
+func VersionedHash(commitment kzg4844.Commitment) common.Hash {
+	hash := common.Hash(sha256.Sum256(commitment[:]))
+	hash[0] = versionedHashVersionKzg
+	return hash
+}
+
+// ComputeCommitmentsAndHashes returns the KZG commitment and versioned hash of each blob.
+func ComputeCommitmentsAndHashes(blobs []kzg4844.Blob) ([]kzg4844.Commitment, []common.Hash, error) {
+	commitments := make([]kzg4844.Commitment, len(blobs))
+	versionedHashes := make([]common.Hash, len(blobs))
+	for i, blob := range blobs {
+		var err error
+		commitments[i], err = kzg4844.BlobToCommitment(blob)
+		if err != nil {
+			return nil, nil, err
+		}
+		versionedHashes[i] = VersionedHash(commitments[i])
+	}
+	return commitments, versionedHashes, nil
+}
+
+// ComputeBlobProofs returns the KZG proof of each blob against its commitment.
+func ComputeBlobProofs(blobs []kzg4844.Blob, commitments []kzg4844.Commitment) ([]kzg4844.Proof, error) {
+	if len(blobs) != len(commitments) {
+		return nil, fmt.Errorf("ComputeBlobProofs got %v blobs but %v commitments", len(blobs), len(commitments))
+	}
+	proofs := make([]kzg4844.Proof, len(blobs))
+	for i := range blobs {
+		var err error
+		proofs[i], err = kzg4844.ComputeBlobProof(blobs[i], commitments[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return proofs, nil
+}
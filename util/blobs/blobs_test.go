@@ -0,0 +1,35 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package blobs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestBlobEncoding(t *testing.T) {
+	for _, size := range []int{0, 1, 31, 32, BlobEncodableData - 10, BlobEncodableData, 3*BlobEncodableData + 17} {
+		data := testhelpers.RandomizeSlice(make([]byte, size))
+		blobs, err := EncodeBlobs(data)
+		testhelpers.RequireImpl(t, err)
+		// RLP adds a few bytes of length prefix, so data exactly filling a blob spills into the next one.
+		if len(blobs) < (size+BlobEncodableData-1)/BlobEncodableData || len(blobs) > size/BlobEncodableData+1 {
+			testhelpers.FailImpl(t, "unexpected number of blobs", len(blobs), "for data size", size)
+		}
+		for _, blob := range blobs {
+			for i := 0; i < len(blob); i += bytesPerFieldElement {
+				if blob[i] != 0 {
+					testhelpers.FailImpl(t, "field element top byte set")
+				}
+			}
+		}
+		decoded, err := DecodeBlobs(blobs)
+		testhelpers.RequireImpl(t, err)
+		if !bytes.Equal(decoded, data) {
+			testhelpers.FailImpl(t, "decoded data doesn't match for size", size)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package headerreader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/blobs"
+	flag "github.com/spf13/pflag"
+)
+
+// BlobClient reads the blobs batches were posted in from a beacon chain node.
+// It implements arbstate.BlobReader.
+type BlobClient struct {
+	ec         headerByHashReader
+	beaconUrl  *url.URL
+	httpClient *http.Client
+
+	// The genesis time and seconds per slot are fetched in Initialize
+	genesisTime    uint64
+	secondsPerSlot uint64
+}
+
+type headerByHashReader interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+type BlobClientConfig struct {
+	BeaconUrl      string        `koanf:"beacon-url"`
+	RequestTimeout time.Duration `koanf:"request-timeout"`
+}
+
+var DefaultBlobClientConfig = BlobClientConfig{
+	BeaconUrl:      "",
+	RequestTimeout: 30 * time.Second,
+}
+
+func BlobClientAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".beacon-url", DefaultBlobClientConfig.BeaconUrl, "beacon chain HTTP API URL to read blobs from, required to read batches posted as blobs")
+	f.Duration(prefix+".request-timeout", DefaultBlobClientConfig.RequestTimeout, "timeout of requests to the beacon chain HTTP API")
+}
+
+func NewBlobClient(config BlobClientConfig, ec arbutil.L1Interface) (*BlobClient, error) {
+	beaconUrl, err := url.Parse(config.BeaconUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse beacon chain URL: %w", err)
+	}
+	return &BlobClient{
+		ec:         ec,
+		beaconUrl:  beaconUrl,
+		httpClient: &http.Client{Timeout: config.RequestTimeout},
+	}, nil
+}
+
+// The beacon chain API wraps every response in a data field
+type beaconResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+func beaconRequest[T any](b *BlobClient, ctx context.Context, path string) (T, error) {
+	var empty T
+	requestUrl, err := url.JoinPath(b.beaconUrl.String(), path)
+	if err != nil {
+		return empty, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return empty, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return empty, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return empty, fmt.Errorf("beacon chain request %v returned status %v: %v", path, resp.Status, string(body))
+	}
+	var full beaconResponse[T]
+	if err := json.NewDecoder(resp.Body).Decode(&full); err != nil {
+		return empty, fmt.Errorf("error decoding beacon chain response to %v: %w", path, err)
+	}
+	return full.Data, nil
+}
+
+type genesisResponse struct {
+	GenesisTime string `json:"genesis_time"`
+}
+
+type specResponse struct {
+	SecondsPerSlot string `json:"SECONDS_PER_SLOT"`
+}
+
+type blobResponseItem struct {
+	Index         string        `json:"index"`
+	Blob          hexutil.Bytes `json:"blob"`
+	KzgCommitment hexutil.Bytes `json:"kzg_commitment"`
+	KzgProof      hexutil.Bytes `json:"kzg_proof"`
+}
+
+func (b *BlobClient) Initialize(ctx context.Context) error {
+	genesis, err := beaconRequest[genesisResponse](b, ctx, "/eth/v1/beacon/genesis")
+	if err != nil {
+		return fmt.Errorf("error calling beacon client in genesisTime: %w", err)
+	}
+	b.genesisTime, err = strconv.ParseUint(genesis.GenesisTime, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid beacon chain genesis time %q: %w", genesis.GenesisTime, err)
+	}
+	spec, err := beaconRequest[specResponse](b, ctx, "/eth/v1/config/spec")
+	if err != nil {
+		return fmt.Errorf("error calling beacon client in secondsPerSlot: %w", err)
+	}
+	b.secondsPerSlot, err = strconv.ParseUint(spec.SecondsPerSlot, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid beacon chain seconds per slot %q: %w", spec.SecondsPerSlot, err)
+	}
+	if b.secondsPerSlot == 0 {
+		return errors.New("beacon chain reported zero seconds per slot")
+	}
+	return nil
+}
+
+// GetBlobs returns the blobs with the given versioned hashes from the block with hash blockHash, in that order.
+// The blobs are checked against their commitments, so they can be trusted as much as the versioned hashes.
+func (b *BlobClient) GetBlobs(ctx context.Context, blockHash common.Hash, versionedHashes []common.Hash) ([]kzg4844.Blob, error) {
+	if b.secondsPerSlot == 0 {
+		return nil, errors.New("BlobClient used before it was initialized")
+	}
+	header, err := b.ec.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if header.Time < b.genesisTime {
+		return nil, fmt.Errorf("block %v timestamp %v is before the beacon chain genesis time %v", blockHash, header.Time, b.genesisTime)
+	}
+	if (header.Time-b.genesisTime)%b.secondsPerSlot != 0 {
+		return nil, fmt.Errorf("block %v timestamp %v isn't at a slot boundary", blockHash, header.Time)
+	}
+	slot := (header.Time - b.genesisTime) / b.secondsPerSlot
+	return b.blobSidecars(ctx, slot, versionedHashes)
+}
+
+func (b *BlobClient) blobSidecars(ctx context.Context, slot uint64, versionedHashes []common.Hash) ([]kzg4844.Blob, error) {
+	response, err := beaconRequest[[]blobResponseItem](b, ctx, fmt.Sprintf("/eth/v1/beacon/blob_sidecars/%d", slot))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching blob sidecars of slot %v: %w", slot, err)
+	}
+	found := make(map[common.Hash]*kzg4844.Blob, len(response))
+	for _, item := range response {
+		var commitment kzg4844.Commitment
+		var proof kzg4844.Proof
+		var blob kzg4844.Blob
+		if len(item.KzgCommitment) != len(commitment) || len(item.KzgProof) != len(proof) || len(item.Blob) != len(blob) {
+			return nil, fmt.Errorf("malformed blob sidecar %v of slot %v", item.Index, slot)
+		}
+		copy(commitment[:], item.KzgCommitment)
+		versionedHash := blobs.VersionedHash(commitment)
+		if !containsHash(versionedHashes, versionedHash) {
+			continue
+		}
+		copy(proof[:], item.KzgProof)
+		copy(blob[:], item.Blob)
+		if err := kzg4844.VerifyBlobProof(blob, commitment, proof); err != nil {
+			return nil, fmt.Errorf("blob sidecar %v of slot %v failed proof verification: %w", item.Index, slot, err)
+		}
+		found[versionedHash] = &blob
+	}
+	output := make([]kzg4844.Blob, len(versionedHashes))
+	for i, versionedHash := range versionedHashes {
+		blob, ok := found[versionedHash]
+		if !ok {
+			return nil, fmt.Errorf("blob with versioned hash %v not found in slot %v", versionedHash, slot)
+		}
+		output[i] = *blob
+	}
+	return output, nil
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
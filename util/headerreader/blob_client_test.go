@@ -0,0 +1,109 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package headerreader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+
+	"github.com/offchainlabs/nitro/util/blobs"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+type testHeaderReader struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (r *testHeaderReader) HeaderByHash(_ context.Context, hash common.Hash) (*types.Header, error) {
+	header, ok := r.headers[hash]
+	if !ok {
+		return nil, errors.New("header not found")
+	}
+	return header, nil
+}
+
+func TestBlobClient(t *testing.T) {
+	ctx := context.Background()
+	data := testhelpers.RandomizeSlice(make([]byte, blobs.BlobEncodableData+100))
+	kzgBlobs, err := blobs.EncodeBlobs(data)
+	testhelpers.RequireImpl(t, err)
+	commitments, versionedHashes, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+	testhelpers.RequireImpl(t, err)
+	proofs, err := blobs.ComputeBlobProofs(kzgBlobs, commitments)
+	testhelpers.RequireImpl(t, err)
+
+	const genesisTime = 1000
+	const slot = 7
+	var sidecars []blobResponseItem
+	for i := range kzgBlobs {
+		sidecars = append(sidecars, blobResponseItem{
+			Index:         "0",
+			Blob:          kzgBlobs[i][:],
+			KzgCommitment: commitments[i][:],
+			KzgProof:      proofs[i][:],
+		})
+	}
+	respond := func(w http.ResponseWriter, data interface{}) {
+		if err := json.NewEncoder(w).Encode(beaconResponse[interface{}]{Data: data}); err != nil {
+			t.Error(err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/beacon/genesis", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, map[string]string{"genesis_time": "1000"})
+	})
+	mux.HandleFunc("/eth/v1/config/spec", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, map[string]string{"SECONDS_PER_SLOT": "12"})
+	})
+	mux.HandleFunc("/eth/v1/beacon/blob_sidecars/7", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, sidecars)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	blockHash := common.Hash{1}
+	config := DefaultBlobClientConfig
+	config.BeaconUrl = server.URL
+	client, err := NewBlobClient(config, nil)
+	testhelpers.RequireImpl(t, err)
+	client.ec = &testHeaderReader{headers: map[common.Hash]*types.Header{
+		blockHash: {Time: genesisTime + slot*12},
+	}}
+	testhelpers.RequireImpl(t, client.Initialize(ctx))
+
+	// Request the blobs in reverse order to check they're returned in the order requested.
+	reversedHashes := []common.Hash{versionedHashes[1], versionedHashes[0]}
+	result, err := client.GetBlobs(ctx, blockHash, reversedHashes)
+	testhelpers.RequireImpl(t, err)
+	if len(result) != 2 || result[0] != kzgBlobs[1] || result[1] != kzgBlobs[0] {
+		testhelpers.FailImpl(t, "unexpected blobs returned")
+	}
+	decoded, err := blobs.DecodeBlobs([]kzg4844.Blob{result[1], result[0]})
+	testhelpers.RequireImpl(t, err)
+	if !bytes.Equal(decoded, data) {
+		testhelpers.FailImpl(t, "decoded blob data doesn't match")
+	}
+
+	_, err = client.GetBlobs(ctx, blockHash, []common.Hash{{2}})
+	if err == nil {
+		testhelpers.FailImpl(t, "expected an error for an unknown versioned hash")
+	}
+
+	// A sidecar whose proof doesn't match its blob must be rejected.
+	sidecars[0].KzgProof = hexutil.Bytes(proofs[1][:])
+	_, err = client.GetBlobs(ctx, blockHash, versionedHashes)
+	if err == nil {
+		testhelpers.FailImpl(t, "expected an error for an invalid blob proof")
+	}
+}
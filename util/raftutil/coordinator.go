@@ -0,0 +1,525 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/raft"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+// The largest command accepted from another replica, which must fit a message
+const maxForwardedCommandSize = 64 * 1024 * 1024
+
+// How long a command forwarded to the leader is valid for, so that it can't be replayed later
+const maxForwardedCommandAge = time.Minute
+
+type Config struct {
+	BindAddr          string        `koanf:"bind-addr"`
+	AdvertiseAddr     string        `koanf:"advertise-addr"`
+	Peers             []string      `koanf:"peers"`
+	ApplyAddr         string        `koanf:"apply-addr"`
+	ApplyUrl          string        `koanf:"apply-url"`
+	DataDir           string        `koanf:"data-dir"`
+	Priorities        []string      `koanf:"priorities"`
+	ApplyTimeout      time.Duration `koanf:"apply-timeout"`
+	SnapshotThreshold uint64        `koanf:"snapshot-threshold"`
+	TLSRootCA         string        `koanf:"tls-root-ca"`
+	TLSCert           string        `koanf:"tls-cert"`
+	TLSPrivateKey     string        `koanf:"tls-private-key"`
+}
+
+var DefaultConfig = Config{
+	BindAddr:          "",
+	AdvertiseAddr:     "",
+	Peers:             []string{},
+	ApplyAddr:         "",
+	ApplyUrl:          "",
+	DataDir:           "",
+	Priorities:        []string{},
+	ApplyTimeout:      5 * time.Second,
+	SnapshotThreshold: 8192,
+	TLSRootCA:         "",
+	TLSCert:           "",
+	TLSPrivateKey:     "",
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".bind-addr", DefaultConfig.BindAddr, "the address to listen for raft traffic from the other replicas on")
+	f.String(prefix+".advertise-addr", DefaultConfig.AdvertiseAddr, "the address the other replicas reach this one's raft listener at, which is also its raft server ID (defaults to the bind address)")
+	f.StringSlice(prefix+".peers", DefaultConfig.Peers, "the advertised raft addresses of every replica, including this one, to bootstrap the group with if it has no state yet (must be the same on every replica)")
+	f.String(prefix+".apply-addr", DefaultConfig.ApplyAddr, "the address to listen for commands forwarded by the other replicas on, which the leader applies")
+	f.String(prefix+".apply-url", DefaultConfig.ApplyUrl, "the URL the other replicas reach this one's command listener at (defaults to http:// followed by the advertised host and the apply port)")
+	f.String(prefix+".data-dir", DefaultConfig.DataDir, "the directory to store raft snapshots in (defaults to a directory in the node's data directory)")
+	f.StringSlice(prefix+".priorities", DefaultConfig.Priorities, "the URLs of the sequencers in order of preference for being chosen (must be the same on every replica)")
+	f.Duration(prefix+".apply-timeout", DefaultConfig.ApplyTimeout, "how long to wait for a command to be appended to the raft log")
+	f.Uint64(prefix+".snapshot-threshold", DefaultConfig.SnapshotThreshold, "the number of raft log entries after which a snapshot is taken and the log is truncated")
+	f.String(prefix+".tls-root-ca", DefaultConfig.TLSRootCA, "the CA which signs every replica's certificate, which raft connections are mutually authenticated against")
+	f.String(prefix+".tls-cert", DefaultConfig.TLSCert, "this replica's certificate for raft connections, which must be valid for the host of its advertised address")
+	f.String(prefix+".tls-private-key", DefaultConfig.TLSPrivateKey, "the private key of this replica's certificate for raft connections")
+}
+
+func (c *Config) advertiseAddr() string {
+	if c.AdvertiseAddr != "" {
+		return c.AdvertiseAddr
+	}
+	return c.BindAddr
+}
+
+func (c *Config) applyUrl() (string, error) {
+	if c.ApplyUrl != "" {
+		return c.ApplyUrl, nil
+	}
+	host, _, err := net.SplitHostPort(c.advertiseAddr())
+	if err != nil {
+		return "", fmt.Errorf("invalid raft advertise address: %w", err)
+	}
+	_, port, err := net.SplitHostPort(c.ApplyAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid raft apply address: %w", err)
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+func (c *Config) Validate() error {
+	if c.BindAddr == "" {
+		return errors.New("raft coordination requires a bind address")
+	}
+	if c.ApplyAddr == "" {
+		return errors.New("raft coordination requires an apply address")
+	}
+	if _, err := c.applyUrl(); err != nil {
+		return err
+	}
+	if c.DataDir == "" {
+		return errors.New("raft coordination requires a data directory")
+	}
+	if len(c.Peers) > 0 {
+		found := false
+		for _, peer := range c.Peers {
+			found = found || peer == c.advertiseAddr()
+		}
+		if !found {
+			return fmt.Errorf("raft peers must include this replica's advertised address %v", c.advertiseAddr())
+		}
+	}
+	if c.SnapshotThreshold == 0 {
+		return errors.New("raft snapshot threshold must be positive")
+	}
+	if c.TLSRootCA == "" || c.TLSCert == "" || c.TLSPrivateKey == "" {
+		return errors.New("raft coordination requires a TLS root CA, certificate and private key to authenticate the other replicas")
+	}
+	return nil
+}
+
+// Signer signs commands forwarded to the leader, and verifies those forwarded by the other replicas.
+type Signer interface {
+	SignMessage(data ...[]byte) ([]byte, error)
+	VerifySignature(ctx context.Context, sig []byte, data ...[]byte) error
+}
+
+// forwarder sends a command to the leader to apply, as only the leader can append to the raft log.
+type forwarder interface {
+	forward(ctx context.Context, applyUrl string, cmd *command) error
+}
+
+// Coordinator is a replica of the sequencer coordination state, which is replicated among the sequencers
+// with raft, so that they can coordinate without an external service. Writes are applied by the leader,
+// to which the other replicas forward them, and reads are from the local replica, which may be behind.
+type Coordinator struct {
+	stopwaiter.StopWaiter
+
+	config    *Config
+	id        raft.ServerID
+	applyUrl  string
+	raft      *raft.Raft
+	fsm       *fsm
+	transport raft.Transport
+	forwarder forwarder
+	signer    Signer
+}
+
+// NewCoordinator starts this replica, storing the raft log in db and snapshots in the configured directory.
+func NewCoordinator(config *Config, db ethdb.KeyValueStore, signer Signer) (*Coordinator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	applyUrl, err := config.applyUrl()
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := raft.NewFileSnapshotStore(config.DataDir, 2, logWriter{})
+	if err != nil {
+		return nil, err
+	}
+	advertise, err := net.ResolveTCPAddr("tcp", config.advertiseAddr())
+	if err != nil {
+		return nil, fmt.Errorf("invalid raft advertise address: %w", err)
+	}
+	streamLayer, err := newTLSStreamLayer(config, advertise)
+	if err != nil {
+		return nil, err
+	}
+	transport := raft.NewNetworkTransport(streamLayer, 3, 10*time.Second, logWriter{})
+	raftConfig := raft.DefaultConfig()
+	raftConfig.SnapshotThreshold = config.SnapshotThreshold
+	forwarder := &httpForwarder{client: &http.Client{Timeout: 2 * config.ApplyTimeout}, signer: signer}
+	coordinator, err := newCoordinator(config, raftConfig, applyUrl, store, snapshots, transport, forwarder, signer)
+	if err != nil {
+		_ = transport.Close()
+		return nil, err
+	}
+	return coordinator, nil
+}
+
+func newCoordinator(
+	config *Config,
+	raftConfig *raft.Config,
+	applyUrl string,
+	store *Store,
+	snapshots raft.SnapshotStore,
+	transport raft.Transport,
+	forwarder forwarder,
+	signer Signer,
+) (*Coordinator, error) {
+	id := raft.ServerID(transport.LocalAddr())
+	raftConfig.LocalID = id
+	raftConfig.LogOutput = logWriter{}
+	raftConfig.LogLevel = "INFO"
+	hasState, err := raft.HasExistingState(store, store, snapshots)
+	if err != nil {
+		return nil, err
+	}
+	if !hasState && len(config.Peers) > 0 {
+		var servers []raft.Server
+		for _, peer := range config.Peers {
+			servers = append(servers, raft.Server{ID: raft.ServerID(peer), Address: raft.ServerAddress(peer)})
+		}
+		log.Info("bootstrapping raft coordination group", "peers", config.Peers)
+		err := raft.BootstrapCluster(raftConfig, store, store, snapshots, transport, raft.Configuration{Servers: servers})
+		if err != nil {
+			return nil, fmt.Errorf("failed to bootstrap raft coordination group: %w", err)
+		}
+	}
+	fsm := newFsm()
+	r, err := raft.NewRaft(raftConfig, fsm, store, store, snapshots, transport)
+	if err != nil {
+		return nil, err
+	}
+	return &Coordinator{
+		config:    config,
+		id:        id,
+		applyUrl:  applyUrl,
+		raft:      r,
+		fsm:       fsm,
+		transport: transport,
+		forwarder: forwarder,
+		signer:    signer,
+	}, nil
+}
+
+func (c *Coordinator) Start(ctxIn context.Context) {
+	c.StopWaiter.Start(ctxIn, c)
+	c.LaunchThread(c.registerApplyUrl)
+	if c.config.ApplyAddr != "" {
+		c.LaunchThread(c.serveApply)
+	}
+}
+
+func (c *Coordinator) StopAndWait() {
+	c.StopWaiter.StopAndWait()
+	if err := c.raft.Shutdown().Error(); err != nil {
+		log.Warn("error shutting down raft", "err", err)
+	}
+	if closer, ok := c.transport.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// registerApplyUrl records the URL this replica accepts commands at whenever it becomes the leader,
+// so that the other replicas know where to forward theirs to.
+func (c *Coordinator) registerApplyUrl(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if c.raft.State() == raft.Leader && c.fsm.applyUrl(c.id) != c.applyUrl {
+			err := c.applyAsLeader(&command{Op: opRegisterApplyUrl, Name: string(c.id), Url: c.applyUrl})
+			if err != nil {
+				log.Warn("failed to register raft apply url", "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.raft.LeaderCh():
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Coordinator) applyAsLeader(cmd *command) error {
+	cmd.Time = time.Now().UnixMilli()
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	future := c.raft.Apply(data, c.config.ApplyTimeout)
+	if err := future.Error(); err != nil {
+		return err
+	}
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (c *Coordinator) apply(ctx context.Context, cmd *command) error {
+	if c.raft.State() == raft.Leader {
+		return c.applyAsLeader(cmd)
+	}
+	_, leader := c.raft.LeaderWithID()
+	if leader == "" {
+		return errors.New("raft coordination group has no leader")
+	}
+	applyUrl := c.fsm.applyUrl(leader)
+	if applyUrl == "" {
+		return fmt.Errorf("raft leader %v hasn't registered where to apply commands yet", leader)
+	}
+	return c.forwarder.forward(ctx, applyUrl, cmd)
+}
+
+func (c *Coordinator) AcquireLockout(ctx context.Context, req *LockoutRequest) error {
+	return c.apply(ctx, &command{Op: opAcquireLockout, Lockout: req})
+}
+
+// ReleaseChosen releases the lockout if url holds it.
+func (c *Coordinator) ReleaseChosen(ctx context.Context, url string) error {
+	return c.apply(ctx, &command{Op: opReleaseChosen, Url: url})
+}
+
+func (c *Coordinator) SetWantsLockout(ctx context.Context, url string, until time.Time) error {
+	return c.apply(ctx, &command{Op: opSetWantsLockout, Url: url, Until: until.UnixMilli()})
+}
+
+func (c *Coordinator) ReleaseWantsLockout(ctx context.Context, url string) error {
+	return c.apply(ctx, &command{Op: opReleaseWantsLockout, Url: url})
+}
+
+// AcquireLock acquires or refreshes the named lock for owner until the given time,
+// and returns an error wrapping ErrConflict if someone else holds it.
+func (c *Coordinator) AcquireLock(ctx context.Context, name string, owner string, until time.Time) error {
+	return c.apply(ctx, &command{Op: opAcquireLock, Name: name, Url: owner, Until: until.UnixMilli()})
+}
+
+// ReleaseLock releases the named lock if owner holds it.
+func (c *Coordinator) ReleaseLock(ctx context.Context, name string, owner string) error {
+	return c.apply(ctx, &command{Op: opReleaseLock, Name: name, Url: owner})
+}
+
+// CurrentChosenSequencer returns the sequencer holding the lockout, or "" if none does.
+func (c *Coordinator) CurrentChosenSequencer() string {
+	return c.fsm.chosen(time.Now())
+}
+
+// SignedMsgCount returns the signed message count written by the chosen sequencer, or nil if there's none.
+func (c *Coordinator) SignedMsgCount() []byte {
+	return c.fsm.signedMsgCount(time.Now())
+}
+
+// Message returns the message at index and its signature, which is nil if it's part of the message.
+// It returns false if there's no such message.
+func (c *Coordinator) Message(index uint64) ([]byte, []byte, bool) {
+	msg := c.fsm.message(index, time.Now())
+	if msg == nil {
+		return nil, nil, false
+	}
+	return msg.Data, msg.Sig, true
+}
+
+func (c *Coordinator) WantsLockout(url string) bool {
+	return c.fsm.wantsLockout(url, time.Now())
+}
+
+// RecommendSequencerWantingLockout returns the top priority sequencer wanting the lockout
+func (c *Coordinator) RecommendSequencerWantingLockout() (string, error) {
+	if len(c.config.Priorities) == 0 {
+		return "", errors.New("sequencer priorities unset")
+	}
+	for _, url := range c.config.Priorities {
+		if c.WantsLockout(url) {
+			return url, nil
+		}
+	}
+	log.Error("no sequencer appears to want the lockout on raft", "priorities", c.config.Priorities)
+	return "", nil
+}
+
+// IsLeader returns whether this replica is the raft leader, which applies the commands of every replica.
+func (c *Coordinator) IsLeader() bool {
+	return c.raft.State() == raft.Leader
+}
+
+type forwardedCommand struct {
+	Command []byte `json:"command"`
+	// When the command was forwarded, in unix milliseconds
+	Sent int64  `json:"sent"`
+	Sig  []byte `json:"sig"`
+}
+
+type applyResult struct {
+	Error    string `json:"error,omitempty"`
+	Conflict bool   `json:"conflict,omitempty"`
+}
+
+// remoteError is an error the leader returned applying a forwarded command.
+type remoteError struct {
+	msg      string
+	conflict bool
+}
+
+func (e *remoteError) Error() string { return e.msg }
+
+func (e *remoteError) Is(target error) bool {
+	return e.conflict && target == ErrConflict
+}
+
+func signedCommandData(sent int64, cmd []byte) [][]byte {
+	var sentBytes [8]byte
+	binary.BigEndian.PutUint64(sentBytes[:], uint64(sent))
+	return [][]byte{sentBytes[:], cmd}
+}
+
+type httpForwarder struct {
+	client *http.Client
+	signer Signer
+}
+
+func (f *httpForwarder) forward(ctx context.Context, applyUrl string, cmd *command) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	forwarded := forwardedCommand{Command: data, Sent: time.Now().UnixMilli()}
+	forwarded.Sig, err = f.signer.SignMessage(signedCommandData(forwarded.Sent, data)...)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(&forwarded)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, applyUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to forward raft command to the leader: %w", err)
+	}
+	defer resp.Body.Close()
+	var result applyResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to read the result of a raft command from the leader (status %v): %w", resp.Status, err)
+	}
+	if result.Error != "" {
+		return &remoteError{msg: result.Error, conflict: result.Conflict}
+	}
+	return nil
+}
+
+// ServeHTTP applies commands forwarded by the other replicas, if this replica is the leader.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result, status := c.handleForwarded(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(&result); err != nil {
+		log.Warn("failed to write raft command result", "err", err)
+	}
+}
+
+func (c *Coordinator) handleForwarded(r *http.Request) (applyResult, int) {
+	if r.Method != http.MethodPost {
+		return applyResult{Error: "raft commands must be posted"}, http.StatusMethodNotAllowed
+	}
+	var forwarded forwardedCommand
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxForwardedCommandSize)).Decode(&forwarded); err != nil {
+		return applyResult{Error: fmt.Sprintf("invalid raft command: %v", err)}, http.StatusBadRequest
+	}
+	if err := c.signer.VerifySignature(r.Context(), forwarded.Sig, signedCommandData(forwarded.Sent, forwarded.Command)...); err != nil {
+		return applyResult{Error: fmt.Sprintf("invalid raft command signature: %v", err)}, http.StatusUnauthorized
+	}
+	if age := time.Since(time.UnixMilli(forwarded.Sent)); age > maxForwardedCommandAge || age < -maxForwardedCommandAge {
+		return applyResult{Error: "expired raft command"}, http.StatusBadRequest
+	}
+	var cmd command
+	if err := json.Unmarshal(forwarded.Command, &cmd); err != nil {
+		return applyResult{Error: fmt.Sprintf("invalid raft command: %v", err)}, http.StatusBadRequest
+	}
+	if c.raft.State() != raft.Leader {
+		return applyResult{Error: "not the raft leader"}, http.StatusServiceUnavailable
+	}
+	if err := c.applyAsLeader(&cmd); err != nil {
+		return applyResult{Error: err.Error(), Conflict: errors.Is(err, ErrConflict)}, http.StatusOK
+	}
+	return applyResult{}, http.StatusOK
+}
+
+func (c *Coordinator) serveApply(ctx context.Context) {
+	server := &http.Server{
+		Addr:              c.config.ApplyAddr,
+		Handler:           c,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		err := server.Shutdown(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			log.Warn("error shutting down raft apply server", "err", err)
+		}
+	}()
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("error serving raft apply server", "err", err)
+	}
+}
+
+// logWriter passes the lines raft logs on to our logger.
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	switch {
+	case strings.Contains(line, "[ERROR]"):
+		log.Error(line)
+	case strings.Contains(line, "[WARN]"):
+		log.Warn(line)
+	case strings.Contains(line, "[INFO]"):
+		log.Info(line)
+	default:
+		log.Debug(line)
+	}
+	return len(p), nil
+}
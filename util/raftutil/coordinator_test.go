@@ -0,0 +1,256 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func Require(t *testing.T, err error, printables ...interface{}) {
+	t.Helper()
+	testhelpers.RequireImpl(t, err, printables...)
+}
+
+func Fail(t *testing.T, printables ...interface{}) {
+	t.Helper()
+	testhelpers.FailImpl(t, printables...)
+}
+
+func follower(coordinators []*Coordinator) *Coordinator {
+	for _, c := range coordinators {
+		if !c.IsLeader() {
+			return c
+		}
+	}
+	return nil
+}
+
+// waitReplicated waits for every replica to have applied everything the leader has.
+func waitReplicated(t *testing.T, coordinators []*Coordinator) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		var lastIndex uint64
+		for _, c := range coordinators {
+			if c.IsLeader() {
+				lastIndex = c.raft.AppliedIndex()
+			}
+		}
+		replicated := lastIndex > 0
+		for _, c := range coordinators {
+			replicated = replicated && c.raft.AppliedIndex() >= lastIndex
+		}
+		if replicated {
+			return
+		}
+	}
+	Fail(t, "raft replicas didn't catch up")
+}
+
+func TestRaftLockout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coordinators := CreateTestCluster(ctx, t, 3, []string{"seq0", "seq1"})
+	seq0 := follower(coordinators)
+	seq1 := coordinators[0]
+	if seq1 == seq0 {
+		seq1 = coordinators[1]
+	}
+
+	lockout := func(c *Coordinator, url string, expected uint64, msg []byte) error {
+		return c.AcquireLockout(ctx, &LockoutRequest{
+			Url:              url,
+			ExpectedMsgCount: expected,
+			MsgCount:         expected + 1,
+			SignedMsgCount:   []byte{byte(expected + 1)},
+			Message:          msg,
+			LockoutUntil:     time.Now().Add(time.Minute),
+			Retention:        time.Minute,
+			SetWantsLockout:  true,
+		})
+	}
+	Require(t, lockout(seq0, "seq0", 0, []byte("msg0")))
+	Require(t, lockout(seq0, "seq0", 1, []byte("msg1")))
+	if err := lockout(seq1, "seq1", 2, []byte("other")); !errors.Is(err, ErrConflict) {
+		Fail(t, "acquired a lockout held by another sequencer", err)
+	}
+	if err := lockout(seq0, "seq0", 1, []byte("stale")); !errors.Is(err, ErrConflict) {
+		Fail(t, "wrote a message behind the message count", err)
+	}
+	waitReplicated(t, coordinators)
+	for _, c := range coordinators {
+		if chosen := c.CurrentChosenSequencer(); chosen != "seq0" {
+			Fail(t, "unexpected chosen sequencer", chosen)
+		}
+		if count := c.SignedMsgCount(); !bytes.Equal(count, []byte{2}) {
+			Fail(t, "unexpected message count", count)
+		}
+		if msg, _, ok := c.Message(1); !ok || string(msg) != "msg1" {
+			Fail(t, "unexpected message", string(msg))
+		}
+		if recommended, err := c.RecommendSequencerWantingLockout(); err != nil || recommended != "seq0" {
+			Fail(t, "unexpected recommended sequencer", recommended, err)
+		}
+	}
+
+	// Handing off to seq1
+	Require(t, seq1.SetWantsLockout(ctx, "seq1", time.Now().Add(time.Minute)))
+	Require(t, seq0.ReleaseWantsLockout(ctx, "seq0"))
+	Require(t, seq0.ReleaseChosen(ctx, "seq0"))
+	Require(t, lockout(seq1, "seq1", 2, nil))
+	waitReplicated(t, coordinators)
+	for _, c := range coordinators {
+		if chosen := c.CurrentChosenSequencer(); chosen != "seq1" {
+			Fail(t, "unexpected chosen sequencer after handoff", chosen)
+		}
+		if recommended, _ := c.RecommendSequencerWantingLockout(); recommended != "seq1" {
+			Fail(t, "unexpected recommended sequencer after handoff", recommended)
+		}
+		if msg, _, ok := c.Message(1); !ok || string(msg) != "msg1" {
+			Fail(t, "message lost after handoff", string(msg))
+		}
+	}
+}
+
+func TestRaftLockoutExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coordinators := CreateTestCluster(ctx, t, 3, []string{"seq0", "seq1"})
+	c := follower(coordinators)
+	req := &LockoutRequest{Url: "seq0", MsgCount: 1, LockoutUntil: time.Now().Add(100 * time.Millisecond), Retention: time.Minute}
+	Require(t, c.AcquireLockout(ctx, req))
+	req = &LockoutRequest{Url: "seq1", ExpectedMsgCount: 1, MsgCount: 1, LockoutUntil: time.Now().Add(time.Minute), Retention: time.Minute}
+	if err := c.AcquireLockout(ctx, req); !errors.Is(err, ErrConflict) {
+		Fail(t, "acquired a lockout held by another sequencer", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if chosen := c.CurrentChosenSequencer(); chosen != "" {
+		Fail(t, "lockout didn't expire", chosen)
+	}
+	Require(t, c.AcquireLockout(ctx, req))
+}
+
+func TestRaftLock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coordinators := CreateTestCluster(ctx, t, 3, nil)
+	c := follower(coordinators)
+	until := time.Now().Add(time.Minute)
+	Require(t, c.AcquireLock(ctx, "maintenance", "a", until))
+	Require(t, c.AcquireLock(ctx, "maintenance", "a", until))
+	if err := c.AcquireLock(ctx, "maintenance", "b", until); !errors.Is(err, ErrConflict) {
+		Fail(t, "acquired a lock held by someone else", err)
+	}
+	Require(t, c.ReleaseLock(ctx, "maintenance", "b"))
+	if err := c.AcquireLock(ctx, "maintenance", "b", until); !errors.Is(err, ErrConflict) {
+		Fail(t, "released a lock held by someone else", err)
+	}
+	Require(t, c.ReleaseLock(ctx, "maintenance", "a"))
+	Require(t, c.AcquireLock(ctx, "maintenance", "b", until))
+}
+
+func TestRaftLeaderFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coordinators := CreateTestCluster(ctx, t, 3, []string{"seq0"})
+	req := &LockoutRequest{Url: "seq0", MsgCount: 1, Message: []byte("msg0"), LockoutUntil: time.Now().Add(time.Minute), Retention: time.Minute}
+	Require(t, follower(coordinators).AcquireLockout(ctx, req))
+	waitReplicated(t, coordinators)
+
+	var remaining []*Coordinator
+	for _, c := range coordinators {
+		if c.IsLeader() {
+			Require(t, c.raft.Shutdown().Error())
+		} else {
+			remaining = append(remaining, c)
+		}
+	}
+	req = &LockoutRequest{Url: "seq0", ExpectedMsgCount: 1, MsgCount: 2, Message: []byte("msg1"), LockoutUntil: time.Now().Add(time.Minute), Retention: time.Minute}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		err := remaining[0].AcquireLockout(ctx, req)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrConflict) {
+			Fail(t, "lost the lockout on leader failover", err)
+		}
+		if time.Since(start) > 10*time.Second {
+			Fail(t, "no new leader", err)
+		}
+	}
+	waitReplicated(t, remaining)
+	for _, c := range remaining {
+		if msg, _, ok := c.Message(1); !ok || string(msg) != "msg1" {
+			Fail(t, "message not replicated after failover", string(msg))
+		}
+		if msg, _, ok := c.Message(0); !ok || string(msg) != "msg0" {
+			Fail(t, "message lost on failover", string(msg))
+		}
+	}
+}
+
+func TestRaftSnapshot(t *testing.T) {
+	f := newFsm()
+	apply := func(cmd *command) interface{} {
+		data, err := json.Marshal(cmd)
+		Require(t, err)
+		return f.Apply(&raft.Log{Data: data})
+	}
+	now := time.Now()
+	for i := uint64(0); i < 3; i++ {
+		res := apply(&command{Op: opAcquireLockout, Time: now.UnixMilli(), Lockout: &LockoutRequest{
+			Url: "seq0", ExpectedMsgCount: i, MsgCount: i + 1, Message: []byte{byte(i)}, LockoutUntil: now.Add(time.Minute), Retention: time.Duration(i+1) * time.Minute,
+		}})
+		if res != nil {
+			Fail(t, "failed to apply lockout", res)
+		}
+	}
+	snapshot, err := f.Snapshot()
+	Require(t, err)
+	store := raft.NewInmemSnapshotStore()
+	snapshotSink, err := store.Create(raft.SnapshotVersionMax, 1, 1, raft.Configuration{}, 1, nil)
+	Require(t, err)
+	Require(t, snapshot.Persist(snapshotSink))
+
+	restored := newFsm()
+	_, reader, err := store.Open(snapshotSink.ID())
+	Require(t, err)
+	Require(t, restored.Restore(reader))
+	if chosen := restored.chosen(now); chosen != "seq0" {
+		Fail(t, "unexpected chosen sequencer after restore", chosen)
+	}
+	// Messages expire in the order they were written.
+	restored.prune(now.Add(90 * time.Second).UnixMilli())
+	if restored.message(0, now) != nil || restored.message(1, now) == nil || restored.message(2, now) == nil {
+		Fail(t, "unexpected messages pruned after restore")
+	}
+}
+
+func TestRaftRetainedMessages(t *testing.T) {
+	f := newFsm()
+	now := time.Now()
+	for i := uint64(0); i < maxRetainedMessages+10; i++ {
+		data, err := json.Marshal(&command{Op: opAcquireLockout, Time: now.UnixMilli(), Lockout: &LockoutRequest{
+			Url: "seq0", ExpectedMsgCount: i, MsgCount: i + 1, Message: []byte{byte(i)}, LockoutUntil: now.Add(time.Minute), Retention: time.Hour,
+		}})
+		Require(t, err)
+		if res := f.Apply(&raft.Log{Data: data}); res != nil {
+			Fail(t, "failed to apply lockout", res)
+		}
+	}
+	if len(f.state.Messages) != maxRetainedMessages {
+		Fail(t, "unexpected number of retained messages", len(f.state.Messages))
+	}
+	if f.message(9, now) != nil || f.message(10, now) == nil || f.message(maxRetainedMessages+9, now) == nil {
+		Fail(t, "expected only the most recent messages to be retained")
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// ErrConflict is returned when the lockout can't be acquired, because another sequencer holds it
+// or the message count isn't what the sequencer expected.
+var ErrConflict = errors.New("failed to catch lock")
+
+// The most messages kept, even if they haven't expired yet, so that the state and its snapshots stay small.
+// Only the most recent messages are needed, by sequencers catching up to the chosen one before taking over.
+const maxRetainedMessages = 1024
+
+const (
+	opAcquireLockout      = "acquireLockout"
+	opReleaseChosen       = "releaseChosen"
+	opSetWantsLockout     = "setWantsLockout"
+	opReleaseWantsLockout = "releaseWantsLockout"
+	opAcquireLock         = "acquireLock"
+	opReleaseLock         = "releaseLock"
+	opRegisterApplyUrl    = "registerApplyUrl"
+)
+
+// LockoutRequest acquires or refreshes the chosen sequencer lockout and optionally writes a message, atomically.
+type LockoutRequest struct {
+	Url string `json:"url"`
+	// The message count the sequencer has, which the replicated message count must not be ahead of
+	ExpectedMsgCount uint64 `json:"expectedMsgCount"`
+	MsgCount         uint64 `json:"msgCount"`
+	SignedMsgCount   []byte `json:"signedMsgCount"`
+	// If non-nil, the message at MsgCount-1 and its signature, which is nil if it's part of the message
+	Message      []byte    `json:"message"`
+	MessageSig   []byte    `json:"messageSig"`
+	LockoutUntil time.Time `json:"lockoutUntil"`
+	// How long the message count and message are kept for
+	Retention       time.Duration `json:"retention"`
+	SetWantsLockout bool          `json:"setWantsLockout"`
+	// If the replicated message count is ahead, succeed without changing anything rather than failing.
+	// This is set by the chosen sequencer refreshing its lockout, as then only its own writes can be ahead.
+	IgnoreIfAhead bool `json:"ignoreIfAhead"`
+}
+
+// command is an operation on the coordination state, appended to the raft log.
+type command struct {
+	Op string `json:"op"`
+	// When the leader appended the command, in unix milliseconds, which expiries are checked against,
+	// so that every replica applies it the same way.
+	Time    int64           `json:"time"`
+	Lockout *LockoutRequest `json:"lockout,omitempty"`
+	// The sequencer, lock owner or server the command is for
+	Url   string `json:"url,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Until int64  `json:"until,omitempty"`
+}
+
+type storedMessage struct {
+	Data  []byte `json:"data"`
+	Sig   []byte `json:"sig"`
+	Until int64  `json:"until"`
+}
+
+type heldLock struct {
+	Owner string `json:"owner"`
+	Until int64  `json:"until"`
+}
+
+// fsmState is the coordination state, which is what a snapshot contains.
+// Expiries are in unix milliseconds.
+type fsmState struct {
+	Chosen         string                    `json:"chosen"`
+	ChosenUntil    int64                     `json:"chosenUntil"`
+	MsgCount       uint64                    `json:"msgCount"`
+	SignedMsgCount []byte                    `json:"signedMsgCount"`
+	MsgCountUntil  int64                     `json:"msgCountUntil"`
+	Messages       map[uint64]*storedMessage `json:"messages"`
+	WantsLockout   map[string]int64          `json:"wantsLockout"`
+	Locks          map[string]*heldLock      `json:"locks"`
+	// The URL each raft server accepts commands to apply at, for when it's the leader
+	ApplyUrls map[string]string `json:"applyUrls"`
+}
+
+func newFsmState() *fsmState {
+	return &fsmState{
+		Messages:     make(map[uint64]*storedMessage),
+		WantsLockout: make(map[string]int64),
+		Locks:        make(map[string]*heldLock),
+		ApplyUrls:    make(map[string]string),
+	}
+}
+
+// fsm applies the commands in the raft log to the coordination state. It's what the redis keys are to the
+// redis backend, with the same expiry semantics, except that expiries are checked against the leader's clock
+// when applying, and against the local clock when reading.
+type fsm struct {
+	mutex sync.RWMutex
+	state *fsmState
+	// The indices of the messages in the order they were written, which is the order they expire in
+	pruneQueue []uint64
+}
+
+func newFsm() *fsm {
+	return &fsm{state: newFsmState()}
+}
+
+func (f *fsm) Apply(entry *raft.Log) interface{} {
+	var cmd command
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+		return fmt.Errorf("failed to decode raft command: %w", err)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.prune(cmd.Time)
+	s := f.state
+	switch cmd.Op {
+	case opAcquireLockout:
+		return f.acquireLockout(cmd.Time, cmd.Lockout)
+	case opReleaseChosen:
+		if s.Chosen == cmd.Url {
+			s.Chosen = ""
+			s.ChosenUntil = 0
+		}
+	case opSetWantsLockout:
+		s.WantsLockout[cmd.Url] = cmd.Until
+	case opReleaseWantsLockout:
+		delete(s.WantsLockout, cmd.Url)
+	case opAcquireLock:
+		if held, ok := s.Locks[cmd.Name]; ok && held.Until > cmd.Time && held.Owner != cmd.Url {
+			return fmt.Errorf("%w: %v is held by %v", ErrConflict, cmd.Name, held.Owner)
+		}
+		s.Locks[cmd.Name] = &heldLock{Owner: cmd.Url, Until: cmd.Until}
+	case opReleaseLock:
+		if held, ok := s.Locks[cmd.Name]; ok && held.Owner == cmd.Url {
+			delete(s.Locks, cmd.Name)
+		}
+	case opRegisterApplyUrl:
+		s.ApplyUrls[cmd.Name] = cmd.Url
+	default:
+		return fmt.Errorf("unknown raft command %v", cmd.Op)
+	}
+	return nil
+}
+
+// The mutex must be held by the caller.
+func (f *fsm) acquireLockout(now int64, req *LockoutRequest) error {
+	if req == nil {
+		return errors.New("lockout command without a request")
+	}
+	s := f.state
+	if s.ChosenUntil > now && s.Chosen != req.Url {
+		return fmt.Errorf("%w: raft shows chosen: %s", ErrConflict, s.Chosen)
+	}
+	var remoteMsgCount uint64
+	if s.MsgCountUntil > now {
+		remoteMsgCount = s.MsgCount
+	}
+	if remoteMsgCount > req.ExpectedMsgCount {
+		if req.IgnoreIfAhead {
+			return nil
+		}
+		return fmt.Errorf("%w: expected msg %d found %d", ErrConflict, req.ExpectedMsgCount, remoteMsgCount)
+	}
+	retainUntil := now + req.Retention.Milliseconds()
+	lockoutUntil := req.LockoutUntil.UnixMilli()
+	s.Chosen = req.Url
+	s.ChosenUntil = lockoutUntil
+	s.MsgCount = req.MsgCount
+	s.SignedMsgCount = req.SignedMsgCount
+	s.MsgCountUntil = retainUntil
+	if req.Message != nil && req.MsgCount > 0 {
+		index := req.MsgCount - 1
+		s.Messages[index] = &storedMessage{Data: req.Message, Sig: req.MessageSig, Until: retainUntil}
+		f.pruneQueue = append(f.pruneQueue, index)
+		for len(s.Messages) > maxRetainedMessages && len(f.pruneQueue) > 0 {
+			delete(s.Messages, f.pruneQueue[0])
+			f.pruneQueue = f.pruneQueue[1:]
+		}
+	}
+	if req.SetWantsLockout {
+		s.WantsLockout[req.Url] = lockoutUntil
+	}
+	return nil
+}
+
+// prune deletes the messages which have expired by now. The mutex must be held by the caller.
+func (f *fsm) prune(now int64) {
+	for len(f.pruneQueue) > 0 {
+		index := f.pruneQueue[0]
+		if msg, ok := f.state.Messages[index]; ok {
+			if msg.Until > now {
+				return
+			}
+			delete(f.state.Messages, index)
+		}
+		f.pruneQueue = f.pruneQueue[1:]
+	}
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	// Stored messages and locks are never modified, only replaced, so they can be shared with the copy.
+	s := *f.state
+	copied := newFsmState()
+	for index, msg := range s.Messages {
+		copied.Messages[index] = msg
+	}
+	for url, until := range s.WantsLockout {
+		copied.WantsLockout[url] = until
+	}
+	for name, held := range s.Locks {
+		copied.Locks[name] = held
+	}
+	for id, url := range s.ApplyUrls {
+		copied.ApplyUrls[id] = url
+	}
+	s.Messages, s.WantsLockout, s.Locks, s.ApplyUrls = copied.Messages, copied.WantsLockout, copied.Locks, copied.ApplyUrls
+	return &fsmSnapshot{state: &s}, nil
+}
+
+func (f *fsm) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+	state := newFsmState()
+	if err := json.NewDecoder(snapshot).Decode(state); err != nil {
+		return fmt.Errorf("failed to decode raft snapshot: %w", err)
+	}
+	pruneQueue := make([]uint64, 0, len(state.Messages))
+	for index := range state.Messages {
+		pruneQueue = append(pruneQueue, index)
+	}
+	sort.Slice(pruneQueue, func(i, j int) bool {
+		untilI, untilJ := state.Messages[pruneQueue[i]].Until, state.Messages[pruneQueue[j]].Until
+		if untilI != untilJ {
+			return untilI < untilJ
+		}
+		return pruneQueue[i] < pruneQueue[j]
+	})
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.state = state
+	f.pruneQueue = pruneQueue
+	return nil
+}
+
+type fsmSnapshot struct {
+	state *fsmState
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s.state); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
+
+func isLive(until int64, now time.Time) bool {
+	return until > now.UnixMilli()
+}
+
+func (f *fsm) chosen(now time.Time) string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !isLive(f.state.ChosenUntil, now) {
+		return ""
+	}
+	return f.state.Chosen
+}
+
+// signedMsgCount returns nil if no message count is set.
+func (f *fsm) signedMsgCount(now time.Time) []byte {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !isLive(f.state.MsgCountUntil, now) {
+		return nil
+	}
+	return f.state.SignedMsgCount
+}
+
+func (f *fsm) message(index uint64, now time.Time) *storedMessage {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	msg, ok := f.state.Messages[index]
+	if !ok || !isLive(msg.Until, now) {
+		return nil
+	}
+	return msg
+}
+
+func (f *fsm) wantsLockout(url string, now time.Time) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return isLive(f.state.WantsLockout[url], now)
+}
+
+func (f *fsm) applyUrl(id raft.ServerID) string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.state.ApplyUrls[string(id)]
+}
@@ -0,0 +1,172 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/hashicorp/raft"
+)
+
+var (
+	logPrefix    = []byte("l") // maps a raft log index to the log entry
+	stablePrefix = []byte("s") // maps a raft stable store key to its value
+)
+
+var errNotFound = errors.New("not found")
+
+// Store implements raft's log and stable stores on a database, which should be a table of its own.
+type Store struct {
+	db ethdb.KeyValueStore
+
+	mutex sync.Mutex
+	// The indices of the first and last log entries, which are both 0 if there are none
+	first, last uint64
+}
+
+func logKey(index uint64) []byte {
+	key := make([]byte, len(logPrefix)+8)
+	copy(key, logPrefix)
+	binary.BigEndian.PutUint64(key[len(logPrefix):], index)
+	return key
+}
+
+func NewStore(db ethdb.KeyValueStore) (*Store, error) {
+	s := &Store{db: db}
+	it := db.NewIterator(logPrefix, nil)
+	defer it.Release()
+	for it.Next() {
+		index := binary.BigEndian.Uint64(it.Key()[len(logPrefix):])
+		if s.first == 0 {
+			s.first = index
+		}
+		s.last = index
+	}
+	return s, it.Error()
+}
+
+func (s *Store) FirstIndex() (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.first, nil
+}
+
+func (s *Store) LastIndex() (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.last, nil
+}
+
+func (s *Store) GetLog(index uint64, entry *raft.Log) error {
+	data, err := s.db.Get(logKey(index))
+	if err != nil {
+		if has, hasErr := s.db.Has(logKey(index)); hasErr == nil && !has {
+			return raft.ErrLogNotFound
+		}
+		return err
+	}
+	return json.Unmarshal(data, entry)
+}
+
+func (s *Store) StoreLog(entry *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{entry})
+}
+
+func (s *Store) StoreLogs(entries []*raft.Log) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	batch := s.db.NewBatch()
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(logKey(entry.Index), data); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if s.first == 0 || entry.Index < s.first {
+			s.first = entry.Index
+		}
+		if entry.Index > s.last {
+			s.last = entry.Index
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteRange(min, max uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	batch := s.db.NewBatch()
+	for index := min; index <= max; index++ {
+		if err := batch.Delete(logKey(index)); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Raft only deletes a prefix or a suffix of the log.
+	switch {
+	case min <= s.first && max >= s.last:
+		s.first, s.last = 0, 0
+	case min <= s.first:
+		s.first = max + 1
+	case max >= s.last:
+		s.last = min - 1
+	}
+	return nil
+}
+
+func stableKey(key []byte) []byte {
+	return append(append([]byte{}, stablePrefix...), key...)
+}
+
+func (s *Store) Set(key []byte, val []byte) error {
+	return s.db.Put(stableKey(key), val)
+}
+
+// Get returns an error if the key isn't set, as raft expects.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	has, err := s.db.Has(stableKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errNotFound
+	}
+	return s.db.Get(stableKey(key))
+}
+
+func (s *Store) SetUint64(key []byte, val uint64) error {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], val)
+	return s.Set(key, data[:])
+}
+
+func (s *Store) GetUint64(key []byte) (uint64, error) {
+	data, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, errors.New("invalid uint64 in raft stable store")
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/hashicorp/raft"
+
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+// inmemForwarder hands commands straight to the in-process replica registered at the apply URL.
+type inmemForwarder struct {
+	mutex    sync.Mutex
+	replicas map[string]*Coordinator
+}
+
+func (f *inmemForwarder) forward(_ context.Context, applyUrl string, cmd *command) error {
+	f.mutex.Lock()
+	target := f.replicas[applyUrl]
+	f.mutex.Unlock()
+	if target == nil {
+		return fmt.Errorf("no replica at %v", applyUrl)
+	}
+	if !target.IsLeader() {
+		return &remoteError{msg: "not the raft leader"}
+	}
+	forwarded := *cmd
+	return target.applyAsLeader(&forwarded)
+}
+
+// CreateTestCluster starts a raft coordination group of in-process replicas, connected by in-memory
+// transports, and returns them once they all know where the leader applies commands.
+// They're stopped when ctx is done.
+func CreateTestCluster(ctx context.Context, t *testing.T, replicas int, priorities []string) []*Coordinator {
+	config := DefaultConfig
+	config.Priorities = priorities
+	forwarder := &inmemForwarder{replicas: make(map[string]*Coordinator)}
+	var transports []*raft.InmemTransport
+	for i := 0; i < replicas; i++ {
+		_, transport := raft.NewInmemTransport(raft.ServerAddress(fmt.Sprintf("replica-%d", i)))
+		for _, other := range transports {
+			transport.Connect(other.LocalAddr(), other)
+			other.Connect(transport.LocalAddr(), transport)
+		}
+		transports = append(transports, transport)
+		config.Peers = append(config.Peers, string(transport.LocalAddr()))
+	}
+	var coordinators []*Coordinator
+	for i, transport := range transports {
+		raftConfig := raft.DefaultConfig()
+		raftConfig.HeartbeatTimeout = 50 * time.Millisecond
+		raftConfig.ElectionTimeout = 50 * time.Millisecond
+		raftConfig.LeaderLeaseTimeout = 50 * time.Millisecond
+		raftConfig.CommitTimeout = 5 * time.Millisecond
+		store, err := NewStore(rawdb.NewMemoryDatabase())
+		testhelpers.RequireImpl(t, err)
+		applyUrl := fmt.Sprintf("inmem://replica-%d", i)
+		coordinator, err := newCoordinator(&config, raftConfig, applyUrl, store, raft.NewInmemSnapshotStore(), transport, forwarder, nil)
+		testhelpers.RequireImpl(t, err)
+		forwarder.mutex.Lock()
+		forwarder.replicas[applyUrl] = coordinator
+		forwarder.mutex.Unlock()
+		coordinator.Start(ctx)
+		coordinators = append(coordinators, coordinator)
+	}
+	go func() {
+		<-ctx.Done()
+		for _, coordinator := range coordinators {
+			coordinator.StopAndWait()
+		}
+	}()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		ready := true
+		for _, coordinator := range coordinators {
+			_, leader := coordinator.raft.LeaderWithID()
+			ready = ready && leader != "" && coordinator.fsm.applyUrl(leader) != ""
+		}
+		if ready {
+			return coordinators
+		}
+		if time.Since(start) > 10*time.Second {
+			testhelpers.FailImpl(t, "raft test cluster didn't elect a leader")
+		}
+	}
+}
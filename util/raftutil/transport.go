@@ -0,0 +1,65 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package raftutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// tlsStreamLayer carries raft traffic over mutually authenticated TLS, so that only replicas with a
+// certificate signed by the configured CA can join the group or send it log entries and votes.
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	config    *tls.Config
+}
+
+func newTLSStreamLayer(config *Config, advertise net.Addr) (*tlsStreamLayer, error) {
+	cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading raft certificate and private key: %w", err)
+	}
+	rootCA, err := os.ReadFile(config.TLSRootCA)
+	if err != nil {
+		return nil, fmt.Errorf("error reading raft root CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rootCA) {
+		return nil, errors.New("raft root CA contains no certificates")
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	listener, err := tls.Listen("tcp", config.BindAddr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsStreamLayer{Listener: listener, advertise: advertise, config: tlsConfig}, nil
+}
+
+func (l *tlsStreamLayer) Addr() net.Addr {
+	return l.advertise
+}
+
+func (l *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(string(address))
+	if err != nil {
+		return nil, err
+	}
+	config := l.config.Clone()
+	config.ServerName = host
+	return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", string(address), config)
+}
@@ -19,6 +19,10 @@ const PRIORITIES_KEY string = "coordinator.priorities"            // Read only
 const WANTS_LOCKOUT_KEY_PREFIX string = "coordinator.liveliness." // Per server. Only written by self
 const MESSAGE_KEY_PREFIX string = "coordinator.msg."              // Per Message. Only written by sequencer holding CHOSEN
 const SIGNATURE_KEY_PREFIX string = "coordinator.msg.sig."        // Per Message. Only written by sequencer holding CHOSEN
+const DRAIN_KEY_PREFIX string = "coordinator.drain."              // Per server. Written by the manager, names the target of a planned failover
+const DRAINED_KEY_PREFIX string = "coordinator.drained."          // Per server. Only written by self, the message count it drained at
+const CATCHUP_KEY_PREFIX string = "coordinator.catchup."          // Per server. Written by the manager, the message count to catch up to
+const PROGRESS_KEY_PREFIX string = "coordinator.progress."        // Per server. Only written by self, the message count it has processed
 const WANTS_LOCKOUT_VAL string = "OK"
 const INVALID_VAL string = "INVALID"
 const INVALID_URL string = "<?INVALID-URL?>"
@@ -28,6 +32,10 @@ type RedisCoordinator struct {
 }
 
 func WantsLockoutKeyFor(url string) string { return WANTS_LOCKOUT_KEY_PREFIX + url }
+func DrainKeyFor(url string) string        { return DRAIN_KEY_PREFIX + url }
+func DrainedKeyFor(url string) string      { return DRAINED_KEY_PREFIX + url }
+func CatchupKeyFor(url string) string      { return CATCHUP_KEY_PREFIX + url }
+func ProgressKeyFor(url string) string     { return PROGRESS_KEY_PREFIX + url }
 
 func NewRedisCoordinator(redisUrl string) (*RedisCoordinator, error) {
 	redisClient, err := RedisClientFromURL(redisUrl)
@@ -4,6 +4,7 @@
 package wsbroadcastserver
 
 import (
+	"compress/flate"
 	"context"
 	"fmt"
 	"math/rand"
@@ -39,8 +40,16 @@ type ClientConnection struct {
 
 	compression bool
 	flateReader *wsflate.Reader
+	binary      bool
+
+	filter atomic.Pointer[SubscriptionFilter]
 
 	delay time.Duration
+
+	// Run by the connection's own thread before anything queued is sent
+	catchup func(ctx context.Context, write func(interface{}) error) error
+	// Only used by the connection's own thread
+	flateWriter *flate.Writer
 }
 
 func NewClientConnection(
@@ -50,6 +59,7 @@ func NewClientConnection(
 	requestedSeqNum arbutil.MessageIndex,
 	connectingIP net.IP,
 	compression bool,
+	binary bool,
 	delay time.Duration,
 ) *ClientConnection {
 	return &ClientConnection{
@@ -64,6 +74,7 @@ func NewClientConnection(
 		out:             make(chan []byte, clientManager.config().MaxSendQueue),
 		compression:     compression,
 		flateReader:     NewFlateReader(),
+		binary:          binary,
 		delay:           delay,
 	}
 }
@@ -76,9 +87,37 @@ func (cc *ClientConnection) Compression() bool {
 	return cc.compression
 }
 
+// Binary returns true if the client negotiated the binary feed encoding instead of JSON.
+func (cc *ClientConnection) Binary() bool {
+	return cc.binary
+}
+
+// SubscriptionFilter returns the filter the client subscribed with, or nil if it receives all messages.
+func (cc *ClientConnection) SubscriptionFilter() *SubscriptionFilter {
+	return cc.filter.Load()
+}
+
+func (cc *ClientConnection) SetSubscriptionFilter(filter *SubscriptionFilter) {
+	cc.filter.Store(filter)
+}
+
+// SetCatchup sets a function to send the client messages ahead of everything
+// queued by Write or broadcasts. It must be called before Start, and is run from
+// the connection's own thread so that a long catchup doesn't hold up the caller.
+func (cc *ClientConnection) SetCatchup(catchup func(ctx context.Context, write func(interface{}) error) error) {
+	cc.catchup = catchup
+}
+
 func (cc *ClientConnection) Start(parentCtx context.Context) {
 	cc.StopWaiter.Start(parentCtx, cc)
 	cc.LaunchThread(func(ctx context.Context) {
+		if cc.catchup != nil {
+			if err := cc.catchup(ctx, cc.writeNow); err != nil {
+				logWarn(err, "error sending catchup to client")
+				cc.clientManager.Remove(cc)
+				return
+			}
+		}
 		if cc.delay != 0 {
 			var delayQueue [][]byte
 			t := time.NewTimer(cc.delay)
@@ -165,7 +204,16 @@ func (cc *ClientConnection) Write(x interface{}) error {
 	cc.ioMutex.Lock()
 	defer cc.ioMutex.Unlock()
 
-	notCompressed, compressed, err := serializeMessage(cc.clientManager, x, !cc.compression, cc.compression)
+	if filter := cc.SubscriptionFilter(); filter != nil {
+		if filterable, ok := x.(FilterableMessage); ok {
+			x = filterable.ApplySubscriptionFilter(filter)
+			if x == nil {
+				return nil
+			}
+		}
+	}
+
+	notCompressed, compressed, err := serializeMessage(&cc.clientManager.flateWriter, x, !cc.compression, cc.compression, cc.binary)
 	if err != nil {
 		return err
 	}
@@ -178,6 +226,26 @@ func (cc *ClientConnection) Write(x interface{}) error {
 	return nil
 }
 
+// writeNow sends x to the client directly rather than queueing it, for use by the connection's own thread.
+func (cc *ClientConnection) writeNow(x interface{}) error {
+	if filter := cc.SubscriptionFilter(); filter != nil {
+		if filterable, ok := x.(FilterableMessage); ok {
+			x = filterable.ApplySubscriptionFilter(filter)
+			if x == nil {
+				return nil
+			}
+		}
+	}
+	notCompressed, compressed, err := serializeMessage(&cc.flateWriter, x, !cc.compression, cc.compression, cc.binary)
+	if err != nil {
+		return err
+	}
+	if cc.compression {
+		return cc.writeRaw(compressed.Bytes())
+	}
+	return cc.writeRaw(notCompressed.Bytes())
+}
+
 func (cc *ClientConnection) writeRaw(p []byte) error {
 	cc.ioMutex.Lock()
 	defer cc.ioMutex.Unlock()
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"compress/flate"
 	"context"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -123,11 +124,15 @@ func (cm *ClientManager) Register(
 	requestedSeqNum arbutil.MessageIndex,
 	connectingIP net.IP,
 	compression bool,
+	binary bool,
+	filter *SubscriptionFilter,
 ) *ClientConnection {
 	createClient := ClientConnectionAction{
-		NewClientConnection(conn, desc, cm, requestedSeqNum, connectingIP, compression, cm.config().ClientDelay),
+		NewClientConnection(conn, desc, cm, requestedSeqNum, connectingIP, compression, binary, cm.config().ClientDelay),
 		true,
 	}
+	// Set before registering, so that catchup is already filtered
+	createClient.cc.SetSubscriptionFilter(filter)
 	cm.clientAction <- createClient
 
 	return createClient.cc
@@ -199,40 +204,82 @@ func (cm *ClientManager) Broadcast(bm interface{}) {
 	cm.broadcastChan <- bm
 }
 
+// serializedMessage holds a broadcast message serialized for one encoding and subscription filter.
+type serializedMessage struct {
+	notCompressed bytes.Buffer
+	compressed    bytes.Buffer
+	// set if the subscription filter left nothing to send
+	empty bool
+}
+
 func (cm *ClientManager) doBroadcast(bm interface{}) ([]*ClientConnection, error) {
 	if err := cm.catchupBuffer.OnDoBroadcast(bm); err != nil {
 		return nil, err
 	}
 	config := cm.config()
-	//                                        /-> wsutil.Writer -> not compressed msg buffer
-	// bm -> json.Encoder -> io.MultiWriter -|
-	//                                        \-> cm.flateWriter -> wsutil.Writer -> compressed msg buffer
-
-	notCompressed, compressed, err := serializeMessage(cm, bm, !config.RequireCompression, config.EnableCompression)
-	if err != nil {
-		return nil, err
+	//                                                                   /-> wsutil.Writer -> not compressed msg buffer
+	// bm -> json.Encoder or encoding.BinaryMarshaler -> io.MultiWriter -|
+	//                                                                   \-> cm.flateWriter -> wsutil.Writer -> compressed msg buffer
+	//
+	// Each encoding and subscription filter in use is serialized once per broadcast, and shared by all clients using it.
+
+	serialized := make(map[string]*serializedMessage)
+	serializeFor := func(client *ClientConnection) (*serializedMessage, error) {
+		key := "json"
+		if client.Binary() {
+			key = "binary"
+		}
+		msg := bm
+		filter := client.SubscriptionFilter()
+		filterable, ok := bm.(FilterableMessage)
+		if filter != nil && ok {
+			key += "/" + filter.key()
+		}
+		if s, ok := serialized[key]; ok {
+			return s, nil
+		}
+		if filter != nil && ok {
+			msg = filterable.ApplySubscriptionFilter(filter)
+		}
+		s := &serializedMessage{}
+		if msg == nil {
+			s.empty = true
+		} else {
+			var err error
+			s.notCompressed, s.compressed, err = serializeMessage(&cm.flateWriter, msg, !config.RequireCompression, config.EnableCompression, client.Binary())
+			if err != nil {
+				return nil, err
+			}
+		}
+		serialized[key] = s
+		return s, nil
 	}
 
 	sendQueueTooLargeCount := 0
 	clientDeleteList := make([]*ClientConnection, 0, len(cm.clientPtrMap))
 	for client := range cm.clientPtrMap {
+		if client.Compression() && !config.EnableCompression {
+			log.Warn("disconnecting because client has enabled compression, but compression support is disabled", "client", client.Name)
+			clientDeleteList = append(clientDeleteList, client)
+			continue
+		}
+		if !client.Compression() && config.RequireCompression {
+			log.Warn("disconnecting because client has disabled compression, but compression support is required", "client", client.Name)
+			clientDeleteList = append(clientDeleteList, client)
+			continue
+		}
+		msg, err := serializeFor(client)
+		if err != nil {
+			return nil, err
+		}
+		if msg.empty {
+			continue
+		}
 		var data []byte
 		if client.Compression() {
-			if config.EnableCompression {
-				data = compressed.Bytes()
-			} else {
-				log.Warn("disconnecting because client has enabled compression, but compression support is disabled", "client", client.Name)
-				clientDeleteList = append(clientDeleteList, client)
-				continue
-			}
+			data = msg.compressed.Bytes()
 		} else {
-			if !config.RequireCompression {
-				data = notCompressed.Bytes()
-			} else {
-				log.Warn("disconnecting because client has disabled compression, but compression support is required", "client", client.Name)
-				clientDeleteList = append(clientDeleteList, client)
-				continue
-			}
+			data = msg.notCompressed.Bytes()
 		}
 		select {
 		case client.out <- data:
@@ -254,36 +301,59 @@ func (cm *ClientManager) doBroadcast(bm interface{}) ([]*ClientConnection, error
 	return clientDeleteList, nil
 }
 
-func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutput, enableCompressedOutput bool) (bytes.Buffer, bytes.Buffer, error) {
+// serializeMessage encodes bm as JSON text frames, or if binary is set, as binary frames using its
+// encoding.BinaryMarshaler implementation. The flate writer is created on first use and reused,
+// so it must not be shared between goroutines.
+func serializeMessage(flateWriter **flate.Writer, bm interface{}, enableNonCompressedOutput, enableCompressedOutput bool, binary bool) (bytes.Buffer, bytes.Buffer, error) {
+	opCode := ws.OpText
+	var binaryEncoded []byte
+	if binary {
+		marshaler, ok := bm.(encoding.BinaryMarshaler)
+		if !ok {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("message of type %T has no binary encoding", bm)
+		}
+		var err error
+		binaryEncoded, err = marshaler.MarshalBinary()
+		if err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+		}
+		opCode = ws.OpBinary
+	}
 	var notCompressed bytes.Buffer
 	var compressed bytes.Buffer
 	writers := []io.Writer{}
 	var notCompressedWriter *wsutil.Writer
 	var compressedWriter *wsutil.Writer
 	if enableNonCompressedOutput {
-		notCompressedWriter = wsutil.NewWriter(&notCompressed, ws.StateServerSide, ws.OpText)
+		notCompressedWriter = wsutil.NewWriter(&notCompressed, ws.StateServerSide, opCode)
 		writers = append(writers, notCompressedWriter)
 	}
 	if enableCompressedOutput {
-		if cm.flateWriter == nil {
+		if *flateWriter == nil {
 			var err error
-			cm.flateWriter, err = flate.NewWriterDict(nil, DeflateCompressionLevel, GetStaticCompressorDictionary())
+			*flateWriter, err = flate.NewWriterDict(nil, DeflateCompressionLevel, GetStaticCompressorDictionary())
 			if err != nil {
 				return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to create flate writer: %w", err)
 			}
 		}
-		compressedWriter = wsutil.NewWriter(&compressed, ws.StateServerSide|ws.StateExtended, ws.OpText)
+		compressedWriter = wsutil.NewWriter(&compressed, ws.StateServerSide|ws.StateExtended, opCode)
 		var msg wsflate.MessageState
 		msg.SetCompressed(true)
 		compressedWriter.SetExtensions(&msg)
-		cm.flateWriter.Reset(compressedWriter)
-		writers = append(writers, cm.flateWriter)
+		(*flateWriter).Reset(compressedWriter)
+		writers = append(writers, *flateWriter)
 	}
 
 	multiWriter := io.MultiWriter(writers...)
-	encoder := json.NewEncoder(multiWriter)
-	if err := encoder.Encode(bm); err != nil {
-		return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+	if binary {
+		if _, err := multiWriter.Write(binaryEncoded); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to write message: %w", err)
+		}
+	} else {
+		encoder := json.NewEncoder(multiWriter)
+		if err := encoder.Encode(bm); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+		}
 	}
 	if notCompressedWriter != nil {
 		if err := notCompressedWriter.Flush(); err != nil {
@@ -291,7 +361,7 @@ func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutp
 		}
 	}
 	if compressedWriter != nil {
-		if err := cm.flateWriter.Close(); err != nil {
+		if err := (*flateWriter).Close(); err != nil {
 			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to close flate writer: %w", err)
 		}
 		if err := compressedWriter.Flush(); err != nil {
@@ -0,0 +1,123 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SubscriptionRequest can be sent by clients as a websocket message after the handshake.
+// Each request replaces the client's previous subscription filter, and a request
+// with a null filter removes it. Other messages are ignored. The initial filter is sent as JSON in the
+// HTTPHeaderSubscriptionFilter handshake header instead, so that it also applies
+// to the catchup sent when the client connects.
+type SubscriptionRequest struct {
+	Filter *SubscriptionFilter `json:"filter"`
+}
+
+// SubscriptionFilter narrows down which feed messages are sent to a client.
+// A message is sent if it matches every criterion set.
+type SubscriptionFilter struct {
+	// L1 incoming message kinds to send, or all kinds if empty
+	Kinds []int `json:"kinds,omitempty"`
+	// Message posters to send messages from, or all posters if empty
+	Posters []common.Address `json:"posters,omitempty"`
+	// Send no messages, only confirmations
+	ConfirmationsOnly bool `json:"confirmationsOnly,omitempty"`
+}
+
+func (f *SubscriptionFilter) Validate() error {
+	for _, kind := range f.Kinds {
+		if kind < 0 || kind > 255 {
+			return fmt.Errorf("invalid message kind %d in subscription filter", kind)
+		}
+	}
+	return nil
+}
+
+func (f *SubscriptionFilter) MatchesKind(kind uint8) bool {
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	for _, k := range f.Kinds {
+		if k == int(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *SubscriptionFilter) MatchesPoster(poster common.Address) bool {
+	if len(f.Posters) == 0 {
+		return true
+	}
+	for _, p := range f.Posters {
+		if p == poster {
+			return true
+		}
+	}
+	return false
+}
+
+// key identifies the filter so clients with identical filters can share a serialized message.
+func (f *SubscriptionFilter) key() string {
+	kinds := append([]int{}, f.Kinds...)
+	sort.Ints(kinds)
+	posters := make([]string, 0, len(f.Posters))
+	for _, poster := range f.Posters {
+		posters = append(posters, poster.Hex())
+	}
+	sort.Strings(posters)
+	return fmt.Sprintf("%v/%s/%t", kinds, strings.Join(posters, ","), f.ConfirmationsOnly)
+}
+
+// FilterableMessage is implemented by broadcast messages that can be narrowed down
+// to what a client subscribed to.
+type FilterableMessage interface {
+	// ApplySubscriptionFilter returns the message to send to a client with the given filter,
+	// or nil if nothing should be sent.
+	ApplySubscriptionFilter(filter *SubscriptionFilter) interface{}
+}
+
+// parseSubscriptionFilter parses the filter a client sent in the handshake.
+func parseSubscriptionFilter(data []byte) (*SubscriptionFilter, error) {
+	var filter SubscriptionFilter
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return nil, fmt.Errorf("malformed subscription filter: %w", err)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+var errNotSubscriptionRequest = errors.New("not a subscription request")
+
+// parseSubscriptionRequest returns an error wrapping errNotSubscriptionRequest if data isn't a
+// subscription request, or another error if it is one but its filter is invalid.
+func parseSubscriptionRequest(data []byte) (*SubscriptionFilter, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotSubscriptionRequest, err)
+	}
+	if _, ok := fields["filter"]; !ok {
+		return nil, fmt.Errorf("%w: no filter field", errNotSubscriptionRequest)
+	}
+	var request SubscriptionRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("malformed subscription filter: %w", err)
+	}
+	if request.Filter != nil {
+		if err := request.Filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return request.Filter, nil
+}
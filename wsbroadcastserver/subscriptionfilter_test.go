@@ -0,0 +1,34 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSubscriptionRequest(t *testing.T) {
+	for _, data := range []string{"ping", "[]", `{"foo":1}`} {
+		if _, err := parseSubscriptionRequest([]byte(data)); !errors.Is(err, errNotSubscriptionRequest) {
+			Fail(t, "expected", data, "to be ignored, got", err)
+		}
+	}
+
+	filter, err := parseSubscriptionRequest([]byte(`{"filter":{"kinds":[3]}}`))
+	Require(t, err)
+	if filter == nil || len(filter.Kinds) != 1 || filter.Kinds[0] != 3 {
+		Fail(t, "unexpected filter", filter)
+	}
+
+	filter, err = parseSubscriptionRequest([]byte(`{"filter":null}`))
+	Require(t, err)
+	if filter != nil {
+		Fail(t, "expected null filter to remove the subscription filter, got", filter)
+	}
+
+	_, err = parseSubscriptionRequest([]byte(`{"filter":{"kinds":[256]}}`))
+	if err == nil || errors.Is(err, errNotSubscriptionRequest) {
+		Fail(t, "expected invalid filter to be rejected, got", err)
+	}
+}
@@ -33,6 +33,7 @@ var (
 	HTTPHeaderFeedClientVersion       = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Client-Version")
 	HTTPHeaderRequestedSequenceNumber = textproto.CanonicalMIMEHeaderKey("Arbitrum-Requested-Sequence-Number")
 	HTTPHeaderChainId                 = textproto.CanonicalMIMEHeaderKey("Arbitrum-Chain-Id")
+	HTTPHeaderSubscriptionFilter      = textproto.CanonicalMIMEHeaderKey("Arbitrum-Subscription-Filter")
 	upgradeToWSTimer                  = metrics.NewRegisteredTimer("arb/feed/clients/upgrade/duration", nil)
 	startWithHeaderTimer              = metrics.NewRegisteredTimer("arb/feed/clients/start/duration", nil)
 )
@@ -40,7 +41,11 @@ var (
 const (
 	FeedServerVersion = 2
 	FeedClientVersion = 2
-	LivenessProbeURI  = "livenessprobe"
+	// Clients announcing at least this version can receive the binary encoding,
+	// which the server acknowledges by answering with the same server version.
+	FeedServerVersionBinary = 3
+	FeedClientVersionBinary = 3
+	LivenessProbeURI        = "livenessprobe"
 )
 
 type BroadcasterConfig struct {
@@ -62,19 +67,51 @@ type BroadcasterConfig struct {
 	LogDisconnect      bool                    `koanf:"log-disconnect"`
 	EnableCompression  bool                    `koanf:"enable-compression" reload:"hot"`  // if reloaded to false will cause disconnection of clients with enabled compression on next broadcast
 	RequireCompression bool                    `koanf:"require-compression" reload:"hot"` // if reloaded to true will cause disconnection of clients with disabled compression on next broadcast
+	EnableBinary       bool                    `koanf:"enable-binary" reload:"hot"`       // reloading will affect only new connections
 	LimitCatchup       bool                    `koanf:"limit-catchup" reload:"hot"`
 	MaxCatchup         int                     `koanf:"max-catchup" reload:"hot"`
 	ConnectionLimits   ConnectionLimiterConfig `koanf:"connection-limits" reload:"hot"`
 	ClientDelay        time.Duration           `koanf:"client-delay" reload:"hot"`
+	Archive            FeedArchiveConfig       `koanf:"archive" reload:"hot"`
 }
 
 func (bc *BroadcasterConfig) Validate() error {
 	if !bc.EnableCompression && bc.RequireCompression {
 		return errors.New("require-compression cannot be true while enable-compression is false")
 	}
+	if bc.Archive.Enable && bc.Archive.Dir == "" {
+		return errors.New("archive.dir must be set when archive.enable is true")
+	}
 	return nil
 }
 
+type FeedArchiveConfig struct {
+	Enable         bool          `koanf:"enable"`
+	Dir            string        `koanf:"dir"`
+	MaxMessages    uint64        `koanf:"max-messages" reload:"hot"`
+	MaxAge         time.Duration `koanf:"max-age" reload:"hot"`
+	RefillMessages int           `koanf:"refill-messages"`
+	MaxReplay      uint64        `koanf:"max-replay" reload:"hot"`
+}
+
+func FeedArchiveConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultFeedArchiveConfig.Enable, "keep every broadcast message on disk so clients can catch up from sequence numbers older than the catchup buffer")
+	f.String(prefix+".dir", DefaultFeedArchiveConfig.Dir, "directory to store the feed archive in")
+	f.Uint64(prefix+".max-messages", DefaultFeedArchiveConfig.MaxMessages, "maximum number of messages to keep in the feed archive (0 means unlimited)")
+	f.Duration(prefix+".max-age", DefaultFeedArchiveConfig.MaxAge, "discard messages from the feed archive after they have been stored for this long (0 means forever)")
+	f.Int(prefix+".refill-messages", DefaultFeedArchiveConfig.RefillMessages, "number of the most recent archived messages to load into the catchup buffer at startup")
+	f.Uint64(prefix+".max-replay", DefaultFeedArchiveConfig.MaxReplay, "maximum number of archived messages to replay to a single client, clients requesting more only get the catchup buffer (0 means unlimited)")
+}
+
+var DefaultFeedArchiveConfig = FeedArchiveConfig{
+	Enable:         false,
+	Dir:            "",
+	MaxMessages:    0,
+	MaxAge:         7 * 24 * time.Hour,
+	RefillMessages: 10_000,
+	MaxReplay:      100_000,
+}
+
 type BroadcasterConfigFetcher func() *BroadcasterConfig
 
 func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -96,10 +133,12 @@ func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".log-disconnect", DefaultBroadcasterConfig.LogDisconnect, "log every client disconnect")
 	f.Bool(prefix+".enable-compression", DefaultBroadcasterConfig.EnableCompression, "enable per message deflate compression support")
 	f.Bool(prefix+".require-compression", DefaultBroadcasterConfig.RequireCompression, "require clients to use compression")
+	f.Bool(prefix+".enable-binary", DefaultBroadcasterConfig.EnableBinary, "send the binary feed encoding to clients that request it, alongside JSON to all other clients")
 	f.Bool(prefix+".limit-catchup", DefaultBroadcasterConfig.LimitCatchup, "only supply catchup buffer if requested sequence number is reasonable")
 	f.Int(prefix+".max-catchup", DefaultBroadcasterConfig.MaxCatchup, "the maximum size of the catchup buffer (-1 means unlimited)")
 	ConnectionLimiterConfigAddOptions(prefix+".connection-limits", f)
 	f.Duration(prefix+".client-delay", DefaultBroadcasterConfig.ClientDelay, "delay the first messages sent to each client by this amount")
+	FeedArchiveConfigAddOptions(prefix+".archive", f)
 }
 
 var DefaultBroadcasterConfig = BroadcasterConfig{
@@ -121,10 +160,12 @@ var DefaultBroadcasterConfig = BroadcasterConfig{
 	LogDisconnect:      false,
 	EnableCompression:  true,
 	RequireCompression: false,
+	EnableBinary:       true,
 	LimitCatchup:       false,
 	MaxCatchup:         -1,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
 	ClientDelay:        0,
+	Archive:            DefaultFeedArchiveConfig,
 }
 
 var DefaultTestBroadcasterConfig = BroadcasterConfig{
@@ -146,10 +187,12 @@ var DefaultTestBroadcasterConfig = BroadcasterConfig{
 	LogDisconnect:      false,
 	EnableCompression:  true,
 	RequireCompression: false,
+	EnableBinary:       true,
 	LimitCatchup:       false,
 	MaxCatchup:         -1,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
 	ClientDelay:        0,
+	Archive:            DefaultFeedArchiveConfig,
 }
 
 type WSBroadcastServer struct {
@@ -204,14 +247,25 @@ func (s *WSBroadcastServer) Start(ctx context.Context) error {
 		HTTPHeaderChainId:           []string{strconv.FormatUint(s.chainId, 10)},
 	})
 
+	binaryHeader := ws.HandshakeHeaderHTTP(http.Header{
+		HTTPHeaderFeedServerVersion: []string{strconv.Itoa(FeedServerVersionBinary)},
+		HTTPHeaderChainId:           []string{strconv.FormatUint(s.chainId, 10)},
+	})
+
 	startTime := time.Now()
-	err := s.StartWithHeader(ctx, header)
+	err := s.startWithHeaders(ctx, header, binaryHeader)
 	elapsed := time.Since(startTime)
 	startWithHeaderTimer.Update(elapsed)
 	return err
 }
 
 func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
+	return s.startWithHeaders(ctx, header, nil)
+}
+
+// startWithHeaders starts the server, answering clients that negotiate the binary
+// encoding with binaryHeader. If binaryHeader is nil, all clients are sent JSON.
+func (s *WSBroadcastServer) startWithHeaders(ctx context.Context, header ws.HandshakeHeader, binaryHeader ws.HandshakeHeader) error {
 	s.startMutex.Lock()
 	defer s.startMutex.Unlock()
 	if s.started {
@@ -250,8 +304,11 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 			negotiate = compress.Negotiate
 		}
 		var feedClientVersionSeen bool
+		var binaryRequested bool
+		var binaryAccepted bool
 		var connectingIP net.IP
 		var requestedSeqNum arbutil.MessageIndex
+		var filter *SubscriptionFilter
 		upgrader := ws.Upgrader{
 			OnRequest: func(uri []byte) error {
 				if strings.Contains(string(uri), LivenessProbeURI) {
@@ -278,6 +335,7 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						)
 					}
 					feedClientVersionSeen = true
+					binaryRequested = feedClientVersion >= FeedClientVersionBinary
 				} else if headerName == HTTPHeaderRequestedSequenceNumber {
 					num, err := strconv.ParseUint(string(value), 0, 64)
 					if err != nil {
@@ -287,6 +345,15 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						)
 					}
 					requestedSeqNum = arbutil.MessageIndex(num)
+				} else if headerName == HTTPHeaderSubscriptionFilter {
+					var err error
+					filter, err = parseSubscriptionFilter(value)
+					if err != nil {
+						return ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusBadRequest),
+							ws.RejectionReason(fmt.Sprintf("Malformed HTTP header %s", HTTPHeaderSubscriptionFilter)),
+						)
+					}
 				} else if headerName == HTTPHeaderCloudflareConnectingIP {
 					connectingIP = net.ParseIP(string(value))
 					log.Trace("Client IP parsed from header", "ip", connectingIP, "header", headerName, "value", string(value))
@@ -317,6 +384,10 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 					)
 				}
 
+				if binaryRequested && config.EnableBinary && binaryHeader != nil {
+					binaryAccepted = true
+					return binaryHeader, nil
+				}
 				return header, nil
 			},
 			Negotiate: negotiate,
@@ -372,7 +443,7 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 		// Register incoming client in clientManager.
 		safeConn := writeDeadliner{conn, config.WriteTimeout}
 
-		client := s.clientManager.Register(safeConn, desc, requestedSeqNum, connectingIP, compressionAccepted)
+		client := s.clientManager.Register(safeConn, desc, requestedSeqNum, connectingIP, compressionAccepted, binaryAccepted, filter)
 
 		// Subscribe to events about conn.
 		err = s.poller.Start(desc, func(ev netpoll.Event) {
@@ -390,11 +461,27 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 
 			// receive client messages, close on error
 			s.clientManager.pool.Schedule(func() {
-				// Ignore any messages sent from client, close on any error
-				if _, _, err := client.Receive(ctx, s.config().ReadTimeout); err != nil {
+				// The only messages clients send are subscription requests, close on any read error
+				data, _, err := client.Receive(ctx, s.config().ReadTimeout)
+				if err != nil {
+					s.clientManager.Remove(client)
+					return
+				}
+				if len(data) == 0 {
+					return
+				}
+				filter, err := parseSubscriptionRequest(data)
+				if errors.Is(err, errNotSubscriptionRequest) {
+					log.Debug("ignoring client message", "client", client.Name, "err", err)
+					return
+				}
+				if err != nil {
+					log.Warn("disconnecting because of invalid subscription request", "client", client.Name, "err", err)
 					s.clientManager.Remove(client)
 					return
 				}
+				client.SetSubscriptionFilter(filter)
+				log.Debug("client subscription filter updated", "client", client.Name, "filter", filter)
 			})
 		})
 